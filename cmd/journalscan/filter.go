@@ -3,6 +3,11 @@ package main
 import "github.com/gentlemanautomaton/volmgmt/usn"
 
 func buildFilter(settings Settings) usn.Filter {
+	var ignore usn.Filter
+	if settings.Ignore != nil {
+		ignore = settings.Ignore.Filter()
+	}
+
 	return func(record usn.Record) bool {
 		if !settings.After.IsZero() {
 			if record.TimeStamp.Before(settings.After) {
@@ -40,6 +45,16 @@ func buildFilter(settings Settings) usn.Filter {
 			}
 		}
 
+		if ignore != nil {
+			if ignore(record) {
+				return false
+			}
+		}
+
+		if !settings.Where.Match(record) {
+			return false
+		}
+
 		return true
 	}
 }
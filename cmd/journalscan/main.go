@@ -9,6 +9,9 @@ import (
 	"time"
 
 	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usn/export"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+	"github.com/gentlemanautomaton/volmgmt/volpath"
 )
 
 func usage(errmsg string) {
@@ -21,7 +24,7 @@ func main() {
 	var settings Settings
 	{
 		flag.Usage = func() {
-			fmt.Fprintf(os.Stderr, "usage: %s [-t type[,type...]] [-include regexp] [-exclude regexp] <volume>[,<volume>...]\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "usage: %s [-t type[,type...]] [-include regexp] [-exclude regexp] [-where expr] [-paths mode] [-long] [-format text|json|csv] [-fields field[,field...]] [-image] <volume>[,<volume>...]\n", os.Args[0])
 			flag.PrintDefaults()
 		}
 
@@ -32,17 +35,36 @@ func main() {
 			include    *regexp.Regexp
 			excludeStr string
 			exclude    *regexp.Regexp
+			ignoreFile string
+			ignore     *usnfilter.IgnoreList
+			whereStr   string
+			where      usn.Filter
 			afterStr   string
 			after      time.Time
 			beforeStr  string
 			before     time.Time
+			pathStr    string
+			pathMode   volpath.Mode
+			longPath   bool
+			image      bool
+			formatStr  string
+			format     export.Format
+			fieldsStr  string
+			fields     []export.Field
 		)
 
 		flag.StringVar(&reasonStr, "t", "*", "journal record types to include (comma-separated)")
 		flag.StringVar(&includeStr, "include", "", "regular expression for file match (inclusion)")
 		flag.StringVar(&excludeStr, "exclude", "", "regular expression for file match (exclusion)")
+		flag.StringVar(&ignoreFile, "exclude-from", "", "file of wildcard patterns for file match (exclusion)")
+		flag.StringVar(&whereStr, "where", "", "filter expression for record match (e.g. \"reason = create and ext = exe\")")
 		flag.StringVar(&afterStr, "after", "", "only show entries at or after this time")
 		flag.StringVar(&beforeStr, "before", "", "only show entries at or before this time")
+		flag.StringVar(&pathStr, "paths", "relative", "path display mode (relative, drive or volume)")
+		flag.BoolVar(&longPath, "long", false, "prefix absolute paths with \\\\?\\")
+		flag.BoolVar(&image, "image", false, "read NTFS volume images or block devices instead of live volumes")
+		flag.StringVar(&formatStr, "format", "text", "output format (text, json or csv)")
+		flag.StringVar(&fieldsStr, "fields", "", "comma-separated fields to include in json or csv output (default all)")
 		flag.Parse()
 
 		if flag.NArg() == 0 {
@@ -54,6 +76,28 @@ func main() {
 			usage(fmt.Sprintf("%v", err))
 		}
 
+		pathMode, err = volpath.ParseMode(pathStr)
+		if err != nil {
+			usage(fmt.Sprintf("%v", err))
+		}
+
+		if image && pathMode != volpath.Relative {
+			usage("Images only support relative paths.")
+		}
+
+		format, err = export.ParseFormat(formatStr)
+		if err != nil {
+			usage(fmt.Sprintf("%v", err))
+		}
+		if format != export.Text {
+			info = os.Stderr
+		}
+
+		fields, err = export.ParseFields(fieldsStr)
+		if err != nil {
+			usage(fmt.Sprintf("%v", err))
+		}
+
 		location, err := time.LoadLocation("Local")
 		if err != nil {
 			fmt.Printf("Unable to load local timezone information: %v\n", err)
@@ -62,22 +106,46 @@ func main() {
 
 		include = compileRegex(includeStr)
 		exclude = compileRegex(excludeStr)
+		ignore = loadIgnoreFile(ignoreFile)
+		where = parseWhere(whereStr)
 		after = parseTime(afterStr, location)
 		before = parseTime(beforeStr, location)
 
 		settings = Settings{
-			Reason:   reason,
-			Include:  include,
-			Exclude:  exclude,
-			After:    after,
-			Before:   before,
-			Location: location,
+			Reason:     reason,
+			Include:    include,
+			Exclude:    exclude,
+			Ignore:     ignore,
+			IgnoreFile: ignoreFile,
+			Where:      where,
+			WhereStr:   whereStr,
+			After:      after,
+			Before:     before,
+			Location:   location,
+			PathMode:   pathMode,
+			LongPath:   longPath,
+			Image:      image,
+			Format:     format,
+			Fields:     fields,
 		}
 	}
 
+	enc, err := newEncoder(settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to prepare %s output: %v\n", settings.Format, err)
+		os.Exit(1)
+	}
+
 	paths := flag.Args()
 
 	for _, path := range paths {
-		scan(context.Background(), path, settings)
+		if settings.Image {
+			scanImage(context.Background(), path, settings, enc)
+		} else {
+			scan(context.Background(), path, settings, enc)
+		}
+		if enc != nil {
+			enc.Flush()
+		}
 	}
 }
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/gentlemanautomaton/volmgmt/usn/export"
+)
+
+// info receives informational messages. When records are written in a
+// machine-readable format it is redirected to standard error, so that
+// standard output holds nothing but records.
+var info io.Writer = os.Stdout
+
+// newEncoder returns an encoder that writes records to standard output in
+// the format specified by settings. It returns nil for text output.
+func newEncoder(settings Settings) (export.Encoder, error) {
+	if settings.Format == export.Text {
+		return nil, nil
+	}
+	return export.NewEncoder(settings.Format, os.Stdout, settings.Fields...)
+}
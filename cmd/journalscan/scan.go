@@ -9,47 +9,51 @@ import (
 
 	"github.com/gentlemanautomaton/volmgmt/fileattr"
 	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usn/export"
+	"github.com/gentlemanautomaton/volmgmt/usn/offline"
 	"github.com/gentlemanautomaton/volmgmt/usnfilter"
-	"github.com/gentlemanautomaton/volmgmt/volume"
 )
 
-func scan(ctx context.Context, path string, settings Settings) {
-	fmt.Printf("Path: \"%s\"\n", path)
+// scanImage prints the change journal of an NTFS volume image or block
+// device. Paths are always volume-relative.
+func scanImage(ctx context.Context, path string, settings Settings, enc export.Encoder) {
+	fmt.Fprintf(info, "Image: \"%s\"\n", path)
 
 	if summary := settings.Summary(); summary != "" {
-		fmt.Print(summary)
+		fmt.Fprint(info, summary)
 	}
 
-	vol, err := volume.New(path)
+	journal, err := offline.NewJournal(path)
 	if err != nil {
-		fmt.Printf("Unable to read \"%s\": %v\n", path, err)
+		fmt.Fprintf(info, "Unable to read NTFS volume from \"%s\": %v\n", path, err)
 		return
 	}
-	defer vol.Close()
-
-	printVolume(vol)
-
-	journal := vol.Journal()
 	defer journal.Close()
 
+	scanJournal(ctx, journal, nil, settings, enc)
+}
+
+// scanJournal prints the records of journal that match settings. Paths are
+// formatted by format, which may be nil.
+func scanJournal(ctx context.Context, journal *usn.Journal, format usn.PathFormatter, settings Settings, enc export.Encoder) {
 	data, err := journal.Query()
 	if err != nil {
-		fmt.Printf("Unable to access USN Journal: %v\n", err)
+		fmt.Fprintf(info, "Unable to access USN Journal: %v\n", err)
 		return
 	}
 
-	fmt.Printf("USN Journal: Present, ID: %d, Oldest USN: %d, Next USN: %d, Supporting Versions: %d-%d\n", data.JournalID, data.FirstUSN, data.NextUSN, data.MinSupportedMajorVersion, data.MaxSupportedMajorVersion)
+	fmt.Fprintf(info, "USN Journal: Present, ID: %d, Oldest USN: %d, Next USN: %d, Supporting Versions: %d-%d\n", data.JournalID, data.FirstUSN, data.NextUSN, data.MinSupportedMajorVersion, data.MaxSupportedMajorVersion)
 
-	fmt.Printf("Scanning MFT...")
+	fmt.Fprintf(info, "Scanning MFT...")
 	start := time.Now()
 	cache, err := journal.Cache(ctx, usnfilter.IsDir, 0, data.FirstUSN)
 	end := time.Now()
 	duration := end.Sub(start)
 	if err != nil {
-		fmt.Printf("failed: %v. Ran %s.\n", err, duration)
+		fmt.Fprintf(info, "failed: %v. Ran %s.\n", err, duration)
 		return
 	}
-	fmt.Printf("done. Completed in %s. Found %d directories.\n", duration, cache.Size())
+	fmt.Fprintf(info, "done. Completed in %s. Found %d directories.\n", duration, cache.Size())
 
 	cacheUpdater := func(record usn.Record) {
 		if usnfilter.IsDir(record) {
@@ -61,14 +65,15 @@ func scan(ctx context.Context, path string, settings Settings) {
 
 	cursor, cursorErr := journal.Cursor(cacheUpdater, settings.Reason, filter, cache.Filer)
 	if cursorErr != nil {
-		fmt.Printf("Unable to create USN journal cursor: %v\n", cursorErr)
+		fmt.Fprintf(info, "Unable to create USN journal cursor: %v\n", cursorErr)
 		return
 	}
 	defer cursor.Close()
+	cursor.SetPathFormatter(format)
 	defer func() { printStats(cursor.Stats()) }()
-	defer fmt.Println("--------")
+	defer fmt.Fprintln(info, "--------")
 
-	fmt.Println("--------")
+	fmt.Fprintln(info, "--------")
 
 	buffer := make([]byte, 262144)
 	i := 0
@@ -76,12 +81,21 @@ func scan(ctx context.Context, path string, settings Settings) {
 		records, cursorErr := cursor.Next(buffer)
 		if cursorErr != nil {
 			if cursorErr != io.EOF {
-				fmt.Printf("Unable to retreive USN journal records: %v\n", cursorErr)
+				fmt.Fprintf(info, "Unable to retreive USN journal records: %v\n", cursorErr)
 			}
 			return
 		}
 
 		for _, record := range records {
+			if enc != nil {
+				if err := enc.Encode(record); err != nil {
+					fmt.Fprintf(info, "Unable to write %s output: %v\n", settings.Format, err)
+					return
+				}
+				i++
+				continue
+			}
+
 			id := record.FileReferenceNumber.String()
 			when := record.TimeStamp.In(settings.Location).Format("2006-01-02 15:04:05.000000 MST")
 			attr := record.FileAttributes.Join("", fileattr.FormatCode)
@@ -93,31 +107,12 @@ func scan(ctx context.Context, path string, settings Settings) {
 	}
 }
 
-func printVolume(vol *volume.Volume) {
-	label, labelErr := vol.Label()
-	name, nameErr := vol.Name()
-	devicePath, devicePathErr := vol.DevicePath()
-
-	fmt.Printf("Volume Label: %s\n", strOrErr(label, labelErr))
-	fmt.Printf("Volume Name: %s\n", strOrErr(name, nameErr))
-	fmt.Printf("NT Namespace Device Path: %s\n", strOrErr(devicePath, devicePathErr))
-	fmt.Printf("Device Information: Number %d, Partition %d, Type %d\n", vol.DeviceNumber(), vol.PartitionNumber(), vol.DeviceType())
-	fmt.Printf("Device Description: Removable: %t, Vendor: %s, Product: %s, Revision: %s, OS S/N: %s\n", vol.RemovableMedia(), vol.VendorID(), vol.ProductID(), vol.ProductRevision(), vol.SerialNumber())
-}
-
 func printStats(total, filtered usn.Stats) {
 	var percent float32
 	if total.Records > 0 {
 		percent = float32(filtered.Records) / float32(total.Records) * 100
 	}
-	fmt.Printf("Matched:     %d/%d USN journal records (%.2f%%)\n", filtered.Records, total.Records, percent)
-	fmt.Printf("First Match: %s\n", filtered.First)
-	fmt.Printf("Last Match:  %s\n", filtered.Last)
-}
-
-func strOrErr(s string, err error) string {
-	if err != nil {
-		return fmt.Sprintf("%v", err)
-	}
-	return fmt.Sprintf("\"%s\"", s)
+	fmt.Fprintf(info, "Matched:     %d/%d USN journal records (%.2f%%)\n", filtered.Records, total.Records, percent)
+	fmt.Fprintf(info, "First Match: %s\n", filtered.First)
+	fmt.Fprintf(info, "Last Match:  %s\n", filtered.Last)
 }
@@ -7,16 +7,28 @@ import (
 	"time"
 
 	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usn/export"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+	"github.com/gentlemanautomaton/volmgmt/volpath"
 )
 
 // Settings hold various settings for a scan.
 type Settings struct {
-	Reason   usn.Reason
-	Include  *regexp.Regexp
-	Exclude  *regexp.Regexp
-	After    time.Time
-	Before   time.Time
-	Location *time.Location
+	Reason     usn.Reason
+	Include    *regexp.Regexp
+	Exclude    *regexp.Regexp
+	Ignore     *usnfilter.IgnoreList
+	IgnoreFile string
+	Where      usn.Filter
+	WhereStr   string
+	After      time.Time
+	Before     time.Time
+	Location   *time.Location
+	PathMode   volpath.Mode
+	LongPath   bool
+	Image      bool
+	Format     export.Format
+	Fields     []export.Field
 }
 
 // Summary returns a multiline summary of the settings.
@@ -30,6 +42,14 @@ func (s Settings) Summary() string {
 		output = append(output, fmt.Sprintf("Exclude: %s", s.Exclude))
 	}
 
+	if s.Ignore != nil {
+		output = append(output, fmt.Sprintf("Exclude From: %s (%d patterns)", s.IgnoreFile, s.Ignore.Len()))
+	}
+
+	if s.Where != nil {
+		output = append(output, fmt.Sprintf("Where: %s", s.WhereStr))
+	}
+
 	if !s.After.IsZero() {
 		output = append(output, fmt.Sprintf("After: %s", s.After))
 	}
@@ -38,6 +58,14 @@ func (s Settings) Summary() string {
 		output = append(output, fmt.Sprintf("Before: %s", s.Before))
 	}
 
+	if s.PathMode != volpath.Relative {
+		output = append(output, fmt.Sprintf("Paths: %s", s.PathMode))
+	}
+
+	if s.Format != export.Text {
+		output = append(output, fmt.Sprintf("Format: %s", s.Format))
+	}
+
 	if len(output) == 0 {
 		return ""
 	}
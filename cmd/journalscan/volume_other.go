@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gentlemanautomaton/volmgmt/usn/export"
+)
+
+// scan reports that live volumes cannot be read on this platform.
+func scan(ctx context.Context, path string, settings Settings, enc export.Encoder) {
+	fmt.Fprintf(info, "Unable to read \"%s\": live volumes can only be read on Windows, use -image to read a volume image\n", path)
+}
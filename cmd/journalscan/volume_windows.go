@@ -0,0 +1,59 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gentlemanautomaton/volmgmt/usn/export"
+	"github.com/gentlemanautomaton/volmgmt/volume"
+)
+
+// scan prints the change journal of a live volume.
+func scan(ctx context.Context, path string, settings Settings, enc export.Encoder) {
+	fmt.Fprintf(info, "Path: \"%s\"\n", path)
+
+	if summary := settings.Summary(); summary != "" {
+		fmt.Fprint(info, summary)
+	}
+
+	vol, err := volume.New(path)
+	if err != nil {
+		fmt.Fprintf(info, "Unable to read \"%s\": %v\n", path, err)
+		return
+	}
+	defer vol.Close()
+
+	printVolume(vol)
+
+	formatter, err := vol.PathFormatter(settings.PathMode, settings.LongPath)
+	if err != nil {
+		fmt.Fprintf(info, "Unable to format %s paths: %v\n", settings.PathMode, err)
+		return
+	}
+
+	journal := vol.Journal()
+	defer journal.Close()
+
+	scanJournal(ctx, journal, formatter.Format, settings, enc)
+}
+
+func printVolume(vol *volume.Volume) {
+	label, labelErr := vol.Label()
+	name, nameErr := vol.Name()
+	devicePath, devicePathErr := vol.DevicePath()
+
+	fmt.Fprintf(info, "Volume Label: %s\n", strOrErr(label, labelErr))
+	fmt.Fprintf(info, "Volume Name: %s\n", strOrErr(name, nameErr))
+	fmt.Fprintf(info, "NT Namespace Device Path: %s\n", strOrErr(devicePath, devicePathErr))
+	fmt.Fprintf(info, "Device Information: Number %d, Partition %d, Type %d\n", vol.DeviceNumber(), vol.PartitionNumber(), vol.DeviceType())
+	fmt.Fprintf(info, "Device Description: Removable: %t, Vendor: %s, Product: %s, Revision: %s, OS S/N: %s\n", vol.RemovableMedia(), vol.VendorID(), vol.ProductID(), vol.ProductRevision(), vol.SerialNumber())
+}
+
+func strOrErr(s string, err error) string {
+	if err != nil {
+		return fmt.Sprintf("%v", err)
+	}
+	return fmt.Sprintf("\"%s\"", s)
+}
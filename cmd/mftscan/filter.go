@@ -10,6 +10,11 @@ import (
 type FileInfoFilter func(os.FileInfo) bool
 
 func buildRecordFilter(settings Settings) usn.Filter {
+	var ignore usn.Filter
+	if settings.Ignore != nil {
+		ignore = settings.Ignore.Filter()
+	}
+
 	return func(record usn.Record) bool {
 		if settings.Include != nil {
 			if record.Path == "" {
@@ -35,6 +40,16 @@ func buildRecordFilter(settings Settings) usn.Filter {
 			}
 		}
 
+		if ignore != nil {
+			if ignore(record) {
+				return false
+			}
+		}
+
+		if !settings.Where.Match(record) {
+			return false
+		}
+
 		return true
 	}
 }
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/ntfs/secure"
+	"github.com/gentlemanautomaton/volmgmt/reparse"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usn/export"
+	"github.com/gentlemanautomaton/volmgmt/vss"
+)
+
+// scanImage scans the files of an NTFS volume image. File sizes and owners
+// are read from the image itself, so paths are always volume-relative.
+func scanImage(ctx context.Context, path string, settings Settings, enc export.Encoder) (summary Summary) {
+	fmt.Fprintf(info, "Image: \"%s\"\n", path)
+
+	if settingsSummary := settings.Summary(); settingsSummary != "" {
+		fmt.Fprint(info, settingsSummary)
+	}
+
+	if settings.Owners {
+		summary.Owners = NewOwnerUsage()
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(info, "Unable to read \"%s\": %v\n", path, err)
+		return
+	}
+	defer f.Close()
+
+	var r io.ReaderAt = f
+	if settings.Snapshot > 0 {
+		shadows, err := vss.Open(f)
+		if err != nil {
+			fmt.Fprintf(info, "Unable to read shadow copies from \"%s\": %v\n", path, err)
+			return
+		}
+		if settings.Snapshot > len(shadows.Snapshots) {
+			fmt.Fprintf(info, "Shadow copy %d not found in \"%s\", which has %d\n", settings.Snapshot, path, len(shadows.Snapshots))
+			return
+		}
+		snapshot := shadows.Snapshots[settings.Snapshot-1]
+		fmt.Fprintf(info, "Shadow Copy: %s (%s)\n", snapshot.CopyID, snapshot.Created.In(settings.Location).Format(time.RFC3339))
+		r = snapshot
+	}
+
+	vol, err := ntfs.Open(r)
+	if err != nil {
+		fmt.Fprintf(info, "Unable to read NTFS volume from \"%s\": %v\n", path, err)
+		return
+	}
+
+	var store *secure.Store
+	if settings.Owners {
+		if store, err = secure.Open(vol); err != nil {
+			fmt.Fprintf(info, "Unable to read security descriptors: %v\n", err)
+		}
+	}
+
+	var streams *StreamReport
+	if settings.Streams {
+		streams = &StreamReport{}
+	}
+
+	cache := usn.NewCache()
+	{
+		fmt.Fprintf(info, "Scanning MFT...")
+		start := time.Now()
+		err = cache.ReadFrom(ctx, vol.MFT().Iter(false))
+		duration := time.Since(start)
+		if err != nil {
+			fmt.Fprintf(info, " failed: %v. Ran %s.\n", err, duration)
+			return
+		}
+		fmt.Fprintf(info, " done. Completed in %s.\n", duration)
+	}
+
+	records := cache.Records()
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Path < records[j].Path
+	})
+	summary.Sizes = make([]int64, 0, len(records))
+
+	recordFilter := buildRecordFilter(settings)
+	fileInfoFilter := buildFileInfoFilter(settings)
+	{
+		fmt.Fprintf(info, "Scanning files...\n")
+		start := time.Now()
+		for i, record := range records {
+			if settings.Progress && i%5000 == 0 {
+				fmt.Fprintf(info, "Scanning files... (%d/%d) %d%%\n", i, len(records), percent(i, len(records)))
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			processEntry(i, record, vol, store, recordFilter, fileInfoFilter, enc, streams, settings.List, settings.Verbose, &summary)
+		}
+		fmt.Fprintf(info, "Scanning files... done. Completed in %s.\n", time.Since(start))
+	}
+
+	return summary
+}
+
+func processEntry(index int, record usn.Record, vol *ntfs.Volume, store *secure.Store, recordFilter usn.Filter, fileInfoFilter FileInfoFilter, enc export.Encoder, streams *StreamReport, list, verbose bool, summary *Summary) {
+	if record.FileAttributes.Match(fileattr.ReparsePoint) && !holdsData(index, record, vol, recordFilter, list && enc == nil, verbose) {
+		summary.Skipped++
+		return
+	}
+	if !recordFilter.Match(record) {
+		return
+	}
+	if record.FileAttributes.Match(fileattr.Directory) {
+		summary.Directories++
+		return
+	}
+
+	e, err := vol.Entry(record.FileReferenceNumber.Segment())
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(info, "%10d: %s: can't read MFT entry: %v\n", index, record.Path, err)
+		}
+		summary.Skipped++
+		return
+	}
+
+	fileInfo := entryInfo{entry: e, name: record.FileName}
+	if !fileInfoFilter(fileInfo) {
+		return
+	}
+	size := fileInfo.Size()
+
+	summary.Files++
+	summary.TotalBytes += size
+	summary.Sizes = append(summary.Sizes, size)
+
+	var owner string
+	if summary.Owners != nil {
+		owner = unknownOwner
+		if store != nil {
+			if sid, err := store.Owner(record.SecurityID); err == nil {
+				owner = ownerString(sid)
+			} else if verbose {
+				fmt.Fprintf(info, "%10d: %s: can't determine owner: %v\n", index, record.Path, err)
+			}
+		}
+		summary.Owners.Add(owner, size)
+	}
+
+	if streams != nil {
+		count, bytes := streams.ReportEntry(index, vol, e, record.Path)
+		summary.Streams += int64(count)
+		summary.StreamBytes += bytes
+	} else if enc != nil {
+		if err := enc.Encode(record); err != nil && verbose {
+			fmt.Fprintf(info, "%10d: %s: can't write record: %v\n", index, record.Path, err)
+		}
+	} else if list {
+		printFile(index, record.Path, size, owner)
+	}
+}
+
+// holdsData reads the reparse point of the file described by record and
+// returns true if the file holds its own data, as files that are compressed
+// by the Windows Overlay Filter, deduplicated or held by a cloud provider
+// do. Such files are scanned like any other. Other reparse points, such as
+// links, are not followed, but they are printed if print is true so that
+// links can be followed by hand.
+func holdsData(index int, record usn.Record, vol *ntfs.Volume, recordFilter usn.Filter, print, verbose bool) bool {
+	e, err := vol.Entry(record.FileReferenceNumber.Segment())
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(info, "%10d: %s: can't read MFT entry: %v\n", index, record.Path, err)
+		}
+		return false
+	}
+	p, err := reparse.FromEntry(vol, e)
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(info, "%10d: %s: can't read reparse point: %v\n", index, record.Path, err)
+		}
+		return false
+	}
+	switch p.(type) {
+	case *reparse.WOF, *reparse.Dedup, *reparse.Cloud:
+		return true
+	}
+	if print && recordFilter.Match(record) {
+		printPoint(index, record.Path, p)
+	}
+	return false
+}
+
+// entryInfo describes a file in a volume image by its MFT entry. It
+// implements os.FileInfo.
+type entryInfo struct {
+	entry ntfs.MFTEntry
+	name  string
+}
+
+func (fi entryInfo) Name() string {
+	return fi.name
+}
+
+// Size returns the size of the file's unnamed data stream.
+func (fi entryInfo) Size() int64 {
+	if s, ok := fi.entry.Stream(""); ok {
+		return int64(s.Size)
+	}
+	return 0
+}
+
+func (fi entryInfo) Mode() os.FileMode {
+	if fi.IsDir() {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+// ModTime returns the last write time recorded in the file's
+// $STANDARD_INFORMATION attribute.
+func (fi entryInfo) ModTime() time.Time {
+	if si := fi.entry.StandardInformation; si != nil {
+		return si.Modified
+	}
+	return time.Time{}
+}
+
+func (fi entryInfo) IsDir() bool {
+	return fi.entry.Directory()
+}
+
+// Sys returns the MFT entry of the file.
+func (fi entryInfo) Sys() any {
+	return fi.entry
+}
@@ -11,6 +11,10 @@ import (
 	"time"
 
 	"github.com/gentlemanautomaton/signaler"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usn/export"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+	"github.com/gentlemanautomaton/volmgmt/volpath"
 )
 
 func usage(errmsg string) {
@@ -32,7 +36,7 @@ func main() {
 	var settings Settings
 	{
 		flag.Usage = func() {
-			fmt.Fprintf(os.Stderr, "usage: %s [-include regexp] [-exclude regexp] [-bigger size] [-smaller size] <volume>[,<volume>...]\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "usage: %s [-include regexp] [-exclude regexp] [-where expr] [-bigger size] [-smaller size] [-paths mode] [-long] [-streams] [-owners] [-format text|json|csv] [-fields field[,field...]] [-image [-snapshot n]] <volume>[,<volume>...]\n", os.Args[0])
 			flag.PrintDefaults()
 		}
 
@@ -41,6 +45,10 @@ func main() {
 			include        *regexp.Regexp
 			excludeStr     string
 			exclude        *regexp.Regexp
+			ignoreFile     string
+			ignore         *usnfilter.IgnoreList
+			whereStr       string
+			where          usn.Filter
 			afterStr       string
 			after          time.Time
 			beforeStr      string
@@ -51,34 +59,82 @@ func main() {
 			smallerThan    int64
 			limit          int
 			list           bool
+			streams        bool
+			owners         bool
+			image          bool
+			snapshot       int
 			verbose        bool
 			progress       bool
+			pathStr        string
+			pathMode       volpath.Mode
+			longPath       bool
+			formatStr      string
+			format         export.Format
+			fieldsStr      string
+			fields         []export.Field
 		)
 
 		flag.StringVar(&includeStr, "include", "", "regular expression for file match (inclusion)")
 		flag.StringVar(&excludeStr, "exclude", "", "regular expression for file match (exclusion)")
+		flag.StringVar(&ignoreFile, "exclude-from", "", "file of wildcard patterns for file match (exclusion)")
+		flag.StringVar(&whereStr, "where", "", "filter expression for record match (e.g. \"attr = H and ext = exe\")")
 		flag.StringVar(&afterStr, "after", "", "only include entries at or after this time")
 		flag.StringVar(&beforeStr, "before", "", "only include entries at or before this time")
 		flag.StringVar(&biggerThanStr, "bigger", "", "only include entries bigger than this file size")
 		flag.StringVar(&smallerThanStr, "smaller", "", "only include entries smaller than this file size")
 		flag.BoolVar(&list, "list", false, "print matched file paths")
+		flag.BoolVar(&streams, "streams", false, "report alternate data streams and Zone.Identifier contents")
+		flag.BoolVar(&owners, "owners", false, "report file owners and disk usage by owner")
+		flag.BoolVar(&image, "image", false, "scan NTFS volume image files instead of live volumes")
+		flag.IntVar(&snapshot, "snapshot", 0, "scan the nth shadow copy of each image, counting from 1 for the oldest")
 		flag.BoolVar(&verbose, "v", false, "print errors")
 		flag.BoolVar(&progress, "p", false, "print progress messages")
 		flag.IntVar(&limit, "limit", runtime.NumCPU(), "number of concurrent file operations to perform")
+		flag.StringVar(&pathStr, "paths", "relative", "path display mode (relative, drive or volume)")
+		flag.BoolVar(&longPath, "long", false, "prefix absolute paths with \\\\?\\")
+		flag.StringVar(&formatStr, "format", "text", "output format (text, json or csv)")
+		flag.StringVar(&fieldsStr, "fields", "", "comma-separated fields to include in json or csv output (default all)")
 		flag.Parse()
 
 		if flag.NArg() == 0 {
 			usage("No volume specified.")
 		}
 
+		if snapshot < 0 || (snapshot > 0 && !image) {
+			usage("Shadow copies can only be scanned in images.")
+		}
+
 		location, err := time.LoadLocation("Local")
 		if err != nil {
 			fmt.Printf("Unable to load local timezone information: %v\n", err)
 			os.Exit(1)
 		}
 
+		pathMode, err = volpath.ParseMode(pathStr)
+		if err != nil {
+			usage(fmt.Sprintf("%v", err))
+		}
+
+		format, err = export.ParseFormat(formatStr)
+		if err != nil {
+			usage(fmt.Sprintf("%v", err))
+		}
+		if format != export.Text {
+			if streams {
+				usage("The stream report requires text output.")
+			}
+			info = os.Stderr
+		}
+
+		fields, err = export.ParseFields(fieldsStr)
+		if err != nil {
+			usage(fmt.Sprintf("%v", err))
+		}
+
 		include = compileRegex(includeStr)
 		exclude = compileRegex(excludeStr)
+		ignore = loadIgnoreFile(ignoreFile)
+		where = parseWhere(whereStr)
 		after = parseTime(afterStr, location)
 		before = parseTime(beforeStr, location)
 		biggerThan = parseSize(biggerThanStr)
@@ -87,18 +143,36 @@ func main() {
 		settings = Settings{
 			Include:     include,
 			Exclude:     exclude,
+			Ignore:      ignore,
+			IgnoreFile:  ignoreFile,
+			Where:       where,
+			WhereStr:    whereStr,
 			After:       after,
 			Before:      before,
 			Location:    location,
 			BiggerThan:  biggerThan,
 			SmallerThan: smallerThan,
 			List:        list,
+			Streams:     streams,
+			Owners:      owners,
+			Image:       image,
+			Snapshot:    snapshot,
 			Progress:    progress,
 			Verbose:     verbose,
 			Limit:       limit,
+			PathMode:    pathMode,
+			LongPath:    longPath,
+			Format:      format,
+			Fields:      fields,
 		}
 	}
 
+	enc, err := newEncoder(settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to prepare %s output: %v\n", settings.Format, err)
+		os.Exit(1)
+	}
+
 	paths := flag.Args()
 
 	var summaries []Summary
@@ -108,22 +182,37 @@ func main() {
 		if ctx.Err() != nil {
 			break
 		}
-		summary := scan(ctx, path, settings)
+		var summary Summary
+		if settings.Image {
+			summary = scanImage(ctx, path, settings, enc)
+		} else {
+			summary = scan(ctx, path, settings, enc)
+		}
 		summaries = append(summaries, summary)
+		if enc != nil {
+			enc.Flush()
+		}
 	}
 	end := time.Now()
 	duration := end.Sub(start)
 
 	if len(summaries) == 1 {
-		fmt.Printf("%s\n", summaries[0])
+		fmt.Fprintf(info, "%s\n", summaries[0])
+		if owners := summaries[0].Owners; owners != nil {
+			owners.WriteTo(info)
+		}
 		return
 	}
 
-	fmt.Printf("Total Time: %s.\n", duration)
+	fmt.Fprintf(info, "Total Time: %s.\n", duration)
 
 	for i := range summaries {
-		fmt.Printf("[%d] \"%s\" %s\n", i, paths[i], summaries[i])
+		fmt.Fprintf(info, "[%d] \"%s\" %s\n", i, paths[i], summaries[i])
 	}
 
-	fmt.Printf("[*] \"%s\" %s\n", strings.Join(paths, "|"), Combine(summaries...))
+	combined := Combine(summaries...)
+	fmt.Fprintf(info, "[*] \"%s\" %s\n", strings.Join(paths, "|"), combined)
+	if combined.Owners != nil {
+		combined.Owners.WriteTo(info)
+	}
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usn/export"
+)
+
+// info receives informational messages. When records are written in a
+// machine-readable format it is redirected to standard error, so that
+// standard output holds nothing but records.
+var info io.Writer = os.Stdout
+
+// newEncoder returns an encoder that writes records to standard output in
+// the format specified by settings. It returns nil for text output.
+//
+// Files are scanned concurrently, so the returned encoder is safe for
+// concurrent use.
+func newEncoder(settings Settings) (export.Encoder, error) {
+	if settings.Format == export.Text {
+		return nil, nil
+	}
+	enc, err := export.NewEncoder(settings.Format, os.Stdout, settings.Fields...)
+	if err != nil {
+		return nil, err
+	}
+	return &syncEncoder{enc: enc}, nil
+}
+
+// syncEncoder serializes access to an encoder.
+type syncEncoder struct {
+	mu  sync.Mutex
+	enc export.Encoder
+}
+
+func (e *syncEncoder) Encode(r usn.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Encode(r)
+}
+
+func (e *syncEncoder) Flush() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Flush()
+}
+
+// printFile prints a matched file. The owner is omitted if it is empty.
+func printFile(index int, path string, size int64, owner string) {
+	if owner != "" {
+		fmt.Printf("%10d: %s: %s: %s\n", index, path, humanize.Bytes(uint64(size)), owner)
+		return
+	}
+	fmt.Printf("%10d: %s: %s\n", index, path, humanize.Bytes(uint64(size)))
+}
+
+func percent(i, total int) int {
+	if i <= 0 || total <= 0 {
+		return 0
+	}
+	return (i * 100) / total
+}
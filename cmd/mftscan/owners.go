@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gentlemanautomaton/volmgmt/security"
+)
+
+// unknownOwner is reported for files whose owner cannot be determined.
+const unknownOwner = "(unknown)"
+
+// Usage is the disk usage of a set of files.
+type Usage struct {
+	Files int64
+	Bytes int64
+}
+
+// OwnerUsage accumulates disk usage by file owner. It is safe for
+// concurrent use.
+type OwnerUsage struct {
+	mu     sync.Mutex
+	owners map[string]*Usage
+}
+
+// NewOwnerUsage returns an empty set of owner usage statistics.
+func NewOwnerUsage() *OwnerUsage {
+	return &OwnerUsage{owners: make(map[string]*Usage)}
+}
+
+// Add records a file of the given size owned by owner.
+func (u *OwnerUsage) Add(owner string, size int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	usage, ok := u.owners[owner]
+	if !ok {
+		usage = new(Usage)
+		u.owners[owner] = usage
+	}
+	usage.Files++
+	usage.Bytes += size
+}
+
+// Merge adds the statistics of other to u.
+func (u *OwnerUsage) Merge(other *OwnerUsage) {
+	if other == nil {
+		return
+	}
+	other.mu.Lock()
+	defer other.mu.Unlock()
+	for owner, usage := range other.owners {
+		u.mu.Lock()
+		total, ok := u.owners[owner]
+		if !ok {
+			total = new(Usage)
+			u.owners[owner] = total
+		}
+		total.Files += usage.Files
+		total.Bytes += usage.Bytes
+		u.mu.Unlock()
+	}
+}
+
+// WriteTo writes a table of disk usage by owner to w, largest first.
+func (u *OwnerUsage) WriteTo(w io.Writer) (int64, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	owners := make([]string, 0, len(u.owners))
+	for owner := range u.owners {
+		owners = append(owners, owner)
+	}
+	sort.Slice(owners, func(i, j int) bool {
+		a, b := u.owners[owners[i]], u.owners[owners[j]]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return owners[i] < owners[j]
+	})
+
+	var total int64
+	n, err := fmt.Fprintf(w, "Usage by Owner:\n")
+	total += int64(n)
+	if err != nil {
+		return total, err
+	}
+	for _, owner := range owners {
+		usage := u.owners[owner]
+		n, err := fmt.Fprintf(w, "%12s %10d files  %s\n", humanize.Bytes(uint64(usage.Bytes)), usage.Files, owner)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ownerString returns a description of an owner, which includes its name
+// if it is a well-known security identifier.
+func ownerString(sid security.SID) string {
+	if name, ok := sid.Name(); ok {
+		return fmt.Sprintf("%s (%s)", name, sid)
+	}
+	return sid.String()
+}
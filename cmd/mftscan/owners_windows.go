@@ -0,0 +1,28 @@
+//go:build windows
+
+package main
+
+import (
+	"syscall"
+
+	"github.com/gentlemanautomaton/volmgmt/security"
+	"golang.org/x/sys/windows"
+)
+
+// fileOwner returns the owner of the file open in handle, which must have
+// been opened with READ_CONTROL access.
+func fileOwner(handle syscall.Handle) (string, error) {
+	sd, err := windows.GetSecurityInfo(windows.Handle(handle), windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION)
+	if err != nil {
+		return "", err
+	}
+	owner, _, err := sd.Owner()
+	if err != nil {
+		return "", err
+	}
+	sid, err := security.ParseSIDString(owner.String())
+	if err != nil {
+		return "", err
+	}
+	return ownerString(sid), nil
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gentlemanautomaton/volmgmt/reparse"
+)
+
+// printPoint prints a reparse point. Links are printed with their targets.
+func printPoint(index int, path string, p reparse.Point) {
+	if link, ok := p.(reparse.Link); ok {
+		fmt.Printf("%10d: %s: %s -> %s\n", index, path, p.Tag(), link.Target())
+		return
+	}
+	fmt.Printf("%10d: %s: %s\n", index, path, p.Tag())
+}
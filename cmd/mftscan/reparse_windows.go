@@ -0,0 +1,37 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/gentlemanautomaton/volmgmt/fileapi"
+	"github.com/gentlemanautomaton/volmgmt/reparse"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// printReparsePoint prints the reparse point of the file described by
+// record, which is not followed by the scan. Links are printed with their
+// targets.
+func printReparsePoint(index int, record usn.Record, volHandle syscall.Handle, verbose bool) {
+	const shareMode = uint32(syscall.FILE_SHARE_READ | syscall.FILE_SHARE_WRITE | syscall.FILE_SHARE_DELETE)
+	const flags = syscall.FILE_FLAG_BACKUP_SEMANTICS | syscall.FILE_FLAG_OPEN_REPARSE_POINT
+	fileHandle, err := fileapi.OpenFileByID(volHandle, record.FileReferenceNumber, 0, shareMode, flags)
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(info, "%10d: %s: can't open reparse point: %v\n", index, record.Path, err)
+		}
+		return
+	}
+	defer syscall.CloseHandle(fileHandle)
+
+	p, err := reparse.Get(fileHandle)
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(info, "%10d: %s: can't read reparse point: %v\n", index, record.Path, err)
+		}
+		return
+	}
+	printPoint(index, record.Path, p)
+}
@@ -7,21 +7,37 @@ import (
 	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usn/export"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+	"github.com/gentlemanautomaton/volmgmt/volpath"
 )
 
 // Settings hold various settings for a scan.
 type Settings struct {
 	Include     *regexp.Regexp
 	Exclude     *regexp.Regexp
+	Ignore      *usnfilter.IgnoreList
+	IgnoreFile  string
+	Where       usn.Filter
+	WhereStr    string
 	After       time.Time
 	Before      time.Time
 	Location    *time.Location
 	BiggerThan  int64 // In bytes
 	SmallerThan int64 // In bytes
 	List        bool
+	Streams     bool
+	Owners      bool
+	Image       bool // Paths are NTFS volume images
+	Snapshot    int  // Shadow copy of each image to scan, counting from 1
 	Progress    bool
 	Verbose     bool
 	Limit       int
+	PathMode    volpath.Mode
+	LongPath    bool
+	Format      export.Format
+	Fields      []export.Field
 }
 
 // Summary returns a multiline summary of the settings.
@@ -35,6 +51,14 @@ func (s Settings) Summary() string {
 		output = append(output, fmt.Sprintf("Exclude: %s", s.Exclude))
 	}
 
+	if s.Ignore != nil {
+		output = append(output, fmt.Sprintf("Exclude From: %s (%d patterns)", s.IgnoreFile, s.Ignore.Len()))
+	}
+
+	if s.Where != nil {
+		output = append(output, fmt.Sprintf("Where: %s", s.WhereStr))
+	}
+
 	if !s.After.IsZero() {
 		output = append(output, fmt.Sprintf("After: %s", s.After))
 	}
@@ -55,6 +79,22 @@ func (s Settings) Summary() string {
 		output = append(output, "List Files: On")
 	}
 
+	if s.Streams {
+		output = append(output, "Alternate Streams: On")
+	}
+
+	if s.Owners {
+		output = append(output, "Owners: On")
+	}
+
+	if s.Image {
+		output = append(output, "Image: On")
+	}
+
+	if s.Snapshot > 0 {
+		output = append(output, fmt.Sprintf("Snapshot: %d", s.Snapshot))
+	}
+
 	if s.Progress {
 		output = append(output, "Progress: On")
 	}
@@ -67,6 +107,14 @@ func (s Settings) Summary() string {
 		output = append(output, fmt.Sprintf("Concurrent Reads: %d", s.Limit))
 	}
 
+	if s.PathMode != volpath.Relative {
+		output = append(output, fmt.Sprintf("Paths: %s", s.PathMode))
+	}
+
+	if s.Format != export.Text {
+		output = append(output, fmt.Sprintf("Format: %s", s.Format))
+	}
+
 	if len(output) == 0 {
 		return ""
 	}
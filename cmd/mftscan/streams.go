@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/volpath"
+)
+
+// maxZoneIdentifier is the largest Zone.Identifier stream that will be
+// printed.
+const maxZoneIdentifier = 4096
+
+// StreamReport prints the alternate data streams of files as they are
+// scanned. It is safe for concurrent use.
+type StreamReport struct {
+	// root formats paths that can be used to open the streams of a file,
+	// regardless of the path mode selected for display.
+	root volpath.Formatter
+
+	mu sync.Mutex
+}
+
+// ReportEntry prints the alternate data streams of the file described by
+// e, which was read from vol. It returns the number of streams that were
+// found and their total size. Files without alternate data streams are not
+// printed.
+func (r *StreamReport) ReportEntry(index int, vol *ntfs.Volume, e ntfs.MFTEntry, path string) (count int, size int64) {
+	streams := e.AlternateStreams()
+	if len(streams) == 0 {
+		return 0, 0
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%10d: %s\n", index, path)
+	for _, s := range streams {
+		size += int64(s.Size)
+		fmt.Fprintf(&b, "%10s  :%s:$DATA: %s\n", "", s.Name, humanize.Bytes(s.Size))
+		if !strings.EqualFold(s.Name, ntfs.ZoneIdentifier) {
+			continue
+		}
+		zone, err := readEntryStream(vol, s, maxZoneIdentifier)
+		writeZone(&b, zone, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Print(b.String())
+
+	return len(streams), size
+}
+
+// writeZone writes the contents of a Zone.Identifier stream to b, or the
+// error that prevented it from being read.
+func writeZone(b *strings.Builder, zone []byte, err error) {
+	if err != nil {
+		fmt.Fprintf(b, "%10s    can't read stream: %v\n", "", err)
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(zone)), "\n") {
+		fmt.Fprintf(b, "%10s    %s\n", "", strings.TrimRight(line, "\r"))
+	}
+}
+
+// readEntryStream reads up to limit bytes from stream s of a file in vol.
+func readEntryStream(vol *ntfs.Volume, s ntfs.Stream, limit int64) ([]byte, error) {
+	sr, err := vol.StreamReader(s)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(io.LimitReader(io.NewSectionReader(sr, 0, sr.Size()), limit))
+}
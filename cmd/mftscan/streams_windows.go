@@ -0,0 +1,75 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gentlemanautomaton/volmgmt/fileapi"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/volpath"
+	"golang.org/x/sys/windows"
+)
+
+// NewStreamReport returns a stream report for the volume with the given
+// volume name.
+func NewStreamReport(volName string) (*StreamReport, error) {
+	root, err := volpath.New(volpath.VolumeName, volName, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamReport{root: root}, nil
+}
+
+// Report prints the alternate data streams of the file open in handle,
+// whose volume-relative path is relPath. It returns the number of streams
+// that were found and their total size. Files without alternate data
+// streams are not printed.
+func (r *StreamReport) Report(index int, handle syscall.Handle, path, relPath string) (count int, size int64, err error) {
+	var info fileapi.StreamInfo
+	if err := fileapi.GetFileInformationByHandleEx(handle, &info); err != nil {
+		if errors.Is(err, windows.ERROR_HANDLE_EOF) {
+			// The file has no streams at all
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	streams := info.Named()
+	if len(streams) == 0 {
+		return 0, 0, nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%10d: %s\n", index, path)
+	for _, s := range streams {
+		size += s.Size
+		fmt.Fprintf(&b, "%10s  %s: %s\n", "", s, humanize.Bytes(uint64(s.Size)))
+		if !strings.EqualFold(s.Name, ntfs.ZoneIdentifier) {
+			continue
+		}
+		zone, err := readStream(r.root.Format(relPath)+":"+s.Name, maxZoneIdentifier)
+		writeZone(&b, zone, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Print(b.String())
+
+	return len(streams), size, nil
+}
+
+// readStream reads up to limit bytes from the file or stream at path.
+func readStream(path string, limit int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, limit))
+}
@@ -13,17 +13,24 @@ type Summary struct {
 	Files       int64
 	TotalBytes  int64
 	Sizes       []int64
+	Streams     int64 // Alternate data streams, if reported
+	StreamBytes int64
+	Owners      *OwnerUsage // Nil unless owners are reported
 }
 
 // String returns a string representation of the summary.
 func (s Summary) String() string {
-	return fmt.Sprintf("Skipped: %d, Directories: %d, Files: %d (Total: %s, Mean: %s, Median: %s)",
+	str := fmt.Sprintf("Skipped: %d, Directories: %d, Files: %d (Total: %s, Mean: %s, Median: %s)",
 		s.Skipped,
 		s.Directories,
 		s.Files,
 		humanize.Bytes(uint64(s.TotalBytes)),
 		humanize.Bytes(uint64(s.Mean())),
 		humanize.Bytes(uint64(s.Median())))
+	if s.Streams > 0 {
+		str += fmt.Sprintf(", Alternate Streams: %d (Total: %s)", s.Streams, humanize.Bytes(uint64(s.StreamBytes)))
+	}
+	return str
 }
 
 // Mean returns the mean file size.
@@ -56,10 +63,18 @@ func Combine(summaries ...Summary) Summary {
 	}
 	combined.Sizes = make([]int64, 0, sizeCount)
 	for _, s := range summaries {
+		if s.Owners != nil {
+			if combined.Owners == nil {
+				combined.Owners = NewOwnerUsage()
+			}
+			combined.Owners.Merge(s.Owners)
+		}
 		combined.Skipped += s.Skipped
 		combined.Directories += s.Directories
 		combined.Files += s.Files
 		combined.TotalBytes += s.TotalBytes
+		combined.Streams += s.Streams
+		combined.StreamBytes += s.StreamBytes
 		combined.Sizes = append(combined.Sizes, s.Sizes...)
 	}
 	return combined
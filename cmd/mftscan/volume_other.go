@@ -0,0 +1,16 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gentlemanautomaton/volmgmt/usn/export"
+)
+
+// scan reports that live volumes cannot be scanned on this platform.
+func scan(ctx context.Context, path string, settings Settings, enc export.Encoder) (summary Summary) {
+	fmt.Fprintf(info, "Unable to read \"%s\": live volumes can only be scanned on Windows, use -image to scan a volume image\n", path)
+	return summary
+}
@@ -0,0 +1,252 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileapi"
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usn/export"
+	"github.com/gentlemanautomaton/volmgmt/volpath"
+	"github.com/gentlemanautomaton/volmgmt/volume"
+	"golang.org/x/sys/windows"
+)
+
+func scan(ctx context.Context, path string, settings Settings, enc export.Encoder) (summary Summary) {
+	fmt.Fprintf(info, "Path: \"%s\"\n", path)
+
+	if settingsSummary := settings.Summary(); settingsSummary != "" {
+		fmt.Fprint(info, settingsSummary)
+	}
+
+	if settings.Owners {
+		summary.Owners = NewOwnerUsage()
+	}
+
+	vol, err := volume.New(path)
+	if err != nil {
+		fmt.Fprintf(info, "Unable to read \"%s\": %v\n", path, err)
+		return
+	}
+	defer vol.Close()
+
+	volName, err := vol.Name()
+	if err != nil {
+		fmt.Fprintf(info, "Unable to determine volume name for \"%s\": %v\n", path, err)
+		return
+	}
+	volHandle := vol.Handle()
+
+	formatter, err := vol.PathFormatter(settings.PathMode, settings.LongPath)
+	if err != nil {
+		fmt.Fprintf(info, "Unable to format %s paths: %v\n", settings.PathMode, err)
+		return
+	}
+
+	var streams *StreamReport
+	if settings.Streams {
+		streams, err = NewStreamReport(volName)
+		if err != nil {
+			fmt.Fprintf(info, "Unable to prepare stream report: %v\n", err)
+			return
+		}
+	}
+
+	mft := vol.MFT()
+	defer mft.Close()
+
+	iter, err := mft.Enumerate(nil, usn.Min, usn.Max)
+	if err != nil {
+		fmt.Fprintf(info, "Unable to open MFT: %v\n", err)
+		return
+	}
+	defer iter.Close()
+
+	cache := usn.NewCache()
+	{
+		fmt.Fprintf(info, "Scanning MFT...")
+		start := time.Now()
+		err = cache.ReadFrom(ctx, iter)
+		end := time.Now()
+		duration := end.Sub(start)
+		if err != nil {
+			fmt.Fprintf(info, " failed: %v. Ran %s.\n", err, duration)
+			return
+		}
+		fmt.Fprintf(info, " done. Completed in %s.\n", duration)
+	}
+
+	var records []usn.Record
+	{
+		fmt.Fprintf(info, "Sorting file paths...")
+		start := time.Now()
+		records = cache.Records()
+		sort.Slice(records, func(i, j int) bool {
+			return records[i].Path < records[j].Path
+		})
+		end := time.Now()
+		duration := end.Sub(start)
+		fmt.Fprintf(info, " done. Completed in %s.\n", duration)
+	}
+	summary.Sizes = make([]int64, 0, len(records))
+
+	recordFilter := buildRecordFilter(settings)
+	fileInfoFilter := buildFileInfoFilter(settings)
+	{
+		fmt.Fprintf(info, "Scanning files...\n")
+		start := time.Now()
+		type token struct{}
+		sem := make(chan token, settings.Limit)
+		for i, record := range records {
+			sem <- token{}
+			if settings.Progress && i%5000 == 0 {
+				fmt.Fprintf(info, "Scanning files... (%d/%d) %d%%\n", i, len(records), percent(i, len(records)))
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			go func(i int, record usn.Record) {
+				processRecord(i, record, volHandle, volName, recordFilter, fileInfoFilter, formatter, enc, streams, settings.List, settings.Verbose, &summary)
+				<-sem
+			}(i, record)
+		}
+		// Wait for outstanding file operations to finish
+		for i := 0; i < cap(sem); i++ {
+			sem <- token{}
+		}
+		end := time.Now()
+		duration := end.Sub(start)
+		fmt.Fprintf(info, "Scanning files... done. Completed in %s.\n", duration)
+	}
+
+	return summary
+}
+
+func processRecord(index int, record usn.Record, volHandle syscall.Handle, volName string, recordFilter usn.Filter, fileInfoFilter FileInfoFilter, formatter volpath.Formatter, enc export.Encoder, streams *StreamReport, list, verbose bool, summary *Summary) {
+	if record.FileAttributes.Match(fileattr.ReparsePoint) {
+		// Reparse points are not followed, but they are listed so that
+		// links can be followed by hand
+		summary.Skipped++
+		if list && enc == nil && recordFilter.Match(record) {
+			record.Path = formatter.Format(record.Path)
+			printReparsePoint(index, record, volHandle, verbose)
+		}
+		return
+	}
+	if !recordFilter.Match(record) {
+		return
+	}
+	if record.FileAttributes.Match(fileattr.Directory) {
+		summary.Directories++
+		return
+	}
+
+	// Filters operate on volume-relative paths, so the path is only
+	// formatted once the record has been accepted
+	relPath := record.Path
+	record.Path = formatter.Format(record.Path)
+
+	access := uint32(windows.READ_CONTROL)
+	if streams != nil {
+		access |= windows.FILE_READ_ATTRIBUTES
+	}
+	const shareMode = uint32(syscall.FILE_SHARE_READ | syscall.FILE_SHARE_WRITE | syscall.FILE_SHARE_DELETE)
+	fileHandle, err := fileapi.OpenFileByID(volHandle, record.FileReferenceNumber, access, shareMode, syscall.FILE_FLAG_BACKUP_SEMANTICS)
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(info, "%10d: %s: can't open file: %v\n", index, record.Path, err)
+		}
+		summary.Skipped++
+		return
+	}
+	defer syscall.CloseHandle(fileHandle)
+
+	fileInfo := fileapi.FileInfoForHandle{
+		FileName: record.FileName,
+	}
+	fileInfo.ByHandleFileInformation, err = fileapi.GetFileInformationByHandle(fileHandle)
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(info, "%10d: %s: can't get file info: %v\n", index, record.Path, err)
+		}
+		summary.Skipped++
+		return
+	}
+
+	if fileInfo.IsDir() {
+		return
+	}
+	if !fileInfoFilter(fileInfo) {
+		return
+	}
+	size := fileInfo.Size()
+
+	summary.Files++
+	summary.TotalBytes += size
+	summary.Sizes = append(summary.Sizes, size)
+
+	var owner string
+	if summary.Owners != nil {
+		owner, err = fileOwner(fileHandle)
+		if err != nil {
+			if verbose {
+				fmt.Fprintf(info, "%10d: %s: can't determine owner: %v\n", index, record.Path, err)
+			}
+			owner = unknownOwner
+		}
+		summary.Owners.Add(owner, size)
+	}
+
+	if streams != nil {
+		count, bytes, err := streams.Report(index, fileHandle, record.Path, relPath)
+		if err != nil {
+			if verbose {
+				fmt.Fprintf(info, "%10d: %s: can't enumerate streams: %v\n", index, record.Path, err)
+			}
+			return
+		}
+		summary.Streams += int64(count)
+		summary.StreamBytes += bytes
+	} else if enc != nil {
+		if err := enc.Encode(record); err != nil && verbose {
+			fmt.Fprintf(info, "%10d: %s: can't write record: %v\n", index, record.Path, err)
+		}
+	} else if list {
+		printFile(index, record.Path, size, owner)
+	}
+}
+
+func printVolume(vol *volume.Volume) {
+	label, labelErr := vol.Label()
+	name, nameErr := vol.Name()
+	devicePath, devicePathErr := vol.DevicePath()
+
+	fmt.Fprintf(info, "Volume Label: %s\n", strOrErr(label, labelErr))
+	fmt.Fprintf(info, "Volume Name: %s\n", strOrErr(name, nameErr))
+	fmt.Fprintf(info, "NT Namespace Device Path: %s\n", strOrErr(devicePath, devicePathErr))
+	fmt.Fprintf(info, "Device Information: Number %d, Partition %d, Type %d\n", vol.DeviceNumber(), vol.PartitionNumber(), vol.DeviceType())
+	fmt.Fprintf(info, "Device Description: Removable: %t, Vendor: %s, Product: %s, Revision: %s, OS S/N: %s\n", vol.RemovableMedia(), vol.VendorID(), vol.ProductID(), vol.ProductRevision(), vol.SerialNumber())
+}
+
+func printStats(total, filtered usn.Stats) {
+	var percent float32
+	if total.Records > 0 {
+		percent = float32(filtered.Records) / float32(total.Records) * 100
+	}
+	fmt.Fprintf(info, "Matched:     %d/%d USN journal records (%.2f%%)\n", filtered.Records, total.Records, percent)
+	fmt.Fprintf(info, "First Match: %s\n", filtered.First)
+	fmt.Fprintf(info, "Last Match:  %s\n", filtered.Last)
+}
+
+func strOrErr(s string, err error) string {
+	if err != nil {
+		return fmt.Sprintf("%v", err)
+	}
+	return fmt.Sprintf("\"%s\"", s)
+}
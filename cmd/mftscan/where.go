@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+)
+
+func parseWhere(expr string) usn.Filter {
+	filter, err := usnfilter.Parse(expr)
+	if err != nil {
+		usage(fmt.Sprintf("Unable to parse filter expression \"%s\": %v", expr, err))
+	}
+	return filter
+}
@@ -0,0 +1,24 @@
+package main
+
+import "github.com/gentlemanautomaton/volmgmt/usn"
+
+func buildFilter(settings Settings) usn.Filter {
+	var ignore usn.Filter
+	if settings.Ignore != nil {
+		ignore = settings.Ignore.Filter()
+	}
+
+	if ignore == nil && settings.Where == nil {
+		return nil
+	}
+
+	return func(record usn.Record) bool {
+		if ignore != nil {
+			if ignore(record) {
+				return false
+			}
+		}
+
+		return settings.Where.Match(record)
+	}
+}
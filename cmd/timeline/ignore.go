@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+)
+
+func loadIgnoreFile(path string) *usnfilter.IgnoreList {
+	if path == "" {
+		return nil
+	}
+	list, err := usnfilter.LoadIgnoreFile(path)
+	if err != nil {
+		usage(fmt.Sprintf("Unable to load exclusion file: %v", err))
+	}
+	return list
+}
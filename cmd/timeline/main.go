@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/timeline"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+)
+
+// info receives informational messages. Standard output holds nothing but
+// the timeline.
+var info io.Writer = os.Stderr
+
+func usage(errmsg string) {
+	fmt.Fprintf(os.Stderr, "%s\n\n", errmsg)
+	flag.Usage()
+	os.Exit(1)
+}
+
+func main() {
+	var settings Settings
+	{
+		flag.Usage = func() {
+			fmt.Fprintf(os.Stderr, "usage: %s [-image file] [-mft file] [-usnjrnl file] [-sources source[,source...]] [-after time] [-before time] [-where expr] [-exclude-from file] [-format csv|body] [<volume>[,<volume>...]]\n", os.Args[0])
+			flag.PrintDefaults()
+		}
+
+		var (
+			image      string
+			mft        string
+			usnjrnl    string
+			sourcesStr string
+			ignoreFile string
+			ignore     *usnfilter.IgnoreList
+			whereStr   string
+			where      usn.Filter
+			afterStr   string
+			after      time.Time
+			beforeStr  string
+			before     time.Time
+			format     string
+		)
+
+		flag.StringVar(&image, "image", "", "raw NTFS volume image to read the MFT and change journal from")
+		flag.StringVar(&mft, "mft", "", "extracted $MFT file")
+		flag.StringVar(&usnjrnl, "usnjrnl", "", "extracted $UsnJrnl:$J file (paths are resolved with -mft)")
+		flag.StringVar(&sourcesStr, "sources", "mft,journal", "sources to include (mft, journal)")
+		flag.StringVar(&ignoreFile, "exclude-from", "", "file of wildcard patterns for file match (exclusion)")
+		flag.StringVar(&whereStr, "where", "", "filter expression for record match (e.g. \"ext = exe\")")
+		flag.StringVar(&afterStr, "after", "", "only include events at or after this time")
+		flag.StringVar(&beforeStr, "before", "", "only include events at or before this time")
+		flag.StringVar(&format, "format", "csv", "output format (csv or body)")
+		flag.Parse()
+
+		if image == "" && mft == "" && usnjrnl == "" && flag.NArg() == 0 {
+			usage("No image, file or volume specified.")
+		}
+
+		if format != "csv" && format != "body" {
+			usage(fmt.Sprintf("Unknown output format \"%s\".", format))
+		}
+
+		var mftSource, journalSource bool
+		for _, source := range strings.Split(sourcesStr, ",") {
+			switch strings.ToLower(strings.TrimSpace(source)) {
+			case "mft":
+				mftSource = true
+			case "journal":
+				journalSource = true
+			default:
+				usage(fmt.Sprintf("Unknown source \"%s\".", source))
+			}
+		}
+
+		location, err := time.LoadLocation("Local")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to load local timezone information: %v\n", err)
+			os.Exit(1)
+		}
+
+		ignore = loadIgnoreFile(ignoreFile)
+		where = parseWhere(whereStr)
+		after = parseTime(afterStr, location)
+		before = parseTime(beforeStr, location)
+
+		settings = Settings{
+			Image:          image,
+			MFT:            mft,
+			UsnJrnl:        usnjrnl,
+			Volumes:        flag.Args(),
+			IncludeMFT:     mftSource,
+			IncludeJournal: journalSource,
+			Ignore:         ignore,
+			IgnoreFile:     ignoreFile,
+			Where:          where,
+			WhereStr:       whereStr,
+			After:          after,
+			Before:         before,
+			Format:         format,
+		}
+	}
+
+	if summary := settings.Summary(); summary != "" {
+		fmt.Fprint(info, summary)
+	}
+
+	ctx := context.Background()
+	t := timeline.New(timeline.Options{
+		After:  settings.After,
+		Before: settings.Before,
+		Filter: buildFilter(settings),
+	})
+
+	ok := true
+	if settings.Image != "" {
+		ok = readImage(ctx, t, settings) && ok
+	}
+	if settings.MFT != "" || settings.UsnJrnl != "" {
+		ok = readFiles(ctx, t, settings) && ok
+	}
+	for _, path := range settings.Volumes {
+		ok = readVolume(ctx, t, path, settings) && ok
+	}
+
+	fmt.Fprintf(info, "Writing %d entries...\n", t.Len())
+
+	var err error
+	switch settings.Format {
+	case "body":
+		err = timeline.WriteBodyfile(os.Stdout, t.Entries())
+	default:
+		err = timeline.WriteCSV(os.Stdout, t.Events())
+	}
+	if err != nil {
+		fmt.Fprintf(info, "Unable to write %s output: %v\n", settings.Format, err)
+		os.Exit(1)
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+)
+
+// Settings hold various settings for a timeline.
+type Settings struct {
+	Image          string
+	MFT            string
+	UsnJrnl        string
+	Volumes        []string
+	IncludeMFT     bool
+	IncludeJournal bool
+	Ignore         *usnfilter.IgnoreList
+	IgnoreFile     string
+	Where          usn.Filter
+	WhereStr       string
+	After          time.Time
+	Before         time.Time
+	Format         string
+}
+
+// Summary returns a multiline summary of the settings.
+func (s Settings) Summary() string {
+	var output []string
+	if s.Image != "" {
+		output = append(output, fmt.Sprintf("Image: %s", s.Image))
+	}
+
+	if s.MFT != "" {
+		output = append(output, fmt.Sprintf("MFT: %s", s.MFT))
+	}
+
+	if s.UsnJrnl != "" {
+		output = append(output, fmt.Sprintf("UsnJrnl: %s", s.UsnJrnl))
+	}
+
+	if s.Ignore != nil {
+		output = append(output, fmt.Sprintf("Exclude From: %s (%d patterns)", s.IgnoreFile, s.Ignore.Len()))
+	}
+
+	if s.Where != nil {
+		output = append(output, fmt.Sprintf("Where: %s", s.WhereStr))
+	}
+
+	if !s.After.IsZero() {
+		output = append(output, fmt.Sprintf("After: %s", s.After))
+	}
+
+	if !s.Before.IsZero() {
+		output = append(output, fmt.Sprintf("Before: %s", s.Before))
+	}
+
+	if len(output) == 0 {
+		return ""
+	}
+
+	return strings.Join(output, "\n") + "\n"
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/timeline"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// readImage adds the MFT entries and change journal records of an NTFS
+// image to the timeline.
+func readImage(ctx context.Context, t *timeline.Timeline, settings Settings) bool {
+	f, err := os.Open(settings.Image)
+	if err != nil {
+		fmt.Fprintf(info, "Unable to open image: %v\n", err)
+		return false
+	}
+	defer f.Close()
+
+	vol, err := ntfs.Open(f)
+	if err != nil {
+		fmt.Fprintf(info, "Unable to read NTFS volume from \"%s\": %v\n", settings.Image, err)
+		return false
+	}
+
+	cache, ok := buildCache(ctx, vol.MFT())
+	if !ok {
+		return false
+	}
+
+	if settings.IncludeMFT {
+		if !readMFT(ctx, t, vol.MFT(), cache) {
+			return false
+		}
+	}
+
+	if settings.IncludeJournal {
+		journal, err := vol.OpenJournal()
+		if err != nil {
+			fmt.Fprintf(info, "Unable to open change journal: %v\n", err)
+			return false
+		}
+		return readJournal(ctx, t, journal, cache)
+	}
+
+	return true
+}
+
+// readFiles adds the contents of an extracted $MFT and $UsnJrnl:$J to the
+// timeline.
+func readFiles(ctx context.Context, t *timeline.Timeline, settings Settings) bool {
+	var cache *usn.Cache
+
+	if settings.MFT != "" {
+		f, err := os.Open(settings.MFT)
+		if err != nil {
+			fmt.Fprintf(info, "Unable to open $MFT file: %v\n", err)
+			return false
+		}
+		defer f.Close()
+
+		fi, err := f.Stat()
+		if err != nil {
+			fmt.Fprintf(info, "Unable to open $MFT file: %v\n", err)
+			return false
+		}
+
+		mft, err := ntfs.OpenMFT(f, fi.Size())
+		if err != nil {
+			fmt.Fprintf(info, "Unable to read $MFT file \"%s\": %v\n", settings.MFT, err)
+			return false
+		}
+
+		var ok bool
+		if cache, ok = buildCache(ctx, mft); !ok {
+			return false
+		}
+
+		if settings.IncludeMFT {
+			if !readMFT(ctx, t, mft, cache) {
+				return false
+			}
+		}
+	}
+
+	if settings.UsnJrnl != "" && settings.IncludeJournal {
+		f, err := os.Open(settings.UsnJrnl)
+		if err != nil {
+			fmt.Fprintf(info, "Unable to open $UsnJrnl:$J file: %v\n", err)
+			return false
+		}
+		defer f.Close()
+
+		fi, err := f.Stat()
+		if err != nil {
+			fmt.Fprintf(info, "Unable to open $UsnJrnl:$J file: %v\n", err)
+			return false
+		}
+
+		return readJournal(ctx, t, ntfs.NewJournalReader(f, fi.Size()), cache)
+	}
+
+	return true
+}
+
+// buildCache reads every entry of the master file table into a cache that
+// is used to resolve paths. Deleted entries are included so that the paths
+// of deleted files can be resolved as well.
+func buildCache(ctx context.Context, mft *ntfs.MFT) (*usn.Cache, bool) {
+	fmt.Fprintf(info, "Reading MFT...")
+	cache := usn.NewCache()
+	if err := cache.ReadFrom(ctx, mft.Iter(true)); err != nil {
+		fmt.Fprintf(info, "failed: %v\n", err)
+		return nil, false
+	}
+	fmt.Fprintf(info, "done. Found %d files.\n", cache.Size())
+	return cache, true
+}
+
+func readMFT(ctx context.Context, t *timeline.Timeline, mft *ntfs.MFT, cache *usn.Cache) bool {
+	if err := t.ReadMFT(ctx, mft, filer(cache)); err != nil {
+		fmt.Fprintf(info, "Unable to read MFT entries: %v\n", err)
+		return false
+	}
+	return true
+}
+
+func readJournal(ctx context.Context, t *timeline.Timeline, iter usn.Iter, cache *usn.Cache) bool {
+	if err := t.ReadJournal(ctx, iter, filer(cache)); err != nil {
+		fmt.Fprintf(info, "Unable to read change journal records: %v\n", err)
+		return false
+	}
+	return true
+}
+
+// filer returns the filer of cache, or nil if cache is nil.
+func filer(cache *usn.Cache) usn.Filer {
+	if cache == nil {
+		return nil
+	}
+	return cache.Filer
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gentlemanautomaton/volmgmt/timeline"
+)
+
+// readVolume reports that live volumes cannot be read on this platform.
+func readVolume(ctx context.Context, t *timeline.Timeline, path string, settings Settings) bool {
+	fmt.Fprintf(info, "Unable to read \"%s\": live volumes can only be read on Windows, use -image to read a volume image\n", path)
+	return false
+}
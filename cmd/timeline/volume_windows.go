@@ -0,0 +1,81 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/gentlemanautomaton/volmgmt/timeline"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+	"github.com/gentlemanautomaton/volmgmt/volume"
+)
+
+// readVolume adds the change journal records of a live volume to the
+// timeline. The master file table of a live volume does not expose its
+// timestamps, so only journal records are read. Paths are relative to the
+// root of the volume, as they are for images.
+func readVolume(ctx context.Context, t *timeline.Timeline, path string, settings Settings) bool {
+	if !settings.IncludeJournal {
+		return true
+	}
+
+	fmt.Fprintf(info, "Path: \"%s\"\n", path)
+
+	vol, err := volume.New(path)
+	if err != nil {
+		fmt.Fprintf(info, "Unable to read \"%s\": %v\n", path, err)
+		return false
+	}
+	defer vol.Close()
+
+	journal := vol.Journal()
+	defer journal.Close()
+
+	data, err := journal.Query()
+	if err != nil {
+		fmt.Fprintf(info, "Unable to access USN Journal: %v\n", err)
+		return false
+	}
+
+	fmt.Fprintf(info, "Scanning MFT...")
+	cache, err := journal.Cache(ctx, usnfilter.IsDir, 0, data.FirstUSN)
+	if err != nil {
+		fmt.Fprintf(info, "failed: %v\n", err)
+		return false
+	}
+	fmt.Fprintf(info, "done. Found %d directories.\n", cache.Size())
+
+	cacheUpdater := func(record usn.Record) {
+		if usnfilter.IsDir(record) {
+			cache.Set(record)
+		}
+	}
+
+	cursor, err := journal.Cursor(cacheUpdater, usn.ReasonAny, nil, cache.Filer)
+	if err != nil {
+		fmt.Fprintf(info, "Unable to create USN journal cursor: %v\n", err)
+		return false
+	}
+	defer cursor.Close()
+
+	buffer := make([]byte, 262144)
+	for {
+		if err := ctx.Err(); err != nil {
+			return false
+		}
+		records, err := cursor.Next(buffer)
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprintf(info, "Unable to retreive USN journal records: %v\n", err)
+				return false
+			}
+			return true
+		}
+		for _, record := range records {
+			t.AddRecord(record)
+		}
+	}
+}
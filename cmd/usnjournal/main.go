@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"regexp"
@@ -13,10 +14,17 @@ import (
 
 	"github.com/gentlemanautomaton/volmgmt/fileattr"
 	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usn/export"
+	"github.com/gentlemanautomaton/volmgmt/usn/offline"
 	"github.com/gentlemanautomaton/volmgmt/usnfilter"
-	"golang.org/x/sys/windows"
+	"github.com/gentlemanautomaton/volmgmt/volpath"
 )
 
+// info receives informational messages. When records are written in a
+// machine-readable format it is redirected to standard error, so that
+// standard output holds nothing but records.
+var info io.Writer = os.Stdout
+
 func usage(errmsg string) {
 	fmt.Fprintf(os.Stderr, "%s\n\n", errmsg)
 	flag.Usage()
@@ -25,7 +33,7 @@ func usage(errmsg string) {
 
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "usage: %s [-t type[,type...]] [-i regexp] [-e regexp] <volume>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "usage: %s [-t type[,type...]] [-i regexp] [-e regexp] [-where expr] [-paths mode] [-long] [-format text|json|csv] [-fields field[,field...]] [-image] <volume>\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 
@@ -36,13 +44,26 @@ func main() {
 		include      *regexp.Regexp
 		excludeStr   string
 		exclude      *regexp.Regexp
+		whereStr     string
+		where        usn.Filter
 		shouldCreate bool
+		pathStr      string
+		longPath     bool
+		formatStr    string
+		fieldsStr    string
+		image        bool
 	)
 
 	flag.StringVar(&reasonStr, "t", "*", "journal record types to include (comma-separated)")
 	flag.StringVar(&includeStr, "i", "", "regular expression for file match (inclusion)")
 	flag.StringVar(&excludeStr, "e", "", "regular expression for file match (exclusion)")
+	flag.StringVar(&whereStr, "where", "", "filter expression for record match (e.g. \"reason = delete and ext = docx\")")
 	flag.BoolVar(&shouldCreate, "c", false, "create a USN journal if one is not already present for the volume")
+	flag.StringVar(&pathStr, "paths", "relative", "path display mode (relative, drive or volume)")
+	flag.BoolVar(&longPath, "long", false, "prefix absolute paths with \\\\?\\")
+	flag.StringVar(&formatStr, "format", "text", "output format (text, json or csv)")
+	flag.StringVar(&fieldsStr, "fields", "", "comma-separated fields to include in json or csv output (default all)")
+	flag.BoolVar(&image, "image", false, "print the journal of an NTFS volume image or block device instead of monitoring a live volume")
 	flag.Parse()
 
 	if flag.NArg() == 0 {
@@ -52,6 +73,10 @@ func main() {
 		usage("Only a single volume may be specified.")
 	}
 
+	if image && shouldCreate {
+		usage("Journals cannot be created in images.")
+	}
+
 	path := flag.Arg(0)
 
 	reason, err := usn.ParseReason(reasonStr)
@@ -61,39 +86,93 @@ func main() {
 
 	include = compileRegex(includeStr)
 	exclude = compileRegex(excludeStr)
+	where = parseWhere(whereStr)
 
-	journal, err := usn.NewJournal(path)
+	pathMode, err := volpath.ParseMode(pathStr)
 	if err != nil {
-		fmt.Printf("Unable to create monitor: %v\n", err)
+		usage(fmt.Sprintf("%v", err))
+	}
+	if image && pathMode != volpath.Relative {
+		usage("Images only support relative paths.")
+	}
+
+	format, err := export.ParseFormat(formatStr)
+	if err != nil {
+		usage(fmt.Sprintf("%v", err))
+	}
+	if format != export.Text {
+		info = os.Stderr
+	}
+
+	fields, err := export.ParseFields(fieldsStr)
+	if err != nil {
+		usage(fmt.Sprintf("%v", err))
+	}
+
+	var enc export.Encoder
+	if format != export.Text {
+		enc, err = export.NewEncoder(format, os.Stdout, fields...)
+		if err != nil {
+			usage(fmt.Sprintf("%v", err))
+		}
+	}
+
+	formatter, err := pathFormatter(path, pathMode, longPath)
+	if err != nil {
+		fmt.Fprintf(info, "Unable to format %s paths: %v\n", pathMode, err)
+		os.Exit(2)
+	}
+
+	var journal *usn.Journal
+	if image {
+		journal, err = offline.NewJournal(path)
+	} else {
+		journal, err = openJournal(path)
+	}
+	if err != nil {
+		fmt.Fprintf(info, "Unable to create monitor: %v\n", err)
 		os.Exit(2)
 	}
 	defer journal.Close()
 
 	data, err := journal.Query()
-	if err == windows.ERROR_JOURNAL_NOT_ACTIVE && shouldCreate {
-		fmt.Print("USN Journal is not active. Creating new journal...\n")
+	if journalNotActive(err) && shouldCreate {
+		fmt.Fprint(info, "USN Journal is not active. Creating new journal...\n")
 		err = journal.Create(0, 0)
 	}
 
 	if err != nil {
-		fmt.Printf("Unable to access USN Journal: %v\n", err)
+		fmt.Fprintf(info, "Unable to access USN Journal: %v\n", err)
 		os.Exit(2)
 	}
 
 	location, err := time.LoadLocation("Local")
 	if err != nil {
-		fmt.Printf("Unable to load local timezone information: %v\n", err)
+		fmt.Fprintf(info, "Unable to load local timezone information: %v\n", err)
 		os.Exit(2)
 	}
 
+	filter := buildFilter(include, exclude, where)
+
+	if image {
+		// Images do not change, so their journals are printed once from
+		// the first record that has not been purged
+		if err := printJournal(journal, data, reason, filter, formatter, location, enc); err != nil {
+			fmt.Fprintf(info, "Unable to read USN Journal: %v\n", err)
+			os.Exit(2)
+		}
+		return
+	}
+
 	monitor := journal.Monitor()
 	defer monitor.Close()
+	monitor.SetPathFormatter(formatter.Format)
 
 	feed := monitor.Listen(64) // Register the feed before starting the monitor
 
 	cache, err := journal.Cache(context.Background(), usnfilter.IsDir, 0, data.NextUSN)
 	if err != nil {
-		fmt.Printf("Journal cache error: %v\n", err)
+		fmt.Fprintf(info, "Journal cache error: %v\n", err)
 		os.Exit(2)
 	}
 
@@ -102,10 +181,10 @@ func main() {
 			cache.Set(record)
 		}
 	}
-	errC := monitor.Run(data.NextUSN, time.Millisecond*100, reason, cacheUpdater, nil, cache.Filer)
+	errC := monitor.Run(data.NextUSN, time.Millisecond*100, reason, cacheUpdater, filter, cache.Filer)
 
 	done := make(chan struct{})
-	go run(feed, location, include, exclude, done)
+	go run(feed, location, enc, done)
 
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
@@ -115,23 +194,92 @@ func main() {
 	case <-done:
 	case err = <-errC:
 		if err != nil {
-			fmt.Printf("monitor USN Journal: %v\n", err)
+			fmt.Fprintf(info, "monitor USN Journal: %v\n", err)
 		}
 	}
 }
 
-func run(feed <-chan usn.Record, location *time.Location, include, exclude *regexp.Regexp, done chan struct{}) {
+func run(feed <-chan usn.Record, location *time.Location, enc export.Encoder, done chan struct{}) {
 	defer close(done)
 
 	for record := range feed {
+		if err := printRecord(record, location, enc); err != nil {
+			fmt.Fprintf(info, "Unable to write record: %v\n", err)
+			return
+		}
+	}
+}
+
+// printJournal prints the records of a journal that does not change, from
+// the first record that has not been purged to the last.
+func printJournal(journal *usn.Journal, data usn.RawJournalData, reason usn.Reason, filter usn.Filter, formatter volpath.Formatter, location *time.Location, enc export.Encoder) error {
+	cache, err := journal.Cache(context.Background(), usnfilter.IsDir, 0, data.NextUSN)
+	if err != nil {
+		return fmt.Errorf("journal cache: %w", err)
+	}
+
+	cacheUpdater := func(record usn.Record) {
+		if usnfilter.IsDir(record) {
+			cache.Set(record)
+		}
+	}
+	cursor, err := journal.Cursor(cacheUpdater, reason, filter, cache.Filer)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+	cursor.SetPathFormatter(formatter.Format)
+
+	buffer := make([]byte, 262144)
+	for {
+		records, err := cursor.Next(buffer)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := printRecord(record, location, enc); err != nil {
+				return fmt.Errorf("unable to write record: %w", err)
+			}
+		}
+	}
+}
+
+// printRecord prints a journal record as text, or encodes it with enc if
+// it is not nil.
+func printRecord(record usn.Record, location *time.Location, enc export.Encoder) error {
+	if enc != nil {
+		// Flush each record so that consumers see changes as they happen
+		err := enc.Encode(record)
+		if err == nil {
+			err = enc.Flush()
+		}
+		return err
+	}
+
+	id := record.FileReferenceNumber.String()
+	when := record.TimeStamp.In(location).Format("2006-01-02 15:04:05.000000 MST")
+	attr := record.FileAttributes.Join("", fileattr.FormatCode)
+	action := strings.ToUpper(record.Reason.Join("|", usn.ReasonFormatShort))
+
+	fmt.Printf("%s  %d.%d  %-5s  %20s  \"%s\"  %s  %s\n", when, record.MajorVersion, record.MinorVersion, record.SourceInfo, id, record.Path, attr, action)
+	return nil
+}
+
+// buildFilter returns a filter that matches the include and exclude
+// expressions against volume-relative paths, and applies the where filter.
+func buildFilter(include, exclude *regexp.Regexp, where usn.Filter) usn.Filter {
+	return func(record usn.Record) bool {
 		if include != nil {
 			if record.Path == "" {
 				if !include.MatchString(record.FileName) {
-					continue
+					return false
 				}
 			} else {
 				if !include.MatchString(record.Path) {
-					continue
+					return false
 				}
 			}
 		}
@@ -139,21 +287,20 @@ func run(feed <-chan usn.Record, location *time.Location, include, exclude *rege
 		if exclude != nil {
 			if record.Path == "" {
 				if exclude.MatchString(record.FileName) {
-					continue
+					return false
 				}
 			} else {
 				if exclude.MatchString(record.Path) {
-					continue
+					return false
 				}
 			}
 		}
 
-		id := record.FileReferenceNumber.String()
-		when := record.TimeStamp.In(location).Format("2006-01-02 15:04:05.000000 MST")
-		attr := record.FileAttributes.Join("", fileattr.FormatCode)
-		action := strings.ToUpper(record.Reason.Join("|", usn.ReasonFormatShort))
+		if !where.Match(record) {
+			return false
+		}
 
-		fmt.Printf("%s  %d.%d  %-5s  %20s  \"%s\"  %s  %s\n", when, record.MajorVersion, record.MinorVersion, record.SourceInfo, id, record.Path, attr, action)
+		return true
 	}
 }
 
@@ -173,3 +320,11 @@ func compileRegex(re string) *regexp.Regexp {
 	}
 	return c
 }
+
+func parseWhere(expr string) usn.Filter {
+	filter, err := usnfilter.Parse(expr)
+	if err != nil {
+		usage(fmt.Sprintf("Unable to parse filter expression \"%s\": %v", expr, err))
+	}
+	return filter
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/volpath"
+)
+
+// errLiveVolume is returned when a live volume is opened on a platform
+// other than Windows.
+var errLiveVolume = errors.New("live volumes can only be read on Windows, use -image to read a volume image")
+
+// openJournal returns errLiveVolume.
+func openJournal(path string) (*usn.Journal, error) {
+	return nil, errLiveVolume
+}
+
+// journalNotActive returns false, as live volumes cannot be opened.
+func journalNotActive(err error) bool {
+	return false
+}
+
+// pathFormatter returns a formatter for relative paths. Other modes
+// return errLiveVolume.
+func pathFormatter(path string, mode volpath.Mode, longPath bool) (volpath.Formatter, error) {
+	if mode == volpath.Relative {
+		return volpath.Formatter{LongPath: longPath}, nil
+	}
+	return volpath.Formatter{}, errLiveVolume
+}
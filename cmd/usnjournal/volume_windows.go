@@ -0,0 +1,45 @@
+//go:build windows
+
+package main
+
+import (
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/volpath"
+	"github.com/gentlemanautomaton/volmgmt/volumeapi"
+	"golang.org/x/sys/windows"
+)
+
+// openJournal opens the change journal of the live volume at path.
+func openJournal(path string) (*usn.Journal, error) {
+	return usn.NewJournal(path)
+}
+
+// journalNotActive returns true if err reports that the volume does not
+// have an active change journal.
+func journalNotActive(err error) bool {
+	return err == windows.ERROR_JOURNAL_NOT_ACTIVE
+}
+
+// pathFormatter returns a path formatter for the volume upon which path is
+// mounted.
+func pathFormatter(path string, mode volpath.Mode, longPath bool) (volpath.Formatter, error) {
+	if mode == volpath.Relative {
+		return volpath.Formatter{LongPath: longPath}, nil
+	}
+
+	_, name, err := volumeapi.MountPoint(path)
+	if err != nil {
+		return volpath.Formatter{}, err
+	}
+
+	mounts := make(volpath.MountTable)
+	if mode == volpath.DriveLetter {
+		points, err := volumeapi.GetVolumePathNamesForVolumeName(name)
+		if err != nil {
+			return volpath.Formatter{}, err
+		}
+		mounts[name] = points
+	}
+
+	return volpath.New(mode, name, mounts, longPath)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/fsdetect"
+	"github.com/gentlemanautomaton/volmgmt/partition"
+	"github.com/gentlemanautomaton/volmgmt/vhd"
+	"github.com/gentlemanautomaton/volmgmt/vhdx"
+)
+
+// disk is an opened image file.
+type disk interface {
+	io.ReaderAt
+	io.Closer
+	Size() int64
+}
+
+// rawDisk is a raw disk or volume image.
+type rawDisk struct {
+	*os.File
+	size int64
+}
+
+func (d rawDisk) Size() int64 {
+	return d.size
+}
+
+// openImage opens a raw, VHD or VHDX image file. Virtual hard disks are
+// recognized by their file extension.
+func openImage(path string) (disk, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".vhd":
+		return vhd.OpenFile(path)
+	case ".vhdx":
+		return vhdx.OpenFile(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return rawDisk{File: f, size: fi.Size()}, nil
+}
+
+// describeImage prints the file systems held by an image file. If the
+// image holds a partition table, each partition is described. Otherwise the
+// image is treated as a volume.
+func describeImage(path string) {
+	fmt.Fprintf(info, "Querying image information for \"%s\"...\n--------\n", path)
+
+	d, err := openImage(path)
+	if err != nil {
+		fmt.Fprintf(info, "Unable to open image: %v\n", err)
+		return
+	}
+	defer d.Close()
+
+	table, err := partition.Read(d, d.Size())
+	if errors.Is(err, partition.ErrNoTable) {
+		describeVolume("", d)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(info, "Unable to read partition table: %v\n", err)
+		return
+	}
+
+	fmt.Fprintf(info, "Partition Table: %s, %d partitions\n", table.Scheme, len(table.Partitions))
+	for _, p := range table.Partitions {
+		name := fmt.Sprintf("Partition %d", p.Number)
+		if p.Name != "" {
+			name += fmt.Sprintf(" \"%s\"", p.Name)
+		}
+		fmt.Fprintf(info, "%s: Offset %d, Size %d\n", name, p.Start, p.Size)
+		if p.TypeGUID == partition.TypeStorageSpaces {
+			// Pool members are recognized by their partition type
+			fmt.Fprintf(info, "  File System: %s\n", partition.StorageSpaces)
+			continue
+		}
+		describeVolume("  ", p.Reader())
+	}
+}
+
+// describeVolume prints a summary of the file system held by r.
+func describeVolume(prefix string, r io.ReaderAt) {
+	s, err := fsdetect.Detect(r)
+	if err != nil {
+		fmt.Fprintf(info, "%sUnable to identify file system: %v\n", prefix, err)
+		if s.Filesystem == partition.Unknown {
+			return
+		}
+	}
+	fmt.Fprintf(info, "%sFile System: %s\n", prefix, s.Filesystem)
+	if s.Filesystem == partition.Unknown {
+		return
+	}
+	if s.Version != "" {
+		fmt.Fprintf(info, "%sVersion: %s\n", prefix, s.Version)
+	}
+	if s.Label != "" {
+		fmt.Fprintf(info, "%sVolume Label: \"%s\"\n", prefix, s.Label)
+	}
+	if serial := s.Serial(); serial != "" {
+		fmt.Fprintf(info, "%sSerial Number: %s\n", prefix, serial)
+	}
+	if !s.VolumeID.IsZero() {
+		fmt.Fprintf(info, "%sVolume ID: %s\n", prefix, s.VolumeID)
+	}
+	if !s.Created.IsZero() {
+		fmt.Fprintf(info, "%sEncrypted: %s\n", prefix, s.Created.Local().Format("2006-01-02 15:04:05"))
+	}
+	if s.ClusterSize != 0 {
+		fmt.Fprintf(info, "%sCluster Size: %d, Sector Size: %d\n", prefix, s.ClusterSize, s.SectorSize)
+	}
+	if s.TotalSize != 0 {
+		fmt.Fprintf(info, "%sTotal Size: %d\n", prefix, s.TotalSize)
+	}
+}
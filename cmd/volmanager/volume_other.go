@@ -0,0 +1,16 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usn/export"
+)
+
+// queryVolume reports that live volumes cannot be queried on this platform.
+func queryVolume(path string, regex *regexp.Regexp, where usn.Filter, enc export.Encoder) {
+	fmt.Fprintf(info, "Unable to query \"%s\": live volumes can only be queried on Windows, use -image to describe an image file\n", path)
+}
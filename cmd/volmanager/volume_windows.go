@@ -0,0 +1,113 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usn/export"
+	"github.com/gentlemanautomaton/volmgmt/volume"
+	"github.com/gentlemanautomaton/volmgmt/volumeapi"
+)
+
+// queryVolume prints information about a live volume and the file creation
+// and deletion records of its change journal.
+func queryVolume(path string, regex *regexp.Regexp, where usn.Filter, enc export.Encoder) {
+	fmt.Fprintf(info, "Querying volume information for \"%s\"...\n--------\n", path)
+
+	vol, err := volume.New(path)
+	if err != nil {
+		fmt.Fprintf(info, "Unable to create volume handle: %v\n", err)
+		return
+	}
+	defer vol.Close()
+
+	label, labelErr := vol.Label()
+	name, nameErr := vol.Name()
+	deviceID, deviceIDErr := vol.DeviceID()
+	devicePath, devicePathErr := vol.DevicePath()
+
+	fmt.Fprintf(info, "Volume Label: %s\n", strOrErr(label, labelErr))
+	fmt.Fprintf(info, "Volume Name: %s\n", strOrErr(name, nameErr))
+	fmt.Fprintf(info, "Device ID: %s\n", strOrErr(fmt.Sprintf("%s", deviceID), deviceIDErr))
+	fmt.Fprintf(info, "NT Namespace Device Path: %s\n", strOrErr(devicePath, devicePathErr))
+	fmt.Fprintf(info, "Device Information: Number %d, Partition %d, Type %d\n", vol.DeviceNumber(), vol.PartitionNumber(), vol.DeviceType())
+	fmt.Fprintf(info, "Device Description: Removable: %t, Vendor: %s, Product: %s, Revision: %s, OS S/N: %s\n", vol.RemovableMedia(), vol.VendorID(), vol.ProductID(), vol.ProductRevision(), vol.SerialNumber())
+
+	paths, err := vol.Paths()
+	if err != nil {
+		fmt.Fprintf(info, "%v\n", fmt.Errorf("Unable to ascertain volume paths: %v", err))
+	} else if len(paths) > 0 {
+		fmt.Fprintf(info, "Mounts:\n")
+		for i, path := range paths {
+			root, _ := volumeapi.GetVolumeNameForVolumeMountPoint(path)
+			fmt.Fprintf(info, "  Mount %d on \"%s\": \"%s\"\n", i, root, path)
+		}
+	}
+
+	journal := vol.Journal()
+	defer journal.Close()
+
+	journalData, journalDataErr := journal.Query()
+	if journalDataErr != nil {
+		fmt.Fprintf(info, "USN Journal: %v\n", journalDataErr)
+		return
+	}
+
+	fmt.Fprintf(info, "USN Journal: Present, ID: %d, Next USN: %d, Supporting Versions: %d-%d\n", journalData.JournalID, journalData.NextUSN, journalData.MinSupportedMajorVersion, journalData.MaxSupportedMajorVersion)
+
+	cursor, cursorErr := journal.Cursor(nil, usn.ReasonFileCreate|usn.ReasonFileDelete,
+		where, nil)
+	if cursorErr != nil {
+		fmt.Fprintf(info, "Unable to create USN journal cursor: %v\n", cursorErr)
+		return
+	}
+	defer cursor.Close()
+
+	buffer := make([]byte, 262144)
+	i := 0
+	for {
+		records, cursorErr := cursor.Next(buffer)
+		if cursorErr != nil {
+			if cursorErr != io.EOF {
+				fmt.Fprintf(info, "Unable to retreive USN journal records: %v\n", cursorErr)
+			}
+			break
+		}
+
+		for _, record := range records {
+			if regex != nil {
+				if !regex.MatchString(record.FileName) {
+					continue
+				}
+			}
+			if enc != nil {
+				if err := enc.Encode(record); err != nil {
+					fmt.Fprintf(info, "Unable to write record: %v\n", err)
+					return
+				}
+				i++
+				continue
+			}
+			action := "OTHER "
+			if record.Reason&usn.ReasonFileCreate != 0 {
+				action = "CREATE"
+			}
+			if record.Reason&usn.ReasonFileDelete != 0 {
+				action = "DELETE"
+			}
+			fmt.Printf("%s  %s  %s\n", record.TimeStamp.Format("2006-01-02 15:04:05.000000"), action, record.FileName)
+			i++
+		}
+	}
+}
+
+func strOrErr(s string, err error) string {
+	if err != nil {
+		return fmt.Sprintf("%v", err)
+	}
+	return fmt.Sprintf("\"%s\"", s)
+}
@@ -0,0 +1,105 @@
+package fileapi
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/utf16le"
+)
+
+// streamInfoBufferSize is the initial size of the buffer used to retrieve
+// stream information. It accommodates several hundred streams with long
+// names, and is grown by GetFileInformationByHandleEx when it is too small.
+const streamInfoBufferSize = 64 * 1024
+
+// StreamInfo holds the streams of a file. It can be unmarshaled from the
+// FILE_STREAM_INFO windows API structure.
+type StreamInfo struct {
+	Streams []Stream
+}
+
+// Stream describes a stream of a file.
+type Stream struct {
+	Name           string // Empty for the default data stream
+	Type           string // Usually "$DATA"
+	Size           int64
+	AllocationSize int64
+}
+
+// String returns the stream name in the form used to open the stream, such
+// as ":Zone.Identifier:$DATA".
+func (s Stream) String() string {
+	return ":" + s.Name + ":" + s.Type
+}
+
+// Class returns the file information class.
+func (info StreamInfo) Class() FileInfoClass {
+	return FileStreamInfo
+}
+
+// Size returns the the number of bytes required to store StreamInfo in its
+// marshaled form.
+func (info *StreamInfo) Size() int {
+	return streamInfoBufferSize
+}
+
+// Named returns the alternate data streams of the file, omitting the
+// default data stream.
+func (info StreamInfo) Named() []Stream {
+	var named []Stream
+	for _, s := range info.Streams {
+		if s.Name != "" {
+			named = append(named, s)
+		}
+	}
+	return named
+}
+
+// UnmarshalBinary unmarshals the given data into info.
+func (info *StreamInfo) UnmarshalBinary(data []byte) error {
+	// Each FILE_STREAM_INFO entry has a 24 byte header followed by its name
+	//
+	// https://learn.microsoft.com/en-us/windows/win32/api/winbase/ns-winbase-file_stream_info
+	const headerSize = 24
+
+	info.Streams = nil
+	for offset := 0; ; {
+		if offset+headerSize > len(data) {
+			return errors.New("insufficient data for StreamInfo unmarshaling")
+		}
+		entry := data[offset:]
+		next := int(binary.LittleEndian.Uint32(entry[0:]))
+		nameLength := int(binary.LittleEndian.Uint32(entry[4:]))
+		if headerSize+nameLength > len(entry) {
+			return errors.New("insufficient data for StreamInfo unmarshaling")
+		}
+		if nameLength == 0 && next == 0 && offset == 0 {
+			// Directories without named streams have no entries
+			return nil
+		}
+
+		name, typ := splitStreamName(utf16le.Decode(entry[headerSize : headerSize+nameLength]))
+		info.Streams = append(info.Streams, Stream{
+			Name:           name,
+			Type:           typ,
+			Size:           int64(binary.LittleEndian.Uint64(entry[8:])),
+			AllocationSize: int64(binary.LittleEndian.Uint64(entry[16:])),
+		})
+
+		if next == 0 {
+			return nil
+		}
+		offset += next
+	}
+}
+
+// splitStreamName splits a stream name of the form ":name:type" into its
+// name and type.
+func splitStreamName(s string) (name, typ string) {
+	s = strings.TrimPrefix(s, ":")
+	if i := strings.LastIndexByte(s, ':'); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
@@ -0,0 +1,74 @@
+package fileapi_test
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/gentlemanautomaton/volmgmt/fileapi"
+)
+
+// streamEntry encodes a FILE_STREAM_INFO entry. If last is false the entry
+// is padded to an 8 byte boundary and points to the entry that follows it.
+func streamEntry(name string, size, alloc int64, last bool) []byte {
+	u := utf16.Encode([]rune(name))
+	b := make([]byte, 24+len(u)*2)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(u)*2))
+	binary.LittleEndian.PutUint64(b[8:], uint64(size))
+	binary.LittleEndian.PutUint64(b[16:], uint64(alloc))
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[24+i*2:], c)
+	}
+	if last {
+		return b
+	}
+	for len(b)%8 != 0 {
+		b = append(b, 0)
+	}
+	binary.LittleEndian.PutUint32(b[0:], uint32(len(b)))
+	return b
+}
+
+func TestStreamInfoUnmarshalBinary(t *testing.T) {
+	var multi []byte
+	multi = append(multi, streamEntry("::$DATA", 1024, 4096, false)...)
+	multi = append(multi, streamEntry(":Zone.Identifier:$DATA", 26, 32, false)...)
+	multi = append(multi, streamEntry(":notes:$DATA", 7, 8, true)...)
+
+	truncated := streamEntry(":Zone.Identifier:$DATA", 26, 32, true)
+	truncated = truncated[:len(truncated)-4]
+
+	for _, tt := range []struct {
+		name    string
+		data    []byte
+		want    []fileapi.Stream
+		wantErr bool
+	}{
+		{"multiple", multi, []fileapi.Stream{
+			{Name: "", Type: "$DATA", Size: 1024, AllocationSize: 4096},
+			{Name: "Zone.Identifier", Type: "$DATA", Size: 26, AllocationSize: 32},
+			{Name: "notes", Type: "$DATA", Size: 7, AllocationSize: 8},
+		}, false},
+		{"empty directory", make([]byte, 64), nil, false},
+		{"truncated name", truncated, nil, true},
+		{"truncated header", multi[:16], nil, true},
+		{"truncated next entry", multi[:len(multi)-20], nil, true},
+	} {
+		var info fileapi.StreamInfo
+		err := info.UnmarshalBinary(tt.data)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: UnmarshalBinary did not return an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(info.Streams, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, info.Streams, tt.want)
+		}
+	}
+}
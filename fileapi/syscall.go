@@ -1,3 +1,5 @@
+//go:build windows
+
 package fileapi
 
 import (
@@ -15,6 +17,10 @@ var (
 	ErrEmptyBuffer = errors.New("nil or empty buffer provided")
 )
 
+// maxInfoBufferSize limits the size to which the buffer used by
+// GetFileInformationByHandleEx is grown.
+const maxInfoBufferSize = 16 << 20
+
 var (
 	modkernel32 = windows.NewLazySystemDLL("kernel32.dll")
 
@@ -97,19 +103,30 @@ func GetFileInformationByHandle(handle syscall.Handle) (info syscall.ByHandleFil
 // GetFileInformationByHandleEx retrieves information about the file
 // represented by the given system handle. The type of information returned
 // is determined by class.
+//
+// The buffer starts at the size reported by info. For variable-length
+// classes, such as StreamInfo, it is doubled and the call retried while the
+// system reports that more data is available.
 func GetFileInformationByHandleEx(handle syscall.Handle, info FileInfoUnmarshaler) (err error) {
 	buffer := make([]byte, info.Size())
 	if len(buffer) == 0 {
 		return ErrEmptyBuffer
 	}
 
-	r0, _, e := syscall.SyscallN(
-		procGetFileInformationByHandleEx.Addr(),
-		uintptr(handle),
-		uintptr(info.Class()),
-		uintptr(unsafe.Pointer(&buffer[0])),
-		uintptr(len(buffer)))
-	if r0 == 0 {
+	for {
+		r0, _, e := syscall.SyscallN(
+			procGetFileInformationByHandleEx.Addr(),
+			uintptr(handle),
+			uintptr(info.Class()),
+			uintptr(unsafe.Pointer(&buffer[0])),
+			uintptr(len(buffer)))
+		if r0 != 0 {
+			break
+		}
+		if e == windows.ERROR_MORE_DATA && len(buffer) < maxInfoBufferSize {
+			buffer = make([]byte, len(buffer)*2)
+			continue
+		}
 		if e != 0 {
 			return e
 		}
@@ -0,0 +1,108 @@
+package fileattr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/number"
+)
+
+// Parse interprets s as a set of file attributes.
+//
+// Attributes are separated by | or commas. Each attribute may be a name
+// from FormatC or FormatGo, or a numeric value in decimal or in hexadecimal
+// with a 0x prefix. Names are case-insensitive. Single-letter codes from
+// FormatCode may be given individually or concatenated, as in "RHS".
+func Parse(s string) (v Value, err error) {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '|' || r == ',' })
+	if len(parts) == 0 {
+		return 0, errors.New("no file attributes specified")
+	}
+
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		attr, ok := parseAttr(part)
+		if !ok {
+			return 0, fmt.Errorf("unknown file attribute: %s", part)
+		}
+		v |= attr
+	}
+
+	return v, nil
+}
+
+// parseAttr interprets a single file attribute name, number or sequence of
+// codes.
+func parseAttr(s string) (Value, bool) {
+	if n, err := number.ParseUint32(s); err == nil {
+		return Value(n), true
+	}
+
+	for _, format := range []Format{FormatC, FormatGo} {
+		for value, name := range format {
+			if strings.EqualFold(name, s) {
+				return value, true
+			}
+		}
+	}
+
+	var v Value
+	for _, r := range strings.ToUpper(s) {
+		code, ok := lookupCode(r)
+		if !ok {
+			return 0, false
+		}
+		v |= code
+	}
+	return v, s != ""
+}
+
+// lookupCode returns the attribute with the given single-letter code.
+func lookupCode(r rune) (Value, bool) {
+	for value, code := range FormatCode {
+		if code == string(r) {
+			return value, true
+		}
+	}
+	return 0, false
+}
+
+// MarshalText returns the file attributes as a list of Go-style names
+// separated by |. Bits without a name are written as a hexadecimal value.
+// A value of zero is written as 0.
+func (v Value) MarshalText() ([]byte, error) {
+	if v == 0 {
+		return []byte("0"), nil
+	}
+
+	var known Value
+	for attr := range FormatGo {
+		known |= attr
+	}
+
+	text := v.Join("|", FormatGo)
+	if unknown := v &^ known; unknown != 0 {
+		if text != "" {
+			text += "|"
+		}
+		text += fmt.Sprintf("0x%08x", uint32(unknown))
+	}
+
+	return []byte(text), nil
+}
+
+// UnmarshalText parses text as a set of file attributes, as described by
+// Parse. Empty text is interpreted as zero.
+func (v *Value) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*v = 0
+		return nil
+	}
+	attr, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*v = attr
+	return nil
+}
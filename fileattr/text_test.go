@@ -0,0 +1,69 @@
+package fileattr_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  fileattr.Value
+	}{
+		{"Hidden", fileattr.Hidden},
+		{"FILE_ATTRIBUTE_SYSTEM", fileattr.System},
+		{"hidden|system", fileattr.Hidden | fileattr.System},
+		{"H", fileattr.Hidden},
+		{"rhs", fileattr.Readonly | fileattr.Hidden | fileattr.System},
+		{"RHS, Archive", fileattr.Readonly | fileattr.Hidden | fileattr.System | fileattr.Archive},
+		{"0x20", fileattr.Archive},
+		{"16", fileattr.Directory},
+		{"018", fileattr.Hidden | fileattr.Directory},
+		{"Normal", fileattr.Normal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := fileattr.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse returned an error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{"", "|", "Bogus", "HZ"} {
+		if _, err := fileattr.Parse(input); err == nil {
+			t.Errorf("Parse(%q) did not return an error", input)
+		}
+	}
+}
+
+func TestTextRoundTrip(t *testing.T) {
+	values := []fileattr.Value{
+		0,
+		fileattr.Archive,
+		fileattr.Hidden | fileattr.System | fileattr.Directory,
+		fileattr.RecallOnDataAccess | 0x80000000,
+	}
+
+	for _, v := range values {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("Marshal(%s) returned an error: %v", v, err)
+		}
+		var got fileattr.Value
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal(%s) returned an error: %v", data, err)
+		}
+		if got != v {
+			t.Errorf("%s: round trip produced %#x, want %#x", data, uint32(got), uint32(v))
+		}
+	}
+}
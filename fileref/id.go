@@ -2,7 +2,7 @@
 // file identifiers used in NTFS and ReFS file systems.
 //
 // New identifiers are created by calling New64, New128, BigEndian or
-// LittleEndian.
+// LittleEndian. Identifiers can be parsed from text by calling Parse.
 package fileref
 
 import (
@@ -0,0 +1,46 @@
+package fileref
+
+// MaxSegment is the largest MFT segment number that can be stored in a
+// 64-bit NTFS file reference number.
+const MaxSegment = 1<<48 - 1
+
+// NewSegment returns a 64-bit NTFS file reference number for the given MFT
+// segment number and sequence number. Only the lower 48 bits of segment are
+// used.
+func NewSegment(segment uint64, sequence uint16) ID {
+	return New64(int64(uint64(sequence)<<48 | segment&MaxSegment))
+}
+
+// Segment returns the MFT segment number of a 64-bit NTFS file reference
+// number, which is held in its lower 48 bits. It is the index of the file's
+// record within the master file table.
+func (id ID) Segment() uint64 {
+	_, lower := id.Split()
+	return uint64(lower) & MaxSegment
+}
+
+// Sequence returns the sequence number of a 64-bit NTFS file reference
+// number, which is held in its upper 16 bits. NTFS increments the sequence
+// number each time an MFT segment is reused by a new file.
+func (id ID) Sequence() uint16 {
+	_, lower := id.Split()
+	return uint16(uint64(lower) >> 48)
+}
+
+// SameSegment returns true if id and other refer to the same MFT segment,
+// regardless of their sequence numbers. Identifiers that are not 64-bit
+// NTFS file reference numbers have no segment, and are only considered the
+// same segment if they are equal.
+func (id ID) SameSegment(other ID) bool {
+	if !id.IsInt64() || !other.IsInt64() {
+		return id == other
+	}
+	return id.Segment() == other.Segment()
+}
+
+// NewerSequence returns true if the sequence number of id is more recent
+// than that of other. Sequence numbers wrap around, so the comparison uses
+// serial number arithmetic.
+func (id ID) NewerSequence(other ID) bool {
+	return int16(id.Sequence()-other.Sequence()) > 0
+}
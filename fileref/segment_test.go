@@ -0,0 +1,71 @@
+package fileref_test
+
+import (
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+)
+
+func TestSegment(t *testing.T) {
+	tests := []struct {
+		id       fileref.ID
+		segment  uint64
+		sequence uint16
+	}{
+		{fileref.New64(5), 5, 0},
+		{fileref.New64(0x0005000000000005), 5, 5},
+		{fileref.New64(0x0001000000012345), 0x12345, 1},
+		{fileref.New64(-1), fileref.MaxSegment, 0xffff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id.String(), func(t *testing.T) {
+			if got := tt.id.Segment(); got != tt.segment {
+				t.Errorf("Segment() = %#x, want %#x", got, tt.segment)
+			}
+			if got := tt.id.Sequence(); got != tt.sequence {
+				t.Errorf("Sequence() = %d, want %d", got, tt.sequence)
+			}
+			if got := fileref.NewSegment(tt.segment, tt.sequence); got != tt.id {
+				t.Errorf("NewSegment(%#x, %d) = %s, want %s", tt.segment, tt.sequence, got, tt.id)
+			}
+		})
+	}
+}
+
+func TestSameSegment(t *testing.T) {
+	a := fileref.NewSegment(42, 1)
+	b := fileref.NewSegment(42, 2)
+	c := fileref.NewSegment(43, 1)
+	wide := fileref.New128(42, 1)
+
+	if !a.SameSegment(b) {
+		t.Error("identifiers with the same segment were reported as different")
+	}
+	if a.SameSegment(c) {
+		t.Error("identifiers with different segments were reported as the same")
+	}
+	if a.SameSegment(wide) || !wide.SameSegment(wide) {
+		t.Error("128-bit identifiers were not compared for equality")
+	}
+}
+
+func TestNewerSequence(t *testing.T) {
+	tests := []struct {
+		a, b uint16
+		want bool
+	}{
+		{2, 1, true},
+		{1, 2, false},
+		{1, 1, false},
+		{1, 0xffff, true},
+		{0xffff, 1, false},
+	}
+
+	for _, tt := range tests {
+		a, b := fileref.NewSegment(7, tt.a), fileref.NewSegment(7, tt.b)
+		if got := a.NewerSequence(b); got != tt.want {
+			t.Errorf("sequence %d newer than %d = %t, want %t", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
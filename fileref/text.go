@@ -0,0 +1,57 @@
+package fileref
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+)
+
+// ErrInvalidID is returned when a file identifier cannot be parsed.
+var ErrInvalidID = errors.New("invalid file identifier")
+
+// maxID is the largest value that can be held by a file identifier.
+var maxID = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+
+// Parse interprets s as a file identifier. It accepts the decimal
+// representation returned by String, as well as hexadecimal values with a
+// 0x prefix. Values of up to 128 bits are accepted.
+//
+// Negative decimal values are interpreted as signed 64-bit identifiers, so
+// that every value returned by String can be parsed.
+func Parse(s string) (ID, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return New64(n), nil
+	}
+
+	var (
+		bi = new(big.Int)
+		ok bool
+	)
+	if len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		_, ok = bi.SetString(s[2:], 16)
+	} else {
+		_, ok = bi.SetString(s, 10)
+	}
+	if !ok || bi.Sign() < 0 || bi.Cmp(maxID) > 0 {
+		return ID{}, ErrInvalidID
+	}
+
+	var id ID
+	bi.FillBytes(id[:])
+	return id, nil
+}
+
+// MarshalText returns the file identifier in the form returned by String.
+func (id ID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText parses text as a file identifier, as described by Parse.
+func (id *ID) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
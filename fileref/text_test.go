@@ -0,0 +1,68 @@
+package fileref_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  fileref.ID
+	}{
+		{"0", fileref.ID{}},
+		{"42", fileref.New64(42)},
+		{"0x2A", fileref.New64(42)},
+		{"-1", fileref.New64(-1)},
+		{"0x0001000000000000002a", fileref.New128(0x2a, 1)},
+		{"0xffffffffffffffffffffffffffffffff", fileref.New128(-1, -1)},
+		{"340282366920938463463374607431768211455", fileref.New128(-1, -1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := fileref.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse returned an error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{"", "abc", "0x", "-0x5", "1.5", "0x1ffffffffffffffffffffffffffffffff", "340282366920938463463374607431768211456"} {
+		if _, err := fileref.Parse(input); err != fileref.ErrInvalidID {
+			t.Errorf("Parse(%q) returned %v, want %v", input, err, fileref.ErrInvalidID)
+		}
+	}
+}
+
+func TestTextRoundTrip(t *testing.T) {
+	ids := []fileref.ID{
+		{},
+		fileref.New64(5),
+		fileref.New64(0x0005000000001234),
+		fileref.New64(-2),
+		fileref.New128(7, 1),
+		fileref.New128(-1, -1),
+	}
+
+	for _, id := range ids {
+		data, err := json.Marshal(id)
+		if err != nil {
+			t.Fatalf("Marshal(%s) returned an error: %v", id, err)
+		}
+		var got fileref.ID
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal(%s) returned an error: %v", data, err)
+		}
+		if got != id {
+			t.Errorf("%s: round trip produced %s", data, got)
+		}
+	}
+}
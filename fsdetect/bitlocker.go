@@ -0,0 +1,98 @@
+package fsdetect
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/filetime"
+	"github.com/gentlemanautomaton/volmgmt/internal/utf16le"
+)
+
+// ErrInvalidMetadata is returned when the FVE metadata of a BitLocker
+// volume is damaged.
+var ErrInvalidMetadata = errors.New("invalid BitLocker metadata")
+
+// fveSignature identifies a BitLocker volume header and its FVE metadata
+// blocks.
+const fveSignature = "-FVE-FS-"
+
+// FVE metadata entry and value types.
+const (
+	fveEntryDescription = 0x0007
+	fveValueString      = 0x0002
+)
+
+const (
+	fveBlockHeaderSize = 0x40
+	fveHeaderSize      = 0x30
+	maxFVEMetadata     = 64 << 10
+)
+
+// bitlocker summarizes a BitLocker volume header and the first FVE metadata
+// block that can be read. The label is taken from the volume description
+// that BitLocker records when the volume is encrypted, which names the
+// computer, drive letter and date.
+//
+// Volumes encrypted by Windows Vista do not record the location of their
+// metadata in the volume header, so only their geometry is reported.
+func (s *Summary) bitlocker(r io.ReaderAt, sector []byte) error {
+	s.SectorSize = uint32(binary.LittleEndian.Uint16(sector[0x0B:]))
+	s.ClusterSize = s.SectorSize * uint32(sector[0x0D])
+
+	var lastErr error
+	for i := 0; i < 3; i++ {
+		offset := int64(binary.LittleEndian.Uint64(sector[0xB0+i*8:]))
+		if offset == 0 {
+			continue
+		}
+		err := s.fveMetadata(r, offset)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+	}
+	return lastErr
+}
+
+// fveMetadata reads the FVE metadata block at offset.
+func (s *Summary) fveMetadata(r io.ReaderAt, offset int64) error {
+	block := make([]byte, fveBlockHeaderSize+fveHeaderSize)
+	if _, err := r.ReadAt(block, offset); err != nil {
+		return fmt.Errorf("FVE metadata at %d: %w", offset, err)
+	}
+	if string(block[:8]) != fveSignature {
+		return fmt.Errorf("FVE metadata at %d: %w", offset, ErrInvalidMetadata)
+	}
+	header := block[fveBlockHeaderSize:]
+	size := binary.LittleEndian.Uint32(header[0x00:])
+	if size < fveHeaderSize || size > maxFVEMetadata {
+		return fmt.Errorf("FVE metadata at %d: %w: size %d", offset, ErrInvalidMetadata, size)
+	}
+
+	s.Version = fmt.Sprintf("%d", binary.LittleEndian.Uint16(block[0x0A:]))
+	s.TotalSize = int64(binary.LittleEndian.Uint64(block[0x10:]))
+	copy(s.VolumeID[:], header[0x10:])
+	s.Created = filetime.ToTime(binary.LittleEndian.Uint64(header[0x28:]))
+
+	entries := make([]byte, size-fveHeaderSize)
+	if _, err := r.ReadAt(entries, offset+fveBlockHeaderSize+fveHeaderSize); err != nil {
+		return fmt.Errorf("FVE metadata at %d: %w", offset, err)
+	}
+	for len(entries) >= 8 {
+		length := int(binary.LittleEndian.Uint16(entries[0:]))
+		if length < 8 || length > len(entries) {
+			break
+		}
+		typ := binary.LittleEndian.Uint16(entries[2:])
+		valueType := binary.LittleEndian.Uint16(entries[4:])
+		if typ == fveEntryDescription && valueType == fveValueString {
+			s.Label = strings.TrimRight(utf16le.Decode(entries[8:length]), "\x00")
+			break
+		}
+		entries = entries[length:]
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+// Package fsdetect identifies the file system held by a volume and
+// summarizes its boot sector.
+//
+// The volume may be any io.ReaderAt, such as a partition of a disk image, a
+// raw volume image or a block device, so file systems can be identified
+// without mounting them. The label, serial number and geometry reported by
+// GetVolumeInformation on a live volume are recovered from the on-disk
+// structures where the file system keeps them in its first sectors.
+//
+// NTFS keeps its label in the $Volume system file rather than in the boot
+// sector, so it is read from the master file table when that can be
+// reached. ReFS summaries have no label because its metadata is not parsed.
+package fsdetect
@@ -0,0 +1,106 @@
+package fsdetect
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/utf16le"
+	"github.com/gentlemanautomaton/volmgmt/partition"
+)
+
+// extendedBootSignature marks a FAT boot sector that holds a serial number
+// and label. Older versions of DOS wrote 0x28, which has no label.
+const extendedBootSignature = 0x29
+
+// fat summarizes a FAT12, FAT16 or FAT32 boot sector.
+func (s *Summary) fat(sector []byte) {
+	s.SectorSize = uint32(binary.LittleEndian.Uint16(sector[0x0B:]))
+	s.ClusterSize = s.SectorSize * uint32(sector[0x0D])
+
+	sectors := uint64(binary.LittleEndian.Uint16(sector[0x13:]))
+	if sectors == 0 {
+		sectors = uint64(binary.LittleEndian.Uint32(sector[0x20:]))
+	}
+	s.TotalSize = int64(sectors) * int64(s.SectorSize)
+
+	// FAT32 moves the extended boot record after its larger parameter block
+	ebr := sector[0x26:]
+	if s.Filesystem == partition.FAT32 {
+		ebr = sector[0x42:]
+	}
+	switch ebr[0] {
+	case extendedBootSignature:
+		s.Label = fatLabel(ebr[5:16])
+		fallthrough
+	case 0x28:
+		s.SerialNumber = uint64(binary.LittleEndian.Uint32(ebr[1:]))
+	}
+}
+
+// fatLabel returns the label held in a boot sector. The label of a volume
+// without one is recorded as "NO NAME".
+func fatLabel(data []byte) string {
+	label := strings.TrimRight(string(data), " \x00")
+	if label == "NO NAME" {
+		return ""
+	}
+	return label
+}
+
+// exFAT directory entry types.
+const (
+	exfatEndOfDirectory = 0x00
+	exfatVolumeLabel    = 0x83
+)
+
+// maxLabelSearch limits how much of the exFAT root directory is searched
+// for the volume label entry, which is normally among its first entries.
+const maxLabelSearch = 64 << 10
+
+// exfat summarizes an exFAT boot sector. The volume label is read from the
+// root directory.
+func (s *Summary) exfat(r io.ReaderAt, sector []byte) error {
+	sectorShift := uint(sector[0x6C])
+	clusterShift := uint(sector[0x6D])
+	if sectorShift < 9 || sectorShift > 12 || sectorShift+clusterShift > 25 {
+		return nil
+	}
+	s.SectorSize = 1 << sectorShift
+	s.ClusterSize = s.SectorSize << clusterShift
+	s.TotalSize = int64(binary.LittleEndian.Uint64(sector[0x48:])) << sectorShift
+	s.SerialNumber = uint64(binary.LittleEndian.Uint32(sector[0x64:]))
+	revision := binary.LittleEndian.Uint16(sector[0x68:])
+	s.Version = fmt.Sprintf("%d.%02d", revision>>8, revision&0xFF)
+
+	heap := int64(binary.LittleEndian.Uint32(sector[0x58:])) << sectorShift
+	root := int64(binary.LittleEndian.Uint32(sector[0x60:]))
+	if root < 2 {
+		return nil
+	}
+	size := int64(s.ClusterSize)
+	if size > maxLabelSearch {
+		size = maxLabelSearch
+	}
+	dir := make([]byte, size)
+	n, err := r.ReadAt(dir, heap+(root-2)*int64(s.ClusterSize))
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("exFAT root directory: %w", err)
+	}
+	for pos := 0; pos+32 <= n; pos += 32 {
+		entry := dir[pos : pos+32]
+		switch entry[0] {
+		case exfatEndOfDirectory:
+			return nil
+		case exfatVolumeLabel:
+			length := int(entry[1])
+			if length > 11 {
+				length = 11
+			}
+			s.Label = utf16le.Decode(entry[2 : 2+length*2])
+			return nil
+		}
+	}
+	return nil
+}
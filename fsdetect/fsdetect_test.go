@@ -0,0 +1,256 @@
+package fsdetect_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/gentlemanautomaton/volmgmt/fsdetect"
+	"github.com/gentlemanautomaton/volmgmt/internal/ntfstest"
+	ntfsvol "github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/partition"
+)
+
+var volumeID = partition.MustParseGUID("{4967D63B-2E29-4AD8-8399-F6A339E3D001}")
+
+// bootSector returns a volume whose first sector holds the given OEM ID
+// and boot signature.
+func bootSector(oem string, size int) []byte {
+	b := make([]byte, size)
+	copy(b[3:], oem)
+	b[510], b[511] = 0x55, 0xAA
+	return b
+}
+
+func putUTF16(b []byte, s string) int {
+	u := utf16.Encode([]rune(s))
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[i*2:], c)
+	}
+	return len(u) * 2
+}
+
+func ntfs() []byte {
+	b := bootSector("NTFS    ", 512)
+	binary.LittleEndian.PutUint16(b[0x0B:], 512)
+	b[0x0D] = 8
+	binary.LittleEndian.PutUint64(b[0x28:], 2047)
+	b[0x40] = 0xF6 // 1 KiB FILE records
+	b[0x44] = 1    // One cluster per INDX record
+	binary.LittleEndian.PutUint64(b[0x48:], 0x0123456789ABCDEF)
+	return b
+}
+
+func refs() []byte {
+	b := bootSector("ReFS\x00\x00\x00\x00", 512)
+	copy(b[0x10:], "FSRS")
+	binary.LittleEndian.PutUint64(b[0x18:], 4096)
+	binary.LittleEndian.PutUint32(b[0x20:], 512)
+	binary.LittleEndian.PutUint32(b[0x24:], 128)
+	b[0x28], b[0x29] = 3, 4
+	binary.LittleEndian.PutUint64(b[0x38:], 0xFEDCBA9876543210)
+	return b
+}
+
+func fat16() []byte {
+	b := bootSector("MSDOS5.0", 512)
+	binary.LittleEndian.PutUint16(b[0x0B:], 512)
+	b[0x0D] = 4
+	binary.LittleEndian.PutUint16(b[0x0E:], 1)
+	b[0x10] = 2
+	binary.LittleEndian.PutUint16(b[0x11:], 512)
+	binary.LittleEndian.PutUint16(b[0x13:], 20000)
+	binary.LittleEndian.PutUint16(b[0x16:], 20)
+	b[0x26] = 0x29
+	binary.LittleEndian.PutUint32(b[0x27:], 0x1A2B3C4D)
+	copy(b[0x2B:], "BOOT       ") // The file system type label is blank
+	return b
+}
+
+func fat32() []byte {
+	b := bootSector("MSWIN4.1", 512)
+	binary.LittleEndian.PutUint16(b[0x0B:], 512)
+	b[0x0D] = 8
+	binary.LittleEndian.PutUint16(b[0x0E:], 32)
+	b[0x10] = 2
+	binary.LittleEndian.PutUint32(b[0x20:], 1000000)
+	binary.LittleEndian.PutUint32(b[0x24:], 1000)
+	b[0x42] = 0x29
+	binary.LittleEndian.PutUint32(b[0x43:], 0x5E6F7081)
+	copy(b[0x47:], "NO NAME    FAT32   ")
+	return b
+}
+
+func exfat() []byte {
+	b := bootSector("EXFAT   ", 64<<10)
+	binary.LittleEndian.PutUint64(b[0x48:], 1<<20)
+	binary.LittleEndian.PutUint32(b[0x58:], 64) // Cluster heap at sector 64
+	binary.LittleEndian.PutUint32(b[0x60:], 4)  // Root directory at cluster 4
+	binary.LittleEndian.PutUint32(b[0x64:], 0xC0FFEE11)
+	binary.LittleEndian.PutUint16(b[0x68:], 0x0100)
+	b[0x6C], b[0x6D] = 9, 3
+
+	root := b[64*512+2*4096:]
+	root[0] = 0x81 // Allocation bitmap
+	label := root[32:]
+	label[0] = 0x83
+	label[1] = 7
+	putUTF16(label[2:], "Photos!")
+	return b
+}
+
+func bitlocker() []byte {
+	b := bootSector("-FVE-FS-", 64<<10)
+	binary.LittleEndian.PutUint16(b[0x0B:], 512)
+	b[0x0D] = 8
+	binary.LittleEndian.PutUint64(b[0xB0:], 0x2000)
+	binary.LittleEndian.PutUint64(b[0xB8:], 0x4000)
+
+	m := b[0x4000:]
+	copy(m, "-FVE-FS-")
+	binary.LittleEndian.PutUint16(m[0x0A:], 2)
+	binary.LittleEndian.PutUint64(m[0x10:], 1<<30)
+	h := m[0x40:]
+	copy(h[0x10:], volumeID[:])
+	ft := uint64(time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC).UnixNano()/100 + 116444736000000000)
+	binary.LittleEndian.PutUint64(h[0x28:], ft)
+
+	entries := h[0x30:]
+	other := entries[:16] // A key protector, which is skipped
+	binary.LittleEndian.PutUint16(other[0:], 16)
+	binary.LittleEndian.PutUint16(other[2:], 0x0002)
+	desc := entries[16:]
+	n := putUTF16(desc[8:], "DESKTOP E: 6/1/2023\x00")
+	binary.LittleEndian.PutUint16(desc[0:], uint16(8+n))
+	binary.LittleEndian.PutUint16(desc[2:], 0x0007)
+	binary.LittleEndian.PutUint16(desc[4:], 0x0002)
+	binary.LittleEndian.PutUint32(h[0x00:], uint32(0x30+16+8+n))
+	return b
+}
+
+func spaces() []byte {
+	b := make([]byte, 8192)
+	copy(b[4096:], "SPACEDB ")
+	return b
+}
+
+func TestDetect(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		volume []byte
+		want   fsdetect.Summary
+		serial string
+	}{
+		{"NTFS", ntfs(), fsdetect.Summary{
+			Filesystem:   partition.NTFS,
+			SerialNumber: 0x0123456789ABCDEF,
+			SectorSize:   512,
+			ClusterSize:  4096,
+			TotalSize:    2047 * 512,
+		}, "89AB-CDEF"},
+		{"ReFS", refs(), fsdetect.Summary{
+			Filesystem:   partition.ReFS,
+			Version:      "3.4",
+			SerialNumber: 0xFEDCBA9876543210,
+			SectorSize:   512,
+			ClusterSize:  64 << 10,
+			TotalSize:    4096 * 512,
+		}, "7654-3210"},
+		{"FAT16", fat16(), fsdetect.Summary{
+			Filesystem:   partition.FAT16,
+			Label:        "BOOT",
+			SerialNumber: 0x1A2B3C4D,
+			SectorSize:   512,
+			ClusterSize:  2048,
+			TotalSize:    20000 * 512,
+		}, "1A2B-3C4D"},
+		{"FAT32", fat32(), fsdetect.Summary{
+			Filesystem:   partition.FAT32,
+			SerialNumber: 0x5E6F7081,
+			SectorSize:   512,
+			ClusterSize:  4096,
+			TotalSize:    1000000 * 512,
+		}, "5E6F-7081"},
+		{"exFAT", exfat(), fsdetect.Summary{
+			Filesystem:   partition.ExFAT,
+			Version:      "1.00",
+			Label:        "Photos!",
+			SerialNumber: 0xC0FFEE11,
+			SectorSize:   512,
+			ClusterSize:  4096,
+			TotalSize:    1 << 29,
+		}, "C0FF-EE11"},
+		{"BitLocker", bitlocker(), fsdetect.Summary{
+			Filesystem:  partition.BitLocker,
+			Version:     "2",
+			Label:       "DESKTOP E: 6/1/2023",
+			VolumeID:    volumeID,
+			SectorSize:  512,
+			ClusterSize: 4096,
+			TotalSize:   1 << 30,
+			Created:     time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC),
+		}, ""},
+		{"Storage Spaces", spaces(), fsdetect.Summary{Filesystem: partition.StorageSpaces}, ""},
+		{"unknown", make([]byte, 4096), fsdetect.Summary{}, ""},
+		{"short", make([]byte, 100), fsdetect.Summary{}, ""},
+	} {
+		got, err := fsdetect.Detect(bytes.NewReader(tt.volume))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+		if serial := got.Serial(); serial != tt.serial {
+			t.Errorf("%s: serial %q, want %q", tt.name, serial, tt.serial)
+		}
+	}
+}
+
+func TestBitLockerBackupMetadata(t *testing.T) {
+	b := bitlocker()
+	copy(b[0x2000:], "-FVE-FS-") // The first copy has no valid header
+	binary.LittleEndian.PutUint32(b[0x2040:], 0xFFFFFFFF)
+
+	got, err := fsdetect.Detect(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.VolumeID != volumeID || got.Label != "DESKTOP E: 6/1/2023" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestNTFSLabel(t *testing.T) {
+	img := ntfstest.NewImage(64, ntfstest.Run{LCN: 4, Length: 4})
+	img.Set(ntfsvol.RecordVolume, ntfstest.Record{
+		Sequence: ntfsvol.RecordVolume,
+		Flags:    ntfsvol.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.ResidentAttr(ntfsvol.AttrVolumeName, "", ntfstest.UTF16("Archive")),
+		},
+	})
+
+	got, err := fsdetect.Detect(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Filesystem != partition.NTFS || got.Label != "Archive" {
+		t.Errorf("got %+v", got)
+	}
+	if got.ClusterSize != ntfstest.ClusterSize {
+		t.Errorf("got cluster size %d, want %d", got.ClusterSize, ntfstest.ClusterSize)
+	}
+}
+
+func TestNTFSInvalidGeometry(t *testing.T) {
+	b := ntfs()
+	binary.LittleEndian.PutUint64(b[0x28:], 1<<62) // Overflows the volume size
+	if _, err := fsdetect.Detect(bytes.NewReader(b)); !errors.Is(err, ntfsvol.ErrInvalidGeometry) {
+		t.Fatalf("got %v, want ErrInvalidGeometry", err)
+	}
+}
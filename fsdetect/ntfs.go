@@ -0,0 +1,37 @@
+package fsdetect
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+)
+
+// ntfs summarizes an NTFS boot sector. The label is read from $Volume when
+// the master file table can be reached through r; volumes whose file table
+// cannot be read are summarized without one.
+func (s *Summary) ntfs(r io.ReaderAt, sector []byte) error {
+	boot, err := ntfs.ParseBootSector(sector)
+	if err != nil {
+		return err
+	}
+	s.SectorSize = boot.BytesPerSector
+	s.ClusterSize = boot.ClusterSize()
+	s.TotalSize = boot.Size()
+	s.SerialNumber = boot.SerialNumber
+
+	if v, err := ntfs.Open(r); err == nil {
+		s.Label, _ = v.Label()
+	}
+	return nil
+}
+
+// refs summarizes a ReFS boot sector.
+func (s *Summary) refs(sector []byte) {
+	s.SectorSize = binary.LittleEndian.Uint32(sector[0x20:])
+	s.ClusterSize = s.SectorSize * binary.LittleEndian.Uint32(sector[0x24:])
+	s.TotalSize = int64(binary.LittleEndian.Uint64(sector[0x18:])) * int64(s.SectorSize)
+	s.Version = fmt.Sprintf("%d.%d", sector[0x28], sector[0x29])
+	s.SerialNumber = binary.LittleEndian.Uint64(sector[0x38:])
+}
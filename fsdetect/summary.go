@@ -0,0 +1,111 @@
+package fsdetect
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/partition"
+)
+
+// headerSize is the number of bytes read from the start of a volume. It
+// holds the boot sector and the Storage Spaces signature.
+const headerSize = 8192
+
+// spacesSignature marks the metadata held by a Storage Spaces pool disk.
+var spacesSignature = []byte("SPACEDB ")
+
+// Summary describes the file system held by a volume. Fields that the file
+// system does not record are left empty.
+type Summary struct {
+	Filesystem   partition.Filesystem
+	Version      string
+	Label        string
+	SerialNumber uint64
+	VolumeID     partition.GUID // BitLocker volume identifier
+	SectorSize   uint32
+	ClusterSize  uint32
+	TotalSize    int64     // Size of the volume in bytes
+	Created      time.Time // Time a BitLocker volume was encrypted
+}
+
+// Detect identifies the file system held by r and summarizes it. If the
+// file system is not recognized a summary with an Unknown file system is
+// returned.
+func Detect(r io.ReaderAt) (Summary, error) {
+	header := make([]byte, headerSize)
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return Summary{}, err
+	}
+	header = header[:n]
+	if len(header) < 512 {
+		return Summary{}, nil
+	}
+
+	for _, offset := range []int{0, 4096} {
+		if offset+len(spacesSignature) <= len(header) && bytes.HasPrefix(header[offset:], spacesSignature) {
+			return Summary{Filesystem: partition.StorageSpaces}, nil
+		}
+	}
+
+	fs, err := partition.Detect(bytes.NewReader(header))
+	if err != nil {
+		return Summary{}, err
+	}
+	s := Summary{Filesystem: fs}
+	switch fs {
+	case partition.NTFS:
+		if err := s.ntfs(r, header); err != nil {
+			return s, err
+		}
+	case partition.ReFS:
+		s.refs(header)
+	case partition.FAT12, partition.FAT16, partition.FAT32:
+		s.fat(header)
+	case partition.ExFAT:
+		if err := s.exfat(r, header); err != nil {
+			return s, err
+		}
+	case partition.BitLocker:
+		if err := s.bitlocker(r, header); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+// Serial returns the serial number in the form displayed by Windows, which
+// shows its lower 32 bits. It returns an empty string if the file system
+// has no serial number.
+func (s Summary) Serial() string {
+	if s.SerialNumber == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%04X-%04X", uint16(s.SerialNumber>>16), uint16(s.SerialNumber))
+}
+
+// String returns a one-line description of the summary.
+func (s Summary) String() string {
+	out := s.Filesystem.String()
+	if s.Version != "" {
+		out += " " + s.Version
+	}
+	if s.Label != "" {
+		out += fmt.Sprintf(" %q", s.Label)
+	}
+	if serial := s.Serial(); serial != "" {
+		out += ", serial " + serial
+	}
+	if !s.VolumeID.IsZero() {
+		out += ", ID " + s.VolumeID.String()
+	}
+	if s.ClusterSize != 0 {
+		out += fmt.Sprintf(", %d byte clusters", s.ClusterSize)
+	}
+	if s.TotalSize != 0 {
+		out += fmt.Sprintf(", %d bytes", s.TotalSize)
+	}
+	return out
+}
@@ -1,3 +1,5 @@
+//go:build windows
+
 package guidconv
 
 import "golang.org/x/sys/windows"
@@ -0,0 +1,44 @@
+// Package filetime converts between Windows FILETIME values and times.
+//
+// A FILETIME is a count of 100-nanosecond intervals since January 1, 1601
+// UTC. The conversions split it into seconds and nanoseconds so that times
+// beyond the range of time.Duration are preserved.
+package filetime
+
+import "time"
+
+const (
+	// epoch is the number of seconds between the Windows epoch of
+	// January 1, 1601 and the Unix epoch.
+	epoch = 11644473600
+
+	// ticks is the number of 100-nanosecond intervals in a second.
+	ticks = 10000000
+)
+
+// ToTime converts the FILETIME v into a time. A value of zero produces a
+// zero time.
+func ToTime(v uint64) time.Time {
+	if v == 0 {
+		return time.Time{}
+	}
+	sec := int64(v/ticks) - epoch
+	nsec := int64(v%ticks) * 100
+	return time.Unix(sec, nsec).UTC()
+}
+
+// UnixNano converts the FILETIME v into the number of nanoseconds since the
+// Unix epoch. Unlike ToTime it applies no special meaning to zero.
+func UnixNano(v uint64) int64 {
+	return (int64(v) - epoch*ticks) * 100
+}
+
+// FromTime converts t into a FILETIME. The zero time and times before
+// January 1, 1601 produce zero.
+func FromTime(t time.Time) uint64 {
+	sec := t.Unix() + epoch
+	if t.IsZero() || sec < 0 {
+		return 0
+	}
+	return uint64(sec)*ticks + uint64(t.Nanosecond()/100)
+}
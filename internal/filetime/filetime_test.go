@@ -0,0 +1,51 @@
+package filetime_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/filetime"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, want := range []time.Time{
+		time.Date(1601, 1, 1, 0, 0, 0, 100, time.UTC),
+		time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, 6, 15, 12, 30, 45, 123456700, time.UTC),
+		time.Date(2262, 4, 12, 0, 0, 0, 0, time.UTC),
+		time.Date(9999, 12, 31, 23, 59, 59, 999999900, time.UTC),
+	} {
+		v := filetime.FromTime(want)
+		if got := filetime.ToTime(v); !got.Equal(want) {
+			t.Errorf("ToTime(FromTime(%s)) = %s", want, got)
+		}
+	}
+}
+
+func TestKnownValue(t *testing.T) {
+	const v = 132366978451234567
+	want := time.Date(2020, 6, 15, 12, 30, 45, 123456700, time.UTC)
+	if got := filetime.ToTime(v); !got.Equal(want) {
+		t.Errorf("ToTime(%d) = %s, want %s", uint64(v), got, want)
+	}
+}
+
+func TestZero(t *testing.T) {
+	if got := filetime.ToTime(0); !got.IsZero() {
+		t.Errorf("ToTime(0) = %s, want zero time", got)
+	}
+	if got := filetime.FromTime(time.Time{}); got != 0 {
+		t.Errorf("FromTime(zero) = %d, want 0", got)
+	}
+	if got := filetime.FromTime(time.Date(1600, 1, 1, 0, 0, 0, 0, time.UTC)); got != 0 {
+		t.Errorf("FromTime(1600) = %d, want 0", got)
+	}
+}
+
+func TestUnixNano(t *testing.T) {
+	const v = 132366978451234567
+	want := time.Date(2020, 6, 15, 12, 30, 45, 123456700, time.UTC).UnixNano()
+	if got := filetime.UnixNano(v); got != want {
+		t.Errorf("UnixNano(%d) = %d, want %d", uint64(v), got, want)
+	}
+}
@@ -0,0 +1,530 @@
+// Package ntfstest builds synthetic NTFS images for tests.
+//
+// The images are minimal but structurally valid: they have a boot sector, a
+// master file table with update sequence fixups and the system files that
+// the ntfs package looks for.
+package ntfstest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/internal/filetime"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+)
+
+// Geometry of the images.
+const (
+	SectorSize  = 512
+	ClusterSize = 4096
+	RecordSize  = 1024
+)
+
+// Run describes a data run. An LCN of -1 describes a sparse run.
+type Run struct {
+	LCN    int64
+	Length uint64
+}
+
+// Record describes a FILE record.
+type Record struct {
+	Sequence uint16
+	Flags    uint16
+	Base     fileref.ID
+	Attrs    [][]byte
+}
+
+// Image builds an NTFS image in memory.
+type Image struct {
+	data    []byte
+	mftRuns []Run
+	records map[uint64]Record
+}
+
+// NewImage returns an image with the given number of clusters whose
+// master file table is stored in mftRuns. It is populated with the system
+// files found on every NTFS volume.
+func NewImage(clusters int, mftRuns ...Run) *Image {
+	img := &Image{
+		data:    make([]byte, clusters*ClusterSize),
+		mftRuns: mftRuns,
+		records: make(map[uint64]Record),
+	}
+	root := fileref.NewSegment(ntfs.RecordRoot, ntfs.RecordRoot)
+	for _, sys := range []struct {
+		number uint64
+		name   string
+		flags  uint16
+	}{
+		{ntfs.RecordLogFile, "$LogFile", ntfs.RecordInUse},
+		{ntfs.RecordRoot, ".", ntfs.RecordInUse | ntfs.RecordDirectory},
+		{ntfs.RecordSecure, "$Secure", ntfs.RecordInUse | ntfs.RecordIndexView},
+		{ntfs.RecordUpCase, "$UpCase", ntfs.RecordInUse},
+		{ntfs.RecordExtend, "$Extend", ntfs.RecordInUse | ntfs.RecordDirectory},
+	} {
+		img.records[sys.number] = Record{
+			Sequence: uint16(sys.number),
+			Flags:    sys.flags,
+			Attrs: [][]byte{
+				FileNameAttr(root, sys.name, ntfs.NamespaceWin32DOS, fileattr.Hidden|fileattr.System),
+			},
+		}
+	}
+	return img
+}
+
+// mftClusters returns the number of clusters allocated to the MFT.
+func (img *Image) mftClusters() (n uint64) {
+	for _, run := range img.mftRuns {
+		n += run.Length
+	}
+	return n
+}
+
+// Set stores a record in the master file table.
+func (img *Image) Set(number uint64, r Record) {
+	img.records[number] = r
+}
+
+// SetBitmap stores a cluster allocation bitmap for the image in the
+// cluster at lcn. The boot sector, the master file table, the bitmap itself
+// and the given runs are marked as allocated.
+func (img *Image) SetBitmap(lcn int64, allocated ...Run) {
+	clusters := len(img.data) / ClusterSize
+	bitmap := make([]byte, (clusters+7)/8)
+	mark := func(run Run) {
+		for i := int64(0); i < int64(run.Length); i++ {
+			c := run.LCN + i
+			bitmap[c/8] |= 1 << (c % 8)
+		}
+	}
+	mark(Run{LCN: 0, Length: 1})
+	mark(Run{LCN: lcn, Length: 1})
+	for _, run := range append(img.mftRuns, allocated...) {
+		if run.LCN >= 0 {
+			mark(run)
+		}
+	}
+	img.WriteClusters(lcn, bitmap)
+	img.records[ntfs.RecordBitmap] = Record{
+		Sequence: ntfs.RecordBitmap,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			FileNameAttr(fileref.NewSegment(ntfs.RecordRoot, ntfs.RecordRoot), "$Bitmap", ntfs.NamespaceWin32DOS, fileattr.Hidden|fileattr.System),
+			NonResidentAttr(ntfs.AttrData, "", []Run{{LCN: lcn, Length: 1}}, uint64(len(bitmap)), 0, 0, 0),
+		},
+	}
+}
+
+// WriteClusters writes data to the image starting at the given cluster.
+func (img *Image) WriteClusters(lcn int64, data []byte) {
+	copy(img.data[lcn*ClusterSize:], data)
+}
+
+// Bytes assembles the image and returns its contents.
+func (img *Image) Bytes(t testing.TB) []byte {
+	t.Helper()
+
+	img.writeBootSector()
+
+	mftSize := img.mftClusters() * ClusterSize
+	if _, ok := img.records[ntfs.RecordMFT]; !ok {
+		img.records[ntfs.RecordMFT] = Record{
+			Sequence: 1,
+			Flags:    ntfs.RecordInUse,
+			Attrs: [][]byte{
+				FileNameAttr(fileref.NewSegment(ntfs.RecordRoot, ntfs.RecordRoot), "$MFT", ntfs.NamespaceWin32DOS, fileattr.Hidden|fileattr.System),
+				NonResidentAttr(ntfs.AttrData, "", img.mftRuns, mftSize, mftSize, 0, 0),
+			},
+		}
+	}
+
+	for number, r := range img.records {
+		offset := int64(number) * RecordSize
+		if uint64(offset) >= mftSize {
+			t.Fatalf("record %d does not fit in the MFT", number)
+		}
+		data := encodeRecord(t, uint32(number), r)
+		img.writeMFT(offset, data)
+	}
+
+	return img.data
+}
+
+// Reader assembles the image and returns a reader for it.
+func (img *Image) Reader(t testing.TB) *bytes.Reader {
+	return bytes.NewReader(img.Bytes(t))
+}
+
+func (img *Image) writeBootSector() {
+	b := img.data[:SectorSize]
+	b[0], b[1], b[2] = 0xEB, 0x52, 0x90
+	copy(b[3:], "NTFS    ")
+	binary.LittleEndian.PutUint16(b[0x0B:], SectorSize)
+	b[0x0D] = ClusterSize / SectorSize
+	b[0x15] = 0xF8
+	binary.LittleEndian.PutUint64(b[0x28:], uint64(len(img.data)/SectorSize)-1)
+	binary.LittleEndian.PutUint64(b[0x30:], uint64(img.mftRuns[0].LCN))
+	binary.LittleEndian.PutUint64(b[0x38:], 2)
+	b[0x40] = 0xF6 // 2^10 = 1024 bytes per FILE record
+	b[0x44] = 1    // One cluster per INDX record
+	binary.LittleEndian.PutUint64(b[0x48:], 0x1234567890ABCDEF)
+	b[510], b[511] = 0x55, 0xAA
+}
+
+// writeMFT writes data at the given offset within the MFT.
+func (img *Image) writeMFT(offset int64, data []byte) {
+	var vcn int64
+	for _, run := range img.mftRuns {
+		start, end := vcn*ClusterSize, (vcn+int64(run.Length))*ClusterSize
+		if offset >= start && offset < end {
+			copy(img.data[run.LCN*ClusterSize+offset-start:], data)
+			return
+		}
+		vcn += int64(run.Length)
+	}
+}
+
+// encodeRecord encodes a FILE record and protects it with an update
+// sequence.
+func encodeRecord(t testing.TB, number uint32, r Record) []byte {
+	t.Helper()
+	b := RecordImage(t, number, r)
+	Protect(b, 0x30, RecordSize/SectorSize+1, 0x0042)
+	return b
+}
+
+// RecordImage encodes a FILE record without protecting it with an update
+// sequence, which is the form in which records are held in memory.
+func RecordImage(t testing.TB, number uint32, r Record) []byte {
+	t.Helper()
+
+	const (
+		usaOffset  = 0x30
+		usaCount   = RecordSize/SectorSize + 1
+		attrOffset = 0x38
+	)
+
+	b := make([]byte, RecordSize)
+	copy(b, "FILE")
+	binary.LittleEndian.PutUint16(b[0x04:], usaOffset)
+	binary.LittleEndian.PutUint16(b[0x06:], usaCount)
+	binary.LittleEndian.PutUint16(b[0x10:], r.Sequence)
+	binary.LittleEndian.PutUint16(b[0x12:], 1)
+	binary.LittleEndian.PutUint16(b[0x14:], attrOffset)
+	binary.LittleEndian.PutUint16(b[0x16:], r.Flags)
+	binary.LittleEndian.PutUint32(b[0x1C:], RecordSize)
+	base := r.Base.LittleEndian()
+	copy(b[0x20:0x28], base[:8])
+	binary.LittleEndian.PutUint32(b[0x2C:], number)
+
+	offset := attrOffset
+	for i, attr := range r.Attrs {
+		binary.LittleEndian.PutUint16(attr[0x0E:], uint16(i))
+		if offset+len(attr)+8 > RecordSize {
+			t.Fatalf("attributes of record %d do not fit", number)
+		}
+		copy(b[offset:], attr)
+		offset += len(attr)
+	}
+	binary.LittleEndian.PutUint32(b[offset:], 0xFFFFFFFF)
+	offset += 8
+	binary.LittleEndian.PutUint32(b[0x18:], uint32(offset))
+	binary.LittleEndian.PutUint16(b[0x28:], uint16(len(r.Attrs)))
+
+	return b
+}
+
+// Protect applies an update sequence to a multi-sector record, replacing
+// the last two bytes of each sector with usn.
+func Protect(b []byte, usaOffset, usaCount int, usn uint16) {
+	binary.LittleEndian.PutUint16(b[usaOffset:], usn)
+	for i := 1; i < usaCount; i++ {
+		end := i*SectorSize - 2
+		copy(b[usaOffset+i*2:], b[end:end+2])
+		binary.LittleEndian.PutUint16(b[end:], usn)
+	}
+}
+
+// ResidentAttr encodes a resident attribute.
+func ResidentAttr(typ ntfs.AttributeType, name string, value []byte) []byte {
+	nameData := UTF16(name)
+	valueOffset := align8(0x18 + len(nameData))
+	b := make([]byte, align8(valueOffset+len(value)))
+	binary.LittleEndian.PutUint32(b[0x00:], uint32(typ))
+	binary.LittleEndian.PutUint32(b[0x04:], uint32(len(b)))
+	b[0x09] = byte(len(nameData) / 2)
+	binary.LittleEndian.PutUint16(b[0x0A:], 0x18)
+	binary.LittleEndian.PutUint32(b[0x10:], uint32(len(value)))
+	binary.LittleEndian.PutUint16(b[0x14:], uint16(valueOffset))
+	copy(b[0x18:], nameData)
+	copy(b[valueOffset:], value)
+	return b
+}
+
+// NonResidentAttr encodes a non-resident attribute that starts at VCN 0.
+// If initSize is zero it is assumed to equal realSize.
+func NonResidentAttr(typ ntfs.AttributeType, name string, runs []Run, realSize, initSize uint64, flags uint16, compressionUnit uint16) []byte {
+	return NonResidentAttrAt(typ, name, 0, runs, realSize, initSize, flags, compressionUnit)
+}
+
+// NonResidentAttrAt encodes a non-resident attribute that starts at the
+// given VCN. Attributes that start after VCN 0 hold their sizes as zero.
+func NonResidentAttrAt(typ ntfs.AttributeType, name string, startVCN uint64, runs []Run, realSize, initSize uint64, flags uint16, compressionUnit uint16) []byte {
+	if initSize == 0 {
+		initSize = realSize
+	}
+	headerSize := 0x40
+	if compressionUnit != 0 {
+		headerSize = 0x48
+	}
+	nameData := UTF16(name)
+	runsOffset := align8(headerSize + len(nameData))
+	runData := EncodeRuns(runs)
+	b := make([]byte, align8(runsOffset+len(runData)))
+
+	var clusters uint64
+	for _, run := range runs {
+		clusters += run.Length
+	}
+
+	binary.LittleEndian.PutUint32(b[0x00:], uint32(typ))
+	binary.LittleEndian.PutUint32(b[0x04:], uint32(len(b)))
+	b[0x08] = 1
+	b[0x09] = byte(len(nameData) / 2)
+	binary.LittleEndian.PutUint16(b[0x0A:], uint16(headerSize))
+	binary.LittleEndian.PutUint16(b[0x0C:], flags)
+	binary.LittleEndian.PutUint64(b[0x10:], startVCN)
+	binary.LittleEndian.PutUint64(b[0x18:], startVCN+clusters-1)
+	binary.LittleEndian.PutUint16(b[0x20:], uint16(runsOffset))
+	binary.LittleEndian.PutUint16(b[0x22:], compressionUnit)
+	if startVCN == 0 {
+		binary.LittleEndian.PutUint64(b[0x28:], clusters*ClusterSize)
+		binary.LittleEndian.PutUint64(b[0x30:], realSize)
+		binary.LittleEndian.PutUint64(b[0x38:], initSize)
+	}
+	copy(b[headerSize:], nameData)
+	copy(b[runsOffset:], runData)
+	return b
+}
+
+// EncodeRuns encodes data runs as mapping pairs.
+func EncodeRuns(runs []Run) []byte {
+	var (
+		b    []byte
+		prev int64
+	)
+	for _, run := range runs {
+		length := intBytes(int64(run.Length), false)
+		var offset []byte
+		if run.LCN >= 0 {
+			offset = intBytes(run.LCN-prev, true)
+			prev = run.LCN
+		}
+		b = append(b, byte(len(offset)<<4|len(length)))
+		b = append(b, length...)
+		b = append(b, offset...)
+	}
+	return append(b, 0)
+}
+
+// intBytes returns the shortest little-endian encoding of v.
+func intBytes(v int64, signed bool) []byte {
+	var b []byte
+	for {
+		b = append(b, byte(v))
+		v >>= 8
+		last := b[len(b)-1]
+		if signed {
+			if (v == 0 && last&0x80 == 0) || (v == -1 && last&0x80 != 0) {
+				return b
+			}
+		} else if v == 0 {
+			return b
+		}
+	}
+}
+
+// FileNameAttr encodes a resident $FILE_NAME attribute.
+func FileNameAttr(parent fileref.ID, name string, ns ntfs.Namespace, attrs fileattr.Value) []byte {
+	return ResidentAttr(ntfs.AttrFileName, "", FileNameValue(parent, name, ns, attrs, Time, 0))
+}
+
+// FileNameValue encodes the value of a $FILE_NAME attribute.
+func FileNameValue(parent fileref.ID, name string, ns ntfs.Namespace, attrs fileattr.Value, when time.Time, size uint64) []byte {
+	nameData := UTF16(name)
+	b := make([]byte, 0x42+len(nameData))
+	binary.LittleEndian.PutUint64(b[0x00:], uint64(parent.Int64()))
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint64(b[0x08+i*8:], Filetime(when))
+	}
+	binary.LittleEndian.PutUint64(b[0x28:], size)
+	binary.LittleEndian.PutUint64(b[0x30:], size)
+	binary.LittleEndian.PutUint32(b[0x38:], uint32(attrs))
+	b[0x40] = byte(len(nameData) / 2)
+	b[0x41] = byte(ns)
+	copy(b[0x42:], nameData)
+	return b
+}
+
+// Time is the timestamp given to files created by the builder.
+var Time = time.Date(2020, 6, 15, 12, 30, 45, 123456700, time.UTC)
+
+// Filetime converts t to a Windows FILETIME.
+func Filetime(t time.Time) uint64 {
+	return filetime.FromTime(t)
+}
+
+func UTF16(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, len(u)*2)
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[i*2:], c)
+	}
+	return b
+}
+
+func align8(n int) int {
+	return (n + 7) &^ 7
+}
+
+// MFTBytes assembles the image and returns the contents of its master file
+// table, as though it had been extracted from the volume.
+func (img *Image) MFTBytes(t testing.TB) []byte {
+	data := img.Bytes(t)
+	var mft []byte
+	for _, run := range img.mftRuns {
+		start := run.LCN * ClusterSize
+		mft = append(mft, data[start:start+int64(run.Length)*ClusterSize]...)
+	}
+	return mft
+}
+
+// StdInfoAttr encodes a resident $STANDARD_INFORMATION attribute in the
+// NTFS 3.x format.
+func StdInfoAttr(created, modified, changed, accessed time.Time, attrs fileattr.Value, securityID uint32, usn int64) []byte {
+	b := make([]byte, 0x48)
+	binary.LittleEndian.PutUint64(b[0x00:], Filetime(created))
+	binary.LittleEndian.PutUint64(b[0x08:], Filetime(modified))
+	binary.LittleEndian.PutUint64(b[0x10:], Filetime(changed))
+	binary.LittleEndian.PutUint64(b[0x18:], Filetime(accessed))
+	binary.LittleEndian.PutUint32(b[0x20:], uint32(attrs))
+	binary.LittleEndian.PutUint32(b[0x34:], securityID)
+	binary.LittleEndian.PutUint64(b[0x40:], uint64(usn))
+	return ResidentAttr(ntfs.AttrStandardInformation, "", b)
+}
+
+// ListEntry describes an entry in an attribute list.
+type ListEntry struct {
+	Type     ntfs.AttributeType
+	Name     string
+	StartVCN uint64
+	Record   fileref.ID
+	ID       uint16
+}
+
+// AttrListValue encodes the value of an $ATTRIBUTE_LIST attribute.
+func AttrListValue(entries ...ListEntry) []byte {
+	var b []byte
+	for _, e := range entries {
+		name := UTF16(e.Name)
+		entry := make([]byte, align8(0x1A+len(name)))
+		binary.LittleEndian.PutUint32(entry[0x00:], uint32(e.Type))
+		binary.LittleEndian.PutUint16(entry[0x04:], uint16(len(entry)))
+		entry[0x06] = byte(len(name) / 2)
+		entry[0x07] = 0x1A
+		binary.LittleEndian.PutUint64(entry[0x08:], e.StartVCN)
+		binary.LittleEndian.PutUint64(entry[0x10:], uint64(e.Record.Int64()))
+		binary.LittleEndian.PutUint16(entry[0x18:], e.ID)
+		copy(entry[0x1A:], name)
+		b = append(b, entry...)
+	}
+	return b
+}
+
+// CompressLZNT1 compresses data with LZNT1 using a simple greedy search. It
+// is only intended to produce valid input for tests.
+func CompressLZNT1(data []byte) []byte {
+	var out []byte
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > 4096 {
+			chunk = chunk[:4096]
+		}
+		data = data[len(chunk):]
+
+		var body []byte
+		for pos := 0; pos < len(chunk); {
+			flagIndex := len(body)
+			body = append(body, 0)
+			for bit := 0; bit < 8 && pos < len(chunk); bit++ {
+				lengthBits := 12
+				for p := pos - 1; p >= 0x10; p >>= 1 {
+					lengthBits--
+				}
+				maxLength := 1<<lengthBits + 2
+				maxOffset := 1 << (16 - lengthBits)
+
+				bestLength, bestOffset := 0, 0
+				for offset := 1; offset <= pos && offset <= maxOffset; offset++ {
+					length := 0
+					for pos+length < len(chunk) && length < maxLength && chunk[pos+length] == chunk[pos+length-offset] {
+						length++
+					}
+					if length > bestLength {
+						bestLength, bestOffset = length, offset
+					}
+				}
+
+				if bestLength < 3 {
+					body = append(body, chunk[pos])
+					pos++
+					continue
+				}
+				token := uint16((bestOffset-1)<<lengthBits | (bestLength - 3))
+				body = append(body, byte(token), byte(token>>8))
+				body[flagIndex] |= 1 << bit
+				pos += bestLength
+			}
+		}
+
+		header := uint16(len(body)-1) | 0xB000
+		out = append(out, byte(header), byte(header>>8))
+		out = append(out, body...)
+	}
+	return out
+}
+
+// JournalRecord describes a version 2 change journal record.
+type JournalRecord struct {
+	ID     fileref.ID
+	Parent fileref.ID
+	USN    int64
+	Time   time.Time
+	Reason uint32
+	Attrs  fileattr.Value
+	Name   string
+}
+
+// EncodeJournalRecord encodes a version 2 change journal record.
+func EncodeJournalRecord(r JournalRecord) []byte {
+	name := UTF16(r.Name)
+	b := make([]byte, align8(0x3C+len(name)))
+	binary.LittleEndian.PutUint32(b[0x00:], uint32(len(b)))
+	binary.LittleEndian.PutUint16(b[0x04:], 2)
+	binary.LittleEndian.PutUint64(b[0x08:], uint64(r.ID.Int64()))
+	binary.LittleEndian.PutUint64(b[0x10:], uint64(r.Parent.Int64()))
+	binary.LittleEndian.PutUint64(b[0x18:], uint64(r.USN))
+	binary.LittleEndian.PutUint64(b[0x20:], Filetime(r.Time))
+	binary.LittleEndian.PutUint32(b[0x28:], r.Reason)
+	binary.LittleEndian.PutUint32(b[0x34:], uint32(r.Attrs))
+	binary.LittleEndian.PutUint16(b[0x38:], uint16(len(name)))
+	binary.LittleEndian.PutUint16(b[0x3A:], 0x3C)
+	copy(b[0x3C:], name)
+	return b
+}
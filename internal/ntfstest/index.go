@@ -0,0 +1,109 @@
+package ntfstest
+
+import (
+	"encoding/binary"
+
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+)
+
+// IndexEntry encodes an index entry with the given key. If flags includes
+// ntfs.IndexEntrySubnode the entry points to the child node at subnode.
+func IndexEntry(file fileref.ID, key []byte, flags uint16, subnode uint64) []byte {
+	length := align8(0x10 + len(key))
+	if flags&ntfs.IndexEntrySubnode != 0 {
+		length += 8
+	}
+	b := make([]byte, length)
+	binary.LittleEndian.PutUint64(b[0x00:], uint64(file.Int64()))
+	binary.LittleEndian.PutUint16(b[0x08:], uint16(length))
+	binary.LittleEndian.PutUint16(b[0x0A:], uint16(len(key)))
+	binary.LittleEndian.PutUint16(b[0x0C:], flags)
+	copy(b[0x10:], key)
+	if flags&ntfs.IndexEntrySubnode != 0 {
+		binary.LittleEndian.PutUint64(b[length-8:], subnode)
+	}
+	return b
+}
+
+// ViewIndexEntry encodes an entry of a view index, which holds data in
+// place of a file reference.
+func ViewIndexEntry(key, data []byte, flags uint16) []byte {
+	dataOffset := align8(0x10 + len(key))
+	length := align8(dataOffset + len(data))
+	b := make([]byte, length)
+	binary.LittleEndian.PutUint16(b[0x00:], uint16(dataOffset))
+	binary.LittleEndian.PutUint16(b[0x02:], uint16(len(data)))
+	binary.LittleEndian.PutUint16(b[0x08:], uint16(length))
+	binary.LittleEndian.PutUint16(b[0x0A:], uint16(len(key)))
+	binary.LittleEndian.PutUint16(b[0x0C:], flags)
+	copy(b[0x10:], key)
+	copy(b[dataOffset:], data)
+	return b
+}
+
+// IndexRootValue encodes the value of an $INDEX_ROOT attribute for a file
+// name index whose INDX records are blockSize bytes long.
+func IndexRootValue(blockSize uint32, children bool, entries ...[]byte) []byte {
+	return indexRootValue(ntfs.AttrFileName, 1, blockSize, children, entries) // COLLATION_FILE_NAME
+}
+
+// ViewIndexRootValue encodes the value of an $INDEX_ROOT attribute for a
+// view index with the given collation rule.
+func ViewIndexRootValue(collation uint32, blockSize uint32, children bool, entries ...[]byte) []byte {
+	return indexRootValue(0, collation, blockSize, children, entries)
+}
+
+func indexRootValue(typ ntfs.AttributeType, collation uint32, blockSize uint32, children bool, entries [][]byte) []byte {
+	b := make([]byte, 0x10)
+	binary.LittleEndian.PutUint32(b[0x00:], uint32(typ))
+	binary.LittleEndian.PutUint32(b[0x04:], collation)
+	binary.LittleEndian.PutUint32(b[0x08:], blockSize)
+	b[0x0C] = byte(blockSize / ClusterSize)
+	node := indexNode(0x10, 0, children, entries)
+	return append(b, node...)
+}
+
+// IndexBlock encodes an INDX record of the given size holding entries. The
+// slack data is written after the last entry, where stale entries are left
+// behind as an index changes. The record is protected with an update
+// sequence.
+func IndexBlock(vcn uint64, size int, children bool, entries [][]byte, slack []byte) []byte {
+	const (
+		usaOffset  = 0x28
+		nodeOffset = 0x18
+	)
+	usaCount := size/SectorSize + 1
+	start := align8(usaOffset + usaCount*2)
+
+	b := make([]byte, size)
+	copy(b, "INDX")
+	binary.LittleEndian.PutUint16(b[0x04:], usaOffset)
+	binary.LittleEndian.PutUint16(b[0x06:], uint16(usaCount))
+	binary.LittleEndian.PutUint64(b[0x10:], vcn)
+	node := indexNode(start-nodeOffset, size-nodeOffset, children, entries)
+	copy(b[nodeOffset:], node)
+	copy(b[nodeOffset+len(node):], slack)
+	Protect(b, usaOffset, usaCount, 0x0042)
+	return b
+}
+
+// indexNode encodes an index node header followed by entries, which begin
+// at offset start relative to the header. If allocated is zero the node
+// has no slack space.
+func indexNode(start, allocated int, children bool, entries [][]byte) []byte {
+	b := make([]byte, start)
+	for _, entry := range entries {
+		b = append(b, entry...)
+	}
+	if allocated == 0 {
+		allocated = len(b)
+	}
+	binary.LittleEndian.PutUint32(b[0x00:], uint32(start))
+	binary.LittleEndian.PutUint32(b[0x04:], uint32(len(b)))
+	binary.LittleEndian.PutUint32(b[0x08:], uint32(allocated))
+	if children {
+		b[0x0C] = ntfs.IndexNodeHasChildren
+	}
+	return b
+}
@@ -0,0 +1,16 @@
+// Package number parses the numeric values accepted in place of flag and
+// code names in text.
+package number
+
+import "strconv"
+
+// ParseUint32 interprets s as a decimal number, or as a hexadecimal number
+// when it is prefixed with 0x.
+func ParseUint32(s string) (uint32, error) {
+	base := 10
+	if len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		s, base = s[2:], 16
+	}
+	n, err := strconv.ParseUint(s, base, 32)
+	return uint32(n), err
+}
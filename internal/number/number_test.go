@@ -0,0 +1,31 @@
+package number_test
+
+import (
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/number"
+)
+
+func TestParseUint32(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want uint32
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"4096", 4096, true},
+		{"0x1000", 0x1000, true},
+		{"0XfFfFfFfF", 0xFFFFFFFF, true},
+		{"4294967296", 0, false},
+		{"0x100000000", 0, false},
+		{"0x", 0, false},
+		{"010", 10, true},
+		{"-1", 0, false},
+		{"read", 0, false},
+	} {
+		got, err := number.ParseUint32(tt.s)
+		if (err == nil) != tt.ok || (tt.ok && got != tt.want) {
+			t.Errorf("ParseUint32(%q) = %d, %v", tt.s, got, err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+// Package utf16le decodes the little-endian UTF-16 strings found in
+// on-disk Windows structures.
+package utf16le
+
+import (
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+// Decode decodes little-endian UTF-16 data into a string. A trailing odd
+// byte is ignored.
+func Decode(data []byte) string {
+	u := make([]uint16, len(data)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(data[i*2:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// DecodeTerminated decodes little-endian UTF-16 data into a string, stopping
+// at the first NUL character. A trailing odd byte is ignored.
+func DecodeTerminated(data []byte) string {
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0 && data[i+1] == 0 {
+			return Decode(data[:i])
+		}
+	}
+	return Decode(data)
+}
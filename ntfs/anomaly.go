@@ -0,0 +1,117 @@
+package ntfs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Anomaly is a set of timestamp anomalies. Anomalies are commonly caused by
+// timestomping, in which an attacker alters the timestamps of a file to
+// hide it among older files.
+type Anomaly uint32
+
+// Timestamp anomalies.
+const (
+	// AnomalyCreatedBeforeFileName indicates that the $STANDARD_INFORMATION
+	// creation time is earlier than the $FILE_NAME creation time. Tools
+	// that set timestamps through the Windows API only change
+	// $STANDARD_INFORMATION, so a file that claims to predate its own name
+	// has usually been altered.
+	AnomalyCreatedBeforeFileName Anomaly = 1 << iota
+
+	// AnomalyModifiedBeforeFileName indicates that the
+	// $STANDARD_INFORMATION modification time is earlier than the
+	// $FILE_NAME modification time.
+	AnomalyModifiedBeforeFileName
+
+	// AnomalyWholeSeconds indicates that a $STANDARD_INFORMATION timestamp
+	// has no sub-second component. NTFS records timestamps with 100
+	// nanosecond precision, so whole seconds suggest that a timestamp was
+	// set by a tool rather than by the file system.
+	AnomalyWholeSeconds
+
+	// AnomalyCreatedAfterModified indicates that the $STANDARD_INFORMATION
+	// creation time is later than its modification time. Copying a file
+	// preserves its modification time, so this is also common for copies.
+	AnomalyCreatedAfterModified
+)
+
+var anomalyNames = []struct {
+	anomaly Anomaly
+	name    string
+}{
+	{AnomalyCreatedBeforeFileName, "CreatedBeforeFileName"},
+	{AnomalyModifiedBeforeFileName, "ModifiedBeforeFileName"},
+	{AnomalyWholeSeconds, "WholeSeconds"},
+	{AnomalyCreatedAfterModified, "CreatedAfterModified"},
+}
+
+// Match returns true if a contains all of the given anomalies.
+func (a Anomaly) Match(anomalies Anomaly) bool {
+	return a&anomalies == anomalies
+}
+
+// String returns the names of the anomalies in a separated by |.
+func (a Anomaly) String() string {
+	if a == 0 {
+		return ""
+	}
+	var names []string
+	for _, entry := range anomalyNames {
+		if a&entry.anomaly != 0 {
+			names = append(names, entry.name)
+			a &^= entry.anomaly
+		}
+	}
+	if a != 0 {
+		names = append(names, fmt.Sprintf("%#x", uint32(a)))
+	}
+	return strings.Join(names, "|")
+}
+
+// Analyzer examines file timestamps for anomalies. The zero value is ready
+// for use.
+type Analyzer struct {
+	// Tolerance is the amount by which $STANDARD_INFORMATION timestamps
+	// may precede $FILE_NAME timestamps before they are considered
+	// anomalous.
+	Tolerance time.Duration
+}
+
+// Analyze returns the anomalies found in ts. Comparisons that involve
+// timestamps that are not set are skipped.
+func (a Analyzer) Analyze(ts Timestamps) (found Anomaly) {
+	si, fn := ts.StandardInformation, ts.FileName
+
+	if a.before(si.Born, fn.Born) {
+		found |= AnomalyCreatedBeforeFileName
+	}
+	if a.before(si.Modified, fn.Modified) {
+		found |= AnomalyModifiedBeforeFileName
+	}
+	for _, t := range []time.Time{si.Modified, si.Accessed, si.Changed, si.Born} {
+		if !t.IsZero() && t.Nanosecond() == 0 {
+			found |= AnomalyWholeSeconds
+			break
+		}
+	}
+	if !si.Born.IsZero() && !si.Modified.IsZero() && si.Born.After(si.Modified) {
+		found |= AnomalyCreatedAfterModified
+	}
+	return found
+}
+
+// before returns true if si precedes fn by more than the tolerance.
+func (a Analyzer) before(si, fn time.Time) bool {
+	if si.IsZero() || fn.IsZero() {
+		return false
+	}
+	return fn.Sub(si) > a.Tolerance
+}
+
+// Anomalies returns the timestamp anomalies of the entry, as found by the
+// zero Analyzer.
+func (e *MFTEntry) Anomalies() Anomaly {
+	return Analyzer{}.Analyze(e.Timestamps())
+}
@@ -0,0 +1,89 @@
+package ntfs_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/ntfstest"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+)
+
+func TestAnalyzer(t *testing.T) {
+	var (
+		fnTime   = time.Date(2023, 5, 10, 9, 15, 30, 123456700, time.UTC)
+		later    = fnTime.Add(time.Hour)
+		stomped  = time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+		slightly = fnTime.Add(-time.Millisecond)
+		fn       = ntfs.MACB{Modified: fnTime, Accessed: fnTime, Changed: fnTime, Born: fnTime}
+	)
+
+	tests := []struct {
+		name      string
+		analyzer  ntfs.Analyzer
+		si        ntfs.MACB
+		fn        ntfs.MACB
+		anomalies ntfs.Anomaly
+	}{
+		{"Clean", ntfs.Analyzer{}, ntfs.MACB{Modified: later, Accessed: later, Changed: later, Born: fnTime}, fn, 0},
+		{"Stomped", ntfs.Analyzer{}, ntfs.MACB{Modified: stomped, Accessed: later, Changed: later, Born: stomped}, fn,
+			ntfs.AnomalyCreatedBeforeFileName | ntfs.AnomalyModifiedBeforeFileName | ntfs.AnomalyWholeSeconds},
+		{"CreatedAfterModified", ntfs.Analyzer{}, ntfs.MACB{Modified: fnTime, Accessed: later, Changed: later, Born: later}, fn,
+			ntfs.AnomalyCreatedAfterModified},
+		{"WithinTolerance", ntfs.Analyzer{Tolerance: time.Second}, ntfs.MACB{Modified: later, Accessed: later, Changed: later, Born: slightly}, fn, 0},
+		{"BeyondTolerance", ntfs.Analyzer{}, ntfs.MACB{Modified: later, Accessed: later, Changed: later, Born: slightly}, fn,
+			ntfs.AnomalyCreatedBeforeFileName},
+		{"NoFileName", ntfs.Analyzer{}, ntfs.MACB{Modified: later, Accessed: later, Changed: later, Born: stomped.Add(time.Nanosecond * 100)}, ntfs.MACB{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.analyzer.Analyze(ntfs.Timestamps{StandardInformation: tt.si, FileName: tt.fn})
+			if got != tt.anomalies {
+				t.Errorf("got %v, want %v", got, tt.anomalies)
+			}
+		})
+	}
+}
+
+func TestAnomalyString(t *testing.T) {
+	a := ntfs.AnomalyCreatedBeforeFileName | ntfs.AnomalyWholeSeconds | 0x100
+	if got, want := a.String(), "CreatedBeforeFileName|WholeSeconds|0x100"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !a.Match(ntfs.AnomalyWholeSeconds) || a.Match(ntfs.AnomalyCreatedAfterModified) {
+		t.Errorf("Match returned the wrong result")
+	}
+}
+
+func TestEntryTimestamps(t *testing.T) {
+	img := ntfstest.NewImage(64, ntfstest.Run{LCN: 4, Length: 12})
+	stomped := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	img.Set(30, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.StdInfoAttr(stomped, stomped, ntfstest.Time, ntfstest.Time, 0, 0, 0),
+			ntfstest.FileNameAttr(testRoot, "evil.exe", ntfs.NamespaceWin32DOS, 0),
+		},
+	})
+	v, err := ntfs.Open(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := v.Entry(30)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := e.Timestamps()
+	if !ts.StandardInformation.Born.Equal(stomped) || !ts.FileName.Born.Equal(ntfstest.Time) {
+		t.Errorf("timestamps = %+v", ts)
+	}
+	if len(ts.FileNames) != 1 {
+		t.Errorf("len(FileNames) = %d, want 1", len(ts.FileNames))
+	}
+
+	want := ntfs.AnomalyCreatedBeforeFileName | ntfs.AnomalyModifiedBeforeFileName | ntfs.AnomalyWholeSeconds
+	if got := e.Anomalies(); got != want {
+		t.Errorf("Anomalies = %v, want %v", got, want)
+	}
+}
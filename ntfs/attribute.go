@@ -0,0 +1,163 @@
+package ntfs
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/utf16le"
+)
+
+// AttributeType identifies the type of an attribute within a FILE record.
+type AttributeType uint32
+
+// Attribute types.
+const (
+	AttrStandardInformation AttributeType = 0x10
+	AttrAttributeList       AttributeType = 0x20
+	AttrFileName            AttributeType = 0x30
+	AttrObjectID            AttributeType = 0x40
+	AttrSecurityDescriptor  AttributeType = 0x50
+	AttrVolumeName          AttributeType = 0x60
+	AttrVolumeInformation   AttributeType = 0x70
+	AttrData                AttributeType = 0x80
+	AttrIndexRoot           AttributeType = 0x90
+	AttrIndexAllocation     AttributeType = 0xA0
+	AttrBitmap              AttributeType = 0xB0
+	AttrReparsePoint        AttributeType = 0xC0
+	AttrEAInformation       AttributeType = 0xD0
+	AttrEA                  AttributeType = 0xE0
+	AttrLoggedUtilityStream AttributeType = 0x100
+	attrEnd                 AttributeType = 0xFFFFFFFF
+)
+
+var attributeTypeNames = map[AttributeType]string{
+	AttrStandardInformation: "$STANDARD_INFORMATION",
+	AttrAttributeList:       "$ATTRIBUTE_LIST",
+	AttrFileName:            "$FILE_NAME",
+	AttrObjectID:            "$OBJECT_ID",
+	AttrSecurityDescriptor:  "$SECURITY_DESCRIPTOR",
+	AttrVolumeName:          "$VOLUME_NAME",
+	AttrVolumeInformation:   "$VOLUME_INFORMATION",
+	AttrData:                "$DATA",
+	AttrIndexRoot:           "$INDEX_ROOT",
+	AttrIndexAllocation:     "$INDEX_ALLOCATION",
+	AttrBitmap:              "$BITMAP",
+	AttrReparsePoint:        "$REPARSE_POINT",
+	AttrEAInformation:       "$EA_INFORMATION",
+	AttrEA:                  "$EA",
+	AttrLoggedUtilityStream: "$LOGGED_UTILITY_STREAM",
+}
+
+// String returns the name of the attribute type.
+func (t AttributeType) String() string {
+	if name, ok := attributeTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("attribute(%#x)", uint32(t))
+}
+
+// Attribute flags.
+const (
+	AttrFlagCompressed = 0x0001
+	AttrFlagEncrypted  = 0x4000
+	AttrFlagSparse     = 0x8000
+)
+
+// Attribute is an attribute within a FILE record.
+type Attribute struct {
+	Type        AttributeType
+	Name        string
+	NonResident bool
+	Flags       uint16
+	ID          uint16
+
+	// Resident attributes
+	Value []byte
+
+	// Non-resident attributes
+	StartVCN        uint64
+	LastVCN         uint64
+	CompressionUnit uint16 // Log2 of the number of clusters in a compression unit
+	AllocatedSize   uint64
+	RealSize        uint64
+	InitializedSize uint64
+	Runs            []byte // Encoded mapping pairs
+}
+
+// Size returns the size of the attribute's value in bytes.
+func (a Attribute) Size() uint64 {
+	if a.NonResident {
+		return a.RealSize
+	}
+	return uint64(len(a.Value))
+}
+
+// parseAttributes parses the attributes of a FILE record whose fixups have
+// already been applied, starting at the given offset.
+func parseAttributes(data []byte, offset int) ([]Attribute, error) {
+	var attrs []Attribute
+	for {
+		if offset+8 > len(data) {
+			return nil, ErrTruncated
+		}
+		t := AttributeType(binary.LittleEndian.Uint32(data[offset:]))
+		if t == attrEnd {
+			return attrs, nil
+		}
+		length := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		if length < 0x18 || offset+length > len(data) {
+			return nil, ErrTruncated
+		}
+		attr, err := parseAttribute(data[offset : offset+length])
+		if err != nil {
+			return nil, fmt.Errorf("%s attribute at offset %d: %w", t, offset, err)
+		}
+		attrs = append(attrs, attr)
+		offset += length
+	}
+}
+
+// parseAttribute parses a single attribute record.
+func parseAttribute(data []byte) (Attribute, error) {
+	a := Attribute{
+		Type:        AttributeType(binary.LittleEndian.Uint32(data)),
+		NonResident: data[0x08] != 0,
+		Flags:       binary.LittleEndian.Uint16(data[0x0C:]),
+		ID:          binary.LittleEndian.Uint16(data[0x0E:]),
+	}
+
+	nameLength := int(data[0x09]) * 2
+	nameOffset := int(binary.LittleEndian.Uint16(data[0x0A:]))
+	if nameLength > 0 {
+		if nameOffset+nameLength > len(data) {
+			return Attribute{}, ErrTruncated
+		}
+		a.Name = utf16le.Decode(data[nameOffset : nameOffset+nameLength])
+	}
+
+	if !a.NonResident {
+		valueLength := int(binary.LittleEndian.Uint32(data[0x10:]))
+		valueOffset := int(binary.LittleEndian.Uint16(data[0x14:]))
+		if valueOffset+valueLength > len(data) {
+			return Attribute{}, ErrTruncated
+		}
+		a.Value = data[valueOffset : valueOffset+valueLength]
+		return a, nil
+	}
+
+	if len(data) < 0x40 {
+		return Attribute{}, ErrTruncated
+	}
+	a.StartVCN = binary.LittleEndian.Uint64(data[0x10:])
+	a.LastVCN = binary.LittleEndian.Uint64(data[0x18:])
+	runsOffset := int(binary.LittleEndian.Uint16(data[0x20:]))
+	a.CompressionUnit = binary.LittleEndian.Uint16(data[0x22:])
+	a.AllocatedSize = binary.LittleEndian.Uint64(data[0x28:])
+	a.RealSize = binary.LittleEndian.Uint64(data[0x30:])
+	a.InitializedSize = binary.LittleEndian.Uint64(data[0x38:])
+	if runsOffset > len(data) {
+		return Attribute{}, ErrTruncated
+	}
+	a.Runs = data[runsOffset:]
+	return a, nil
+}
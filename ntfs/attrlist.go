@@ -0,0 +1,54 @@
+package ntfs
+
+import (
+	"encoding/binary"
+
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/internal/utf16le"
+)
+
+// maxAttributeList is the largest $ATTRIBUTE_LIST that will be read.
+// Windows limits attribute lists to 256 KiB.
+const maxAttributeList = 256 << 10
+
+// AttributeListEntry is an entry within an $ATTRIBUTE_LIST attribute. Each
+// entry identifies the FILE record that holds one of a file's attributes.
+type AttributeListEntry struct {
+	Type     AttributeType
+	Name     string
+	StartVCN uint64
+	Record   fileref.ID // File reference of the record holding the attribute
+	ID       uint16
+}
+
+// ParseAttributeList parses the value of an $ATTRIBUTE_LIST attribute.
+func ParseAttributeList(data []byte) ([]AttributeListEntry, error) {
+	var entries []AttributeListEntry
+	for offset := 0; offset < len(data); {
+		if offset+0x1A > len(data) {
+			return nil, ErrTruncated
+		}
+		b := data[offset:]
+		length := int(binary.LittleEndian.Uint16(b[0x04:]))
+		if length < 0x1A || length > len(b) {
+			return nil, ErrTruncated
+		}
+		e := AttributeListEntry{
+			Type:     AttributeType(binary.LittleEndian.Uint32(b[0x00:])),
+			StartVCN: binary.LittleEndian.Uint64(b[0x08:]),
+			Record:   fileref.New64(int64(binary.LittleEndian.Uint64(b[0x10:]))),
+			ID:       binary.LittleEndian.Uint16(b[0x18:]),
+		}
+		nameLength := int(b[0x06]) * 2
+		nameOffset := int(b[0x07])
+		if nameLength > 0 {
+			if nameOffset+nameLength > length {
+				return nil, ErrTruncated
+			}
+			e.Name = utf16le.Decode(b[nameOffset : nameOffset+nameLength])
+		}
+		entries = append(entries, e)
+		offset += length
+	}
+	return entries, nil
+}
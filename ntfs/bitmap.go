@@ -0,0 +1,73 @@
+package ntfs
+
+import (
+	"fmt"
+	"io"
+)
+
+// Bitmap is the cluster allocation bitmap of a volume, as stored in the
+// $Bitmap system file. Each bit records whether a cluster is in use.
+type Bitmap struct {
+	data     []byte
+	clusters uint64
+}
+
+// Bitmap reads the cluster allocation bitmap of the volume.
+func (v *Volume) Bitmap() (*Bitmap, error) {
+	e, err := v.Entry(RecordBitmap)
+	if err != nil {
+		return nil, fmt.Errorf("$Bitmap: %w", err)
+	}
+	sr, err := v.OpenStream(e, "")
+	if err != nil {
+		return nil, fmt.Errorf("$Bitmap: %w", err)
+	}
+	// The bitmap holds a bit for each cluster, padded to a multiple of
+	// eight bytes
+	clusters := v.Boot.TotalClusters()
+	if limit := (clusters + 63) / 64 * 8; uint64(sr.Size()) > limit {
+		return nil, fmt.Errorf("$Bitmap: size %d exceeds the %d bytes needed for %d clusters", sr.Size(), limit, clusters)
+	}
+	data := make([]byte, sr.Size())
+	if _, err := sr.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("$Bitmap: %w", err)
+	}
+	if n := uint64(len(data)) * 8; clusters > n {
+		clusters = n
+	}
+	return &Bitmap{data: data, clusters: clusters}, nil
+}
+
+// Len returns the number of clusters described by the bitmap.
+func (b *Bitmap) Len() uint64 {
+	return b.clusters
+}
+
+// Allocated returns true if the given logical cluster is in use. Clusters
+// beyond the end of the volume are reported as allocated.
+func (b *Bitmap) Allocated(lcn uint64) bool {
+	if lcn >= b.clusters {
+		return true
+	}
+	return b.data[lcn/8]&(1<<(lcn%8)) != 0
+}
+
+// AllocatedCount returns the number of clusters in the range of n clusters
+// starting at lcn that are in use.
+func (b *Bitmap) AllocatedCount(lcn, n uint64) (count uint64) {
+	// Clusters beyond the end of the volume are counted without visiting
+	// each of them
+	if lcn >= b.clusters {
+		return n
+	}
+	if n > b.clusters-lcn {
+		count = n - (b.clusters - lcn)
+		n = b.clusters - lcn
+	}
+	for i := uint64(0); i < n; i++ {
+		if b.Allocated(lcn + i) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,124 @@
+package ntfs
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// BootSectorSize is the size of an NTFS boot sector in bytes.
+const BootSectorSize = 512
+
+// maxClusterSize is the largest cluster size supported by NTFS.
+const maxClusterSize = 2 << 20
+
+var (
+	// ErrNotNTFS is returned when a boot sector does not describe an NTFS
+	// volume.
+	ErrNotNTFS = errors.New("not an NTFS volume")
+
+	// ErrInvalidGeometry is returned when a boot sector describes an
+	// impossible volume geometry.
+	ErrInvalidGeometry = errors.New("invalid NTFS volume geometry")
+)
+
+// BootSector holds the volume geometry described by an NTFS boot sector.
+type BootSector struct {
+	OEMID             string
+	BytesPerSector    uint32
+	SectorsPerCluster uint32
+	TotalSectors      uint64
+	MFTCluster        uint64 // Logical cluster number of $MFT
+	MFTMirrorCluster  uint64 // Logical cluster number of $MFTMirr
+	FileRecordSize    uint32 // Size of a FILE record in bytes
+	IndexRecordSize   uint32 // Size of an INDX record in bytes
+	SerialNumber      uint64
+}
+
+// ParseBootSector parses the NTFS boot sector held in data.
+func ParseBootSector(data []byte) (BootSector, error) {
+	if len(data) < BootSectorSize {
+		return BootSector{}, ErrNotNTFS
+	}
+	if string(data[3:11]) != "NTFS    " || data[510] != 0x55 || data[511] != 0xAA {
+		return BootSector{}, ErrNotNTFS
+	}
+
+	b := BootSector{
+		OEMID:            string(data[3:11]),
+		BytesPerSector:   uint32(binary.LittleEndian.Uint16(data[0x0B:])),
+		TotalSectors:     binary.LittleEndian.Uint64(data[0x28:]),
+		MFTCluster:       binary.LittleEndian.Uint64(data[0x30:]),
+		MFTMirrorCluster: binary.LittleEndian.Uint64(data[0x38:]),
+		SerialNumber:     binary.LittleEndian.Uint64(data[0x48:]),
+	}
+
+	// Cluster sizes above 64 KiB are stored as a negative power of two
+	spc := data[0x0D]
+	switch {
+	case spc == 0:
+		return BootSector{}, ErrInvalidGeometry
+	case spc <= 0x80:
+		b.SectorsPerCluster = uint32(spc)
+	default:
+		shift := 256 - uint32(spc)
+		if shift > 31 {
+			return BootSector{}, ErrInvalidGeometry
+		}
+		b.SectorsPerCluster = 1 << shift
+	}
+
+	if b.BytesPerSector < 256 || b.BytesPerSector > 4096 || b.BytesPerSector&(b.BytesPerSector-1) != 0 {
+		return BootSector{}, ErrInvalidGeometry
+	}
+	if b.SectorsPerCluster&(b.SectorsPerCluster-1) != 0 {
+		return BootSector{}, ErrInvalidGeometry
+	}
+	if uint64(b.BytesPerSector)*uint64(b.SectorsPerCluster) > maxClusterSize {
+		return BootSector{}, ErrInvalidGeometry
+	}
+	if b.TotalSectors > math.MaxInt64/uint64(b.BytesPerSector) {
+		return BootSector{}, ErrInvalidGeometry
+	}
+
+	var ok bool
+	if b.FileRecordSize, ok = recordSize(int8(data[0x40]), b.ClusterSize()); !ok {
+		return BootSector{}, ErrInvalidGeometry
+	}
+	if b.IndexRecordSize, ok = recordSize(int8(data[0x44]), b.ClusterSize()); !ok {
+		return BootSector{}, ErrInvalidGeometry
+	}
+
+	return b, nil
+}
+
+// recordSize interprets a clusters-per-record value from the boot sector.
+// Positive values are a number of clusters. Negative values are a power of
+// two in bytes.
+func recordSize(v int8, clusterSize uint32) (uint32, bool) {
+	var size uint32
+	switch {
+	case v > 0:
+		size = uint32(v) * clusterSize
+	case v < 0 && v > -32:
+		size = 1 << uint32(-v)
+	default:
+		return 0, false
+	}
+	return size, size >= 512 && size <= 65536
+}
+
+// ClusterSize returns the size of a cluster in bytes.
+func (b BootSector) ClusterSize() uint32 {
+	return b.BytesPerSector * b.SectorsPerCluster
+}
+
+// TotalClusters returns the number of clusters in the volume.
+func (b BootSector) TotalClusters() uint64 {
+	return b.TotalSectors / uint64(b.SectorsPerCluster)
+}
+
+// Size returns the size of the volume in bytes.
+func (b BootSector) Size() int64 {
+	return int64(b.TotalSectors) * int64(b.BytesPerSector)
+}
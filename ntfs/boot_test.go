@@ -0,0 +1,81 @@
+package ntfs_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/ntfstest"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+)
+
+func TestParseBootSector(t *testing.T) {
+	img := ntfstest.NewImage(64, ntfstest.Run{LCN: 4, Length: 4})
+	data := img.Bytes(t)[:ntfs.BootSectorSize]
+
+	boot, err := ntfs.ParseBootSector(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if boot.BytesPerSector != ntfstest.SectorSize {
+		t.Errorf("BytesPerSector = %d, want %d", boot.BytesPerSector, ntfstest.SectorSize)
+	}
+	if boot.ClusterSize() != ntfstest.ClusterSize {
+		t.Errorf("ClusterSize = %d, want %d", boot.ClusterSize(), ntfstest.ClusterSize)
+	}
+	if boot.FileRecordSize != ntfstest.RecordSize {
+		t.Errorf("FileRecordSize = %d, want %d", boot.FileRecordSize, ntfstest.RecordSize)
+	}
+	if boot.IndexRecordSize != ntfstest.ClusterSize {
+		t.Errorf("IndexRecordSize = %d, want %d", boot.IndexRecordSize, ntfstest.ClusterSize)
+	}
+	if boot.MFTCluster != 4 {
+		t.Errorf("MFTCluster = %d, want 4", boot.MFTCluster)
+	}
+	if boot.TotalClusters() != 63 {
+		t.Errorf("TotalClusters = %d, want 63", boot.TotalClusters())
+	}
+}
+
+func TestParseBootSectorLargeCluster(t *testing.T) {
+	img := ntfstest.NewImage(64, ntfstest.Run{LCN: 4, Length: 4})
+	data := img.Bytes(t)[:ntfs.BootSectorSize]
+	data[0x0D] = 0xF7 // 2^9 sectors per cluster
+	data[0x44] = 0xF4 // 2^12 bytes per INDX record
+
+	boot, err := ntfs.ParseBootSector(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := uint32(512 * 512); boot.ClusterSize() != want {
+		t.Errorf("ClusterSize = %d, want %d", boot.ClusterSize(), want)
+	}
+}
+
+func TestParseBootSectorErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(b []byte)
+		want   error
+	}{
+		{"Signature", func(b []byte) { copy(b[3:], "EXFAT   ") }, ntfs.ErrNotNTFS},
+		{"Marker", func(b []byte) { b[511] = 0 }, ntfs.ErrNotNTFS},
+		{"SectorSize", func(b []byte) { b[0x0B], b[0x0C] = 0x00, 0x03 }, ntfs.ErrInvalidGeometry},
+		{"SectorsPerCluster", func(b []byte) { b[0x0D] = 0 }, ntfs.ErrInvalidGeometry},
+		{"ClusterPowerOfTwo", func(b []byte) { b[0x0D] = 3 }, ntfs.ErrInvalidGeometry},
+		{"RecordSize", func(b []byte) { b[0x40] = 0 }, ntfs.ErrInvalidGeometry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := ntfstest.NewImage(64, ntfstest.Run{LCN: 4, Length: 4})
+			data := img.Bytes(t)[:ntfs.BootSectorSize]
+			tt.modify(data)
+			if _, err := ntfs.ParseBootSector(data); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := ntfs.ParseBootSector(make([]byte, 100)); !errors.Is(err, ntfs.ErrNotNTFS) {
+		t.Errorf("short sector: err = %v, want %v", err, ntfs.ErrNotNTFS)
+	}
+}
@@ -0,0 +1,204 @@
+package ntfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// IndexNameFileName is the name of the index of file names held by every
+// directory.
+const IndexNameFileName = "$I30"
+
+// ErrNotDirectory is returned when the directory index of a file that is
+// not a directory is requested.
+var ErrNotDirectory = errors.New("not a directory")
+
+// fileAttrIndexView is set in the attributes of a $FILE_NAME when the file
+// has a file name index, which marks it as a directory.
+const fileAttrIndexView = 0x10000000
+
+// maxIndexBlockSize is the largest INDX record that will be read.
+const maxIndexBlockSize = 65536
+
+// IndexEntries reads the entries of the named index of e. The entries of
+// the root node are returned first, followed by the entries of each INDX
+// record in the order they are stored.
+//
+// If slack is true, stale entries carved from the slack space of each node
+// are included and marked with Slack. The entries of INDX records that the
+// index bitmap marks as unused are also included and marked with Slack.
+// Only the entries of file name indexes are carved.
+func (v *Volume) IndexEntries(e MFTEntry, name string, slack bool) ([]IndexEntry, error) {
+	rootStream, ok := e.attributeStream(AttrIndexRoot, name)
+	if !ok {
+		return nil, fmt.Errorf("MFT record %d: %s:%s: %w", e.Number, AttrIndexRoot, name, ErrStreamNotFound)
+	}
+	root, err := ParseIndexRoot(rootStream.Value)
+	if err != nil {
+		return nil, fmt.Errorf("MFT record %d: %s:%s: %w", e.Number, AttrIndexRoot, name, err)
+	}
+	view := root.Type != AttrFileName
+
+	var entries []IndexEntry
+	entries = appendNode(entries, root.Node, slack, false)
+
+	alloc, ok := e.attributeStream(AttrIndexAllocation, name)
+	if !ok {
+		return entries, nil
+	}
+	if root.BlockSize < sectorStride || root.BlockSize > maxIndexBlockSize {
+		return nil, fmt.Errorf("MFT record %d: %s:%s: invalid index block size %d", e.Number, AttrIndexRoot, name, root.BlockSize)
+	}
+
+	sr, err := v.StreamReader(alloc)
+	if err != nil {
+		return nil, fmt.Errorf("MFT record %d: %s:%s: %w", e.Number, AttrIndexAllocation, name, err)
+	}
+
+	// Index records are never sparse, so only the records that are stored
+	// on the volume are read, no matter what size the stream claims to be
+	size := int64(root.BlockSize)
+	blocks := min(sr.Size(), sr.allocated()) / size
+
+	// The bitmap holds a bit for each record, padded to a multiple of
+	// eight bytes
+	var bitmap []byte
+	if s, ok := e.attributeStream(AttrBitmap, name); ok {
+		if bitmap, err = v.readStream(s, (blocks/64+1)*8); err != nil {
+			return nil, fmt.Errorf("MFT record %d: %s:%s: %w", e.Number, AttrBitmap, name, err)
+		}
+	}
+
+	for i := int64(0); i < blocks; i++ {
+		used := i/8 < int64(len(bitmap)) && bitmap[i/8]&(1<<(i%8)) != 0
+		if !used && !slack {
+			continue
+		}
+		// Entries refer to the block, so each needs its own buffer
+		block := make([]byte, size)
+		if _, err := sr.ReadAt(block, i*size); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("MFT record %d: %s:%s: %w", e.Number, AttrIndexAllocation, name, err)
+		}
+		b, err := ParseIndexBlock(block, view)
+		if err != nil {
+			if used {
+				return nil, fmt.Errorf("MFT record %d: %s:%s: INDX record %d: %w", e.Number, AttrIndexAllocation, name, i, err)
+			}
+			// Unused records may never have been written
+			continue
+		}
+		entries = appendNode(entries, b.Node, slack, !used)
+	}
+	return entries, nil
+}
+
+// appendNode appends the entries of node to entries. If stale is true the
+// node is no longer part of the index and all of its entries are marked
+// with Slack.
+func appendNode(entries []IndexEntry, node IndexNode, slack, stale bool) []IndexEntry {
+	for _, entry := range node.Entries {
+		entry.Slack = stale
+		entries = append(entries, entry)
+	}
+	if slack {
+		entries = append(entries, node.Slack...)
+	}
+	return entries
+}
+
+// readStream reads the entire contents of s. It returns ErrTruncated if s
+// is larger than limit bytes.
+func (v *Volume) readStream(s Stream, limit int64) ([]byte, error) {
+	sr, err := v.StreamReader(s)
+	if err != nil {
+		return nil, err
+	}
+	if sr.Size() > limit {
+		return nil, ErrTruncated
+	}
+	data := make([]byte, sr.Size())
+	if _, err := sr.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data, nil
+}
+
+// DirectoryEntry is a file name found in the $I30 index of a directory.
+type DirectoryEntry struct {
+	File     fileref.ID
+	FileName FileName
+	Slack    bool // The entry was recovered from slack space
+}
+
+// Record returns a change journal record describing the directory entry.
+// Entries recovered from slack space are reported as deleted, although the
+// file may have been renamed or moved to another directory instead.
+func (d DirectoryEntry) Record() usn.Record {
+	r := usn.Record{
+		FileReferenceNumber:       d.File,
+		ParentFileReferenceNumber: d.FileName.Parent,
+		FileName:                  d.FileName.Name,
+		FileAttributes:            d.FileName.Attributes,
+		TimeStamp:                 d.FileName.Changed,
+	}
+	if d.FileName.Attributes&fileAttrIndexView != 0 {
+		r.FileAttributes |= fileattr.Directory
+	}
+	if d.Slack {
+		r.Reason = usn.ReasonFileDelete
+	}
+	return r
+}
+
+// Directory reads the file names listed by the $I30 index of the directory
+// described by e. Entries for DOS 8.3 names are included alongside their
+// long names.
+//
+// If slack is true, entries recovered from the slack space of the index
+// are included. Recovered entries that duplicate a live entry, or that name
+// a different parent directory, are omitted.
+func (v *Volume) Directory(e MFTEntry, slack bool) ([]DirectoryEntry, error) {
+	if !e.Directory() {
+		return nil, fmt.Errorf("MFT record %d: %w", e.Number, ErrNotDirectory)
+	}
+	entries, err := v.IndexEntries(e, IndexNameFileName, slack)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		segment uint64
+		name    string
+	}
+	live := make(map[key]bool)
+	var dir []DirectoryEntry
+	for pass := 0; pass < 2; pass++ {
+		for _, entry := range entries {
+			if entry.Slack != (pass == 1) || len(entry.Key) == 0 {
+				continue
+			}
+			fn, err := ParseFileName(entry.Key)
+			if err != nil {
+				continue
+			}
+			k := key{entry.File.Segment(), fn.Name}
+			if entry.Slack {
+				if fn.Parent.Segment() != e.Number || live[k] {
+					continue
+				}
+			}
+			live[k] = true
+			dir = append(dir, DirectoryEntry{
+				File:     entry.File,
+				FileName: fn,
+				Slack:    entry.Slack,
+			})
+		}
+	}
+	return dir, nil
+}
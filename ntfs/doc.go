@@ -0,0 +1,14 @@
+// Package ntfs reads NTFS file systems directly from their on-disk
+// structures.
+//
+// Volumes are opened from an io.ReaderAt, such as a raw disk image, a dd
+// file or a block device. No operating system support for NTFS is required,
+// so volumes can be examined on any platform.
+//
+// The contents of a file's streams are read with a StreamReader. To extract
+// the change journal, for example, locate $UsnJrnl with Volume.SystemFiles,
+// read its entry with Volume.Entry and open its $J stream with
+// Volume.OpenStream.
+//
+// https://flatcap.github.io/linux-ntfs/ntfs/
+package ntfs
@@ -0,0 +1,266 @@
+package ntfs
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+)
+
+// MFTEntry is a decoded FILE record from the master file table.
+//
+// Attribute values refer to the data the entry was parsed from, which must
+// not be modified while the entry is in use.
+type MFTEntry struct {
+	Number              uint64 // Record number within the master file table
+	Header              RecordHeader
+	StandardInformation *StandardInformation // Nil if the record has none
+	FileNames           []FileName           // In the order they appear
+	Streams             []Stream             // $DATA attributes, grouped by name
+	AttributeList       []AttributeListEntry // Nil unless the file has an $ATTRIBUTE_LIST
+	Attributes          []Attribute          // All attributes, including those in extension records
+
+	// Incomplete is set when the file's attributes are spread across
+	// several records and some of them could not be read. This happens
+	// when a non-resident attribute list is read from an extracted $MFT
+	// file, which does not hold the clusters the list is stored in.
+	Incomplete bool
+}
+
+// Stream is a $DATA attribute. The unnamed stream holds the contents of a
+// file and named streams are alternate data streams.
+type Stream struct {
+	Name            string
+	Flags           uint16 // Attribute flags, such as AttrFlagCompressed
+	NonResident     bool
+	Size            uint64
+	AllocatedSize   uint64
+	InitializedSize uint64
+	Value           []byte      // Resident streams only
+	Fragments       []Attribute // Non-resident streams only, ordered by starting VCN
+}
+
+// ParseRecord parses a raw FILE record of any size. Its update sequence
+// fixups are applied in place, so data must hold the record exactly as it
+// is stored on disk.
+//
+// Only the attributes held within the record itself are decoded. Use
+// MFT.Entry to follow a file's attribute list into its extension records.
+func ParseRecord(data []byte) (MFTEntry, error) {
+	if err := ApplyFixups(data, "FILE"); err != nil {
+		return MFTEntry{}, err
+	}
+	e, err := parseEntry(data)
+	if err != nil {
+		return MFTEntry{}, err
+	}
+	if err := e.decode(); err != nil {
+		return MFTEntry{}, err
+	}
+	return e, nil
+}
+
+// ParseEntry parses a FILE record whose update sequence fixups have already
+// been applied. This is the form in which records are held in memory and
+// logged in $LogFile.
+func ParseEntry(data []byte) (MFTEntry, error) {
+	if len(data) < 4 || string(data[:4]) != "FILE" {
+		return MFTEntry{}, ErrBadSignature
+	}
+	e, err := parseEntry(data)
+	if err != nil {
+		return MFTEntry{}, err
+	}
+	if err := e.decode(); err != nil {
+		return MFTEntry{}, err
+	}
+	return e, nil
+}
+
+// parseEntry parses the header and attributes of a FILE record whose fixups
+// have already been applied. The attributes are not decoded.
+func parseEntry(data []byte) (MFTEntry, error) {
+	header, err := parseRecordHeader(data)
+	if err != nil {
+		return MFTEntry{}, err
+	}
+	attrs, err := parseAttributes(data[:header.BytesInUse], int(header.AttributeOffset))
+	if err != nil {
+		return MFTEntry{}, err
+	}
+	return MFTEntry{
+		Number:     uint64(header.RecordNumber),
+		Header:     header,
+		Attributes: attrs,
+	}, nil
+}
+
+// decode decodes the entry's attributes.
+func (e *MFTEntry) decode() error {
+	e.StandardInformation = nil
+	e.FileNames = nil
+	e.Streams = nil
+
+	for _, attr := range e.Attributes {
+		switch attr.Type {
+		case AttrStandardInformation:
+			if attr.NonResident || e.StandardInformation != nil {
+				continue
+			}
+			si, err := ParseStandardInformation(attr.Value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", attr.Type, err)
+			}
+			e.StandardInformation = &si
+		case AttrFileName:
+			if attr.NonResident {
+				continue
+			}
+			fn, err := ParseFileName(attr.Value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", attr.Type, err)
+			}
+			e.FileNames = append(e.FileNames, fn)
+		case AttrAttributeList:
+			if attr.NonResident || e.AttributeList != nil {
+				continue
+			}
+			list, err := ParseAttributeList(attr.Value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", attr.Type, err)
+			}
+			e.AttributeList = list
+		case AttrData:
+			e.addStream(attr)
+		}
+	}
+
+	for i := range e.Streams {
+		fragments := e.Streams[i].Fragments
+		sort.Slice(fragments, func(a, b int) bool {
+			return fragments[a].StartVCN < fragments[b].StartVCN
+		})
+	}
+	return nil
+}
+
+// addStream adds a $DATA attribute to the stream of the same name.
+func (e *MFTEntry) addStream(attr Attribute) {
+	i := 0
+	for i < len(e.Streams) && e.Streams[i].Name != attr.Name {
+		i++
+	}
+	if i == len(e.Streams) {
+		e.Streams = append(e.Streams, Stream{Name: attr.Name})
+	}
+	e.Streams[i].add(attr)
+}
+
+// attributeStream groups the attributes of the given type and name into a
+// stream, so that attributes other than $DATA can be read with a
+// StreamReader. It returns false if the entry has no such attribute.
+func (e *MFTEntry) attributeStream(typ AttributeType, name string) (Stream, bool) {
+	s := Stream{Name: name}
+	found := false
+	for _, attr := range e.Attributes {
+		if attr.Type == typ && attr.Name == name {
+			s.add(attr)
+			found = true
+		}
+	}
+	sort.Slice(s.Fragments, func(a, b int) bool {
+		return s.Fragments[a].StartVCN < s.Fragments[b].StartVCN
+	})
+	return s, found
+}
+
+// add adds an attribute to the stream.
+func (s *Stream) add(attr Attribute) {
+	if !attr.NonResident {
+		s.Flags = attr.Flags
+		s.Size = uint64(len(attr.Value))
+		s.AllocatedSize = s.Size
+		s.InitializedSize = s.Size
+		s.Value = attr.Value
+		return
+	}
+
+	s.NonResident = true
+	s.Fragments = append(s.Fragments, attr)
+	if attr.StartVCN == 0 {
+		// Only the first fragment holds the sizes of the stream
+		s.Flags = attr.Flags
+		s.Size = attr.RealSize
+		s.AllocatedSize = attr.AllocatedSize
+		s.InitializedSize = attr.InitializedSize
+	}
+}
+
+// ID returns the file reference number of the file described by the entry.
+//
+// NTFS increments the sequence number of a record when its file is
+// deleted, so for records that are not in use the sequence number is
+// reduced by one to produce the reference number the file had while it
+// existed.
+func (e *MFTEntry) ID() fileref.ID {
+	seq := e.Header.Sequence
+	if !e.InUse() && seq > 1 {
+		seq--
+	}
+	return fileref.NewSegment(e.Number, seq)
+}
+
+// InUse returns true if the entry describes an existing file.
+func (e *MFTEntry) InUse() bool {
+	return e.Header.InUse()
+}
+
+// Deleted returns true if the entry describes a file that has been deleted.
+// The contents of deleted entries remain until the record is reused.
+func (e *MFTEntry) Deleted() bool {
+	return !e.Header.InUse()
+}
+
+// Directory returns true if the entry describes a directory.
+func (e *MFTEntry) Directory() bool {
+	return e.Header.Directory()
+}
+
+// Name returns the preferred file name of the entry. Long names are
+// preferred over DOS 8.3 names. It returns false if the entry has no file
+// names.
+func (e *MFTEntry) Name() (FileName, bool) {
+	for _, fn := range e.FileNames {
+		if fn.Namespace != NamespaceDOS {
+			return fn, true
+		}
+	}
+	if len(e.FileNames) > 0 {
+		return e.FileNames[0], true
+	}
+	return FileName{}, false
+}
+
+// Stream returns the $DATA stream with the given name. The unnamed stream
+// holds the contents of the file. It returns false if there is no such
+// stream.
+func (e *MFTEntry) Stream(name string) (Stream, bool) {
+	for _, s := range e.Streams {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Stream{}, false
+}
+
+// AlternateStreams returns the named $DATA streams of the entry, which are
+// its alternate data streams.
+func (e *MFTEntry) AlternateStreams() []Stream {
+	var streams []Stream
+	for _, s := range e.Streams {
+		if s.Name != "" {
+			streams = append(streams, s)
+		}
+	}
+	return streams
+}
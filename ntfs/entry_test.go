@@ -0,0 +1,266 @@
+package ntfs_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/internal/ntfstest"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+)
+
+var (
+	testCreated  = time.Date(2019, 3, 1, 8, 0, 0, 0, time.UTC)
+	testModified = time.Date(2021, 7, 4, 16, 20, 0, 0, time.UTC)
+	testRoot     = fileref.NewSegment(ntfs.RecordRoot, ntfs.RecordRoot)
+	testUsers    = fileref.NewSegment(30, 1)
+)
+
+// newEntryImage returns an image that holds a small directory tree:
+//
+//	30  Users                directory
+//	31  Users\report.docx    non-resident data and a named stream
+//	32  Users\old.txt        deleted
+//	33  Users\big.bin        resident attribute list, extension record 34
+//	35  Users\huge.bin       non-resident attribute list, extension record 36
+func newEntryImage() *ntfstest.Image {
+	img := ntfstest.NewImage(64, ntfstest.Run{LCN: 4, Length: 12})
+
+	img.Set(30, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse | ntfs.RecordDirectory,
+		Attrs: [][]byte{
+			ntfstest.StdInfoAttr(testCreated, testModified, testModified, testModified, 0, 0x100, 1000),
+			ntfstest.FileNameAttr(testRoot, "Users", ntfs.NamespaceWin32DOS, 0),
+		},
+	})
+
+	img.Set(31, ntfstest.Record{
+		Sequence: 2,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.StdInfoAttr(testCreated, testModified, testModified, testModified, fileattr.Archive, 0x101, 2000),
+			ntfstest.FileNameAttr(testUsers, "REPORT~1.DOC", ntfs.NamespaceDOS, fileattr.Archive),
+			ntfstest.FileNameAttr(testUsers, "report.docx", ntfs.NamespaceWin32, fileattr.Archive),
+			ntfstest.NonResidentAttr(ntfs.AttrData, "", []ntfstest.Run{{LCN: 40, Length: 2}}, 6000, 0, 0, 0),
+			ntfstest.ResidentAttr(ntfs.AttrData, "Zone.Identifier", []byte("[ZoneTransfer]\r\nZoneId=3\r\n")),
+		},
+	})
+
+	img.Set(32, ntfstest.Record{
+		Sequence: 4,
+		Flags:    0,
+		Attrs: [][]byte{
+			ntfstest.StdInfoAttr(testCreated, testModified, testModified, testModified, fileattr.Archive, 0x101, 3000),
+			ntfstest.FileNameAttr(testUsers, "old.txt", ntfs.NamespaceWin32DOS, fileattr.Archive),
+		},
+	})
+
+	big := fileref.NewSegment(33, 1)
+	img.Set(33, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.StdInfoAttr(testCreated, testModified, testModified, testModified, fileattr.Archive, 0x101, 4000),
+			ntfstest.ResidentAttr(ntfs.AttrAttributeList, "", ntfstest.AttrListValue(
+				ntfstest.ListEntry{Type: ntfs.AttrStandardInformation, Record: big},
+				ntfstest.ListEntry{Type: ntfs.AttrFileName, Record: fileref.NewSegment(34, 1)},
+				ntfstest.ListEntry{Type: ntfs.AttrData, Record: big},
+				ntfstest.ListEntry{Type: ntfs.AttrData, StartVCN: 2, Record: fileref.NewSegment(34, 1)},
+			)),
+			ntfstest.NonResidentAttr(ntfs.AttrData, "", []ntfstest.Run{{LCN: 42, Length: 2}}, 4*ntfstest.ClusterSize, 0, 0, 0),
+		},
+	})
+	img.Set(34, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Base:     big,
+		Attrs: [][]byte{
+			ntfstest.FileNameAttr(testUsers, "big.bin", ntfs.NamespaceWin32DOS, fileattr.Archive),
+			ntfstest.NonResidentAttrAt(ntfs.AttrData, "", 2, []ntfstest.Run{{LCN: 44, Length: 2}}, 0, 0, 0, 0),
+		},
+	})
+
+	huge := fileref.NewSegment(35, 1)
+	list := ntfstest.AttrListValue(
+		ntfstest.ListEntry{Type: ntfs.AttrStandardInformation, Record: huge},
+		ntfstest.ListEntry{Type: ntfs.AttrAttributeList, Record: huge},
+		ntfstest.ListEntry{Type: ntfs.AttrFileName, Record: fileref.NewSegment(36, 1)},
+	)
+	img.WriteClusters(50, list)
+	img.Set(35, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.StdInfoAttr(testCreated, testModified, testModified, testModified, fileattr.Archive, 0x101, 5000),
+			ntfstest.NonResidentAttr(ntfs.AttrAttributeList, "", []ntfstest.Run{{LCN: 50, Length: 1}}, uint64(len(list)), 0, 0, 0),
+		},
+	})
+	img.Set(36, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Base:     huge,
+		Attrs: [][]byte{
+			ntfstest.FileNameAttr(testUsers, "huge.bin", ntfs.NamespaceWin32DOS, fileattr.Archive),
+		},
+	})
+
+	return img
+}
+
+func TestParseRecord(t *testing.T) {
+	mft := newEntryImage().MFTBytes(t)
+	e, err := ntfs.ParseRecord(mft[31*ntfstest.RecordSize : 32*ntfstest.RecordSize])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Number != 31 {
+		t.Errorf("Number = %d, want 31", e.Number)
+	}
+	if want := fileref.NewSegment(31, 2); e.ID() != want {
+		t.Errorf("ID = %v, want %v", e.ID(), want)
+	}
+	if !e.InUse() || e.Deleted() || e.Directory() {
+		t.Errorf("InUse = %t, Deleted = %t, Directory = %t", e.InUse(), e.Deleted(), e.Directory())
+	}
+
+	si := e.StandardInformation
+	if si == nil {
+		t.Fatal("StandardInformation is nil")
+	}
+	if !si.Created.Equal(testCreated) || !si.Modified.Equal(testModified) {
+		t.Errorf("Created = %v, Modified = %v", si.Created, si.Modified)
+	}
+	if si.Attributes != fileattr.Archive || si.SecurityID != 0x101 || si.USN != 2000 {
+		t.Errorf("Attributes = %v, SecurityID = %#x, USN = %d", si.Attributes, si.SecurityID, si.USN)
+	}
+
+	if len(e.FileNames) != 2 {
+		t.Fatalf("len(FileNames) = %d, want 2", len(e.FileNames))
+	}
+	if e.FileNames[0].Namespace != ntfs.NamespaceDOS || e.FileNames[0].Name != "REPORT~1.DOC" {
+		t.Errorf("FileNames[0] = %s (%d)", e.FileNames[0].Name, e.FileNames[0].Namespace)
+	}
+	if fn, ok := e.Name(); !ok || fn.Name != "report.docx" || fn.Parent != testUsers {
+		t.Errorf("Name = %q in %v, want %q in %v", fn.Name, fn.Parent, "report.docx", testUsers)
+	}
+
+	data, ok := e.Stream("")
+	if !ok {
+		t.Fatal("unnamed stream not found")
+	}
+	if !data.NonResident || data.Size != 6000 || data.AllocatedSize != 2*ntfstest.ClusterSize || len(data.Fragments) != 1 {
+		t.Errorf("unnamed stream = %+v", data)
+	}
+	if ads := e.AlternateStreams(); len(ads) != 1 || ads[0].Name != ntfs.ZoneIdentifier {
+		t.Errorf("AlternateStreams = %+v", ads)
+	}
+	zone, ok := e.Stream(ntfs.ZoneIdentifier)
+	if !ok {
+		t.Fatal("Zone.Identifier stream not found")
+	}
+	if zone.NonResident || !bytes.HasPrefix(zone.Value, []byte("[ZoneTransfer]")) || zone.Size != uint64(len(zone.Value)) {
+		t.Errorf("Zone.Identifier stream = %+v", zone)
+	}
+}
+
+func TestParseRecordDeleted(t *testing.T) {
+	mft := newEntryImage().MFTBytes(t)
+	e, err := ntfs.ParseRecord(mft[32*ntfstest.RecordSize : 33*ntfstest.RecordSize])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.InUse() || !e.Deleted() {
+		t.Errorf("InUse = %t, Deleted = %t", e.InUse(), e.Deleted())
+	}
+	if want := fileref.NewSegment(32, 3); e.ID() != want {
+		t.Errorf("ID = %v, want %v", e.ID(), want)
+	}
+}
+
+func TestParseRecordTornWrite(t *testing.T) {
+	mft := newEntryImage().MFTBytes(t)
+	record := mft[31*ntfstest.RecordSize : 32*ntfstest.RecordSize]
+	binary.LittleEndian.PutUint16(record[2*ntfstest.SectorSize-2:], 0)
+	if _, err := ntfs.ParseRecord(record); err != ntfs.ErrFixup {
+		t.Errorf("err = %v, want %v", err, ntfs.ErrFixup)
+	}
+}
+
+func TestEntryAttributeList(t *testing.T) {
+	v, err := ntfs.Open(newEntryImage().Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []uint64{33, 35} {
+		e, err := v.Entry(n)
+		if err != nil {
+			t.Fatalf("record %d: %v", n, err)
+		}
+		if e.Incomplete {
+			t.Errorf("record %d: Incomplete = true", n)
+		}
+		if len(e.AttributeList) == 0 {
+			t.Errorf("record %d: AttributeList is empty", n)
+		}
+		if fn, ok := e.Name(); !ok || fn.Parent != testUsers {
+			t.Errorf("record %d: name %q in %v", n, fn.Name, fn.Parent)
+		}
+	}
+
+	e, err := v.Entry(33)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, ok := e.Stream("")
+	if !ok {
+		t.Fatal("unnamed stream not found")
+	}
+	if len(data.Fragments) != 2 || data.Fragments[0].StartVCN != 0 || data.Fragments[1].StartVCN != 2 {
+		t.Errorf("fragments = %+v", data.Fragments)
+	}
+	if data.Size != 4*ntfstest.ClusterSize {
+		t.Errorf("Size = %d, want %d", data.Size, 4*ntfstest.ClusterSize)
+	}
+}
+
+func TestOpenMFT(t *testing.T) {
+	data := newEntryImage().MFTBytes(t)
+	mft, err := ntfs.OpenMFT(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mft.RecordSize() != ntfstest.RecordSize {
+		t.Errorf("RecordSize = %d, want %d", mft.RecordSize(), ntfstest.RecordSize)
+	}
+	if want := uint64(len(data) / ntfstest.RecordSize); mft.RecordCount() != want {
+		t.Errorf("RecordCount = %d, want %d", mft.RecordCount(), want)
+	}
+
+	// Resident attribute lists can be followed in an extracted table
+	big, err := mft.Entry(33)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fn, ok := big.Name(); big.Incomplete || !ok || fn.Name != "big.bin" {
+		t.Errorf("record 33: Incomplete = %t, name = %q", big.Incomplete, fn.Name)
+	}
+
+	// Non-resident attribute lists cannot
+	huge, err := mft.Entry(35)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !huge.Incomplete {
+		t.Error("record 35: Incomplete = false, want true")
+	}
+
+	if _, err := ntfs.OpenMFT(bytes.NewReader(make([]byte, 4096)), 4096); err != ntfs.ErrBadSignature {
+		t.Errorf("zeroed table: err = %v, want %v", err, ntfs.ErrBadSignature)
+	}
+}
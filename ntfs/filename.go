@@ -0,0 +1,59 @@
+package ntfs
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/internal/filetime"
+	"github.com/gentlemanautomaton/volmgmt/internal/utf16le"
+)
+
+// Namespace identifies the naming convention of a $FILE_NAME attribute.
+type Namespace uint8
+
+// File name namespaces.
+const (
+	NamespacePOSIX    Namespace = 0 // Case-sensitive, any character except / and NUL
+	NamespaceWin32    Namespace = 1 // Long file name
+	NamespaceDOS      Namespace = 2 // 8.3 short file name
+	NamespaceWin32DOS Namespace = 3 // Long file name that is also a valid 8.3 name
+)
+
+// FileName holds the contents of a $FILE_NAME attribute.
+type FileName struct {
+	Parent        fileref.ID
+	Created       time.Time
+	Modified      time.Time
+	Changed       time.Time // MFT record change time
+	Accessed      time.Time
+	AllocatedSize uint64
+	RealSize      uint64
+	Attributes    fileattr.Value
+	Namespace     Namespace
+	Name          string
+}
+
+// ParseFileName parses the value of a $FILE_NAME attribute.
+func ParseFileName(data []byte) (FileName, error) {
+	if len(data) < 0x42 {
+		return FileName{}, ErrTruncated
+	}
+	length := int(data[0x40]) * 2
+	if 0x42+length > len(data) {
+		return FileName{}, ErrTruncated
+	}
+	return FileName{
+		Parent:        fileref.New64(int64(binary.LittleEndian.Uint64(data))),
+		Created:       filetime.ToTime(binary.LittleEndian.Uint64(data[0x08:])),
+		Modified:      filetime.ToTime(binary.LittleEndian.Uint64(data[0x10:])),
+		Changed:       filetime.ToTime(binary.LittleEndian.Uint64(data[0x18:])),
+		Accessed:      filetime.ToTime(binary.LittleEndian.Uint64(data[0x20:])),
+		AllocatedSize: binary.LittleEndian.Uint64(data[0x28:]),
+		RealSize:      binary.LittleEndian.Uint64(data[0x30:]),
+		Attributes:    fileattr.Value(binary.LittleEndian.Uint32(data[0x38:])),
+		Namespace:     Namespace(data[0x41]),
+		Name:          utf16le.Decode(data[0x42 : 0x42+length]),
+	}, nil
+}
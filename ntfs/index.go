@@ -0,0 +1,256 @@
+package ntfs
+
+import (
+	"encoding/binary"
+	"strings"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+)
+
+// Index entry flags.
+const (
+	IndexEntrySubnode = 0x0001 // The entry points to a child node
+	IndexEntryLast    = 0x0002 // The entry ends its node and has no key
+)
+
+// Index node flags.
+const (
+	IndexNodeHasChildren = 0x01
+)
+
+const (
+	indexNodeHeaderSize  = 0x10
+	indexEntryHeaderSize = 0x10
+	fileNameMinSize      = 0x42
+)
+
+// Bounds on the timestamps of $FILE_NAME attributes carved from slack
+// space. Stale data rarely holds values within them by chance.
+var (
+	carveNotBefore = time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	carveNotAfter  = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// IndexEntry is an entry in an index node.
+//
+// The entries of file name indexes, such as the $I30 index of a directory,
+// refer to a file and have a $FILE_NAME key. The entries of view indexes,
+// such as $Secure:$SII, carry data instead.
+type IndexEntry struct {
+	File    fileref.ID // File name indexes only
+	Data    []byte     // View indexes only
+	Key     []byte
+	Flags   uint16
+	Subnode uint64 // VCN of the child node, if Flags has IndexEntrySubnode
+	Slack   bool   // The entry was carved from slack space
+}
+
+// IndexNode is a node of an index B-tree.
+type IndexNode struct {
+	Flags   uint8
+	Entries []IndexEntry
+
+	// Slack holds the stale entries that were carved from the unused space
+	// at the end of the node. Only file name indexes are carved.
+	Slack []IndexEntry
+}
+
+// IndexRoot holds the contents of an $INDEX_ROOT attribute, which is the
+// root node of an index.
+type IndexRoot struct {
+	Type             AttributeType // Type of the indexed attribute, or zero for view indexes
+	Collation        uint32
+	BlockSize        uint32 // Size of an INDX record in bytes
+	ClustersPerBlock uint8
+	Node             IndexNode
+}
+
+// IndexBlock is an INDX record from an $INDEX_ALLOCATION attribute, which
+// holds a node of an index.
+type IndexBlock struct {
+	LSN  uint64
+	VCN  uint64
+	Node IndexNode
+}
+
+// ParseIndexRoot parses the value of an $INDEX_ROOT attribute.
+func ParseIndexRoot(data []byte) (IndexRoot, error) {
+	if len(data) < 0x10+indexNodeHeaderSize {
+		return IndexRoot{}, ErrTruncated
+	}
+	r := IndexRoot{
+		Type:             AttributeType(binary.LittleEndian.Uint32(data[0x00:])),
+		Collation:        binary.LittleEndian.Uint32(data[0x04:]),
+		BlockSize:        binary.LittleEndian.Uint32(data[0x08:]),
+		ClustersPerBlock: data[0x0C],
+	}
+	node, err := parseIndexNode(data, 0x10, r.Type != AttrFileName)
+	if err != nil {
+		return IndexRoot{}, err
+	}
+	r.Node = node
+	return r, nil
+}
+
+// ParseIndexBlock parses an INDX record. Its update sequence fixups are
+// applied in place, so data must hold the record exactly as it is stored on
+// disk. If view is true the entries are parsed as the entries of a view
+// index.
+func ParseIndexBlock(data []byte, view bool) (IndexBlock, error) {
+	if err := ApplyFixups(data, "INDX"); err != nil {
+		return IndexBlock{}, err
+	}
+	if len(data) < 0x18+indexNodeHeaderSize {
+		return IndexBlock{}, ErrTruncated
+	}
+	node, err := parseIndexNode(data, 0x18, view)
+	if err != nil {
+		return IndexBlock{}, err
+	}
+	return IndexBlock{
+		LSN:  binary.LittleEndian.Uint64(data[0x08:]),
+		VCN:  binary.LittleEndian.Uint64(data[0x10:]),
+		Node: node,
+	}, nil
+}
+
+// parseIndexNode parses the index node whose header is at offset within
+// data.
+func parseIndexNode(data []byte, offset int, view bool) (IndexNode, error) {
+	h := data[offset:]
+	start := offset + int(binary.LittleEndian.Uint32(h[0x00:]))
+	used := offset + int(binary.LittleEndian.Uint32(h[0x04:]))
+	allocated := offset + int(binary.LittleEndian.Uint32(h[0x08:]))
+	if start > used || used > len(data) {
+		return IndexNode{}, ErrTruncated
+	}
+	if allocated > len(data) || allocated < used {
+		allocated = len(data)
+	}
+	node := IndexNode{Flags: h[0x0C]}
+
+	for pos := start; ; {
+		if pos+indexEntryHeaderSize > used {
+			return IndexNode{}, ErrTruncated
+		}
+		entry, length, err := parseIndexEntry(data[pos:used], view)
+		if err != nil {
+			return IndexNode{}, err
+		}
+		node.Entries = append(node.Entries, entry)
+		if entry.Flags&IndexEntryLast != 0 {
+			break
+		}
+		pos += length
+	}
+
+	if !view {
+		node.Slack = carveIndexEntries(data[:allocated], used)
+	}
+	return node, nil
+}
+
+// parseIndexEntry parses the index entry at the start of data and returns
+// it with its length.
+func parseIndexEntry(data []byte, view bool) (IndexEntry, int, error) {
+	length := int(binary.LittleEndian.Uint16(data[0x08:]))
+	keyLength := int(binary.LittleEndian.Uint16(data[0x0A:]))
+	e := IndexEntry{
+		Flags: binary.LittleEndian.Uint16(data[0x0C:]),
+	}
+	if length < indexEntryHeaderSize || length > len(data) || indexEntryHeaderSize+keyLength > length {
+		return IndexEntry{}, 0, ErrTruncated
+	}
+	if e.Flags&IndexEntryLast == 0 || keyLength > 0 {
+		e.Key = data[indexEntryHeaderSize : indexEntryHeaderSize+keyLength]
+	}
+	if e.Flags&IndexEntrySubnode != 0 {
+		if length < indexEntryHeaderSize+keyLength+8 {
+			return IndexEntry{}, 0, ErrTruncated
+		}
+		e.Subnode = binary.LittleEndian.Uint64(data[length-8:])
+	}
+
+	if view {
+		dataOffset := int(binary.LittleEndian.Uint16(data[0x00:]))
+		dataLength := int(binary.LittleEndian.Uint16(data[0x02:]))
+		if dataLength > 0 {
+			if dataOffset+dataLength > length {
+				return IndexEntry{}, 0, ErrTruncated
+			}
+			e.Data = data[dataOffset : dataOffset+dataLength]
+		}
+	} else if e.Flags&IndexEntryLast == 0 {
+		e.File = fileref.New64(int64(binary.LittleEndian.Uint64(data)))
+	}
+
+	return e, length, nil
+}
+
+// carveIndexEntries searches the slack space of a file name index node,
+// which begins at offset start within data, for the entries of files that
+// were once indexed by the node. Entries are recognized by a plausible
+// $FILE_NAME key whose length agrees with the entry header.
+func carveIndexEntries(data []byte, start int) (entries []IndexEntry) {
+	pos := (start + 7) &^ 7
+	for pos+indexEntryHeaderSize+fileNameMinSize <= len(data) {
+		entry, length, ok := carveIndexEntry(data[pos:])
+		if !ok {
+			pos += 8
+			continue
+		}
+		entries = append(entries, entry)
+		pos += length
+	}
+	return entries
+}
+
+// carveIndexEntry attempts to recognize a file name index entry at the
+// start of data.
+func carveIndexEntry(data []byte) (IndexEntry, int, bool) {
+	length := int(binary.LittleEndian.Uint16(data[0x08:]))
+	keyLength := int(binary.LittleEndian.Uint16(data[0x0A:]))
+	flags := binary.LittleEndian.Uint16(data[0x0C:])
+	if keyLength < fileNameMinSize || indexEntryHeaderSize+keyLength > len(data) {
+		return IndexEntry{}, 0, false
+	}
+	if length%8 != 0 || length < indexEntryHeaderSize+keyLength || length > indexEntryHeaderSize+keyLength+7+8 {
+		return IndexEntry{}, 0, false
+	}
+	if flags&^(IndexEntrySubnode|IndexEntryLast) != 0 {
+		return IndexEntry{}, 0, false
+	}
+
+	key := data[indexEntryHeaderSize : indexEntryHeaderSize+keyLength]
+	if fileNameMinSize+int(key[0x40])*2 != keyLength {
+		return IndexEntry{}, 0, false
+	}
+	fn, err := ParseFileName(key)
+	if err != nil || !plausibleFileName(fn) {
+		return IndexEntry{}, 0, false
+	}
+
+	return IndexEntry{
+		File:  fileref.New64(int64(binary.LittleEndian.Uint64(data))),
+		Key:   key,
+		Flags: flags,
+		Slack: true,
+	}, length, true
+}
+
+// plausibleFileName returns true if fn could be a real $FILE_NAME.
+func plausibleFileName(fn FileName) bool {
+	if fn.Name == "" || fn.Namespace > NamespaceWin32DOS || fn.Parent.IsZero() {
+		return false
+	}
+	if strings.ContainsAny(fn.Name, "\x00/�") {
+		return false
+	}
+	for _, t := range []time.Time{fn.Created, fn.Modified, fn.Changed, fn.Accessed} {
+		if t.Before(carveNotBefore) || t.After(carveNotAfter) {
+			return false
+		}
+	}
+	return true
+}
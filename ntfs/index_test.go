@@ -0,0 +1,226 @@
+package ntfs_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/internal/ntfstest"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+var testDocs = fileref.NewSegment(40, 2)
+
+// fileNameEntry encodes a file name index entry for a file in testDocs.
+func fileNameEntry(file fileref.ID, parent fileref.ID, name string) []byte {
+	key := ntfstest.FileNameValue(parent, name, ntfs.NamespaceWin32DOS, fileattr.Archive, ntfstest.Time, 0)
+	return ntfstest.IndexEntry(file, key, 0, 0)
+}
+
+// newIndexImage returns an image holding a directory whose $I30 index has
+// a root node and two INDX records:
+//
+//	root     a.txt, then a last entry pointing to VCN 0
+//	VCN 0    b.txt, with deleted.txt, b.txt and moved.txt in its slack
+//	VCN 1    unused, still holding old.txt
+func newIndexImage() *ntfstest.Image {
+	img := ntfstest.NewImage(64, ntfstest.Run{LCN: 4, Length: 12})
+
+	last := ntfstest.IndexEntry(fileref.ID{}, nil, ntfs.IndexEntryLast, 0)
+
+	var slack []byte
+	slack = append(slack, fileNameEntry(fileref.NewSegment(42, 1), testDocs, "deleted.txt")...)
+	slack = append(slack, fileNameEntry(fileref.NewSegment(41, 1), testDocs, "b.txt")...)
+	slack = append(slack, make([]byte, 24)...)
+	slack = append(slack, fileNameEntry(fileref.NewSegment(43, 1), testRoot, "moved.txt")...)
+
+	blocks := append(
+		ntfstest.IndexBlock(0, ntfstest.ClusterSize, false, [][]byte{
+			fileNameEntry(fileref.NewSegment(41, 1), testDocs, "b.txt"),
+			last,
+		}, slack),
+		ntfstest.IndexBlock(1, ntfstest.ClusterSize, false, [][]byte{
+			fileNameEntry(fileref.NewSegment(44, 3), testDocs, "old.txt"),
+			last,
+		}, nil)...,
+	)
+	img.WriteClusters(40, blocks)
+
+	img.Set(testDocs.Segment(), ntfstest.Record{
+		Sequence: 2,
+		Flags:    ntfs.RecordInUse | ntfs.RecordDirectory,
+		Attrs: [][]byte{
+			ntfstest.FileNameAttr(testRoot, "Docs", ntfs.NamespaceWin32DOS, 0),
+			ntfstest.ResidentAttr(ntfs.AttrIndexRoot, "$I30", ntfstest.IndexRootValue(ntfstest.ClusterSize, true,
+				fileNameEntry(fileref.NewSegment(45, 1), testDocs, "a.txt"),
+				ntfstest.IndexEntry(fileref.ID{}, nil, ntfs.IndexEntryLast|ntfs.IndexEntrySubnode, 0),
+			)),
+			ntfstest.NonResidentAttr(ntfs.AttrIndexAllocation, "$I30", []ntfstest.Run{{LCN: 40, Length: 2}}, 2*ntfstest.ClusterSize, 2*ntfstest.ClusterSize, 0, 0),
+			ntfstest.ResidentAttr(ntfs.AttrBitmap, "$I30", []byte{0x01, 0, 0, 0, 0, 0, 0, 0}),
+		},
+	})
+	img.Set(46, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.FileNameAttr(testRoot, "file.txt", ntfs.NamespaceWin32DOS, 0),
+		},
+	})
+	return img
+}
+
+func TestIndexEntries(t *testing.T) {
+	v, err := ntfs.Open(newIndexImage().Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := v.Entry(testDocs.Segment())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := v.IndexEntries(e, ntfs.IndexNameFileName, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("IndexEntries returned %d entries, want 4", len(entries))
+	}
+	if root := entries[1]; root.Flags&ntfs.IndexEntrySubnode == 0 || root.Subnode != 0 || len(root.Key) != 0 {
+		t.Errorf("last root entry = %+v", root)
+	}
+	for _, entry := range entries {
+		if entry.Slack {
+			t.Errorf("entry for %s marked as slack", entry.File)
+		}
+	}
+
+	entries, err = v.IndexEntries(e, ntfs.IndexNameFileName, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slack int
+	for _, entry := range entries {
+		if entry.Slack {
+			slack++
+		}
+	}
+	// Three carved entries, plus both entries of the unused record
+	if slack != 5 {
+		t.Errorf("IndexEntries returned %d slack entries, want 5", slack)
+	}
+}
+
+func TestIndexEntriesOversized(t *testing.T) {
+	root := ntfstest.ResidentAttr(ntfs.AttrIndexRoot, "$I30", ntfstest.IndexRootValue(ntfstest.ClusterSize, true,
+		fileNameEntry(fileref.NewSegment(45, 1), testDocs, "a.txt"),
+		ntfstest.IndexEntry(fileref.ID{}, nil, ntfs.IndexEntryLast|ntfs.IndexEntrySubnode, 0),
+	))
+	alloc := func(size uint64) []byte {
+		return ntfstest.NonResidentAttr(ntfs.AttrIndexAllocation, "$I30", []ntfstest.Run{{LCN: 40, Length: 2}}, size, size, 0, 0)
+	}
+	tests := []struct {
+		name   string
+		alloc  []byte
+		bitmap []byte
+		slack  int // Slack entries, or -1 if an error is expected
+	}{
+		{"Allocation", alloc(1 << 62), ntfstest.ResidentAttr(ntfs.AttrBitmap, "$I30", []byte{0x01, 0, 0, 0, 0, 0, 0, 0}), 5},
+		{"Bitmap", alloc(2 * ntfstest.ClusterSize), ntfstest.NonResidentAttr(ntfs.AttrBitmap, "$I30", []ntfstest.Run{{LCN: 50, Length: 1}}, 1<<62, 1<<62, 0, 0), -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := newIndexImage()
+			img.Set(testDocs.Segment(), ntfstest.Record{
+				Sequence: 2,
+				Flags:    ntfs.RecordInUse | ntfs.RecordDirectory,
+				Attrs: [][]byte{
+					ntfstest.FileNameAttr(testRoot, "Docs", ntfs.NamespaceWin32DOS, 0),
+					root,
+					tt.alloc,
+					tt.bitmap,
+				},
+			})
+			v, err := ntfs.Open(img.Reader(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			e, err := v.Entry(testDocs.Segment())
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries, err := v.IndexEntries(e, ntfs.IndexNameFileName, true)
+			if tt.slack < 0 {
+				if err == nil {
+					t.Error("IndexEntries accepted an oversized bitmap")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var slack int
+			for _, entry := range entries {
+				if entry.Slack {
+					slack++
+				}
+			}
+			if slack != tt.slack {
+				t.Errorf("IndexEntries returned %d slack entries, want %d", slack, tt.slack)
+			}
+		})
+	}
+}
+
+func TestDirectory(t *testing.T) {
+	v, err := ntfs.Open(newIndexImage().Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := v.Entry(testDocs.Segment())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := v.Directory(e, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		segment uint64
+		name    string
+		slack   bool
+	}{
+		{45, "a.txt", false},
+		{41, "b.txt", false},
+		{42, "deleted.txt", true},
+		{44, "old.txt", true},
+	}
+	if len(dir) != len(want) {
+		t.Fatalf("Directory returned %d entries, want %d: %+v", len(dir), len(want), dir)
+	}
+	for i, w := range want {
+		d := dir[i]
+		if d.File.Segment() != w.segment || d.FileName.Name != w.name || d.Slack != w.slack {
+			t.Errorf("entry %d = %d %q slack=%t, want %d %q slack=%t", i, d.File.Segment(), d.FileName.Name, d.Slack, w.segment, w.name, w.slack)
+		}
+	}
+
+	r := dir[2].Record()
+	if r.FileName != "deleted.txt" || r.ParentFileReferenceNumber != testDocs || r.Reason != usn.ReasonFileDelete {
+		t.Errorf("Record() = %+v", r)
+	}
+	if !r.TimeStamp.Equal(ntfstest.Time) {
+		t.Errorf("Record().TimeStamp = %v, want %v", r.TimeStamp, ntfstest.Time)
+	}
+
+	file, err := v.Entry(46)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Directory(file, false); !errors.Is(err, ntfs.ErrNotDirectory) {
+		t.Errorf("Directory of a file returned %v, want ErrNotDirectory", err)
+	}
+}
@@ -0,0 +1,236 @@
+package ntfs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// JournalReader reads change journal records from the $J stream of
+// $UsnJrnl. It implements usn.Iter, which allows journals read from images
+// and extracted files to be used in place of a live journal.
+//
+// The $J stream is sparse. Records that have been purged from the journal
+// are deallocated and read as zeros, which the reader skips.
+type JournalReader struct {
+	r      io.ReaderAt
+	size   int64
+	offset int64
+}
+
+// NewJournalReader returns a reader for the records held in r, which holds
+// the contents of a $J stream. The size of the stream is given in bytes.
+func NewJournalReader(r io.ReaderAt, size int64) *JournalReader {
+	return &JournalReader{r: r, size: size}
+}
+
+// Journal is the change journal of a volume, held in the $Max and $J
+// streams of $UsnJrnl.
+type Journal struct {
+	ID              uint64
+	MaximumSize     uint64
+	AllocationDelta uint64
+	LowestValidUSN  usn.USN
+	FirstUSN        usn.USN // The first record that has not been purged
+	NextUSN         usn.USN // The USN that will be given to the next record
+
+	stream *StreamReader
+}
+
+// Journal reads the description of the change journal of the volume from
+// $Max and opens its $J stream. It returns ErrNotFound if the volume has
+// no change journal.
+func (v *Volume) Journal() (*Journal, error) {
+	id, err := v.FindUsnJournal()
+	if err != nil {
+		return nil, err
+	}
+	e, err := v.Entry(id.Segment())
+	if err != nil {
+		return nil, fmt.Errorf("$UsnJrnl: %w", err)
+	}
+
+	info := make([]byte, 32)
+	mr, err := v.OpenStream(e, "$Max")
+	if err != nil {
+		return nil, fmt.Errorf("$UsnJrnl: %w", err)
+	}
+	if mr.Size() < int64(len(info)) {
+		return nil, fmt.Errorf("$UsnJrnl: \"$Max\": %w", ErrTruncated)
+	}
+	if _, err := mr.ReadAt(info, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("$UsnJrnl: \"$Max\": %w", err)
+	}
+
+	s, ok := e.Stream("$J")
+	if !ok {
+		return nil, fmt.Errorf("$UsnJrnl: \"$J\": %w", ErrStreamNotFound)
+	}
+	sr, err := v.StreamReader(s)
+	if err != nil {
+		return nil, fmt.Errorf("$UsnJrnl: \"$J\": %w", err)
+	}
+
+	j := &Journal{
+		MaximumSize:     binary.LittleEndian.Uint64(info[0x00:]),
+		AllocationDelta: binary.LittleEndian.Uint64(info[0x08:]),
+		ID:              binary.LittleEndian.Uint64(info[0x10:]),
+		LowestValidUSN:  usn.USN(binary.LittleEndian.Uint64(info[0x18:])),
+		NextUSN:         usn.USN(sr.Size()),
+		stream:          sr,
+	}
+
+	// Records that have been purged are deallocated, so the first record
+	// is held by the first allocated cluster
+	if s.NonResident {
+		extents, err := s.Extents()
+		if err != nil {
+			return nil, fmt.Errorf("$UsnJrnl: \"$J\": %w", err)
+		}
+		j.FirstUSN = j.NextUSN
+		for _, e := range extents {
+			if !e.Sparse() {
+				j.FirstUSN = usn.USN(int64(e.VCN) * v.cluster)
+				break
+			}
+		}
+	}
+	if j.FirstUSN < j.LowestValidUSN {
+		j.FirstUSN = j.LowestValidUSN
+	}
+	return j, nil
+}
+
+// Reader returns a reader for the records of the journal, beginning with
+// the first record that has not been purged.
+func (j *Journal) Reader() *JournalReader {
+	jr := NewJournalReader(j.stream, j.stream.Size())
+	jr.Seek(j.FirstUSN)
+	return jr
+}
+
+// OpenJournal returns a reader for the change journal of the volume. Reading
+// begins at the first allocated cluster of the $J stream, which skips the
+// records that have been purged. It returns ErrNotFound if the volume has
+// no change journal.
+func (v *Volume) OpenJournal() (*JournalReader, error) {
+	j, err := v.Journal()
+	if err != nil {
+		return nil, err
+	}
+	return j.Reader(), nil
+}
+
+// Seek sets the offset of the next record to be read, which is also its
+// USN.
+func (jr *JournalReader) Seek(offset usn.USN) {
+	if offset < 0 {
+		offset = 0
+	}
+	jr.offset = int64(offset)
+}
+
+// Offset returns the offset of the next record to be read, which is also
+// its USN.
+func (jr *JournalReader) Offset() usn.USN {
+	return usn.USN(jr.offset)
+}
+
+// Next reads records from the journal and appends them to data. The
+// provided buffer is used to read the journal in large blocks; if it is
+// smaller than usn.MaxRecordSize a larger buffer is allocated. It returns
+// io.EOF when the end of the journal is reached.
+func (jr *JournalReader) Next(buffer []byte, data []usn.Record) ([]usn.Record, error) {
+	if len(buffer) < usn.MaxRecordSize {
+		buffer = make([]byte, usn.MaxRecordSize)
+	}
+	found := false
+	for {
+		_, err := jr.scan(buffer, func(_ usn.USN, b []byte) bool {
+			var record usn.Record
+			if record.UnmarshalBinary(b) == nil {
+				data = append(data, record)
+				found = true
+			}
+			return true
+		})
+		if err != nil {
+			return data, err
+		}
+		if found {
+			return data, nil
+		}
+	}
+}
+
+// NextRaw calls fn with the USN and on-disk contents of each record that
+// follows the current offset, until fn returns false or the end of the
+// journal is reached. The record is only valid for the duration of the
+// call. When fn returns false the offset is left at the record it was
+// given. The buffer is used as it is by Next. It returns io.EOF when the
+// end of the journal is reached.
+func (jr *JournalReader) NextRaw(buffer []byte, fn func(u usn.USN, record []byte) bool) error {
+	if len(buffer) < usn.MaxRecordSize {
+		buffer = make([]byte, usn.MaxRecordSize)
+	}
+	for {
+		more, err := jr.scan(buffer, fn)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// scan reads a block of the journal into buffer and calls fn with the USN
+// and contents of each complete record it holds, until fn returns false.
+// The offset is advanced past the records that fn accepted and any space
+// that does not hold a record. It returns false if fn stopped it, and
+// io.EOF at the end of the journal.
+func (jr *JournalReader) scan(buffer []byte, fn func(u usn.USN, record []byte) bool) (bool, error) {
+	if jr.offset >= jr.size {
+		return true, io.EOF
+	}
+	b := buffer
+	if remaining := jr.size - jr.offset; int64(len(b)) > remaining {
+		b = b[:remaining]
+	}
+	n, err := jr.r.ReadAt(b, jr.offset)
+	if err != nil && err != io.EOF {
+		return true, err
+	}
+	b = b[:n]
+	if n == 0 {
+		return true, io.EOF
+	}
+
+	pos := 0
+	for pos+8 <= len(b) {
+		length := int(binary.LittleEndian.Uint32(b[pos:]))
+		major := binary.LittleEndian.Uint16(b[pos+4:])
+		if length == 0 || length%8 != 0 || length > usn.MaxRecordSize || major < 2 || major > 4 {
+			// Skip padding and unallocated space one record
+			// alignment unit at a time
+			pos += 8
+			continue
+		}
+		if pos+length > len(b) {
+			if pos == 0 {
+				// The record extends beyond the end of the journal
+				pos = len(b)
+			}
+			break
+		}
+		if !fn(usn.USN(jr.offset+int64(pos)), b[pos:pos+length]) {
+			jr.offset += int64(pos)
+			return false, nil
+		}
+		pos += length
+	}
+	if pos == 0 {
+		pos = len(b)
+	}
+	jr.offset += int64(pos)
+	return true, nil
+}
@@ -0,0 +1,150 @@
+package ntfs_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/internal/ntfstest"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// testJournal returns the contents of a $J stream that begins with a
+// purged region of zeros, followed by a page holding two records and a
+// page holding one.
+func testJournal() []byte {
+	const purged = 2 * ntfstest.ClusterSize
+	j := make([]byte, purged+2*ntfstest.ClusterSize)
+	first := append(
+		ntfstest.EncodeJournalRecord(ntfstest.JournalRecord{ID: fileref.NewSegment(31, 2), Parent: testUsers, USN: purged, Time: ntfstest.Time, Reason: uint32(usn.ReasonFileCreate), Attrs: fileattr.Archive, Name: "report.docx"}),
+		ntfstest.EncodeJournalRecord(ntfstest.JournalRecord{ID: fileref.NewSegment(31, 2), Parent: testUsers, USN: purged + 96, Time: ntfstest.Time, Reason: uint32(usn.ReasonFileCreate | usn.ReasonClose), Attrs: fileattr.Archive, Name: "report.docx"})...,
+	)
+	copy(j[purged:], first)
+	copy(j[purged+ntfstest.ClusterSize:], ntfstest.EncodeJournalRecord(ntfstest.JournalRecord{ID: fileref.NewSegment(32, 3), Parent: testUsers, USN: purged + ntfstest.ClusterSize, Time: testModified, Reason: uint32(usn.ReasonFileDelete | usn.ReasonClose), Name: "old.txt"}))
+	return j
+}
+
+func readJournal(t *testing.T, iter usn.Iter) []usn.Record {
+	t.Helper()
+	var records []usn.Record
+	buffer := make([]byte, 16384)
+	for {
+		batch, err := iter.Next(buffer, nil)
+		records = append(records, batch...)
+		if err != nil {
+			if err != io.EOF {
+				t.Fatal(err)
+			}
+			return records
+		}
+	}
+}
+
+func TestJournalReader(t *testing.T) {
+	j := testJournal()
+	records := readJournal(t, ntfs.NewJournalReader(bytes.NewReader(j), int64(len(j))))
+	if len(records) != 3 {
+		t.Fatalf("read %d records, want 3", len(records))
+	}
+	if records[0].FileName != "report.docx" || records[0].Reason != usn.ReasonFileCreate {
+		t.Errorf("record 0 = %s %v", records[0].FileName, records[0].Reason)
+	}
+	if records[2].FileName != "old.txt" || records[2].USN != 3*ntfstest.ClusterSize || !records[2].TimeStamp.Equal(testModified) {
+		t.Errorf("record 2 = %s %d %v", records[2].FileName, records[2].USN, records[2].TimeStamp)
+	}
+}
+
+// openJournalVolume returns a volume whose change journal holds the records
+// of testJournal.
+func openJournalVolume(t *testing.T) *ntfs.Volume {
+	t.Helper()
+	img := newEntryImage()
+	j := testJournal()
+	img.WriteClusters(60, j[2*ntfstest.ClusterSize:])
+	extend := fileref.NewSegment(ntfs.RecordExtend, ntfs.RecordExtend)
+	info := make([]byte, 32)
+	binary.LittleEndian.PutUint64(info[0x00:], 32<<20)
+	binary.LittleEndian.PutUint64(info[0x10:], 0x1234)
+	img.Set(40, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.FileNameAttr(extend, "$UsnJrnl", ntfs.NamespaceWin32DOS, fileattr.Hidden|fileattr.System),
+			ntfstest.ResidentAttr(ntfs.AttrData, "$Max", info),
+			ntfstest.NonResidentAttr(ntfs.AttrData, "$J", []ntfstest.Run{{LCN: -1, Length: 2}, {LCN: 60, Length: 2}}, uint64(len(j)), 0, ntfs.AttrFlagSparse, 0),
+		},
+	})
+	v, err := ntfs.Open(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestJournal(t *testing.T) {
+	j, err := openJournalVolume(t).Journal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.ID != 0x1234 || j.MaximumSize != 32<<20 {
+		t.Errorf("ID %#x, maximum size %d", j.ID, j.MaximumSize)
+	}
+	if j.FirstUSN != 2*ntfstest.ClusterSize || j.NextUSN != 4*ntfstest.ClusterSize {
+		t.Errorf("first USN %d, next USN %d", j.FirstUSN, j.NextUSN)
+	}
+
+	// Reading from the second page skips the first two records
+	jr := j.Reader()
+	jr.Seek(3 * ntfstest.ClusterSize)
+	var usns []usn.USN
+	if err := jr.NextRaw(nil, func(u usn.USN, record []byte) bool {
+		usns = append(usns, u)
+		return true
+	}); err != io.EOF {
+		t.Fatalf("err = %v, want %v", err, io.EOF)
+	}
+	if len(usns) != 1 || usns[0] != 3*ntfstest.ClusterSize {
+		t.Errorf("USNs = %v, want [%d]", usns, 3*ntfstest.ClusterSize)
+	}
+	if jr.Offset() != j.NextUSN {
+		t.Errorf("offset = %d, want %d", jr.Offset(), j.NextUSN)
+	}
+}
+
+func TestOpenJournal(t *testing.T) {
+	v := openJournalVolume(t)
+	journal, err := v.OpenJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Resolve the paths of journal records with the master file table
+	cache := usn.NewCache()
+	if err := cache.ReadFrom(context.Background(), v.MFT().Iter(true)); err != nil {
+		t.Fatal(err)
+	}
+	records := readJournal(t, journal)
+	if len(records) != 3 {
+		t.Fatalf("read %d records, want 3", len(records))
+	}
+	filer := usn.Filer(cache.Filer)
+	if path := filer.Path(records[0]); path != `Users\report.docx` {
+		t.Errorf("path = %q, want %q", path, `Users\report.docx`)
+	}
+}
+
+func TestOpenJournalMissing(t *testing.T) {
+	v, err := ntfs.Open(newEntryImage().Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.OpenJournal(); !errors.Is(err, ntfs.ErrNotFound) {
+		t.Errorf("err = %v, want %v", err, ntfs.ErrNotFound)
+	}
+}
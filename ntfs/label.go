@@ -0,0 +1,35 @@
+package ntfs
+
+import (
+	"fmt"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/utf16le"
+)
+
+// maxLabelSize is the largest $VOLUME_NAME value that will be read. Labels
+// are limited to 32 characters, so this leaves ample room.
+const maxLabelSize = 4 << 10
+
+// Label returns the volume label, which is held in the $VOLUME_NAME
+// attribute of $Volume. It returns an empty string if the volume has no
+// label.
+func (v *Volume) Label() (string, error) {
+	e, err := v.Entry(RecordVolume)
+	if err != nil {
+		return "", fmt.Errorf("$Volume: %w", err)
+	}
+	for _, attr := range e.Attributes {
+		if attr.Type != AttrVolumeName {
+			continue
+		}
+		if !attr.NonResident {
+			return utf16le.Decode(attr.Value), nil
+		}
+		data, err := v.readAttribute(attr, maxLabelSize)
+		if err != nil {
+			return "", fmt.Errorf("$Volume: %s: %w", attr.Type, err)
+		}
+		return utf16le.Decode(data), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,16 @@
+// Package logfile parses the NTFS transaction log, $LogFile.
+//
+// NTFS records redo and undo operations for changes to its metadata in
+// $LogFile before they are applied. The log is circular and small, so it
+// only covers the most recent changes, but it often holds evidence of file
+// creation, deletion and renaming after the change journal has wrapped or
+// been deleted.
+//
+// Log records that can be attributed to a file are converted to change
+// journal records by Iter, so they can be used with usn.Cache, the filters
+// of the usnfilter package and the timeline package. The converted records
+// have no USN, because LSNs are not comparable with USNs, so they are
+// merged with change journal records by time.
+//
+// https://flatcap.github.io/linux-ntfs/ntfs/files/logfile.html
+package logfile
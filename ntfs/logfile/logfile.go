@@ -0,0 +1,198 @@
+package logfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+)
+
+// LogFile is an NTFS transaction log.
+type LogFile struct {
+	Restart RestartPage // The current restart page
+
+	r    io.ReaderAt
+	size int64
+}
+
+// Open opens the transaction log held in r, which holds the contents of a
+// $LogFile stream. The size of the stream is given in bytes.
+func Open(r io.ReaderAt, size int64) (*LogFile, error) {
+	l := &LogFile{r: r, size: size}
+
+	first, ferr := readRestartPage(r, 0)
+	second := int64(4096)
+	if ferr == nil {
+		second = int64(first.SystemPageSize)
+	}
+	next, nerr := readRestartPage(r, second)
+
+	switch {
+	case ferr != nil && nerr != nil:
+		return nil, ErrNoRestartArea
+	case ferr != nil:
+		l.Restart = next
+	case nerr != nil || first.RestartArea.CurrentLSN >= next.RestartArea.CurrentLSN:
+		l.Restart = first
+	default:
+		l.Restart = next
+	}
+
+	if fileSize := int64(l.Restart.RestartArea.FileSize); fileSize > 0 && fileSize < l.size {
+		l.size = fileSize
+	}
+	return l, nil
+}
+
+// OpenVolume opens the transaction log of vol.
+func OpenVolume(vol *ntfs.Volume) (*LogFile, error) {
+	e, err := vol.Entry(ntfs.RecordLogFile)
+	if err != nil {
+		return nil, fmt.Errorf("$LogFile: %w", err)
+	}
+	sr, err := vol.OpenStream(e, "")
+	if err != nil {
+		return nil, fmt.Errorf("$LogFile: %w", err)
+	}
+	return Open(sr, sr.Size())
+}
+
+// readRestartPage reads the restart page at off.
+func readRestartPage(r io.ReaderAt, off int64) (RestartPage, error) {
+	header := make([]byte, 0x20)
+	if _, err := r.ReadAt(header, off); err != nil {
+		return RestartPage{}, err
+	}
+	size := binary.LittleEndian.Uint32(header[0x10:])
+	if !validPageSize(size) {
+		return RestartPage{}, ErrUnsupported
+	}
+	data := make([]byte, size)
+	if _, err := r.ReadAt(data, off); err != nil {
+		return RestartPage{}, err
+	}
+	return ParseRestartPage(data)
+}
+
+// offset returns the offset within the log of the record with the given
+// LSN. The upper bits of an LSN count the number of times the log has
+// wrapped, and the lower bits are its offset in units of 8 bytes.
+func (l *LogFile) offset(lsn uint64) int64 {
+	bits := l.Restart.RestartArea.SequenceNumberBits
+	return int64((lsn << bits) >> (bits - 3))
+}
+
+// Records reads the records held in the log's record pages and returns
+// them in LSN order.
+//
+// Each page is scanned for records whose LSN matches their position in the
+// log, which skips the unused space in pages and the stale copies of pages
+// written near the end of the log. Pages that fail their update sequence
+// check are skipped. A record that wraps from the end of the log to its
+// start is not returned.
+func (l *LogFile) Records() ([]Record, error) {
+	pages, err := l.readPages()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		pageSize   = int(l.Restart.LogPageSize)
+		dataOffset = int(l.Restart.RestartArea.PageDataOffset)
+		first      = 2 * int64(l.Restart.SystemPageSize)
+		seen       = make(map[uint64]bool)
+		records    []Record
+	)
+	for i, page := range pages {
+		if page == nil {
+			continue
+		}
+		start := first + int64(i)*int64(pageSize)
+		for pos := dataOffset; pos+recordHeaderSize <= pageSize; {
+			lsn := binary.LittleEndian.Uint64(page[pos:])
+			if lsn == 0 || l.offset(lsn) != start+int64(pos) {
+				pos += 8
+				continue
+			}
+
+			length := recordHeaderSize + int(binary.LittleEndian.Uint32(page[pos+0x18:]))
+			data, ok := l.assemble(pages, i, pos, length)
+			if !ok {
+				pos += 8
+				continue
+			}
+			r, err := parseRecord(data)
+			if err != nil {
+				pos += 8
+				continue
+			}
+			if !seen[lsn] {
+				seen[lsn] = true
+				records = append(records, r)
+			}
+
+			if pos+length > pageSize {
+				// The remainder of the page belongs to this record
+				break
+			}
+			pos += (length + 7) &^ 7
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].LSN < records[j].LSN })
+	return records, nil
+}
+
+// readPages reads the record pages of the log and applies their fixups.
+// Pages that are not valid are returned as nil.
+func (l *LogFile) readPages() ([][]byte, error) {
+	pageSize := int64(l.Restart.LogPageSize)
+	first := 2 * int64(l.Restart.SystemPageSize)
+	if l.size <= first {
+		return nil, nil
+	}
+
+	pages := make([][]byte, (l.size-first)/pageSize)
+	for i := range pages {
+		page := make([]byte, pageSize)
+		if _, err := l.r.ReadAt(page, first+int64(i)*pageSize); err != nil {
+			if err == io.EOF {
+				return pages[:i], nil
+			}
+			return nil, err
+		}
+		if ntfs.ApplyFixups(page, "RCRD") == nil {
+			pages[i] = page
+		}
+	}
+	return pages, nil
+}
+
+// assemble returns the length bytes of the record that begins at pos
+// within page i. Records that do not fit within a page continue in the
+// data area of the following pages.
+func (l *LogFile) assemble(pages [][]byte, i, pos, length int) ([]byte, bool) {
+	page := pages[i]
+	if pos+length <= len(page) {
+		return page[pos : pos+length], true
+	}
+	if length > int(l.size) {
+		return nil, false
+	}
+
+	dataOffset := int(l.Restart.RestartArea.PageDataOffset)
+	data := append([]byte(nil), page[pos:]...)
+	for i++; len(data) < length; i++ {
+		if i >= len(pages) || pages[i] == nil {
+			return nil, false
+		}
+		chunk := pages[i][dataOffset:]
+		if remaining := length - len(data); len(chunk) > remaining {
+			chunk = chunk[:remaining]
+		}
+		data = append(data, chunk...)
+	}
+	return data, true
+}
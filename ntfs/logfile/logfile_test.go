@@ -0,0 +1,358 @@
+package logfile_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/internal/ntfstest"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/ntfs/logfile"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+const (
+	pageSize   = 4096
+	dataOffset = 0x40
+	seqBits    = 48
+	logSize    = 16 * pageSize
+	wrap       = 1 << (64 - seqBits) // LSN of the first pass through the log
+)
+
+var (
+	root    = fileref.NewSegment(ntfs.RecordRoot, ntfs.RecordRoot)
+	created = time.Date(2023, 3, 14, 15, 9, 26, 0, time.UTC)
+)
+
+// logWriter writes log records to the record pages of a log.
+type logWriter struct {
+	data   []byte
+	offset int // Offset of the next record within the log
+}
+
+func newLogWriter() *logWriter {
+	w := &logWriter{data: make([]byte, logSize), offset: 2*pageSize + dataOffset}
+	w.restartPage(0, 3000)
+	w.restartPage(pageSize, 2000)
+	return w
+}
+
+func (w *logWriter) restartPage(offset int, current uint64) {
+	p := w.data[offset : offset+pageSize]
+	copy(p, "RSTR")
+	binary.LittleEndian.PutUint16(p[0x04:], 0x1E)
+	binary.LittleEndian.PutUint16(p[0x06:], pageSize/512+1)
+	binary.LittleEndian.PutUint32(p[0x10:], pageSize)
+	binary.LittleEndian.PutUint32(p[0x14:], pageSize)
+	binary.LittleEndian.PutUint16(p[0x18:], 0x30)
+	binary.LittleEndian.PutUint16(p[0x1A:], 1)
+	binary.LittleEndian.PutUint16(p[0x1C:], 1)
+
+	a := p[0x30:]
+	binary.LittleEndian.PutUint64(a[0x00:], current)
+	binary.LittleEndian.PutUint16(a[0x08:], 1)
+	binary.LittleEndian.PutUint16(a[0x0E:], logfile.RestartClean)
+	binary.LittleEndian.PutUint32(a[0x10:], seqBits)
+	binary.LittleEndian.PutUint16(a[0x14:], 0xD0)
+	binary.LittleEndian.PutUint16(a[0x16:], 0x30)
+	binary.LittleEndian.PutUint64(a[0x18:], logSize)
+	binary.LittleEndian.PutUint16(a[0x24:], 0x30)
+	binary.LittleEndian.PutUint16(a[0x26:], dataOffset)
+
+	c := a[0x30:]
+	binary.LittleEndian.PutUint64(c[0x08:], current)
+	binary.LittleEndian.PutUint32(c[0x1C:], 8)
+	copy(c[0x20:], ntfstest.UTF16("NTFS"))
+}
+
+type operation struct {
+	redo, undo         logfile.Operation
+	redoData, undoData []byte
+	vcn                uint64
+	blockOffset        uint16
+}
+
+// write appends a client record to the log and returns its LSN. Records
+// that do not fit in the current page continue in the next one.
+func (w *logWriter) write(op operation) uint64 {
+	client := make([]byte, 0x20)
+	binary.LittleEndian.PutUint16(client[0x00:], uint16(op.redo))
+	binary.LittleEndian.PutUint16(client[0x02:], uint16(op.undo))
+	binary.LittleEndian.PutUint16(client[0x04:], 0x20)
+	binary.LittleEndian.PutUint16(client[0x06:], uint16(len(op.redoData)))
+	undoOffset := 0x20 + (len(op.redoData)+7)&^7
+	binary.LittleEndian.PutUint16(client[0x08:], uint16(undoOffset))
+	binary.LittleEndian.PutUint16(client[0x0A:], uint16(len(op.undoData)))
+	binary.LittleEndian.PutUint16(client[0x14:], op.blockOffset)
+	binary.LittleEndian.PutUint64(client[0x18:], op.vcn)
+	client = append(client, op.redoData...)
+	client = append(client, make([]byte, undoOffset-len(client))...)
+	client = append(client, op.undoData...)
+
+	if w.offset%pageSize+0x30 > pageSize {
+		w.offset += pageSize - w.offset%pageSize + dataOffset
+	}
+	lsn := wrap | uint64(w.offset/8)
+	record := make([]byte, 0x30)
+	binary.LittleEndian.PutUint64(record[0x00:], lsn)
+	binary.LittleEndian.PutUint32(record[0x18:], uint32(len(client)))
+	binary.LittleEndian.PutUint32(record[0x20:], logfile.ClientRecord)
+	binary.LittleEndian.PutUint32(record[0x24:], 0x18)
+	record = append(record, client...)
+
+	for len(record) > 0 {
+		n := copy(w.data[w.offset:w.offset+pageSize-w.offset%pageSize], record)
+		record = record[n:]
+		w.offset += n
+		if len(record) > 0 {
+			w.offset += dataOffset
+		}
+	}
+	w.offset = (w.offset + 7) &^ 7
+	return lsn
+}
+
+// Bytes protects the pages of the log and returns its contents.
+func (w *logWriter) Bytes() []byte {
+	data := append([]byte(nil), w.data...)
+	for offset := 0; offset < len(data); offset += pageSize {
+		p := data[offset : offset+pageSize]
+		if offset < 2*pageSize {
+			ntfstest.Protect(p, 0x1E, pageSize/512+1, 0x0007)
+			continue
+		}
+		copy(p, "RCRD")
+		binary.LittleEndian.PutUint16(p[0x04:], 0x28)
+		binary.LittleEndian.PutUint16(p[0x06:], pageSize/512+1)
+		ntfstest.Protect(p, 0x28, pageSize/512+1, 0x0009)
+	}
+	return data
+}
+
+func fileRecord(t *testing.T, number uint32, name string) []byte {
+	image := ntfstest.RecordImage(t, number, ntfstest.Record{
+		Sequence: 3,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.StdInfoAttr(created, created, created, created, fileattr.Archive, 0, 0),
+			ntfstest.FileNameAttr(root, name, ntfs.NamespaceWin32DOS, fileattr.Archive),
+		},
+	})
+	// The log holds the used part of the record
+	used := binary.LittleEndian.Uint32(image[0x18:])
+	return image[:used]
+}
+
+func indexEntry(id fileref.ID, name string) []byte {
+	key := ntfstest.FileNameValue(root, name, ntfs.NamespaceWin32DOS, fileattr.Archive, created, 0)
+	b := make([]byte, 0x10+(len(key)+7)&^7)
+	binary.LittleEndian.PutUint64(b[0x00:], uint64(id.Int64()))
+	binary.LittleEndian.PutUint16(b[0x08:], uint16(len(b)))
+	binary.LittleEndian.PutUint16(b[0x0A:], uint16(len(key)))
+	copy(b[0x10:], key)
+	return b
+}
+
+// newLog returns a log that records the creation of new.txt in record 40
+// and the deletion of old.txt from record 41.
+func newLog(t *testing.T) ([]byte, []uint64) {
+	w := newLogWriter()
+	var lsns []uint64
+
+	// Create new.txt in record 40, which is cluster 10 of the MFT
+	lsns = append(lsns, w.write(operation{
+		redo: logfile.InitializeFileRecordSegment, undo: logfile.Noop,
+		redoData: fileRecord(t, 40, "new.txt"), vcn: 10,
+	}))
+	lsns = append(lsns, w.write(operation{
+		redo: logfile.AddIndexEntryRoot, undo: logfile.DeleteIndexEntryRoot,
+		redoData: indexEntry(fileref.NewSegment(40, 3), "new.txt"), vcn: 1, blockOffset: 2,
+	}))
+	lsns = append(lsns, w.write(operation{
+		redo: logfile.UpdateResidentValue, undo: logfile.UpdateResidentValue,
+		redoData: []byte{1, 2, 3, 4}, undoData: []byte{0, 0, 0, 0}, vcn: 10,
+	}))
+
+	// Delete old.txt from record 41. Pad the log so that the deallocation
+	// spans two pages.
+	for w.offset%pageSize < pageSize-0x200 {
+		w.write(operation{redo: logfile.Noop, undo: logfile.Noop, redoData: make([]byte, 0x100)})
+	}
+	lsns = append(lsns, w.write(operation{
+		redo: logfile.DeleteIndexEntryAllocation, undo: logfile.AddIndexEntryAllocation,
+		undoData: indexEntry(fileref.NewSegment(41, 3), "old.txt"),
+	}))
+	lsns = append(lsns, w.write(operation{
+		redo: logfile.DeallocateFileRecordSegment, undo: logfile.InitializeFileRecordSegment,
+		undoData: fileRecord(t, 41, "old.txt"), vcn: 10, blockOffset: 2,
+	}))
+
+	return w.Bytes(), lsns
+}
+
+func TestRestart(t *testing.T) {
+	data, _ := newLog(t)
+	log, err := logfile.Open(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := log.Restart.RestartArea
+	if a.CurrentLSN != 3000 || !a.Clean() || a.SequenceNumberBits != seqBits || a.PageDataOffset != dataOffset {
+		t.Errorf("unexpected restart area: %+v", a)
+	}
+	if len(a.Clients) != 1 || a.Clients[0].Name != "NTFS" {
+		t.Errorf("unexpected clients: %+v", a.Clients)
+	}
+
+	// The second restart page is used when the first is damaged
+	data[0x200-1]++
+	if log, err = logfile.Open(bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	if log.Restart.RestartArea.CurrentLSN != 2000 {
+		t.Errorf("current LSN %d, want 2000", log.Restart.RestartArea.CurrentLSN)
+	}
+
+	data[pageSize+0x200-1]++
+	if _, err := logfile.Open(bytes.NewReader(data), int64(len(data))); err != logfile.ErrNoRestartArea {
+		t.Errorf("Open returned %v, want %v", err, logfile.ErrNoRestartArea)
+	}
+}
+
+func TestRecords(t *testing.T) {
+	data, lsns := newLog(t)
+
+	// A stale copy of the first record page near the end of the log must
+	// not produce duplicate records
+	copy(data[15*pageSize:], data[2*pageSize:3*pageSize])
+
+	log, err := logfile.Open(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := log.Records()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byLSN := make(map[uint64]logfile.Record)
+	for i, r := range records {
+		if i > 0 && r.LSN <= records[i-1].LSN {
+			t.Fatalf("records are not in LSN order")
+		}
+		byLSN[r.LSN] = r
+	}
+	for _, lsn := range lsns {
+		if _, ok := byLSN[lsn]; !ok {
+			t.Fatalf("record %#x not found", lsn)
+		}
+	}
+
+	boot := ntfs.BootSector{BytesPerSector: 512, SectorsPerCluster: 8, FileRecordSize: 1024}
+	for i, want := range []struct {
+		redo    logfile.Operation
+		segment fileref.ID
+		ok      bool
+	}{
+		{logfile.InitializeFileRecordSegment, fileref.NewSegment(40, 3), true},
+		{logfile.AddIndexEntryRoot, fileref.NewSegment(5, 0), true},
+		{logfile.UpdateResidentValue, fileref.NewSegment(40, 0), true},
+		{logfile.DeleteIndexEntryAllocation, fileref.ID{}, false},
+		{logfile.DeallocateFileRecordSegment, fileref.NewSegment(41, 3), true},
+	} {
+		r := byLSN[lsns[i]]
+		if r.Redo != want.redo || r.TransactionID != 0x18 {
+			t.Errorf("record %d: redo %s, transaction %#x", i, r.Redo, r.TransactionID)
+		}
+		if segment, ok := r.Segment(boot); segment != want.segment || ok != want.ok {
+			t.Errorf("record %d: segment %v, %t, want %v, %t", i, segment, ok, want.segment, want.ok)
+		}
+	}
+
+	e, ok := byLSN[lsns[4]].Entry()
+	if !ok {
+		t.Fatal("deallocation does not carry a record image")
+	}
+	if fn, ok := e.Name(); !ok || fn.Name != "old.txt" || !e.StandardInformation.Created.Equal(created) {
+		t.Errorf("unexpected record image: %+v", e)
+	}
+}
+
+func TestIter(t *testing.T) {
+	data, _ := newLog(t)
+	log, err := logfile.Open(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	boot := ntfs.BootSector{BytesPerSector: 512, SectorsPerCluster: 8, FileRecordSize: 1024}
+	iter := log.Iter(boot)
+	records, err := iter.Next(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := iter.Next(nil, nil); err != io.EOF {
+		t.Fatalf("second call to Next returned %v, want %v", err, io.EOF)
+	}
+
+	type result struct {
+		usn    usn.USN
+		id     fileref.ID
+		parent fileref.ID
+		name   string
+		reason usn.Reason
+	}
+	want := []result{
+		{0, fileref.NewSegment(40, 3), root, "new.txt", usn.ReasonFileCreate},
+		{0, fileref.NewSegment(40, 3), root, "new.txt", usn.ReasonRenameNewName},
+		{0, fileref.NewSegment(41, 3), root, "old.txt", usn.ReasonRenameOldName},
+		{0, fileref.NewSegment(41, 3), root, "old.txt", usn.ReasonFileDelete},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("found %d records, want %d", len(records), len(want))
+	}
+	for i, r := range records {
+		got := result{r.USN, r.FileReferenceNumber, r.ParentFileReferenceNumber, r.FileName, r.Reason}
+		if got != want[i] {
+			t.Errorf("record %d = %+v, want %+v", i, got, want[i])
+		}
+		if !r.TimeStamp.Equal(created) {
+			t.Errorf("record %d time stamp %v, want %v", i, r.TimeStamp, created)
+		}
+	}
+}
+
+func TestOpenVolume(t *testing.T) {
+	data, lsns := newLog(t)
+
+	img := ntfstest.NewImage(64, ntfstest.Run{LCN: 4, Length: 12})
+	img.WriteClusters(30, data)
+	img.Set(ntfs.RecordLogFile, ntfstest.Record{
+		Sequence: ntfs.RecordLogFile,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.FileNameAttr(root, "$LogFile", ntfs.NamespaceWin32DOS, fileattr.Hidden|fileattr.System),
+			ntfstest.NonResidentAttr(ntfs.AttrData, "", []ntfstest.Run{{LCN: 30, Length: logSize / ntfstest.ClusterSize}}, logSize, 0, 0, 0),
+		},
+	})
+	vol, err := ntfs.Open(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := logfile.OpenVolume(vol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := log.Records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) == 0 || records[0].LSN != lsns[0] {
+		t.Fatalf("unexpected records: %d", len(records))
+	}
+}
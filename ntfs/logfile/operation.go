@@ -0,0 +1,111 @@
+package logfile
+
+import "fmt"
+
+// Operation is an NTFS log operation code.
+type Operation uint16
+
+// Log operations.
+const (
+	Noop                         Operation = 0x00
+	CompensationLogRecord        Operation = 0x01
+	InitializeFileRecordSegment  Operation = 0x02
+	DeallocateFileRecordSegment  Operation = 0x03
+	WriteEndOfFileRecordSegment  Operation = 0x04
+	CreateAttribute              Operation = 0x05
+	DeleteAttribute              Operation = 0x06
+	UpdateResidentValue          Operation = 0x07
+	UpdateNonresidentValue       Operation = 0x08
+	UpdateMappingPairs           Operation = 0x09
+	DeleteDirtyClusters          Operation = 0x0A
+	SetNewAttributeSizes         Operation = 0x0B
+	AddIndexEntryRoot            Operation = 0x0C
+	DeleteIndexEntryRoot         Operation = 0x0D
+	AddIndexEntryAllocation      Operation = 0x0E
+	DeleteIndexEntryAllocation   Operation = 0x0F
+	WriteEndOfIndexBuffer        Operation = 0x10
+	SetIndexEntryVCNRoot         Operation = 0x11
+	SetIndexEntryVCNAllocation   Operation = 0x12
+	UpdateFileNameRoot           Operation = 0x13
+	UpdateFileNameAllocation     Operation = 0x14
+	SetBitsInNonresidentBitMap   Operation = 0x15
+	ClearBitsInNonresidentBitMap Operation = 0x16
+	HotFix                       Operation = 0x17
+	EndTopLevelAction            Operation = 0x18
+	PrepareTransaction           Operation = 0x19
+	CommitTransaction            Operation = 0x1A
+	ForgetTransaction            Operation = 0x1B
+	OpenNonresidentAttribute     Operation = 0x1C
+	OpenAttributeTableDump       Operation = 0x1D
+	AttributeNamesDump           Operation = 0x1E
+	DirtyPageTableDump           Operation = 0x1F
+	TransactionTableDump         Operation = 0x20
+	UpdateRecordDataRoot         Operation = 0x21
+	UpdateRecordDataAllocation   Operation = 0x22
+	UpdateRelativeDataInIndex    Operation = 0x23
+	UpdateRelativeDataInIndex2   Operation = 0x24
+	ZeroEndOfFileRecord          Operation = 0x25
+)
+
+var operationNames = [...]string{
+	"Noop",
+	"CompensationLogRecord",
+	"InitializeFileRecordSegment",
+	"DeallocateFileRecordSegment",
+	"WriteEndOfFileRecordSegment",
+	"CreateAttribute",
+	"DeleteAttribute",
+	"UpdateResidentValue",
+	"UpdateNonresidentValue",
+	"UpdateMappingPairs",
+	"DeleteDirtyClusters",
+	"SetNewAttributeSizes",
+	"AddIndexEntryRoot",
+	"DeleteIndexEntryRoot",
+	"AddIndexEntryAllocation",
+	"DeleteIndexEntryAllocation",
+	"WriteEndOfIndexBuffer",
+	"SetIndexEntryVCNRoot",
+	"SetIndexEntryVCNAllocation",
+	"UpdateFileNameRoot",
+	"UpdateFileNameAllocation",
+	"SetBitsInNonresidentBitMap",
+	"ClearBitsInNonresidentBitMap",
+	"HotFix",
+	"EndTopLevelAction",
+	"PrepareTransaction",
+	"CommitTransaction",
+	"ForgetTransaction",
+	"OpenNonresidentAttribute",
+	"OpenAttributeTableDump",
+	"AttributeNamesDump",
+	"DirtyPageTableDump",
+	"TransactionTableDump",
+	"UpdateRecordDataRoot",
+	"UpdateRecordDataAllocation",
+	"UpdateRelativeDataInIndex",
+	"UpdateRelativeDataInIndex2",
+	"ZeroEndOfFileRecord",
+}
+
+// String returns the name of the operation.
+func (op Operation) String() string {
+	if int(op) < len(operationNames) {
+		return operationNames[op]
+	}
+	return fmt.Sprintf("Operation(%#x)", uint16(op))
+}
+
+// FileRecord returns true if the operation targets a FILE record in the
+// master file table.
+func (op Operation) FileRecord() bool {
+	switch op {
+	case InitializeFileRecordSegment, DeallocateFileRecordSegment,
+		WriteEndOfFileRecordSegment, CreateAttribute, DeleteAttribute,
+		UpdateResidentValue, UpdateMappingPairs, SetNewAttributeSizes,
+		AddIndexEntryRoot, DeleteIndexEntryRoot, SetIndexEntryVCNRoot,
+		UpdateFileNameRoot, UpdateRecordDataRoot, ZeroEndOfFileRecord:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package logfile
+
+import (
+	"encoding/binary"
+
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+)
+
+const (
+	recordHeaderSize = 0x30
+	clientHeaderSize = 0x20
+)
+
+// Record types.
+const (
+	ClientRecord  = 1 // Redo and undo operations
+	ClientRestart = 2 // Checkpoint
+)
+
+// Record is a log record.
+type Record struct {
+	LSN           uint64
+	PreviousLSN   uint64 // Previous record of the same client
+	UndoNextLSN   uint64
+	Type          uint32
+	TransactionID uint32
+	Flags         uint16
+
+	// The remaining fields are only set for client records, which describe
+	// NTFS operations.
+	Redo               Operation
+	Undo               Operation
+	RedoData           []byte
+	UndoData           []byte
+	TargetAttribute    uint16 // Index of the target in the open attribute table
+	RecordOffset       uint16 // Offset of the change within its record
+	AttributeOffset    uint16 // Offset of the change within its attribute
+	ClusterBlockOffset uint16 // Offset of the target from TargetVCN in 512 byte blocks
+	TargetVCN          uint64
+	LCNs               []uint64
+
+	// Data holds the record's client data. For client records this
+	// includes the operation header and the redo and undo data.
+	Data []byte
+}
+
+// parseRecord parses a log record whose header and client data are held in
+// data.
+func parseRecord(data []byte) (Record, error) {
+	if len(data) < recordHeaderSize {
+		return Record{}, ntfs.ErrTruncated
+	}
+	r := Record{
+		LSN:           binary.LittleEndian.Uint64(data[0x00:]),
+		PreviousLSN:   binary.LittleEndian.Uint64(data[0x08:]),
+		UndoNextLSN:   binary.LittleEndian.Uint64(data[0x10:]),
+		Type:          binary.LittleEndian.Uint32(data[0x20:]),
+		TransactionID: binary.LittleEndian.Uint32(data[0x24:]),
+		Flags:         binary.LittleEndian.Uint16(data[0x28:]),
+	}
+	length := int(binary.LittleEndian.Uint32(data[0x18:]))
+	if recordHeaderSize+length > len(data) {
+		return Record{}, ntfs.ErrTruncated
+	}
+	r.Data = data[recordHeaderSize : recordHeaderSize+length]
+
+	if r.Type != ClientRecord {
+		return r, nil
+	}
+	if len(r.Data) < clientHeaderSize {
+		return Record{}, ntfs.ErrTruncated
+	}
+	b := r.Data
+	r.Redo = Operation(binary.LittleEndian.Uint16(b[0x00:]))
+	r.Undo = Operation(binary.LittleEndian.Uint16(b[0x02:]))
+	redoOffset := int(binary.LittleEndian.Uint16(b[0x04:]))
+	redoLength := int(binary.LittleEndian.Uint16(b[0x06:]))
+	undoOffset := int(binary.LittleEndian.Uint16(b[0x08:]))
+	undoLength := int(binary.LittleEndian.Uint16(b[0x0A:]))
+	r.TargetAttribute = binary.LittleEndian.Uint16(b[0x0C:])
+	lcns := int(binary.LittleEndian.Uint16(b[0x0E:]))
+	r.RecordOffset = binary.LittleEndian.Uint16(b[0x10:])
+	r.AttributeOffset = binary.LittleEndian.Uint16(b[0x12:])
+	r.ClusterBlockOffset = binary.LittleEndian.Uint16(b[0x14:])
+	r.TargetVCN = binary.LittleEndian.Uint64(b[0x18:])
+
+	if clientHeaderSize+lcns*8 > len(b) || redoOffset+redoLength > len(b) || undoOffset+undoLength > len(b) {
+		return Record{}, ntfs.ErrTruncated
+	}
+	for i := 0; i < lcns; i++ {
+		r.LCNs = append(r.LCNs, binary.LittleEndian.Uint64(b[clientHeaderSize+i*8:]))
+	}
+	if redoLength > 0 {
+		r.RedoData = b[redoOffset : redoOffset+redoLength]
+	}
+	if undoLength > 0 {
+		r.UndoData = b[undoOffset : undoOffset+undoLength]
+	}
+
+	return r, nil
+}
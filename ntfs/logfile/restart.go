@@ -0,0 +1,138 @@
+package logfile
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/utf16le"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+)
+
+var (
+	// ErrNoRestartArea is returned when neither restart page of a log holds
+	// a valid restart area.
+	ErrNoRestartArea = errors.New("$LogFile has no valid restart area")
+
+	// ErrUnsupported is returned when a log uses a page size or version
+	// that is not supported.
+	ErrUnsupported = errors.New("unsupported $LogFile version or geometry")
+)
+
+// Restart area flags.
+const (
+	RestartClean = 0x0002 // The volume was dismounted cleanly
+)
+
+// RestartPage is a restart page, which begins the log. Each log holds two
+// restart pages, and the valid page with the greater current LSN is
+// current.
+type RestartPage struct {
+	ChkdskLSN      uint64 // Set when chkdsk has modified the page
+	SystemPageSize uint32
+	LogPageSize    uint32
+	MinorVersion   int16
+	MajorVersion   int16
+	RestartArea    RestartArea
+}
+
+// RestartArea describes the state of the log.
+type RestartArea struct {
+	CurrentLSN         uint64
+	LogClients         uint16
+	Flags              uint16
+	SequenceNumberBits uint32 // Number of bits of an LSN that hold its wrap count
+	FileSize           uint64
+	LastLSNDataLength  uint32
+	RecordHeaderLength uint16
+	PageDataOffset     uint16 // Offset of the first log record in a record page
+	OpenCount          uint32
+	Clients            []Client
+}
+
+// Client is a client of the log. NTFS is the only client in practice.
+type Client struct {
+	OldestLSN  uint64
+	RestartLSN uint64 // LSN of the client's last checkpoint
+	Name       string
+}
+
+// Clean returns true if the volume was dismounted cleanly.
+func (a RestartArea) Clean() bool {
+	return a.Flags&RestartClean != 0
+}
+
+// ParseRestartPage parses a restart page. Its update sequence fixups are
+// applied in place, so data must hold the page exactly as it is stored on
+// disk.
+func ParseRestartPage(data []byte) (RestartPage, error) {
+	if len(data) >= 4 && string(data[:4]) == "CHKD" {
+		copy(data, "RSTR")
+	}
+	if err := ntfs.ApplyFixups(data, "RSTR"); err != nil {
+		return RestartPage{}, err
+	}
+	if len(data) < 0x20 {
+		return RestartPage{}, ntfs.ErrTruncated
+	}
+
+	p := RestartPage{
+		ChkdskLSN:      binary.LittleEndian.Uint64(data[0x08:]),
+		SystemPageSize: binary.LittleEndian.Uint32(data[0x10:]),
+		LogPageSize:    binary.LittleEndian.Uint32(data[0x14:]),
+		MinorVersion:   int16(binary.LittleEndian.Uint16(data[0x1A:])),
+		MajorVersion:   int16(binary.LittleEndian.Uint16(data[0x1C:])),
+	}
+	if !validPageSize(p.SystemPageSize) || !validPageSize(p.LogPageSize) {
+		return RestartPage{}, ErrUnsupported
+	}
+	if p.MajorVersion < 1 || p.MajorVersion > 2 {
+		return RestartPage{}, ErrUnsupported
+	}
+
+	offset := int(binary.LittleEndian.Uint16(data[0x18:]))
+	if offset+0x30 > len(data) {
+		return RestartPage{}, ntfs.ErrTruncated
+	}
+	b := data[offset:]
+	a := RestartArea{
+		CurrentLSN:         binary.LittleEndian.Uint64(b[0x00:]),
+		LogClients:         binary.LittleEndian.Uint16(b[0x08:]),
+		Flags:              binary.LittleEndian.Uint16(b[0x0E:]),
+		SequenceNumberBits: binary.LittleEndian.Uint32(b[0x10:]),
+		FileSize:           binary.LittleEndian.Uint64(b[0x18:]),
+		LastLSNDataLength:  binary.LittleEndian.Uint32(b[0x20:]),
+		RecordHeaderLength: binary.LittleEndian.Uint16(b[0x24:]),
+		PageDataOffset:     binary.LittleEndian.Uint16(b[0x26:]),
+		OpenCount:          binary.LittleEndian.Uint32(b[0x28:]),
+	}
+	if a.SequenceNumberBits < 4 || a.SequenceNumberBits > 60 {
+		return RestartPage{}, ErrUnsupported
+	}
+	if a.PageDataOffset < 0x28 || uint32(a.PageDataOffset) >= p.LogPageSize {
+		return RestartPage{}, ErrUnsupported
+	}
+
+	clients := offset + int(binary.LittleEndian.Uint16(b[0x16:]))
+	for i := 0; i < int(a.LogClients); i++ {
+		c := clients + i*0xA0
+		if c+0xA0 > len(data) {
+			return RestartPage{}, ntfs.ErrTruncated
+		}
+		length := int(binary.LittleEndian.Uint32(data[c+0x1C:]))
+		if length > 0x80 {
+			length = 0x80
+		}
+		a.Clients = append(a.Clients, Client{
+			OldestLSN:  binary.LittleEndian.Uint64(data[c:]),
+			RestartLSN: binary.LittleEndian.Uint64(data[c+0x08:]),
+			Name:       utf16le.Decode(data[c+0x20 : c+0x20+length]),
+		})
+	}
+
+	p.RestartArea = a
+	return p, nil
+}
+
+func validPageSize(size uint32) bool {
+	return size >= 512 && size <= 64<<10 && size&(size-1) == 0
+}
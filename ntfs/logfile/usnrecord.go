@@ -0,0 +1,206 @@
+package logfile
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// operation returns the operation that describes the record. Records that
+// undo an earlier change carry a no-op redo operation.
+func (r Record) operation() Operation {
+	if r.Redo == Noop || r.Redo == CompensationLogRecord {
+		return r.Undo
+	}
+	return r.Redo
+}
+
+// Segment returns the file reference number of the MFT record that r
+// changes, if its operation targets a FILE record. The cluster and FILE
+// record sizes of the volume are taken from boot.
+//
+// The sequence number is taken from the FILE record image carried by r, if
+// it has one, and is otherwise zero.
+func (r Record) Segment(boot ntfs.BootSector) (fileref.ID, bool) {
+	if r.Type != ClientRecord || !r.operation().FileRecord() || boot.FileRecordSize == 0 {
+		return fileref.ID{}, false
+	}
+	offset := r.TargetVCN*uint64(boot.ClusterSize()) + uint64(r.ClusterBlockOffset)*512
+	var seq uint16
+	if data := r.recordImage(); data != nil {
+		seq = binary.LittleEndian.Uint16(data[0x10:])
+	}
+	return fileref.NewSegment(offset/uint64(boot.FileRecordSize), seq), true
+}
+
+// recordImage returns the FILE record image that is carried by an
+// InitializeFileRecordSegment operation in the record's redo or undo data,
+// or nil if it has none.
+func (r Record) recordImage() []byte {
+	var data []byte
+	switch {
+	case r.Redo == InitializeFileRecordSegment:
+		data = r.RedoData
+	case r.Undo == InitializeFileRecordSegment:
+		data = r.UndoData
+	}
+	if len(data) < 0x30 {
+		return nil
+	}
+	return data
+}
+
+// Entry decodes the FILE record image that is carried by an
+// InitializeFileRecordSegment operation in the record's redo or undo data.
+// Deallocations carry the image of the deleted record as undo data.
+func (r Record) Entry() (ntfs.MFTEntry, bool) {
+	data := r.recordImage()
+	if data == nil {
+		return ntfs.MFTEntry{}, false
+	}
+
+	// The logged image omits the unused end of the record
+	size := int(binary.LittleEndian.Uint32(data[0x1C:]))
+	if size < len(data) || size > 64<<10 {
+		size = len(data)
+	}
+	record := make([]byte, size)
+	copy(record, data)
+	e, err := ntfs.ParseEntry(record)
+	if err != nil {
+		return ntfs.MFTEntry{}, false
+	}
+	return e, true
+}
+
+// FileName decodes the directory index entry that is added by an
+// AddIndexEntryRoot or AddIndexEntryAllocation operation in the record's
+// redo or undo data. Removals carry the removed entry as undo data. The
+// file reference number of the file the entry refers to is returned with
+// its $FILE_NAME.
+func (r Record) FileName() (fileref.ID, ntfs.FileName, bool) {
+	var data []byte
+	switch {
+	case r.Redo == AddIndexEntryRoot || r.Redo == AddIndexEntryAllocation:
+		data = r.RedoData
+	case r.Undo == AddIndexEntryRoot || r.Undo == AddIndexEntryAllocation:
+		data = r.UndoData
+	default:
+		return fileref.ID{}, ntfs.FileName{}, false
+	}
+
+	// Index entry header
+	if len(data) < 0x10 {
+		return fileref.ID{}, ntfs.FileName{}, false
+	}
+	keyLength := int(binary.LittleEndian.Uint16(data[0x0A:]))
+	if 0x10+keyLength > len(data) {
+		return fileref.ID{}, ntfs.FileName{}, false
+	}
+	fn, err := ntfs.ParseFileName(data[0x10 : 0x10+keyLength])
+	if err != nil {
+		return fileref.ID{}, ntfs.FileName{}, false
+	}
+	id := fileref.New64(int64(binary.LittleEndian.Uint64(data)))
+	return id, fn, true
+}
+
+// USNRecord converts r to a change journal record. It returns false if r
+// does not describe a file.
+//
+// LSNs and USNs are unrelated sequences, so the record's USN is left zero
+// rather than holding the LSN of r. Records from the log should be ordered
+// against change journal records by their time stamps.
+//
+// File record initializations are reported as usn.ReasonFileCreate and
+// deallocations as usn.ReasonFileDelete. Index entries that are added to or
+// removed from a directory are reported as usn.ReasonRenameNewName and
+// usn.ReasonRenameOldName, because the log does not distinguish a rename
+// from the creation or deletion of a file.
+func (r Record) USNRecord(boot ntfs.BootSector) (usn.Record, bool) {
+	if r.Type != ClientRecord {
+		return usn.Record{}, false
+	}
+
+	var record usn.Record
+	switch op := r.operation(); op {
+	case InitializeFileRecordSegment, DeallocateFileRecordSegment:
+		e, ok := r.Entry()
+		if !ok {
+			return usn.Record{}, false
+		}
+		if id, ok := r.Segment(boot); ok {
+			e.Number = id.Segment()
+		}
+		record = e.Record()
+		if op == InitializeFileRecordSegment {
+			record.Reason = usn.ReasonFileCreate
+		} else {
+			record.Reason = usn.ReasonFileDelete
+		}
+	case AddIndexEntryRoot, AddIndexEntryAllocation, DeleteIndexEntryRoot, DeleteIndexEntryAllocation:
+		id, fn, ok := r.FileName()
+		if !ok {
+			return usn.Record{}, false
+		}
+		record = usn.Record{
+			FileReferenceNumber:       id,
+			ParentFileReferenceNumber: fn.Parent,
+			TimeStamp:                 fn.Changed,
+			FileAttributes:            fn.Attributes,
+			FileName:                  fn.Name,
+		}
+		if op == AddIndexEntryRoot || op == AddIndexEntryAllocation {
+			record.Reason = usn.ReasonRenameNewName
+		} else {
+			record.Reason = usn.ReasonRenameOldName
+		}
+	default:
+		return usn.Record{}, false
+	}
+
+	return record, true
+}
+
+// Iter returns an iterator over the log that produces change journal
+// records for the log records that describe files. Records are returned in
+// LSN order, but carry no USN; see USNRecord. The cluster and FILE record
+// sizes of the volume are taken from boot.
+func (l *LogFile) Iter(boot ntfs.BootSector) *Iter {
+	return &Iter{log: l, boot: boot}
+}
+
+// Iter is an iterator over the records of a transaction log. It implements
+// usn.Iter.
+type Iter struct {
+	log     *LogFile
+	boot    ntfs.BootSector
+	records []Record
+	loaded  bool
+	next    int
+}
+
+// Next appends the change journal records of the log to data. The log is
+// read in full by the first call. The provided buffer is not used. It
+// returns io.EOF when there are no more records.
+func (it *Iter) Next(buffer []byte, data []usn.Record) ([]usn.Record, error) {
+	if !it.loaded {
+		records, err := it.log.Records()
+		if err != nil {
+			return data, err
+		}
+		it.records, it.loaded = records, true
+	}
+	for ; it.next < len(it.records); it.next++ {
+		if record, ok := it.records[it.next].USNRecord(it.boot); ok {
+			data = append(data, record)
+		}
+	}
+	if len(data) > 0 {
+		return data, nil
+	}
+	return data, io.EOF
+}
@@ -0,0 +1,99 @@
+package ntfs
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrCompression is returned when compressed data cannot be decoded.
+var ErrCompression = errors.New("invalid LZNT1 compressed data")
+
+// lznt1ChunkSize is the amount of data held by each LZNT1 chunk once it has
+// been decompressed.
+const lznt1ChunkSize = 4096
+
+// decompressLZNT1 decompresses LZNT1 data from src into dst, which NTFS
+// uses for compressed attributes. It returns the number of bytes written.
+// Decompression stops when dst is full or when a zero chunk header is
+// reached. Chunks that decompress to less than 4 KiB are padded with zeros
+// in dst, so that no stale data remains between them.
+//
+// The data is made up of chunks that each decompress to at most 4 KiB.
+// Each chunk is either stored as-is or is made up of groups of eight
+// tokens, where a flag byte indicates which tokens are literal bytes and
+// which are back-references into the chunk's output.
+func decompressLZNT1(dst, src []byte) (int, error) {
+	var n int
+	for chunk := 0; len(src) >= 2 && chunk < len(dst); chunk += lznt1ChunkSize {
+		header := binary.LittleEndian.Uint16(src)
+		if header == 0 {
+			break
+		}
+		size := int(header&0x0FFF) + 1
+		src = src[2:]
+		if size > len(src) {
+			return n, ErrCompression
+		}
+		data := src[:size]
+		src = src[size:]
+
+		out := dst[chunk:]
+		if len(out) > lznt1ChunkSize {
+			out = out[:lznt1ChunkSize]
+		}
+
+		var written int
+		if header&0x8000 == 0 {
+			written = copy(out, data)
+		} else {
+			var err error
+			if written, err = decompressChunk(out, data); err != nil {
+				return n, err
+			}
+		}
+		clear(out[written:])
+		n = chunk + written
+	}
+	return n, nil
+}
+
+// decompressChunk decompresses a single compressed LZNT1 chunk.
+func decompressChunk(out, data []byte) (int, error) {
+	pos := 0
+	for i := 0; i < len(data) && pos < len(out); {
+		flags := data[i]
+		i++
+		for bit := 0; bit < 8 && i < len(data) && pos < len(out); bit++ {
+			if flags&(1<<bit) == 0 {
+				out[pos] = data[i]
+				pos++
+				i++
+				continue
+			}
+			if i+2 > len(data) {
+				return pos, ErrCompression
+			}
+			token := int(binary.LittleEndian.Uint16(data[i:]))
+			i += 2
+
+			// The split between offset and length bits depends on how
+			// far into the chunk the output has progressed
+			lengthMask, offsetShift := 0x0FFF, 12
+			for p := pos - 1; p >= 0x10; p >>= 1 {
+				lengthMask >>= 1
+				offsetShift--
+			}
+			offset := token>>offsetShift + 1
+			length := token&lengthMask + 3
+			if offset > pos {
+				return pos, ErrCompression
+			}
+			// Copy byte by byte because the ranges may overlap
+			for ; length > 0 && pos < len(out); length-- {
+				out[pos] = out[pos-offset]
+				pos++
+			}
+		}
+	}
+	return pos, nil
+}
@@ -0,0 +1,227 @@
+package ntfs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// ErrRecordSize is returned when the record size of an extracted master
+// file table cannot be determined.
+var ErrRecordSize = errors.New("unable to determine FILE record size")
+
+// MFT provides access to the entries of a master file table. It is either
+// part of a volume or an extracted $MFT file.
+type MFT struct {
+	r          io.ReaderAt // Reads from the table's own data
+	vol        *Volume     // Nil for extracted tables
+	recordSize int
+	count      uint64
+}
+
+// OpenMFT opens a master file table that has been extracted from a volume,
+// such as a $MFT file collected during an investigation. The size of the
+// file is given in bytes. The size of its records is determined from the
+// first record.
+//
+// Extracted tables do not include the clusters of the volume, so the
+// attributes of files with non-resident attribute lists are incomplete.
+func OpenMFT(r io.ReaderAt, size int64) (*MFT, error) {
+	header := make([]byte, 0x20)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("unable to read $MFT record: %w", err)
+	}
+	if string(header[:4]) != "FILE" {
+		return nil, ErrBadSignature
+	}
+	recordSize := binary.LittleEndian.Uint32(header[0x1C:])
+	if recordSize < sectorStride || recordSize > 65536 || recordSize&(recordSize-1) != 0 {
+		return nil, ErrRecordSize
+	}
+	return &MFT{
+		r:          r,
+		recordSize: int(recordSize),
+		count:      uint64(size) / uint64(recordSize),
+	}, nil
+}
+
+// RecordSize returns the size of a FILE record in bytes.
+func (m *MFT) RecordSize() int {
+	return m.recordSize
+}
+
+// RecordCount returns the number of records in the master file table,
+// including records that are not in use.
+func (m *MFT) RecordCount() uint64 {
+	return m.count
+}
+
+// ReadRecord reads the FILE record with the given record number. Its update
+// sequence fixups are applied before it is returned.
+func (m *MFT) ReadRecord(n uint64) ([]byte, error) {
+	if n >= m.count {
+		return nil, fmt.Errorf("MFT record %d: %w", n, ErrNotFound)
+	}
+	record := make([]byte, m.recordSize)
+	if _, err := m.r.ReadAt(record, int64(n)*int64(m.recordSize)); err != nil {
+		return nil, fmt.Errorf("MFT record %d: %w", n, err)
+	}
+	if err := ApplyFixups(record, "FILE"); err != nil {
+		return nil, fmt.Errorf("MFT record %d: %w", n, err)
+	}
+	return record, nil
+}
+
+// Entry reads and decodes the entry with the given record number. If the
+// file has an attribute list, the attributes held in its extension records
+// are included.
+func (m *MFT) Entry(n uint64) (MFTEntry, error) {
+	record, err := m.ReadRecord(n)
+	if err != nil {
+		return MFTEntry{}, err
+	}
+	e, err := m.entry(n, record)
+	if err != nil {
+		return MFTEntry{}, fmt.Errorf("MFT record %d: %w", n, err)
+	}
+	return e, nil
+}
+
+// entry decodes the entry held in record, which has had its fixups
+// applied.
+func (m *MFT) entry(n uint64, record []byte) (MFTEntry, error) {
+	e, err := parseEntry(record)
+	if err != nil {
+		return MFTEntry{}, err
+	}
+	e.Number = n
+	if err := m.resolve(&e); err != nil {
+		return MFTEntry{}, err
+	}
+	if err := e.decode(); err != nil {
+		return MFTEntry{}, err
+	}
+	return e, nil
+}
+
+// resolve adds the attributes held in the extension records of e, as
+// listed by its attribute list.
+func (m *MFT) resolve(e *MFTEntry) error {
+	var list *Attribute
+	for i := range e.Attributes {
+		if e.Attributes[i].Type == AttrAttributeList {
+			list = &e.Attributes[i]
+			break
+		}
+	}
+	if list == nil {
+		return nil
+	}
+
+	data := list.Value
+	if list.NonResident {
+		if m.vol == nil {
+			e.Incomplete = true
+			return nil
+		}
+		var err error
+		if data, err = m.vol.readAttribute(*list, maxAttributeList); err != nil {
+			return fmt.Errorf("%s: %w", list.Type, err)
+		}
+	}
+	entries, err := ParseAttributeList(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", list.Type, err)
+	}
+	e.AttributeList = entries
+
+	seen := map[uint64]bool{e.Number: true}
+	for _, entry := range entries {
+		segment := entry.Record.Segment()
+		if seen[segment] {
+			continue
+		}
+		seen[segment] = true
+
+		record, err := m.ReadRecord(segment)
+		if err != nil {
+			e.Incomplete = true
+			continue
+		}
+		ext, err := parseEntry(record)
+		if err != nil || ext.Header.BaseRecord&0xFFFFFFFFFFFF != e.Number {
+			e.Incomplete = true
+			continue
+		}
+		e.Attributes = append(e.Attributes, ext.Attributes...)
+	}
+	return nil
+}
+
+// Iter returns an iterator over the entries of the master file table that
+// produces change journal records. Entries are returned in record number
+// order. Extension records and records that cannot be parsed are skipped.
+// If deleted is true the entries of deleted files are included.
+func (m *MFT) Iter(deleted bool) *Iter {
+	return &Iter{m: m, deleted: deleted}
+}
+
+// Iter is an iterator over the entries of a master file table. It
+// implements usn.Iter.
+type Iter struct {
+	m       *MFT
+	next    uint64
+	deleted bool
+}
+
+// Next reads entries from the master file table and appends their records
+// to data. The provided buffer is used to read several FILE records at a
+// time. It returns io.EOF when there are no more entries.
+func (it *Iter) Next(buffer []byte, data []usn.Record) ([]usn.Record, error) {
+	size := it.m.recordSize
+	if len(buffer) < size {
+		buffer = make([]byte, size)
+	}
+	for it.next < it.m.count {
+		batch := uint64(len(buffer) / size)
+		if remaining := it.m.count - it.next; batch > remaining {
+			batch = remaining
+		}
+		b := buffer[:batch*uint64(size)]
+		if _, err := it.m.r.ReadAt(b, int64(it.next)*int64(size)); err != nil {
+			return data, fmt.Errorf("MFT record %d: %w", it.next, err)
+		}
+		for i := uint64(0); i < batch; i++ {
+			record := b[i*uint64(size) : (i+1)*uint64(size)]
+			if e, ok := it.entry(it.next+i, record); ok {
+				data = append(data, e.Record())
+			}
+		}
+		it.next += batch
+		if len(data) > 0 {
+			return data, nil
+		}
+	}
+	return data, io.EOF
+}
+
+// entry decodes the entry held in record. It returns false if the entry
+// should be skipped.
+func (it *Iter) entry(n uint64, record []byte) (MFTEntry, bool) {
+	if ApplyFixups(record, "FILE") != nil {
+		return MFTEntry{}, false
+	}
+	header, err := parseRecordHeader(record)
+	if err != nil || header.BaseRecord != 0 || (!header.InUse() && !it.deleted) {
+		return MFTEntry{}, false
+	}
+	e, err := it.m.entry(n, record)
+	if err != nil || len(e.FileNames) == 0 {
+		// Reserved records have no names and cannot be placed in a path
+		return MFTEntry{}, false
+	}
+	return e, true
+}
@@ -0,0 +1,117 @@
+package ntfs
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// sectorStride is the distance between update sequence fixups. NTFS uses
+// 512 bytes regardless of the sector size of the underlying device.
+const sectorStride = 512
+
+var (
+	// ErrBadSignature is returned when a record does not start with the
+	// expected signature.
+	ErrBadSignature = errors.New("record has an invalid signature")
+
+	// ErrBadRecord is returned when a record was marked as bad by chkdsk.
+	ErrBadRecord = errors.New("record is marked as bad")
+
+	// ErrFixup is returned when a record's update sequence does not match
+	// the values at the end of its sectors, which indicates a torn write or
+	// corruption.
+	ErrFixup = errors.New("record update sequence mismatch")
+
+	// ErrTruncated is returned when a structure extends beyond the data
+	// that holds it.
+	ErrTruncated = errors.New("structure is truncated")
+)
+
+// Record flags.
+const (
+	RecordInUse     = 0x0001
+	RecordDirectory = 0x0002
+	RecordExtension = 0x0004 // $Extend files
+	RecordIndexView = 0x0008 // View index, such as $Secure or $ObjId
+)
+
+// RecordHeader is the header of a FILE record.
+type RecordHeader struct {
+	LSN             uint64 // $LogFile sequence number of the last change
+	Sequence        uint16 // Incremented each time the record is reused
+	LinkCount       uint16 // Number of hard links
+	AttributeOffset uint16 // Offset of the first attribute
+	Flags           uint16
+	BytesInUse      uint32
+	BytesAllocated  uint32
+	BaseRecord      uint64 // File reference of the base record, or zero for base records
+	NextAttributeID uint16
+	RecordNumber    uint32 // Only present in NTFS 3.1 and later
+}
+
+// InUse returns true if the record is in use. Records that are not in use
+// belong to deleted files.
+func (h RecordHeader) InUse() bool {
+	return h.Flags&RecordInUse != 0
+}
+
+// Directory returns true if the record describes a directory.
+func (h RecordHeader) Directory() bool {
+	return h.Flags&RecordDirectory != 0
+}
+
+// parseRecordHeader parses the header of a FILE record whose fixups have
+// already been applied.
+func parseRecordHeader(data []byte) (RecordHeader, error) {
+	if len(data) < 0x30 {
+		return RecordHeader{}, ErrTruncated
+	}
+	h := RecordHeader{
+		LSN:             binary.LittleEndian.Uint64(data[0x08:]),
+		Sequence:        binary.LittleEndian.Uint16(data[0x10:]),
+		LinkCount:       binary.LittleEndian.Uint16(data[0x12:]),
+		AttributeOffset: binary.LittleEndian.Uint16(data[0x14:]),
+		Flags:           binary.LittleEndian.Uint16(data[0x16:]),
+		BytesInUse:      binary.LittleEndian.Uint32(data[0x18:]),
+		BytesAllocated:  binary.LittleEndian.Uint32(data[0x1C:]),
+		BaseRecord:      binary.LittleEndian.Uint64(data[0x20:]),
+		NextAttributeID: binary.LittleEndian.Uint16(data[0x28:]),
+		RecordNumber:    binary.LittleEndian.Uint32(data[0x2C:]),
+	}
+	if int(h.AttributeOffset) >= len(data) || int(h.BytesInUse) > len(data) {
+		return RecordHeader{}, ErrTruncated
+	}
+	return h, nil
+}
+
+// ApplyFixups verifies the signature of a multi-sector record such as a
+// FILE or INDX record and restores the bytes that were replaced by its
+// update sequence. The data is modified in place.
+func ApplyFixups(data []byte, signature string) error {
+	if len(data) < 8 {
+		return ErrTruncated
+	}
+	switch string(data[:4]) {
+	case signature:
+	case "BAAD":
+		return ErrBadRecord
+	default:
+		return ErrBadSignature
+	}
+
+	offset := int(binary.LittleEndian.Uint16(data[4:]))
+	count := int(binary.LittleEndian.Uint16(data[6:]))
+	if count == 0 || offset+count*2 > len(data) || (count-1)*sectorStride > len(data) {
+		return ErrTruncated
+	}
+
+	usn := data[offset : offset+2]
+	for i := 1; i < count; i++ {
+		end := i*sectorStride - 2
+		if data[end] != usn[0] || data[end+1] != usn[1] {
+			return ErrFixup
+		}
+		copy(data[end:end+2], data[offset+i*2:])
+	}
+	return nil
+}
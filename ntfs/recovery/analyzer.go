@@ -0,0 +1,195 @@
+package recovery
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// DeletedFile describes the MFT entry of a deleted file.
+type DeletedFile struct {
+	Entry ntfs.MFTEntry
+	ID    fileref.ID // File reference number the file had while it existed
+	Path  string     // Last known path, relative to the root of the volume
+
+	// Orphan is set when the file's path could not be traced back to the
+	// root of the volume because a parent directory's record has been
+	// reused. Path then begins with the outermost directory that could be
+	// identified.
+	Orphan bool
+
+	Size        uint64 // Size of the unnamed data stream
+	Status      Status
+	Clusters    uint64 // Number of clusters allocated to the file when it was deleted
+	Reallocated uint64 // Number of those clusters that are now in use
+
+	// DeletedAt is the time of the change journal record that reported the
+	// file's deletion, and DeleteUSN is its update sequence number. Both are
+	// zero if no such record was found.
+	DeletedAt time.Time
+	DeleteUSN usn.USN
+}
+
+// Analyzer finds deleted files on an NTFS volume.
+type Analyzer struct {
+	vol       *ntfs.Volume
+	bitmap    *ntfs.Bitmap
+	cache     *usn.Cache
+	deletions map[fileref.ID]usn.Record
+}
+
+// NewAnalyzer prepares an analyzer for the given volume. It reads the
+// cluster allocation bitmap and every entry of the master file table,
+// including those of deleted files, which are used to reconstruct paths.
+func NewAnalyzer(ctx context.Context, vol *ntfs.Volume) (*Analyzer, error) {
+	bitmap, err := vol.Bitmap()
+	if err != nil {
+		return nil, err
+	}
+	cache := usn.NewCache()
+	if err := cache.ReadFrom(ctx, vol.MFT().Iter(true)); err != nil {
+		return nil, err
+	}
+	return &Analyzer{
+		vol:       vol,
+		bitmap:    bitmap,
+		cache:     cache,
+		deletions: make(map[fileref.ID]usn.Record),
+	}, nil
+}
+
+// ReadJournal reads change journal records from iter and remembers the
+// records that report the deletion of a file, which are used to determine
+// when deleted files were deleted. It returns when iter returns io.EOF or
+// an error, or when ctx is cancelled.
+func (a *Analyzer) ReadJournal(ctx context.Context, iter usn.Iter) error {
+	var (
+		buffer  = make([]byte, 1<<20)
+		records []usn.Record
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var err error
+		records, err = iter.Next(buffer, records[:0])
+		for _, r := range records {
+			if r.Reason.Match(usn.ReasonFileDelete) {
+				a.deletions[r.FileReferenceNumber] = r
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Deleted returns the deleted files of the volume in MFT record order.
+// Records that have never been used, extension records and records without
+// names are skipped.
+func (a *Analyzer) Deleted(ctx context.Context) ([]DeletedFile, error) {
+	var files []DeletedFile
+	mft := a.vol.MFT()
+	count := mft.RecordCount()
+	for n := uint64(0); n < count; n++ {
+		if n%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return files, err
+			}
+		}
+		e, err := mft.Entry(n)
+		if err != nil || !e.Deleted() || e.Header.BaseRecord != 0 || len(e.FileNames) == 0 {
+			continue
+		}
+		files = append(files, a.analyze(e))
+	}
+	return files, nil
+}
+
+// analyze examines the entry of a deleted file.
+func (a *Analyzer) analyze(e ntfs.MFTEntry) DeletedFile {
+	f := DeletedFile{
+		Entry: e,
+		ID:    e.ID(),
+	}
+	f.Path, f.Orphan = a.path(e.Record())
+
+	if r, ok := a.deletions[f.ID]; ok {
+		f.DeletedAt = r.TimeStamp
+		f.DeleteUSN = r.USN
+	}
+
+	s, ok := e.Stream("")
+	if !ok || s.Size == 0 {
+		f.Status = NoData
+		return f
+	}
+	f.Size = s.Size
+	if !s.NonResident {
+		f.Status = Recoverable
+		return f
+	}
+
+	extents, err := s.Extents()
+	if err != nil {
+		f.Status = Overwritten
+		return f
+	}
+	for _, ext := range extents {
+		if ext.Sparse() {
+			continue
+		}
+		f.Clusters += ext.Length
+		f.Reallocated += a.bitmap.AllocatedCount(uint64(ext.LCN), ext.Length)
+	}
+	switch {
+	case f.Clusters == 0:
+		f.Status = Recoverable // Entirely sparse
+	case f.Reallocated == 0:
+		f.Status = Recoverable
+	case f.Reallocated < f.Clusters:
+		f.Status = Partial
+	default:
+		f.Status = Overwritten
+	}
+	return f
+}
+
+// path reconstructs the path of r by following the parent references of
+// its file names. Each parent reference includes a sequence number, so a
+// directory whose record has been reused by another file is not mistaken
+// for the file's parent. It returns true if the path could not be traced
+// back to the root of the volume.
+func (a *Analyzer) path(r usn.Record) (path string, orphan bool) {
+	parts := []string{r.FileName}
+	seen := map[fileref.ID]bool{r.FileReferenceNumber: true}
+	for {
+		parent := r.ParentFileReferenceNumber
+		if parent.IsZero() || parent == r.FileReferenceNumber {
+			break
+		}
+		if parent.Segment() == ntfs.RecordRoot {
+			break
+		}
+		next, ok := a.cache.Get(parent)
+		if !ok || seen[parent] {
+			orphan = true
+			break
+		}
+		seen[parent] = true
+		parts = append(parts, next.FileName)
+		r = next
+	}
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, `\`), orphan
+}
@@ -0,0 +1,8 @@
+// Package recovery finds and recovers deleted files on NTFS volumes.
+//
+// When a file is deleted NTFS clears the in-use flag of its MFT record and
+// marks its clusters as free, but leaves both intact until they are reused.
+// An Analyzer lists the records of deleted files, reconstructs their paths,
+// determines whether their clusters have since been reallocated and
+// extracts whatever content remains.
+package recovery
@@ -0,0 +1,139 @@
+package recovery
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+)
+
+var (
+	// ErrNoData is returned when a deleted file has no content to recover.
+	ErrNoData = errors.New("file has no content")
+
+	// ErrOverwritten is returned when a deleted file's content has been
+	// entirely overwritten.
+	ErrOverwritten = errors.New("file content has been overwritten")
+
+	// ErrPartialCompressed is returned when a compressed file has been
+	// partially overwritten. Compressed data cannot be decoded once any of
+	// its clusters have been replaced.
+	ErrPartialCompressed = errors.New("compressed file content has been partially overwritten")
+)
+
+// Reader returns a reader for the recoverable content of f and the size of
+// that content in bytes. Clusters that have been reallocated to other files
+// read as zeros.
+func (a *Analyzer) Reader(f DeletedFile) (io.ReaderAt, int64, error) {
+	switch f.Status {
+	case NoData:
+		return nil, 0, ErrNoData
+	case Overwritten:
+		return nil, 0, ErrOverwritten
+	}
+
+	s, ok := f.Entry.Stream("")
+	if !ok {
+		return nil, 0, ErrNoData
+	}
+	sr, err := a.vol.StreamReader(s)
+	if err != nil {
+		return nil, 0, err
+	}
+	if f.Status != Partial {
+		return sr, sr.Size(), nil
+	}
+	if s.Compressed() {
+		return nil, 0, ErrPartialCompressed
+	}
+
+	extents, err := s.Extents()
+	if err != nil {
+		return nil, 0, err
+	}
+	return &maskedReader{
+		r:       sr,
+		cluster: int64(a.vol.Boot.ClusterSize()),
+		extents: extents,
+		bitmap:  a.bitmap,
+	}, sr.Size(), nil
+}
+
+// Extract writes the recoverable content of f to a new file within dir and
+// returns the path of the new file. The file is named after the deleted
+// file's MFT segment and sequence numbers and its name, so that deleted
+// files with the same name do not collide.
+func (a *Analyzer) Extract(f DeletedFile, dir string) (path string, err error) {
+	r, size, err := a.Reader(f)
+	if err != nil {
+		return "", err
+	}
+
+	name := f.Path
+	if i := strings.LastIndexByte(name, '\\'); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.NewReplacer("/", "_", `\`, "_").Replace(name)
+	path = filepath.Join(dir, fmt.Sprintf("%d-%d_%s", f.ID.Segment(), f.ID.Sequence(), name))
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+			path = ""
+		}
+	}()
+
+	_, err = io.Copy(out, io.NewSectionReader(r, 0, size))
+	return path, err
+}
+
+// maskedReader reads a stream and replaces the content of clusters that
+// have been reallocated with zeros.
+type maskedReader struct {
+	r       io.ReaderAt
+	cluster int64
+	extents []ntfs.Extent
+	bitmap  *ntfs.Bitmap
+}
+
+// ReadAt implements io.ReaderAt.
+func (m *maskedReader) ReadAt(p []byte, off int64) (int, error) {
+	n, err := m.r.ReadAt(p, off)
+	for pos := off; pos < off+int64(n); {
+		vcn := uint64(pos / m.cluster)
+		end := (int64(vcn) + 1) * m.cluster
+		if end > off+int64(n) {
+			end = off + int64(n)
+		}
+		if m.reallocated(vcn) {
+			clear(p[pos-off : end-off])
+		}
+		pos = end
+	}
+	return n, err
+}
+
+// reallocated returns true if the given virtual cluster is mapped to a
+// logical cluster that is now in use.
+func (m *maskedReader) reallocated(vcn uint64) bool {
+	i := sort.Search(len(m.extents), func(i int) bool {
+		return m.extents[i].VCN+m.extents[i].Length > vcn
+	})
+	if i == len(m.extents) || m.extents[i].VCN > vcn || m.extents[i].Sparse() {
+		return false
+	}
+	e := m.extents[i]
+	return m.bitmap.Allocated(uint64(e.LCN) + vcn - e.VCN)
+}
@@ -0,0 +1,235 @@
+package recovery_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/internal/ntfstest"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/ntfs/recovery"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+var (
+	root      = fileref.NewSegment(ntfs.RecordRoot, ntfs.RecordRoot)
+	deletedAt = time.Date(2024, 2, 29, 13, 45, 0, 0, time.UTC)
+)
+
+// contents returns n bytes of recognizable file content.
+func contents(n int, seed byte) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = seed + byte(i%199)
+	}
+	return b
+}
+
+// newImage returns an image holding these deleted files:
+//
+//	31  Docs\a.txt   recoverable
+//	32  Docs\b.bin   second cluster reallocated
+//	34  Old\c.txt    resident, in a deleted directory
+//	35  d.txt        parent directory reused, clusters reallocated
+//	37  Empty        directory
+func newImage(t *testing.T) (*ntfstest.Image, map[string][]byte) {
+	img := ntfstest.NewImage(128, ntfstest.Run{LCN: 4, Length: 12})
+	img.SetBitmap(30, ntfstest.Run{LCN: 51, Length: 1}, ntfstest.Run{LCN: 60, Length: 1})
+	want := make(map[string][]byte)
+
+	dir := func(n uint64, seq uint16, flags uint16, parent fileref.ID, name string) {
+		img.Set(n, ntfstest.Record{
+			Sequence: seq,
+			Flags:    flags | ntfs.RecordDirectory,
+			Attrs:    [][]byte{ntfstest.FileNameAttr(parent, name, ntfs.NamespaceWin32DOS, 0)},
+		})
+	}
+	file := func(n uint64, seq uint16, parent fileref.ID, name string, data []byte) {
+		img.Set(n, ntfstest.Record{
+			Sequence: seq,
+			Attrs: [][]byte{
+				ntfstest.StdInfoAttr(ntfstest.Time, ntfstest.Time, ntfstest.Time, ntfstest.Time, 0, 0, 0),
+				ntfstest.FileNameAttr(parent, name, ntfs.NamespaceWin32DOS, 0),
+				data,
+			},
+		})
+	}
+
+	docs := fileref.NewSegment(30, 1)
+	dir(30, 1, ntfs.RecordInUse, root, "Docs")
+
+	want["a.txt"] = contents(2*ntfstest.ClusterSize-10, 1)
+	img.WriteClusters(40, want["a.txt"])
+	file(31, 3, docs, "a.txt", ntfstest.NonResidentAttr(ntfs.AttrData, "", []ntfstest.Run{{LCN: 40, Length: 2}}, uint64(len(want["a.txt"])), 0, 0, 0))
+
+	b := contents(2*ntfstest.ClusterSize, 2)
+	img.WriteClusters(50, b)
+	img.WriteClusters(51, contents(ntfstest.ClusterSize, 99)) // Another file's data
+	want["b.bin"] = append(b[:ntfstest.ClusterSize:ntfstest.ClusterSize], make([]byte, ntfstest.ClusterSize)...)
+	file(32, 2, docs, "b.bin", ntfstest.NonResidentAttr(ntfs.AttrData, "", []ntfstest.Run{{LCN: 50, Length: 2}}, uint64(len(b)), 0, 0, 0))
+
+	dir(33, 2, 0, root, "Old")
+	want["c.txt"] = []byte("resident content")
+	file(34, 2, fileref.NewSegment(33, 1), "c.txt", ntfstest.ResidentAttr(ntfs.AttrData, "", want["c.txt"]))
+
+	dir(36, 2, ntfs.RecordInUse, root, "New")
+	file(35, 2, fileref.NewSegment(36, 1), "d.txt", ntfstest.NonResidentAttr(ntfs.AttrData, "", []ntfstest.Run{{LCN: 60, Length: 1}}, 100, 0, 0, 0))
+
+	dir(37, 2, 0, root, "Empty")
+
+	return img, want
+}
+
+func newAnalyzer(t *testing.T) (*recovery.Analyzer, map[string][]byte) {
+	img, want := newImage(t)
+	vol, err := ntfs.Open(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := recovery.NewAnalyzer(context.Background(), vol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, want
+}
+
+// journal is a usn.Iter that returns a fixed set of records.
+type journal []usn.Record
+
+func (j *journal) Next(buffer []byte, data []usn.Record) ([]usn.Record, error) {
+	if len(*j) == 0 {
+		return data, io.EOF
+	}
+	data = append(data, *j...)
+	*j = nil
+	return data, nil
+}
+
+func TestDeleted(t *testing.T) {
+	a, _ := newAnalyzer(t)
+
+	files, err := a.Deleted(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		id          fileref.ID
+		path        string
+		orphan      bool
+		status      recovery.Status
+		reallocated uint64
+	}
+	want := []result{
+		{fileref.NewSegment(31, 2), `Docs\a.txt`, false, recovery.Recoverable, 0},
+		{fileref.NewSegment(32, 1), `Docs\b.bin`, false, recovery.Partial, 1},
+		{fileref.NewSegment(33, 1), `Old`, false, recovery.NoData, 0},
+		{fileref.NewSegment(34, 1), `Old\c.txt`, false, recovery.Recoverable, 0},
+		{fileref.NewSegment(35, 1), `d.txt`, true, recovery.Overwritten, 1},
+		{fileref.NewSegment(37, 1), `Empty`, false, recovery.NoData, 0},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("found %d deleted files, want %d", len(files), len(want))
+	}
+	for i, f := range files {
+		got := result{f.ID, f.Path, f.Orphan, f.Status, f.Reallocated}
+		if got != want[i] {
+			t.Errorf("file %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestDeletionTime(t *testing.T) {
+	a, _ := newAnalyzer(t)
+
+	j := journal{
+		{FileReferenceNumber: fileref.NewSegment(31, 2), Reason: usn.ReasonFileDelete | usn.ReasonClose, USN: 8192, TimeStamp: deletedAt, FileName: "a.txt"},
+		{FileReferenceNumber: fileref.NewSegment(32, 1), Reason: usn.ReasonDataOverwrite, USN: 8300, TimeStamp: deletedAt, FileName: "b.bin"},
+	}
+	if err := a.ReadJournal(context.Background(), &j); err != nil {
+		t.Fatalf("ReadJournal: %v", err)
+	}
+
+	files, err := a.Deleted(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		switch f.Path {
+		case `Docs\a.txt`:
+			if !f.DeletedAt.Equal(deletedAt) || f.DeleteUSN != 8192 {
+				t.Errorf("a.txt deleted at %v (USN %d)", f.DeletedAt, f.DeleteUSN)
+			}
+		default:
+			if !f.DeletedAt.IsZero() {
+				t.Errorf("%s deleted at %v", f.Path, f.DeletedAt)
+			}
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	a, want := newAnalyzer(t)
+	files, err := a.Deleted(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for _, f := range files {
+		path, err := a.Extract(f, dir)
+		switch f.Status {
+		case recovery.NoData:
+			if !errors.Is(err, recovery.ErrNoData) {
+				t.Errorf("%s: err = %v, want %v", f.Path, err, recovery.ErrNoData)
+			}
+			continue
+		case recovery.Overwritten:
+			if !errors.Is(err, recovery.ErrOverwritten) {
+				t.Errorf("%s: err = %v, want %v", f.Path, err, recovery.ErrOverwritten)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", f.Path, err)
+			continue
+		}
+
+		name := filepath.Base(f.Path)
+		if want := filepath.Join(dir, f.ID.String()); filepath.Dir(path) != dir {
+			t.Errorf("%s extracted to %s, not within %s", f.Path, path, want)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want[name[len(name)-5:]]) {
+			t.Errorf("%s: extracted content differs", f.Path)
+		}
+	}
+}
+
+func TestNewAnalyzerBitmapSize(t *testing.T) {
+	img, _ := newImage(t)
+	img.Set(ntfs.RecordBitmap, ntfstest.Record{
+		Sequence: ntfs.RecordBitmap,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.FileNameAttr(root, "$Bitmap", ntfs.NamespaceWin32DOS, 0),
+			ntfstest.NonResidentAttr(ntfs.AttrData, "", []ntfstest.Run{{LCN: 30, Length: 1}}, 1<<62, 1<<62, 0, 0),
+		},
+	})
+	vol, err := ntfs.Open(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recovery.NewAnalyzer(context.Background(), vol); err == nil {
+		t.Error("NewAnalyzer accepted a $Bitmap larger than the volume")
+	}
+}
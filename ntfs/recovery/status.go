@@ -0,0 +1,39 @@
+package recovery
+
+// Status describes how much of a deleted file's content can be recovered.
+type Status uint8
+
+// Recovery statuses.
+const (
+	// NoData indicates that the file has no content to recover, such as a
+	// directory or an empty file.
+	NoData Status = iota
+
+	// Recoverable indicates that none of the file's clusters have been
+	// reallocated, or that its content is resident in its MFT record.
+	Recoverable
+
+	// Partial indicates that some of the file's clusters have been
+	// reallocated to other files.
+	Partial
+
+	// Overwritten indicates that all of the file's clusters have been
+	// reallocated to other files.
+	Overwritten
+)
+
+// String returns a string representation of the status.
+func (s Status) String() string {
+	switch s {
+	case NoData:
+		return "no data"
+	case Recoverable:
+		return "recoverable"
+	case Partial:
+		return "partial"
+	case Overwritten:
+		return "overwritten"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,37 @@
+package ntfs
+
+import "fmt"
+
+// maxReparseData is the largest $REPARSE_POINT value that will be read.
+const maxReparseData = 16 << 10
+
+// ReparsePoint returns the $REPARSE_POINT attribute of the entry. Its
+// value holds a REPARSE_DATA_BUFFER. It returns false if the entry has no
+// reparse point.
+func (e *MFTEntry) ReparsePoint() (Attribute, bool) {
+	for _, attr := range e.Attributes {
+		if attr.Type == AttrReparsePoint {
+			return attr, true
+		}
+	}
+	return Attribute{}, false
+}
+
+// ReparseData reads the value of the $REPARSE_POINT attribute of e, which
+// must belong to a file on the volume. Reparse data is usually resident,
+// but large values are stored in clusters. It returns nil if the entry has
+// no reparse point.
+func (v *Volume) ReparseData(e MFTEntry) ([]byte, error) {
+	attr, ok := e.ReparsePoint()
+	if !ok {
+		return nil, nil
+	}
+	if !attr.NonResident {
+		return attr.Value, nil
+	}
+	data, err := v.readAttribute(attr, maxReparseData)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", attr.Type, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,129 @@
+package ntfs
+
+import (
+	"errors"
+	"io"
+	"math"
+	"sort"
+)
+
+// ErrInvalidRuns is returned when the mapping pairs of a non-resident
+// attribute cannot be decoded.
+var ErrInvalidRuns = errors.New("invalid data runs")
+
+// Extent maps a range of virtual clusters within an attribute to logical
+// clusters within the volume.
+type Extent struct {
+	VCN    uint64 // First virtual cluster within the attribute
+	LCN    int64  // First logical cluster within the volume, or -1 if sparse
+	Length uint64 // Number of clusters
+}
+
+// Sparse returns true if the extent is not backed by any clusters. Sparse
+// extents read as zeros.
+func (e Extent) Sparse() bool {
+	return e.LCN < 0
+}
+
+// DecodeRuns decodes the data runs, also known as mapping pairs, of a
+// non-resident attribute into a list of extents. The attribute's first
+// virtual cluster number is given by vcn.
+//
+// Each run is encoded as a header byte followed by a length and an offset.
+// The offset is relative to the previous run and may be negative. Runs
+// without an offset are sparse.
+//
+// Runs whose virtual or logical clusters cannot be addressed by a signed
+// 64-bit cluster number are rejected. Runs that lie beyond the end of a
+// volume are rejected when the volume reads the attribute.
+func DecodeRuns(data []byte, vcn uint64) ([]Extent, error) {
+	var (
+		extents []Extent
+		lcn     int64
+	)
+	for i := 0; i < len(data); {
+		header := data[i]
+		if header == 0 {
+			return extents, nil
+		}
+		lengthSize := int(header & 0x0F)
+		offsetSize := int(header >> 4)
+		i++
+		if lengthSize == 0 || lengthSize > 8 || offsetSize > 8 || i+lengthSize+offsetSize > len(data) {
+			return nil, ErrInvalidRuns
+		}
+
+		length := uint64(0)
+		for b := lengthSize - 1; b >= 0; b-- {
+			length = length<<8 | uint64(data[i+b])
+		}
+		i += lengthSize
+		if length > math.MaxInt64 || vcn > math.MaxInt64-length {
+			return nil, ErrInvalidRuns
+		}
+
+		e := Extent{VCN: vcn, LCN: -1, Length: length}
+		if offsetSize > 0 {
+			// The offset is a signed value relative to the previous run
+			delta := int64(int8(data[i+offsetSize-1]))
+			for b := offsetSize - 2; b >= 0; b-- {
+				delta = delta<<8 | int64(data[i+b])
+			}
+			if delta > 0 && lcn > math.MaxInt64-delta {
+				return nil, ErrInvalidRuns
+			}
+			lcn += delta
+			if lcn < 0 || uint64(lcn) > math.MaxInt64-length {
+				return nil, ErrInvalidRuns
+			}
+			e.LCN = lcn
+		}
+		i += offsetSize
+
+		extents = append(extents, e)
+		vcn += length
+	}
+	return extents, nil
+}
+
+// readExtents reads len(p) bytes starting at byte offset off within the
+// clusters mapped by extents. Sparse extents read as zeros. It returns
+// io.EOF if the range extends beyond the mapped clusters.
+func readExtents(r io.ReaderAt, extents []Extent, clusterSize int64, p []byte, off int64) (n int, err error) {
+	for n < len(p) {
+		pos := off + int64(n)
+		vcn := uint64(pos / clusterSize)
+		e, ok := findExtent(extents, vcn)
+		if !ok {
+			return n, io.EOF
+		}
+		within := pos - int64(e.VCN)*clusterSize
+		chunk := int64(e.Length)*clusterSize - within
+		if chunk <= 0 {
+			// The extent is too large for its byte offsets to be computed
+			return n, ErrInvalidRuns
+		}
+		if remaining := int64(len(p) - n); chunk > remaining {
+			chunk = remaining
+		}
+		dst := p[n : n+int(chunk)]
+		if e.Sparse() {
+			clear(dst)
+		} else if _, err := r.ReadAt(dst, e.LCN*clusterSize+within); err != nil {
+			return n, err
+		}
+		n += int(chunk)
+	}
+	return n, nil
+}
+
+// findExtent returns the extent that contains the given virtual cluster.
+func findExtent(extents []Extent, vcn uint64) (Extent, bool) {
+	i := sort.Search(len(extents), func(i int) bool {
+		return extents[i].VCN+extents[i].Length > vcn
+	})
+	if i < len(extents) && extents[i].VCN <= vcn {
+		return extents[i], true
+	}
+	return Extent{}, false
+}
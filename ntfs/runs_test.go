@@ -0,0 +1,95 @@
+package ntfs_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/ntfstest"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+)
+
+func TestDecodeRuns(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		vcn  uint64
+		want []ntfs.Extent
+	}{
+		{"Empty", []byte{0}, 0, nil},
+		{"Single", []byte{0x21, 0x04, 0x00, 0x01, 0x00}, 0, []ntfs.Extent{
+			{VCN: 0, LCN: 256, Length: 4},
+		}},
+		{"NegativeOffset", []byte{0x21, 0x04, 0x00, 0x01, 0x11, 0x02, 0x80, 0x00}, 0, []ntfs.Extent{
+			{VCN: 0, LCN: 256, Length: 4},
+			{VCN: 4, LCN: 128, Length: 2},
+		}},
+		{"Sparse", []byte{0x11, 0x02, 0x10, 0x01, 0x05, 0x11, 0x01, 0x10, 0x00}, 0, []ntfs.Extent{
+			{VCN: 0, LCN: 16, Length: 2},
+			{VCN: 2, LCN: -1, Length: 5},
+			{VCN: 7, LCN: 32, Length: 1},
+		}},
+		{"StartVCN", []byte{0x11, 0x03, 0x20, 0x00}, 10, []ntfs.Extent{
+			{VCN: 10, LCN: 32, Length: 3},
+		}},
+		{"Unterminated", ntfstest.EncodeRuns([]ntfstest.Run{{LCN: 300, Length: 70000}})[:6], 0, []ntfs.Extent{
+			{VCN: 0, LCN: 300, Length: 70000},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ntfs.DecodeRuns(tt.data, tt.vcn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRunsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"ZeroLength", []byte{0x10, 0x05, 0x00}},
+		{"Truncated", []byte{0x21, 0x04, 0x00}},
+		{"NegativeLCN", []byte{0x11, 0x04, 0xF0, 0x00}},
+		{"OversizedField", []byte{0x19, 0x01, 0x00}},
+		{"LengthOverflow", []byte{0x08, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{"VCNOverflow", []byte{
+			0x08, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F,
+			0x08, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F,
+		}},
+		{"LCNOverflow", []byte{0x81, 0x02, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F}},
+		{"LCNDeltaOverflow", []byte{
+			0x81, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x3F,
+			0x81, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ntfs.DecodeRuns(tt.data, 0); !errors.Is(err, ntfs.ErrInvalidRuns) {
+				t.Errorf("err = %v, want %v", err, ntfs.ErrInvalidRuns)
+			}
+		})
+	}
+}
+
+func TestEncodeRunsRoundTrip(t *testing.T) {
+	runs := []ntfstest.Run{{LCN: 1000, Length: 8}, {LCN: -1, Length: 8}, {LCN: 20, Length: 300}, {LCN: 70000, Length: 1}}
+	got, err := ntfs.DecodeRuns(ntfstest.EncodeRuns(runs), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vcn uint64
+	for i, run := range runs {
+		want := ntfs.Extent{VCN: vcn, LCN: run.LCN, Length: run.Length}
+		if got[i] != want {
+			t.Errorf("extent %d = %+v, want %+v", i, got[i], want)
+		}
+		vcn += run.Length
+	}
+}
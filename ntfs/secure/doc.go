@@ -0,0 +1,10 @@
+// Package secure reads the security descriptors of an NTFS volume from its
+// $Secure system file.
+//
+// NTFS stores each distinct security descriptor once, in the $SDS stream
+// of $Secure, and files refer to their descriptor by the security ID held
+// in their $STANDARD_INFORMATION attribute. The $SII index maps security
+// IDs to descriptors and the $SDH index maps descriptor hashes to them. A
+// Store uses these indexes to resolve the security IDs reported in change
+// journal records and MFT entries.
+package secure
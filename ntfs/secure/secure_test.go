@@ -0,0 +1,216 @@
+package secure_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/ntfstest"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/ntfs/secure"
+	"github.com/gentlemanautomaton/volmgmt/security"
+)
+
+// Collation rules of the indexes of $Secure.
+var collations = map[string]uint32{
+	secure.IndexSII: 0x10, // COLLATION_NTOFS_ULONG
+	secure.IndexSDH: 0x12, // COLLATION_NTOFS_SECURITY_HASH
+}
+
+// descriptor encodes a self-relative security descriptor with an owner, a
+// group and a DACL granting full control to the owner.
+func descriptor(t *testing.T, owner, group string) []byte {
+	o, err := security.ParseSIDString(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := security.ParseSIDString(group)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ace := make([]byte, 8)
+	ace[0] = byte(security.AccessAllowed)
+	binary.LittleEndian.PutUint16(ace[2:], uint16(8+len(o.Bytes())))
+	binary.LittleEndian.PutUint32(ace[4:], 0x001F01FF)
+	ace = append(ace, o.Bytes()...)
+	acl := make([]byte, 8)
+	acl[0] = 2
+	binary.LittleEndian.PutUint16(acl[2:], uint16(8+len(ace)))
+	binary.LittleEndian.PutUint16(acl[4:], 1)
+	acl = append(acl, ace...)
+
+	b := make([]byte, 20)
+	b[0] = 1
+	binary.LittleEndian.PutUint16(b[2:], uint16(security.SelfRelative|security.DACLPresent))
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(b)))
+	b = append(b, o.Bytes()...)
+	binary.LittleEndian.PutUint32(b[8:], uint32(len(b)))
+	b = append(b, g.Bytes()...)
+	binary.LittleEndian.PutUint32(b[16:], uint32(len(b)))
+	return append(b, acl...)
+}
+
+// sds encodes the contents of a $SDS stream holding the given descriptors
+// and returns their headers.
+func sds(descriptors map[uint32][]byte, ids ...uint32) ([]byte, []secure.Entry) {
+	var data []byte
+	var entries []secure.Entry
+	for _, id := range ids {
+		sd := descriptors[id]
+		entry := secure.Entry{
+			Hash:   id * 7919,
+			ID:     id,
+			Offset: uint64(len(data)),
+			Length: uint32(20 + len(sd)),
+		}
+		entries = append(entries, entry)
+		data = append(data, header(entry)...)
+		data = append(data, sd...)
+		for len(data)%16 != 0 {
+			data = append(data, 0)
+		}
+	}
+	return data, entries
+}
+
+// header encodes the header of a descriptor in $SDS.
+func header(e secure.Entry) []byte {
+	b := make([]byte, 20)
+	binary.LittleEndian.PutUint32(b[0:], e.Hash)
+	binary.LittleEndian.PutUint32(b[4:], e.ID)
+	binary.LittleEndian.PutUint64(b[8:], e.Offset)
+	binary.LittleEndian.PutUint32(b[16:], e.Length)
+	return b
+}
+
+// newSecureImage returns an image whose $Secure file holds two security
+// descriptors. If index is empty $Secure has no indexes.
+func newSecureImage(t *testing.T, index string) *ntfstest.Image {
+	img := ntfstest.NewImage(64, ntfstest.Run{LCN: 4, Length: 12})
+
+	data, entries := sds(map[uint32][]byte{
+		0x100: descriptor(t, "S-1-5-18", "S-1-5-18"),
+		0x101: descriptor(t, "S-1-5-32-544", "S-1-5-18"),
+	}, 0x100, 0x101)
+	img.WriteClusters(40, data)
+
+	attrs := [][]byte{
+		ntfstest.NonResidentAttr(ntfs.AttrData, secure.StreamSDS, []ntfstest.Run{{LCN: 40, Length: 1}}, uint64(len(data)), uint64(len(data)), 0, 0),
+	}
+	var indexEntries [][]byte
+	for _, e := range entries {
+		var key []byte
+		if index == secure.IndexSDH {
+			key = binary.LittleEndian.AppendUint32(key, e.Hash)
+		}
+		key = binary.LittleEndian.AppendUint32(key, e.ID)
+		indexEntries = append(indexEntries, ntfstest.ViewIndexEntry(key, header(e), 0))
+	}
+	indexEntries = append(indexEntries, ntfstest.ViewIndexEntry(nil, nil, ntfs.IndexEntryLast))
+	if index != "" {
+		attrs = append(attrs, ntfstest.ResidentAttr(ntfs.AttrIndexRoot, index, ntfstest.ViewIndexRootValue(collations[index], ntfstest.ClusterSize, false, indexEntries...)))
+	}
+
+	img.Set(ntfs.RecordSecure, ntfstest.Record{
+		Sequence: ntfs.RecordSecure,
+		Flags:    ntfs.RecordInUse,
+		Attrs:    attrs,
+	})
+	return img
+}
+
+func TestStore(t *testing.T) {
+	for _, index := range []string{secure.IndexSII, secure.IndexSDH, ""} {
+		v, err := ntfs.Open(newSecureImage(t, index).Reader(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := secure.Open(v)
+		if err != nil {
+			t.Fatalf("%q: %v", index, err)
+		}
+
+		if ids := s.IDs(); len(ids) != 2 || ids[0] != 0x100 || ids[1] != 0x101 {
+			t.Errorf("%q: IDs = %v", index, ids)
+		}
+		owner, err := s.Owner(0x101)
+		if err != nil {
+			t.Fatalf("%q: %v", index, err)
+		}
+		if name, _ := owner.Name(); name != `BUILTIN\Administrators` {
+			t.Errorf("%q: Owner = %s (%s)", index, owner, name)
+		}
+		d, err := s.Descriptor(0x100)
+		if err != nil {
+			t.Fatalf("%q: %v", index, err)
+		}
+		if got, want := d.SDDL(), "O:SYG:SYD:(A;;FA;;;SY)"; got != want {
+			t.Errorf("%q: SDDL = %s, want %s", index, got, want)
+		}
+		if _, err := s.Descriptor(0x102); !errors.Is(err, secure.ErrNotFound) {
+			t.Errorf("%q: Descriptor of an unknown ID returned %v, want ErrNotFound", index, err)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	data, want := sds(map[uint32][]byte{
+		0x100: descriptor(t, "S-1-5-18", "S-1-5-18"),
+		0x101: descriptor(t, "S-1-5-32-544", "S-1-5-18"),
+		0x102: descriptor(t, "S-1-5-32-545", "S-1-5-18"),
+	}, 0x100, 0x101, 0x102)
+
+	// Place the last descriptor in the second block, after the mirror of
+	// the first
+	last := want[2]
+	first := data[:last.Offset]
+	data = append(append(append([]byte(nil), first...), make([]byte, 256<<10-len(first))...), first...)
+	data = append(data, make([]byte, 512<<10-len(data))...)
+	last.Offset = uint64(len(data))
+	data = append(data, header(last)...)
+	data = append(data, descriptor(t, "S-1-5-32-545", "S-1-5-18")...)
+	want[2] = last
+
+	entries, err := secure.Scan(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Scan returned %d entries, want %d", len(entries), len(want))
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestScanFullBlock(t *testing.T) {
+	// The first descriptor is padded to fill its block exactly, so the scan
+	// reaches the mirror without encountering unused space
+	full := secure.Entry{Hash: 0x100 * 7919, ID: 0x100, Offset: 0, Length: 256 << 10}
+	block := append(header(full), descriptor(t, "S-1-5-18", "S-1-5-18")...)
+	block = append(block, make([]byte, 256<<10-len(block))...)
+	data := append(append([]byte(nil), block...), block...)
+
+	sd := descriptor(t, "S-1-5-32-544", "S-1-5-18")
+	next := secure.Entry{Hash: 0x101 * 7919, ID: 0x101, Offset: uint64(len(data)), Length: uint32(20 + len(sd))}
+	data = append(data, header(next)...)
+	data = append(data, sd...)
+
+	entries, err := secure.Scan(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []secure.Entry{full, next}
+	if len(entries) != len(want) {
+		t.Fatalf("Scan returned %d entries, want %d", len(entries), len(want))
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package secure
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/security"
+)
+
+var (
+	// ErrNotFound is returned when a security ID is not present in $Secure.
+	ErrNotFound = errors.New("security ID not found")
+
+	// ErrMismatch is returned when a descriptor in $SDS does not match the
+	// index entry that refers to it.
+	ErrMismatch = errors.New("security descriptor does not match its index entry")
+)
+
+// Names of the streams and indexes of $Secure.
+const (
+	StreamSDS = "$SDS"
+	IndexSII  = "$SII"
+	IndexSDH  = "$SDH"
+)
+
+const (
+	headerSize = 20
+
+	// $SDS is written in blocks of 256 KiB, each of which is followed by
+	// a mirror copy of itself.
+	blockSize = 256 << 10
+
+	// maxDescriptorSize is the largest security descriptor that will be
+	// read.
+	maxDescriptorSize = 64 << 10
+)
+
+// Entry is the header of a security descriptor in $SDS. The index entries
+// of $SII and $SDH hold a copy of it.
+type Entry struct {
+	Hash   uint32
+	ID     uint32
+	Offset uint64 // Offset of the header within $SDS
+	Length uint32 // Length of the header and descriptor
+}
+
+// parseEntry parses the header of a security descriptor.
+func parseEntry(data []byte) (Entry, error) {
+	if len(data) < headerSize {
+		return Entry{}, security.ErrTruncated
+	}
+	return Entry{
+		Hash:   binary.LittleEndian.Uint32(data[0:]),
+		ID:     binary.LittleEndian.Uint32(data[4:]),
+		Offset: binary.LittleEndian.Uint64(data[8:]),
+		Length: binary.LittleEndian.Uint32(data[16:]),
+	}, nil
+}
+
+// Store provides access to the security descriptors of a volume. It is
+// safe for concurrent use.
+type Store struct {
+	sds     io.ReaderAt
+	size    int64
+	entries map[uint32]Entry
+
+	mu          sync.Mutex
+	descriptors map[uint32]security.Descriptor
+}
+
+// Open reads the $Secure file of v.
+//
+// The descriptors are located with the $SII index. If it cannot be read the
+// $SDH index is used, and if that cannot be read either $SDS is scanned
+// for descriptors.
+func Open(v *ntfs.Volume) (*Store, error) {
+	e, err := v.Entry(ntfs.RecordSecure)
+	if err != nil {
+		return nil, fmt.Errorf("$Secure: %w", err)
+	}
+	sds, err := v.OpenStream(e, StreamSDS)
+	if err != nil {
+		return nil, fmt.Errorf("$Secure: %w", err)
+	}
+
+	s := &Store{
+		sds:         sds,
+		size:        sds.Size(),
+		descriptors: make(map[uint32]security.Descriptor),
+	}
+	for _, name := range []string{IndexSII, IndexSDH} {
+		if s.entries, err = indexEntries(v, e, name); err == nil {
+			return s, nil
+		}
+	}
+	entries, err := Scan(sds, sds.Size())
+	if err != nil {
+		return nil, fmt.Errorf("$Secure:%s: %w", StreamSDS, err)
+	}
+	s.entries = make(map[uint32]Entry, len(entries))
+	for _, entry := range entries {
+		s.entries[entry.ID] = entry
+	}
+	return s, nil
+}
+
+// indexEntries reads the entries of the named index of $Secure.
+func indexEntries(v *ntfs.Volume, e ntfs.MFTEntry, name string) (map[uint32]Entry, error) {
+	index, err := v.IndexEntries(e, name, false)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[uint32]Entry, len(index))
+	for _, ie := range index {
+		if ie.Flags&ntfs.IndexEntryLast != 0 && len(ie.Data) == 0 {
+			continue
+		}
+		entry, err := parseEntry(ie.Data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		entries[entry.ID] = entry
+	}
+	return entries, nil
+}
+
+// Scan searches the contents of a $SDS stream for security descriptors.
+// It is used when the indexes of $Secure are damaged or unavailable.
+// Descriptors are returned in the order they are stored, and the mirror
+// copies are skipped.
+func Scan(r io.ReaderAt, size int64) ([]Entry, error) {
+	var entries []Entry
+	header := make([]byte, headerSize)
+	for pos := int64(0); pos+headerSize <= size; {
+		if _, err := r.ReadAt(header, pos); err != nil && err != io.EOF {
+			return entries, err
+		}
+		entry, _ := parseEntry(header)
+		blockEnd := (pos/blockSize + 1) * blockSize
+		if entry.Offset != uint64(pos) || entry.Length < headerSize || pos+int64(entry.Length) > blockEnd {
+			// The rest of the block is unused, so skip it and its mirror.
+			// A block filled exactly leaves pos at the start of the mirror,
+			// so the next primary block is found from pos itself.
+			pos = (pos/(2*blockSize) + 1) * 2 * blockSize
+			continue
+		}
+		entries = append(entries, entry)
+		pos += (int64(entry.Length) + 15) &^ 15
+	}
+	return entries, nil
+}
+
+// IDs returns the security IDs held by the store in ascending order.
+func (s *Store) IDs() []uint32 {
+	ids := make([]uint32, 0, len(s.entries))
+	for id := range s.entries {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+	return ids
+}
+
+// Entry returns the $SDS header of the descriptor with the given security
+// ID.
+func (s *Store) Entry(id uint32) (Entry, bool) {
+	entry, ok := s.entries[id]
+	return entry, ok
+}
+
+// Raw returns the self-relative security descriptor with the given
+// security ID.
+func (s *Store) Raw(id uint32) ([]byte, error) {
+	entry, ok := s.entries[id]
+	if !ok {
+		return nil, fmt.Errorf("security ID %d: %w", id, ErrNotFound)
+	}
+	if entry.Length < headerSize || entry.Length > maxDescriptorSize || int64(entry.Offset)+int64(entry.Length) > s.size {
+		return nil, fmt.Errorf("security ID %d: %w", id, security.ErrTruncated)
+	}
+	data := make([]byte, entry.Length)
+	if _, err := s.sds.ReadAt(data, int64(entry.Offset)); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("security ID %d: %w", id, err)
+	}
+	stored, _ := parseEntry(data)
+	if stored != entry {
+		return nil, fmt.Errorf("security ID %d: %w", id, ErrMismatch)
+	}
+	return data[headerSize:], nil
+}
+
+// Descriptor returns the security descriptor with the given security ID.
+func (s *Store) Descriptor(id uint32) (security.Descriptor, error) {
+	s.mu.Lock()
+	d, ok := s.descriptors[id]
+	s.mu.Unlock()
+	if ok {
+		return d, nil
+	}
+
+	data, err := s.Raw(id)
+	if err != nil {
+		return security.Descriptor{}, err
+	}
+	d, err = security.Parse(data)
+	if err != nil {
+		return security.Descriptor{}, fmt.Errorf("security ID %d: %w", id, err)
+	}
+
+	s.mu.Lock()
+	s.descriptors[id] = d
+	s.mu.Unlock()
+	return d, nil
+}
+
+// Owner returns the owner of the security descriptor with the given
+// security ID.
+func (s *Store) Owner(id uint32) (security.SID, error) {
+	d, err := s.Descriptor(id)
+	if err != nil {
+		return security.SID{}, err
+	}
+	if d.Owner == nil {
+		return security.SID{}, fmt.Errorf("security ID %d: descriptor has no owner", id)
+	}
+	return *d.Owner, nil
+}
@@ -0,0 +1,54 @@
+package ntfs
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/internal/filetime"
+)
+
+// StandardInformation holds the contents of a $STANDARD_INFORMATION
+// attribute.
+//
+// The fields following Attributes were introduced by NTFS 3.0 and are zero
+// on volumes created by earlier versions.
+type StandardInformation struct {
+	Created      time.Time
+	Modified     time.Time
+	Changed      time.Time // MFT record change time
+	Accessed     time.Time
+	Attributes   fileattr.Value
+	MaxVersions  uint32
+	Version      uint32
+	ClassID      uint32
+	OwnerID      uint32 // Index into $Quota
+	SecurityID   uint32 // Index into $Secure
+	QuotaCharged uint64
+	USN          int64 // Change journal sequence number of the last change
+}
+
+// ParseStandardInformation parses the value of a $STANDARD_INFORMATION
+// attribute.
+func ParseStandardInformation(data []byte) (StandardInformation, error) {
+	if len(data) < 0x30 {
+		return StandardInformation{}, ErrTruncated
+	}
+	si := StandardInformation{
+		Created:     filetime.ToTime(binary.LittleEndian.Uint64(data[0x00:])),
+		Modified:    filetime.ToTime(binary.LittleEndian.Uint64(data[0x08:])),
+		Changed:     filetime.ToTime(binary.LittleEndian.Uint64(data[0x10:])),
+		Accessed:    filetime.ToTime(binary.LittleEndian.Uint64(data[0x18:])),
+		Attributes:  fileattr.Value(binary.LittleEndian.Uint32(data[0x20:])),
+		MaxVersions: binary.LittleEndian.Uint32(data[0x24:]),
+		Version:     binary.LittleEndian.Uint32(data[0x28:]),
+		ClassID:     binary.LittleEndian.Uint32(data[0x2C:]),
+	}
+	if len(data) >= 0x48 {
+		si.OwnerID = binary.LittleEndian.Uint32(data[0x30:])
+		si.SecurityID = binary.LittleEndian.Uint32(data[0x34:])
+		si.QuotaCharged = binary.LittleEndian.Uint64(data[0x38:])
+		si.USN = int64(binary.LittleEndian.Uint64(data[0x40:]))
+	}
+	return si, nil
+}
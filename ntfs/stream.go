@@ -0,0 +1,232 @@
+package ntfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrStreamNotFound is returned when a file does not have the requested
+// stream.
+var ErrStreamNotFound = errors.New("stream not found")
+
+// ZoneIdentifier is the name of the alternate data stream in which Windows
+// records the security zone that a downloaded file came from.
+const ZoneIdentifier = "Zone.Identifier"
+
+var errNegativeOffset = errors.New("negative offset")
+
+// maxUnitSize is the largest compression unit that will be decompressed.
+// NTFS always writes units of 16 clusters, and it only compresses volumes
+// whose clusters are 4 KiB or smaller.
+const maxUnitSize = 64 << 10
+
+// Extents decodes the data runs of a non-resident stream and returns the
+// extents of all of its fragments. It returns nil for resident streams.
+func (s Stream) Extents() ([]Extent, error) {
+	var extents []Extent
+	for _, frag := range s.Fragments {
+		runs, err := DecodeRuns(frag.Runs, frag.StartVCN)
+		if err != nil {
+			return nil, err
+		}
+		extents = append(extents, runs...)
+	}
+	return extents, nil
+}
+
+// Compressed returns true if the stream is stored with NTFS compression.
+func (s Stream) Compressed() bool {
+	return s.NonResident && s.Flags&AttrFlagCompressed != 0 && len(s.Fragments) > 0 && s.Fragments[0].CompressionUnit != 0
+}
+
+// StreamReader reads the contents of a stream from a volume. It implements
+// io.ReaderAt and is safe for concurrent use.
+//
+// Sparse extents read as zeros, as does the region between a stream's
+// initialized size and its real size. Compressed streams are decompressed
+// one compression unit at a time.
+type StreamReader struct {
+	r        io.ReaderAt
+	cluster  int64
+	size     int64
+	init     int64
+	value    []byte   // Resident streams only
+	extents  []Extent // Non-resident streams only
+	unitSize int64    // Size of a compression unit in bytes, or zero
+}
+
+// OpenStream returns a reader for the named stream of e. The unnamed
+// stream holds the contents of the file.
+func (v *Volume) OpenStream(e MFTEntry, name string) (*StreamReader, error) {
+	s, ok := e.Stream(name)
+	if !ok {
+		return nil, fmt.Errorf("MFT record %d: %q: %w", e.Number, name, ErrStreamNotFound)
+	}
+	return v.StreamReader(s)
+}
+
+// StreamReader returns a reader for s, which must belong to a file on the
+// volume.
+func (v *Volume) StreamReader(s Stream) (*StreamReader, error) {
+	sr := &StreamReader{
+		r:       v.r,
+		cluster: v.cluster,
+		size:    int64(s.Size),
+		init:    int64(s.InitializedSize),
+	}
+	if !s.NonResident {
+		sr.value = s.Value
+		return sr, nil
+	}
+	extents, err := s.Extents()
+	if err != nil {
+		return nil, err
+	}
+	if err := v.checkExtents(extents); err != nil {
+		return nil, err
+	}
+	sr.extents = extents
+	if s.Compressed() {
+		unit := s.Fragments[0].CompressionUnit
+		if unit > 16 || v.cluster<<unit > maxUnitSize {
+			return nil, fmt.Errorf("compression unit of 2^%d clusters: %w", unit, ErrCompression)
+		}
+		sr.unitSize = v.cluster << unit
+	}
+	if sr.init > sr.size {
+		sr.init = sr.size
+	}
+	return sr, nil
+}
+
+// Size returns the size of the stream in bytes.
+func (sr *StreamReader) Size() int64 {
+	return sr.size
+}
+
+// allocated returns the length of the stream up to the end of its last
+// extent that is stored on the volume. Resident streams are entirely
+// allocated.
+func (sr *StreamReader) allocated() int64 {
+	if sr.value != nil {
+		return int64(len(sr.value))
+	}
+	for i := len(sr.extents) - 1; i >= 0; i-- {
+		if e := sr.extents[i]; !e.Sparse() {
+			return int64(e.VCN+e.Length) * sr.cluster
+		}
+	}
+	return 0
+}
+
+// ReadAt reads len(p) bytes from the stream starting at byte offset off.
+func (sr *StreamReader) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errNegativeOffset
+	}
+	if off >= sr.size {
+		return 0, io.EOF
+	}
+	if remaining := sr.size - off; int64(len(p)) > remaining {
+		p = p[:remaining]
+		err = io.EOF
+	}
+
+	if sr.value != nil {
+		return copy(p, sr.value[off:]), err
+	}
+
+	// Data beyond the initialized size reads as zeros
+	valid := p
+	if off+int64(len(p)) > sr.init {
+		if off >= sr.init {
+			valid = nil
+		} else {
+			valid = p[:sr.init-off]
+		}
+		clear(p[len(valid):])
+	}
+
+	if len(valid) > 0 {
+		var rerr error
+		if sr.unitSize > 0 {
+			_, rerr = sr.readCompressed(valid, off)
+		} else {
+			_, rerr = readExtents(sr.r, sr.extents, sr.cluster, valid, off)
+		}
+		if rerr == io.EOF {
+			// The extents do not cover the stream's initialized size
+			rerr = io.ErrUnexpectedEOF
+		}
+		if rerr != nil {
+			return 0, rerr
+		}
+	}
+
+	return len(p), err
+}
+
+// readCompressed reads from a compressed stream one compression unit at a
+// time.
+func (sr *StreamReader) readCompressed(p []byte, off int64) (n int, err error) {
+	var unit []byte
+	for n < len(p) {
+		pos := off + int64(n)
+		start := pos / sr.unitSize * sr.unitSize
+		if unit == nil {
+			unit = make([]byte, sr.unitSize)
+		}
+		if err := sr.readUnit(unit, start); err != nil {
+			return n, err
+		}
+		n += copy(p[n:], unit[pos-start:])
+	}
+	return n, nil
+}
+
+// readUnit reads and decompresses the compression unit that starts at the
+// given byte offset.
+//
+// A unit whose clusters are all allocated is stored uncompressed and a unit
+// whose clusters are all sparse reads as zeros. Otherwise the allocated
+// clusters at the start of the unit hold LZNT1 compressed data.
+func (sr *StreamReader) readUnit(unit []byte, start int64) error {
+	clusters := sr.unitSize / sr.cluster
+	vcn := uint64(start / sr.cluster)
+
+	var allocated int64
+	for allocated < clusters {
+		e, ok := findExtent(sr.extents, vcn+uint64(allocated))
+		if !ok {
+			return io.EOF
+		}
+		if e.Sparse() {
+			break
+		}
+		allocated += int64(e.VCN+e.Length) - int64(vcn) - allocated
+	}
+	if allocated > clusters {
+		allocated = clusters
+	}
+
+	switch allocated {
+	case 0:
+		clear(unit)
+		return nil
+	case clusters:
+		_, err := readExtents(sr.r, sr.extents, sr.cluster, unit, start)
+		return err
+	}
+
+	compressed := make([]byte, allocated*sr.cluster)
+	if _, err := readExtents(sr.r, sr.extents, sr.cluster, compressed, start); err != nil {
+		return err
+	}
+	n, err := decompressLZNT1(unit, compressed)
+	if err != nil {
+		return err
+	}
+	clear(unit[n:])
+	return nil
+}
@@ -0,0 +1,342 @@
+package ntfs_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/ntfstest"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+)
+
+// Records holding the files created by newStreamImage.
+const (
+	recordPlain      = 40
+	recordCompressed = 41
+	recordUninit     = 42
+	recordResident   = 43
+)
+
+// Compression units hold 16 clusters.
+const (
+	testCompressionUnit = 4
+	testUnitSize        = ntfstest.ClusterSize << testCompressionUnit
+)
+
+// pattern returns n bytes of data that differ from one cluster to the
+// next.
+func pattern(n int, seed byte) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i/ntfstest.ClusterSize)*7 + seed + byte(i%251)
+	}
+	return b
+}
+
+// streamFiles holds the expected contents of the files created by
+// newStreamImage.
+type streamFiles struct {
+	plain      []byte
+	compressed []byte
+	uninit     []byte
+	resident   []byte
+}
+
+// newStreamImage returns an image holding files that exercise each of the
+// ways a stream can be stored.
+func newStreamImage(t *testing.T) (*ntfstest.Image, streamFiles) {
+	img := ntfstest.NewImage(256, ntfstest.Run{LCN: 4, Length: 12})
+	var files streamFiles
+
+	// A fragmented file with a sparse hole, ending part way through its
+	// last cluster
+	files.plain = pattern(4*ntfstest.ClusterSize-100, 1)
+	clear(files.plain[ntfstest.ClusterSize : 3*ntfstest.ClusterSize])
+	img.WriteClusters(40, files.plain[:ntfstest.ClusterSize])
+	img.WriteClusters(44, files.plain[3*ntfstest.ClusterSize:])
+	img.Set(recordPlain, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.FileNameAttr(testRoot, "plain.bin", ntfs.NamespaceWin32DOS, 0),
+			ntfstest.NonResidentAttr(ntfs.AttrData, "", []ntfstest.Run{{LCN: 40, Length: 1}, {LCN: -1, Length: 2}, {LCN: 44, Length: 1}}, uint64(len(files.plain)), 0, ntfs.AttrFlagSparse, 0),
+		},
+	})
+
+	// A compressed file with three units: one compressed, one sparse and
+	// one that did not compress well enough and is stored as-is
+	text := bytes.Repeat([]byte("The quick brown fox jumps over the lazy dog. "), testUnitSize/45+1)[:testUnitSize]
+	stored := pattern(testUnitSize, 3)
+	files.compressed = append(append(append([]byte(nil), text...), make([]byte, testUnitSize)...), stored[:testUnitSize-500]...)
+	compressed := ntfstest.CompressLZNT1(text)
+	compressedClusters := uint64((len(compressed) + ntfstest.ClusterSize - 1) / ntfstest.ClusterSize)
+	if compressedClusters >= 16 {
+		t.Fatalf("test data compressed to %d clusters", compressedClusters)
+	}
+	img.WriteClusters(100, compressed)
+	img.WriteClusters(120, stored)
+	img.Set(recordCompressed, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.FileNameAttr(testRoot, "compressed.txt", ntfs.NamespaceWin32DOS, 0),
+			ntfstest.NonResidentAttr(ntfs.AttrData, "", []ntfstest.Run{
+				{LCN: 100, Length: compressedClusters},
+				{LCN: -1, Length: 32 - compressedClusters},
+				{LCN: 120, Length: 16},
+			}, uint64(len(files.compressed)), 0, ntfs.AttrFlagCompressed, testCompressionUnit),
+		},
+	})
+
+	// A file whose initialized size is smaller than its real size, with
+	// stale data in the uninitialized region of its cluster
+	files.uninit = pattern(ntfstest.ClusterSize, 5)
+	img.WriteClusters(50, files.uninit)
+	clear(files.uninit[1000:])
+	img.Set(recordUninit, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.FileNameAttr(testRoot, "uninit.bin", ntfs.NamespaceWin32DOS, 0),
+			ntfstest.NonResidentAttr(ntfs.AttrData, "", []ntfstest.Run{{LCN: 50, Length: 1}}, ntfstest.ClusterSize, 1000, 0, 0),
+		},
+	})
+
+	files.resident = []byte("hello, world")
+	img.Set(recordResident, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.FileNameAttr(testRoot, "hello.txt", ntfs.NamespaceWin32DOS, 0),
+			ntfstest.ResidentAttr(ntfs.AttrData, "", files.resident),
+		},
+	})
+
+	return img, files
+}
+
+func TestStreamReader(t *testing.T) {
+	img, files := newStreamImage(t)
+	v, err := ntfs.Open(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		record uint64
+		want   []byte
+	}{
+		{"Plain", recordPlain, files.plain},
+		{"Compressed", recordCompressed, files.compressed},
+		{"Uninitialized", recordUninit, files.uninit},
+		{"Resident", recordResident, files.resident},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := v.Entry(tt.record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sr, err := v.OpenStream(e, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sr.Size() != int64(len(tt.want)) {
+				t.Errorf("Size = %d, want %d", sr.Size(), len(tt.want))
+			}
+
+			got, err := io.ReadAll(io.NewSectionReader(sr, 0, sr.Size()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("contents differ")
+			}
+
+			// Unaligned reads that cross cluster and unit boundaries
+			for _, off := range []int64{0, 1, ntfstest.ClusterSize - 3, testUnitSize - 10} {
+				if off >= sr.Size() {
+					continue
+				}
+				p := make([]byte, 64)
+				n, err := sr.ReadAt(p, off)
+				if err != nil && err != io.EOF {
+					t.Fatalf("ReadAt(%d): %v", off, err)
+				}
+				if !bytes.Equal(p[:n], tt.want[off:off+int64(n)]) {
+					t.Errorf("ReadAt(%d) returned the wrong data", off)
+				}
+			}
+
+			if _, err := sr.ReadAt(make([]byte, 1), sr.Size()); err != io.EOF {
+				t.Errorf("ReadAt(Size): err = %v, want %v", err, io.EOF)
+			}
+		})
+	}
+}
+
+func TestStreamExtents(t *testing.T) {
+	img, _ := newStreamImage(t)
+	v, err := ntfs.Open(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := v.Entry(recordPlain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := e.Stream("")
+	extents, err := s.Extents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(extents) != 3 || !extents[1].Sparse() || extents[2].LCN != 44 || extents[2].VCN != 3 {
+		t.Errorf("extents = %+v", extents)
+	}
+}
+
+func TestOpenStreamNotFound(t *testing.T) {
+	img, _ := newStreamImage(t)
+	v, err := ntfs.Open(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := v.Entry(recordResident)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.OpenStream(e, "$J"); !errors.Is(err, ntfs.ErrStreamNotFound) {
+		t.Errorf("err = %v, want %v", err, ntfs.ErrStreamNotFound)
+	}
+}
+
+func TestStreamReaderRunsOutOfRange(t *testing.T) {
+	img, _ := newStreamImage(t)
+	tests := []struct {
+		record uint64
+		run    ntfstest.Run
+	}{
+		{44, ntfstest.Run{LCN: 1, Length: 1 << 62}},
+		{45, ntfstest.Run{LCN: 250, Length: 10}},
+		{46, ntfstest.Run{LCN: -1, Length: 1 << 52}},
+	}
+	for _, tt := range tests {
+		img.Set(tt.record, ntfstest.Record{
+			Sequence: 1,
+			Flags:    ntfs.RecordInUse,
+			Attrs: [][]byte{
+				ntfstest.FileNameAttr(testRoot, "bad.bin", ntfs.NamespaceWin32DOS, 0),
+				ntfstest.NonResidentAttr(ntfs.AttrData, "", []ntfstest.Run{tt.run}, ntfstest.ClusterSize, ntfstest.ClusterSize, 0, 0),
+			},
+		})
+	}
+
+	v, err := ntfs.Open(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		e, err := v.Entry(tt.record)
+		if err != nil {
+			t.Fatalf("record %d: %v", tt.record, err)
+		}
+		if _, err := v.OpenStream(e, ""); !errors.Is(err, ntfs.ErrInvalidRuns) {
+			t.Errorf("record %d: err = %v, want %v", tt.record, err, ntfs.ErrInvalidRuns)
+		}
+	}
+}
+
+// storedChunk encodes data as an LZNT1 chunk that is stored uncompressed.
+func storedChunk(data []byte) []byte {
+	header := uint16(0x3000 | (len(data) - 1))
+	return append([]byte{byte(header), byte(header >> 8)}, data...)
+}
+
+func TestStreamReaderShortChunk(t *testing.T) {
+	img := ntfstest.NewImage(256, ntfstest.Run{LCN: 4, Length: 12})
+
+	// The first unit decompresses to text throughout. The second holds a
+	// short chunk between two full ones, whose gap must read as zeros
+	// rather than as the text left behind by the first unit.
+	text := bytes.Repeat([]byte("The quick brown fox jumps over the lazy dog. "), testUnitSize/45+1)[:testUnitSize]
+	first := ntfstest.CompressLZNT1(text)
+	full := pattern(4096, 9)
+	short := pattern(100, 11)
+	var second []byte
+	second = append(second, storedChunk(full)...)
+	second = append(second, storedChunk(short)...)
+	second = append(second, storedChunk(full)...)
+
+	want := append([]byte(nil), text...)
+	unit := make([]byte, testUnitSize)
+	copy(unit, full)
+	copy(unit[4096:], short)
+	copy(unit[8192:], full)
+	want = append(want, unit...)
+
+	firstClusters := uint64((len(first) + ntfstest.ClusterSize - 1) / ntfstest.ClusterSize)
+	secondClusters := uint64((len(second) + ntfstest.ClusterSize - 1) / ntfstest.ClusterSize)
+	img.WriteClusters(100, first)
+	img.WriteClusters(120, second)
+	img.Set(recordCompressed, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.FileNameAttr(testRoot, "short.txt", ntfs.NamespaceWin32DOS, 0),
+			ntfstest.NonResidentAttr(ntfs.AttrData, "", []ntfstest.Run{
+				{LCN: 100, Length: firstClusters},
+				{LCN: -1, Length: 16 - firstClusters},
+				{LCN: 120, Length: secondClusters},
+				{LCN: -1, Length: 16 - secondClusters},
+			}, uint64(len(want)), uint64(len(want)), ntfs.AttrFlagCompressed, testCompressionUnit),
+		},
+	})
+
+	v, err := ntfs.Open(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := v.Entry(recordCompressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr, err := v.OpenStream(e, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, len(want))
+	if _, err := sr.ReadAt(got, 0); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("byte %d = %#x, want %#x", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStreamReaderCompressionUnitTooLarge(t *testing.T) {
+	img, _ := newStreamImage(t)
+	img.Set(44, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.FileNameAttr(testRoot, "huge.bin", ntfs.NamespaceWin32DOS, 0),
+			ntfstest.NonResidentAttr(ntfs.AttrData, "", []ntfstest.Run{{LCN: 100, Length: 1}}, ntfstest.ClusterSize, ntfstest.ClusterSize, ntfs.AttrFlagCompressed, 40),
+		},
+	})
+
+	v, err := ntfs.Open(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := v.Entry(44)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.OpenStream(e, ""); !errors.Is(err, ntfs.ErrCompression) {
+		t.Errorf("err = %v, want %v", err, ntfs.ErrCompression)
+	}
+}
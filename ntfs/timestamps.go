@@ -0,0 +1,66 @@
+package ntfs
+
+import "time"
+
+// MACB is a set of NTFS file timestamps, named after the order in which
+// forensic tools conventionally list them.
+type MACB struct {
+	Modified time.Time // Last write to the file's data
+	Accessed time.Time // Last access to the file's data
+	Changed  time.Time // Last change to the file's MFT record
+	Born     time.Time // Creation of the file
+}
+
+// IsZero returns true if none of the timestamps are set.
+func (m MACB) IsZero() bool {
+	return m.Modified.IsZero() && m.Accessed.IsZero() && m.Changed.IsZero() && m.Born.IsZero()
+}
+
+// MACB returns the timestamps held by the $STANDARD_INFORMATION attribute.
+func (si StandardInformation) MACB() MACB {
+	return MACB{
+		Modified: si.Modified,
+		Accessed: si.Accessed,
+		Changed:  si.Changed,
+		Born:     si.Created,
+	}
+}
+
+// MACB returns the timestamps held by the $FILE_NAME attribute.
+func (fn FileName) MACB() MACB {
+	return MACB{
+		Modified: fn.Modified,
+		Accessed: fn.Accessed,
+		Changed:  fn.Changed,
+		Born:     fn.Created,
+	}
+}
+
+// Timestamps holds both sets of timestamps recorded for a file.
+//
+// The $STANDARD_INFORMATION timestamps are the ones reported by Windows and
+// can be changed by any program with write access to the file. The
+// $FILE_NAME timestamps are maintained by the file system itself and are
+// normally only updated when a file is created, renamed or moved, which
+// makes them harder to tamper with.
+type Timestamps struct {
+	StandardInformation MACB
+	FileName            MACB   // Timestamps of the preferred file name
+	FileNames           []MACB // Timestamps of each file name, in the order of MFTEntry.FileNames
+}
+
+// Timestamps returns the timestamps of the entry. Timestamps that the
+// entry does not hold are zero.
+func (e *MFTEntry) Timestamps() Timestamps {
+	var ts Timestamps
+	if e.StandardInformation != nil {
+		ts.StandardInformation = e.StandardInformation.MACB()
+	}
+	if fn, ok := e.Name(); ok {
+		ts.FileName = fn.MACB()
+	}
+	for _, fn := range e.FileNames {
+		ts.FileNames = append(ts.FileNames, fn.MACB())
+	}
+	return ts
+}
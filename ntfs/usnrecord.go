@@ -0,0 +1,37 @@
+package ntfs
+
+import (
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// Record returns the entry as a change journal record, in the same form as
+// the records produced by usn.MFT enumeration. This allows the records of
+// raw master file tables to be used with usn.Cache, usn.Filer and the
+// filters of the usnfilter package.
+//
+// The record's time stamp is the MFT record change time. The records of
+// deleted entries carry usn.ReasonFileDelete.
+func (e *MFTEntry) Record() usn.Record {
+	r := usn.Record{
+		FileReferenceNumber: e.ID(),
+	}
+	if fn, ok := e.Name(); ok {
+		r.ParentFileReferenceNumber = fn.Parent
+		r.FileName = fn.Name
+		r.FileAttributes = fn.Attributes
+	}
+	if si := e.StandardInformation; si != nil {
+		r.USN = usn.USN(si.USN)
+		r.TimeStamp = si.Changed
+		r.SecurityID = si.SecurityID
+		r.FileAttributes = si.Attributes
+	}
+	if e.Directory() {
+		r.FileAttributes |= fileattr.Directory
+	}
+	if e.Deleted() {
+		r.Reason = usn.ReasonFileDelete
+	}
+	return r
+}
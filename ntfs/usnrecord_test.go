@@ -0,0 +1,122 @@
+package ntfs_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+)
+
+func TestEntryRecord(t *testing.T) {
+	v, err := ntfs.Open(newEntryImage().Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := v.Entry(30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := e.Record()
+	if r.FileReferenceNumber != testUsers || r.ParentFileReferenceNumber != testRoot {
+		t.Errorf("FileReferenceNumber = %v, ParentFileReferenceNumber = %v", r.FileReferenceNumber, r.ParentFileReferenceNumber)
+	}
+	if r.FileName != "Users" || r.USN != 1000 || r.SecurityID != 0x100 {
+		t.Errorf("FileName = %q, USN = %d, SecurityID = %#x", r.FileName, r.USN, r.SecurityID)
+	}
+	if r.FileAttributes&fileattr.Directory == 0 {
+		t.Errorf("FileAttributes = %v, want directory", r.FileAttributes)
+	}
+	if !r.TimeStamp.Equal(testModified) {
+		t.Errorf("TimeStamp = %v, want %v", r.TimeStamp, testModified)
+	}
+
+	e, err = v.Entry(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := e.Record(); r.Reason != usn.ReasonFileDelete {
+		t.Errorf("deleted entry Reason = %v, want %v", r.Reason, usn.ReasonFileDelete)
+	}
+}
+
+// readCache reads the records produced by iter into a new cache.
+func readCache(t *testing.T, iter usn.Iter) *usn.Cache {
+	t.Helper()
+	cache := usn.NewCache()
+	if err := cache.ReadFrom(context.Background(), iter); err != nil {
+		t.Fatal(err)
+	}
+	return cache
+}
+
+func TestIter(t *testing.T) {
+	v, err := ntfs.Open(newEntryImage().Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := readCache(t, v.MFT().Iter(false))
+	paths := make(map[string]bool)
+	for _, r := range cache.Records() {
+		paths[r.Path] = true
+	}
+	for _, path := range []string{`$MFT`, `$Extend`, `Users`, `Users\report.docx`, `Users\big.bin`, `Users\huge.bin`} {
+		if !paths[path] {
+			t.Errorf("path %q not found", path)
+		}
+	}
+	if paths[`Users\old.txt`] {
+		t.Error("deleted file was included")
+	}
+
+	// Extension records are not returned on their own
+	if _, ok := cache.Get(fileref.NewSegment(34, 1)); ok {
+		t.Error("extension record 34 was included")
+	}
+
+	cache = readCache(t, v.MFT().Iter(true))
+	r, ok := cache.Get(fileref.NewSegment(32, 3))
+	if !ok {
+		t.Fatal("deleted file was not included")
+	}
+	if path := usn.Filer(cache.Filer).Path(r); path != `Users\old.txt` {
+		t.Errorf("deleted file path = %q, want %q", path, `Users\old.txt`)
+	}
+}
+
+func TestIterSmallBuffer(t *testing.T) {
+	data := newEntryImage().MFTBytes(t)
+	mft, err := ntfs.OpenMFT(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		iter    = mft.Iter(false)
+		records []usn.Record
+	)
+	for {
+		batch, err := iter.Next(make([]byte, 100), nil)
+		records = append(records, batch...)
+		if err != nil {
+			break
+		}
+	}
+
+	filter := usnfilter.PathContains("report")
+	found := 0
+	for _, r := range records {
+		if filter.Match(r) {
+			found++
+		}
+	}
+	if found != 1 {
+		t.Errorf("found %d records matching report, want 1", found)
+	}
+}
@@ -0,0 +1,342 @@
+package ntfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+)
+
+// ErrNotFound is returned when a file cannot be found.
+var ErrNotFound = errors.New("file not found")
+
+// Well-known MFT record numbers of NTFS system files.
+const (
+	RecordMFT       = 0
+	RecordMFTMirror = 1
+	RecordLogFile   = 2
+	RecordVolume    = 3
+	RecordAttrDef   = 4
+	RecordRoot      = 5
+	RecordBitmap    = 6
+	RecordBoot      = 7
+	RecordBadClus   = 8
+	RecordSecure    = 9
+	RecordUpCase    = 10
+	RecordExtend    = 11
+)
+
+// firstUserRecord is the first MFT record that is not reserved for system
+// files. Records 12 through 15 are reserved but unused, and records 16
+// through 23 are used for the contents of $Extend.
+const firstUserRecord = 16
+
+// Volume is an NTFS volume that is read directly from its on-disk
+// structures.
+type Volume struct {
+	Boot BootSector
+
+	r       io.ReaderAt
+	cluster int64
+	mft     *MFT
+}
+
+// Open opens the NTFS volume stored in r. The boot sector must be located
+// at offset zero.
+func Open(r io.ReaderAt) (*Volume, error) {
+	sector := make([]byte, BootSectorSize)
+	if _, err := r.ReadAt(sector, 0); err != nil {
+		return nil, fmt.Errorf("unable to read boot sector: %w", err)
+	}
+	boot, err := ParseBootSector(sector)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Volume{
+		Boot:    boot,
+		r:       r,
+		cluster: int64(boot.ClusterSize()),
+	}
+
+	// Bootstrap the MFT by reading its first record directly from the
+	// location given by the boot sector
+	record := make([]byte, boot.FileRecordSize)
+	if _, err := r.ReadAt(record, int64(boot.MFTCluster)*v.cluster); err != nil {
+		return nil, fmt.Errorf("unable to read $MFT record: %w", err)
+	}
+	if err := ApplyFixups(record, "FILE"); err != nil {
+		return nil, fmt.Errorf("$MFT record: %w", err)
+	}
+	header, err := parseRecordHeader(record)
+	if err != nil {
+		return nil, fmt.Errorf("$MFT record: %w", err)
+	}
+	attrs, err := parseAttributes(record[:header.BytesInUse], int(header.AttributeOffset))
+	if err != nil {
+		return nil, fmt.Errorf("$MFT record: %w", err)
+	}
+	var (
+		extents []Extent
+		size    uint64
+	)
+	for _, attr := range attrs {
+		if attr.Type != AttrData || attr.Name != "" || !attr.NonResident {
+			continue
+		}
+		runs, err := DecodeRuns(attr.Runs, attr.StartVCN)
+		if err == nil {
+			err = v.checkExtents(runs)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("$MFT data runs: %w", err)
+		}
+		extents = append(extents, runs...)
+		if attr.StartVCN == 0 {
+			size = attr.RealSize
+		}
+	}
+	if len(extents) == 0 {
+		return nil, errors.New("$MFT record has no data attribute")
+	}
+
+	v.mft = &MFT{
+		r:          v.extentReader(extents),
+		vol:        v,
+		recordSize: int(boot.FileRecordSize),
+		count:      size / uint64(boot.FileRecordSize),
+	}
+
+	// When the MFT is heavily fragmented the rest of its data runs are
+	// held in extension records named by its attribute list. Those records
+	// always lie within the extents held in the base record, so they can
+	// be read with the extents found so far.
+	for _, attr := range attrs {
+		if attr.Type == AttrAttributeList {
+			if err := v.resolveMFT(); err != nil {
+				return nil, fmt.Errorf("$MFT attribute list: %w", err)
+			}
+			break
+		}
+	}
+
+	return v, nil
+}
+
+// resolveMFT replaces the extents of the master file table with those of
+// its complete $DATA stream, including the fragments held in its extension
+// records.
+func (v *Volume) resolveMFT() error {
+	e, err := v.mft.Entry(RecordMFT)
+	if err != nil {
+		return err
+	}
+	s, ok := e.Stream("")
+	if !ok || !s.NonResident {
+		return errors.New("$MFT record has no data attribute")
+	}
+	extents, err := s.Extents()
+	if err != nil {
+		return err
+	}
+	if err := v.checkExtents(extents); err != nil {
+		return err
+	}
+	v.mft.r = v.extentReader(extents)
+	v.mft.count = s.Size / uint64(v.mft.recordSize)
+	return nil
+}
+
+// MFT returns the master file table of the volume.
+func (v *Volume) MFT() *MFT {
+	return v.mft
+}
+
+// RecordSize returns the size of a FILE record in bytes.
+func (v *Volume) RecordSize() int {
+	return v.mft.RecordSize()
+}
+
+// RecordCount returns the number of records in the master file table,
+// including records that are not in use.
+func (v *Volume) RecordCount() uint64 {
+	return v.mft.RecordCount()
+}
+
+// ReadRecord reads the FILE record with the given record number from the
+// master file table. Its update sequence fixups are applied before it is
+// returned.
+func (v *Volume) ReadRecord(n uint64) ([]byte, error) {
+	return v.mft.ReadRecord(n)
+}
+
+// Entry reads and decodes the master file table entry with the given
+// record number.
+func (v *Volume) Entry(n uint64) (MFTEntry, error) {
+	return v.mft.Entry(n)
+}
+
+// extentReader returns a reader for the clusters mapped by extents.
+func (v *Volume) extentReader(extents []Extent) io.ReaderAt {
+	return readerFunc(func(p []byte, off int64) (int, error) {
+		return readExtents(v.r, extents, v.cluster, p, off)
+	})
+}
+
+// readAttribute reads the value of a non-resident attribute that is held
+// in a single attribute record. It returns ErrTruncated if the value is
+// larger than limit.
+func (v *Volume) readAttribute(attr Attribute, limit uint64) ([]byte, error) {
+	if attr.RealSize > limit {
+		return nil, ErrTruncated
+	}
+	extents, err := DecodeRuns(attr.Runs, attr.StartVCN)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.checkExtents(extents); err != nil {
+		return nil, err
+	}
+	data := make([]byte, attr.RealSize)
+	if _, err := readExtents(v.r, extents, v.cluster, data, 0); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// checkExtents returns ErrInvalidRuns if any of the extents maps clusters
+// beyond the end of the volume, or maps virtual clusters whose byte offsets
+// do not fit in an int64.
+func (v *Volume) checkExtents(extents []Extent) error {
+	var (
+		total  = v.Boot.TotalClusters()
+		maxVCN = uint64(math.MaxInt64 / v.cluster)
+	)
+	for _, e := range extents {
+		if e.Length > maxVCN || e.VCN > maxVCN-e.Length {
+			return fmt.Errorf("%w: virtual cluster %d is out of range", ErrInvalidRuns, e.VCN+e.Length)
+		}
+		if !e.Sparse() && (e.Length > total || uint64(e.LCN) > total-e.Length) {
+			return fmt.Errorf("%w: cluster %d is beyond the end of the volume", ErrInvalidRuns, uint64(e.LCN)+e.Length-1)
+		}
+	}
+	return nil
+}
+
+// readerFunc is a function that implements io.ReaderAt.
+type readerFunc func(p []byte, off int64) (int, error)
+
+// ReadAt calls f(p, off).
+func (f readerFunc) ReadAt(p []byte, off int64) (int, error) {
+	return f(p, off)
+}
+
+// SystemFiles holds the file reference numbers of NTFS system files.
+type SystemFiles struct {
+	MFT     fileref.ID
+	LogFile fileref.ID
+	Secure  fileref.ID
+	UpCase  fileref.ID
+	UsnJrnl fileref.ID // Zero if the volume has no change journal
+}
+
+// SystemFiles locates the system files of the volume.
+func (v *Volume) SystemFiles() (files SystemFiles, err error) {
+	for _, sys := range []struct {
+		number uint64
+		name   string
+		id     *fileref.ID
+	}{
+		{RecordMFT, "$MFT", &files.MFT},
+		{RecordLogFile, "$LogFile", &files.LogFile},
+		{RecordSecure, "$Secure", &files.Secure},
+		{RecordUpCase, "$UpCase", &files.UpCase},
+	} {
+		if *sys.id, err = v.systemFile(sys.number, sys.name); err != nil {
+			return SystemFiles{}, err
+		}
+	}
+
+	files.UsnJrnl, err = v.FindUsnJournal()
+	if errors.Is(err, ErrNotFound) {
+		err = nil
+	}
+	return files, err
+}
+
+// systemFile verifies that the given record holds the named system file
+// and returns its file reference number.
+func (v *Volume) systemFile(n uint64, name string) (fileref.ID, error) {
+	e, err := v.Entry(n)
+	if err != nil {
+		return fileref.ID{}, fmt.Errorf("%s: %w", name, err)
+	}
+	if e.InUse() {
+		for _, fn := range e.FileNames {
+			if fn.Name == name {
+				return e.ID(), nil
+			}
+		}
+	}
+	return fileref.ID{}, fmt.Errorf("%s: %w", name, ErrNotFound)
+}
+
+// FindUsnJournal locates the $UsnJrnl file, which lives in the $Extend
+// directory but does not have a fixed record number. It returns ErrNotFound
+// if the volume has no change journal.
+//
+// The file is looked up in the directory index of $Extend. If the index
+// cannot be read the master file table is searched instead.
+func (v *Volume) FindUsnJournal() (fileref.ID, error) {
+	id, err := v.lookupUsnJournal()
+	if err == nil || errors.Is(err, ErrNotFound) {
+		return id, err
+	}
+	return v.scanUsnJournal()
+}
+
+// lookupUsnJournal looks up $UsnJrnl in the directory index of $Extend.
+func (v *Volume) lookupUsnJournal() (fileref.ID, error) {
+	extend, err := v.Entry(RecordExtend)
+	if err != nil {
+		return fileref.ID{}, err
+	}
+	dir, err := v.Directory(extend, false)
+	if err != nil {
+		return fileref.ID{}, err
+	}
+	for _, d := range dir {
+		if !strings.EqualFold(d.FileName.Name, "$UsnJrnl") {
+			continue
+		}
+		e, err := v.Entry(d.File.Segment())
+		if err != nil {
+			return fileref.ID{}, err
+		}
+		if !e.InUse() || e.ID() != d.File {
+			return fileref.ID{}, fmt.Errorf("$UsnJrnl: index entry refers to %s, which is not in use", d.File)
+		}
+		return d.File, nil
+	}
+	return fileref.ID{}, fmt.Errorf("$UsnJrnl: %w", ErrNotFound)
+}
+
+// scanUsnJournal searches the master file table for $UsnJrnl.
+func (v *Volume) scanUsnJournal() (fileref.ID, error) {
+	count := v.RecordCount()
+	for n := uint64(firstUserRecord); n < count; n++ {
+		e, err := v.Entry(n)
+		if err != nil || !e.InUse() || e.Header.BaseRecord != 0 {
+			continue
+		}
+		for _, fn := range e.FileNames {
+			if fn.Parent.Segment() == RecordExtend && strings.EqualFold(fn.Name, "$UsnJrnl") {
+				return e.ID(), nil
+			}
+		}
+	}
+	return fileref.ID{}, fmt.Errorf("$UsnJrnl: %w", ErrNotFound)
+}
@@ -0,0 +1,262 @@
+package ntfs_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/internal/ntfstest"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+)
+
+// newJournalImage returns an image with a fragmented MFT whose second
+// fragment precedes the first, and a change journal in record 40.
+func newJournalImage() *ntfstest.Image {
+	img := ntfstest.NewImage(64, ntfstest.Run{LCN: 20, Length: 2}, ntfstest.Run{LCN: 4, Length: 12})
+	extend := fileref.NewSegment(ntfs.RecordExtend, ntfs.RecordExtend)
+	img.Set(40, ntfstest.Record{
+		Sequence: 3,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.FileNameAttr(extend, "$UsnJrnl", ntfs.NamespaceWin32DOS, fileattr.Hidden|fileattr.System),
+		},
+	})
+	return img
+}
+
+func TestOpen(t *testing.T) {
+	v, err := ntfs.Open(newJournalImage().Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.RecordSize(); got != ntfstest.RecordSize {
+		t.Errorf("RecordSize = %d, want %d", got, ntfstest.RecordSize)
+	}
+	if got, want := v.RecordCount(), uint64(14*ntfstest.ClusterSize/ntfstest.RecordSize); got != want {
+		t.Errorf("RecordCount = %d, want %d", got, want)
+	}
+}
+
+func TestOpenMFTAttributeList(t *testing.T) {
+	// The second fragment of the MFT is described by an extension record
+	// that lies within the first fragment
+	first := []ntfstest.Run{{LCN: 4, Length: 4}}
+	second := []ntfstest.Run{{LCN: 20, Length: 8}}
+	img := ntfstest.NewImage(64, append(first, second...)...)
+	root := fileref.NewSegment(ntfs.RecordRoot, ntfs.RecordRoot)
+	base := fileref.NewSegment(ntfs.RecordMFT, 1)
+	size := uint64(12 * ntfstest.ClusterSize)
+	img.Set(ntfs.RecordMFT, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.ResidentAttr(ntfs.AttrAttributeList, "", ntfstest.AttrListValue(
+				ntfstest.ListEntry{Type: ntfs.AttrFileName, Record: base},
+				ntfstest.ListEntry{Type: ntfs.AttrData, Record: base},
+				ntfstest.ListEntry{Type: ntfs.AttrData, StartVCN: 4, Record: fileref.NewSegment(15, 1)},
+			)),
+			ntfstest.FileNameAttr(root, "$MFT", ntfs.NamespaceWin32DOS, fileattr.Hidden|fileattr.System),
+			ntfstest.NonResidentAttr(ntfs.AttrData, "", first, size, size, 0, 0),
+		},
+	})
+	img.Set(15, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Base:     base,
+		Attrs: [][]byte{
+			ntfstest.NonResidentAttrAt(ntfs.AttrData, "", 4, second, 0, 0, 0, 0),
+		},
+	})
+	img.Set(40, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.FileNameAttr(root, "beyond.txt", ntfs.NamespaceWin32DOS, 0),
+		},
+	})
+
+	v, err := ntfs.Open(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := v.RecordCount(), size/ntfstest.RecordSize; got != want {
+		t.Errorf("RecordCount = %d, want %d", got, want)
+	}
+	e, err := v.Entry(40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := e.Name(); name.Name != "beyond.txt" {
+		t.Errorf("record 40 has name %q, want %q", name.Name, "beyond.txt")
+	}
+}
+
+func TestOpenNotNTFS(t *testing.T) {
+	if _, err := ntfs.Open(bytes.NewReader(make([]byte, 4096))); !errors.Is(err, ntfs.ErrNotNTFS) {
+		t.Errorf("err = %v, want %v", err, ntfs.ErrNotNTFS)
+	}
+}
+
+func TestReadRecord(t *testing.T) {
+	v, err := ntfs.Open(newJournalImage().Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Record 40 lies in the second fragment of the MFT
+	record, err := v.ReadRecord(40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(record[:4]) != "FILE" {
+		t.Fatalf("record 40 has signature %q", record[:4])
+	}
+	if record[ntfstest.SectorSize-2] == 0x42 && record[ntfstest.SectorSize-1] == 0x00 {
+		t.Errorf("record 40 fixups were not applied")
+	}
+
+	if _, err := v.ReadRecord(v.RecordCount()); !errors.Is(err, ntfs.ErrNotFound) {
+		t.Errorf("ReadRecord past end: err = %v, want %v", err, ntfs.ErrNotFound)
+	}
+}
+
+func TestReadRecordTornWrite(t *testing.T) {
+	img := newJournalImage()
+	data := img.Bytes(t)
+
+	// Damage the end of the first sector of record 40, which is the
+	// eighth record in the fragment at cluster 4
+	offset := 4*ntfstest.ClusterSize + (40-8)*ntfstest.RecordSize + ntfstest.SectorSize - 1
+	data[offset] ^= 0xFF
+
+	v, err := ntfs.Open(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.ReadRecord(40); !errors.Is(err, ntfs.ErrFixup) {
+		t.Errorf("err = %v, want %v", err, ntfs.ErrFixup)
+	}
+	if _, err := v.ReadRecord(20); !errors.Is(err, ntfs.ErrBadSignature) {
+		t.Errorf("unused record: err = %v, want %v", err, ntfs.ErrBadSignature)
+	}
+}
+
+func TestSystemFiles(t *testing.T) {
+	v, err := ntfs.Open(newJournalImage().Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := v.SystemFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ntfs.SystemFiles{
+		MFT:     fileref.NewSegment(ntfs.RecordMFT, 1),
+		LogFile: fileref.NewSegment(ntfs.RecordLogFile, ntfs.RecordLogFile),
+		Secure:  fileref.NewSegment(ntfs.RecordSecure, ntfs.RecordSecure),
+		UpCase:  fileref.NewSegment(ntfs.RecordUpCase, ntfs.RecordUpCase),
+		UsnJrnl: fileref.NewSegment(40, 3),
+	}
+	if files != want {
+		t.Errorf("SystemFiles = %+v, want %+v", files, want)
+	}
+}
+
+func TestSystemFilesWithoutJournal(t *testing.T) {
+	img := ntfstest.NewImage(64, ntfstest.Run{LCN: 4, Length: 4})
+	v, err := ntfs.Open(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := v.SystemFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !files.UsnJrnl.IsZero() {
+		t.Errorf("UsnJrnl = %v, want zero", files.UsnJrnl)
+	}
+	if _, err := v.FindUsnJournal(); !errors.Is(err, ntfs.ErrNotFound) {
+		t.Errorf("FindUsnJournal: err = %v, want %v", err, ntfs.ErrNotFound)
+	}
+}
+
+func TestFindUsnJournalIndex(t *testing.T) {
+	extend := fileref.NewSegment(ntfs.RecordExtend, ntfs.RecordExtend)
+	journal := fileref.NewSegment(41, 1)
+	tests := []struct {
+		name    string
+		entries [][]byte
+		want    fileref.ID
+		err     error
+	}{
+		{"Listed", [][]byte{fileNameEntry(fileref.NewSegment(42, 1), extend, "$ObjId"), fileNameEntry(journal, extend, "$UsnJrnl")}, journal, nil},
+		{"NotListed", [][]byte{fileNameEntry(fileref.NewSegment(42, 1), extend, "$ObjId")}, fileref.ID{}, ntfs.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Record 40 also claims to be the journal, but only the file
+			// named by the index of $Extend should be found
+			img := newJournalImage()
+			img.Set(journal.Segment(), ntfstest.Record{
+				Sequence: 1,
+				Flags:    ntfs.RecordInUse,
+				Attrs: [][]byte{
+					ntfstest.FileNameAttr(extend, "$UsnJrnl", ntfs.NamespaceWin32DOS, fileattr.Hidden|fileattr.System),
+				},
+			})
+			entries := append(tt.entries, ntfstest.IndexEntry(fileref.ID{}, nil, ntfs.IndexEntryLast, 0))
+			img.Set(ntfs.RecordExtend, ntfstest.Record{
+				Sequence: ntfs.RecordExtend,
+				Flags:    ntfs.RecordInUse | ntfs.RecordDirectory,
+				Attrs: [][]byte{
+					ntfstest.FileNameAttr(testRoot, "$Extend", ntfs.NamespaceWin32DOS, fileattr.Hidden|fileattr.System),
+					ntfstest.ResidentAttr(ntfs.AttrIndexRoot, "$I30", ntfstest.IndexRootValue(ntfstest.ClusterSize, false, entries...)),
+				},
+			})
+			v, err := ntfs.Open(img.Reader(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := v.FindUsnJournal()
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Errorf("FindUsnJournal = %v, %v, want %v, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestSystemFilesMissing(t *testing.T) {
+	img := ntfstest.NewImage(64, ntfstest.Run{LCN: 4, Length: 4})
+	img.Set(ntfs.RecordUpCase, ntfstest.Record{Sequence: 10, Flags: 0})
+	v, err := ntfs.Open(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.SystemFiles(); !errors.Is(err, ntfs.ErrNotFound) {
+		t.Errorf("err = %v, want %v", err, ntfs.ErrNotFound)
+	}
+}
+
+func TestLabel(t *testing.T) {
+	img := ntfstest.NewImage(64, ntfstest.Run{LCN: 4, Length: 4})
+	img.Set(ntfs.RecordVolume, ntfstest.Record{
+		Sequence: ntfs.RecordVolume,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.ResidentAttr(ntfs.AttrVolumeName, "", ntfstest.UTF16("Data")),
+		},
+	})
+	v, err := ntfs.Open(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	label, err := v.Label()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if label != "Data" {
+		t.Errorf("Label = %q, want %q", label, "Data")
+	}
+}
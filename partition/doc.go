@@ -0,0 +1,9 @@
+// Package partition reads MBR and GPT partition tables from disk images.
+//
+// Tables are read from an io.ReaderAt, such as a raw disk image or a block
+// device. Each partition in a table is itself an io.ReaderAt that is bounded
+// to the partition's extent, so it can be handed directly to a file system
+// reader such as the ntfs package.
+//
+// https://uefi.org/specs/UEFI/2.10/05_GUID_Partition_Table_Format.html
+package partition
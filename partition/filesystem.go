@@ -0,0 +1,121 @@
+package partition
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Filesystem identifies the file system held by a partition.
+type Filesystem int
+
+// Recognized file systems.
+const (
+	Unknown Filesystem = iota
+	NTFS
+	ReFS
+	FAT12
+	FAT16
+	FAT32
+	ExFAT
+	BitLocker
+	StorageSpaces
+)
+
+// String returns a string representation of the file system.
+func (fs Filesystem) String() string {
+	switch fs {
+	case NTFS:
+		return "NTFS"
+	case ReFS:
+		return "ReFS"
+	case FAT12:
+		return "FAT12"
+	case FAT16:
+		return "FAT16"
+	case FAT32:
+		return "FAT32"
+	case ExFAT:
+		return "exFAT"
+	case BitLocker:
+		return "BitLocker"
+	case StorageSpaces:
+		return "Storage Spaces"
+	default:
+		return "Unknown"
+	}
+}
+
+// Detect identifies the file system held by r by examining its boot
+// sector. Unknown is returned if the file system is not recognized.
+func Detect(r io.ReaderAt) (Filesystem, error) {
+	var sector [512]byte
+	if _, err := r.ReadAt(sector[:], 0); err != nil {
+		if err == io.EOF {
+			return Unknown, nil
+		}
+		return Unknown, err
+	}
+	return detect(sector[:]), nil
+}
+
+// detect identifies the file system described by a boot sector.
+func detect(sector []byte) Filesystem {
+	oem := sector[3:11]
+	switch {
+	case bytes.Equal(oem, []byte("NTFS    ")):
+		return NTFS
+	case bytes.Equal(oem, []byte("ReFS\x00\x00\x00\x00")) && bytes.Equal(sector[0x10:0x14], []byte("FSRS")):
+		return ReFS
+	case bytes.Equal(oem, []byte("EXFAT   ")):
+		return ExFAT
+	case bytes.Equal(oem, []byte("-FVE-FS-")):
+		return BitLocker
+	}
+
+	if sector[510] != 0x55 || sector[511] != 0xAA {
+		return Unknown
+	}
+	return fatType(sector)
+}
+
+// fatType identifies a FAT file system from the number of clusters described
+// by its BIOS parameter block. The file system type label is informational
+// and is not consulted. Unknown is returned if the parameter block is not
+// valid.
+func fatType(sector []byte) Filesystem {
+	bytesPerSector := uint64(binary.LittleEndian.Uint16(sector[0x0B:]))
+	sectorsPerCluster := uint64(sector[0x0D])
+	reserved := uint64(binary.LittleEndian.Uint16(sector[0x0E:]))
+	fats := uint64(sector[0x10])
+	rootEntries := uint64(binary.LittleEndian.Uint16(sector[0x11:]))
+	if bytesPerSector < 512 || bytesPerSector > 4096 || bytesPerSector&(bytesPerSector-1) != 0 {
+		return Unknown
+	}
+	if sectorsPerCluster == 0 || sectorsPerCluster&(sectorsPerCluster-1) != 0 || reserved == 0 || fats == 0 {
+		return Unknown
+	}
+
+	fatSize := uint64(binary.LittleEndian.Uint16(sector[0x16:]))
+	if fatSize == 0 {
+		fatSize = uint64(binary.LittleEndian.Uint32(sector[0x24:]))
+	}
+	total := uint64(binary.LittleEndian.Uint16(sector[0x13:]))
+	if total == 0 {
+		total = uint64(binary.LittleEndian.Uint32(sector[0x20:]))
+	}
+	rootSectors := (rootEntries*32 + bytesPerSector - 1) / bytesPerSector
+	overhead := reserved + fats*fatSize + rootSectors
+	if fatSize == 0 || total <= overhead {
+		return Unknown
+	}
+
+	switch clusters := (total - overhead) / sectorsPerCluster; {
+	case clusters < 4085:
+		return FAT12
+	case clusters < 65525:
+		return FAT16
+	default:
+		return FAT32
+	}
+}
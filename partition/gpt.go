@@ -0,0 +1,197 @@
+package partition
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/utf16le"
+)
+
+const (
+	gptSignature      = "EFI PART"
+	gptHeaderMinSize  = 92
+	gptEntryMinSize   = 128
+	gptEntriesMaxSize = 4 << 20 // Largest partition entry array that we're willing to allocate
+)
+
+// GPT partition attributes.
+const (
+	AttrRequired      = 1 << 0  // Required for the platform to function
+	AttrNoBlockIO     = 1 << 1  // Firmware must not produce a block I/O protocol
+	AttrLegacyBIOS    = 1 << 2  // Bootable by legacy BIOS
+	AttrReadOnly      = 1 << 60 // Basic data partition is read-only
+	AttrShadowCopy    = 1 << 61 // Basic data partition is a shadow copy
+	AttrHidden        = 1 << 62 // Basic data partition is hidden
+	AttrNoDriveLetter = 1 << 63 // Basic data partition is not assigned a drive letter
+)
+
+// GPTHeader is a GUID partition table header.
+type GPTHeader struct {
+	Revision       uint32
+	HeaderSize     uint32
+	HeaderCRC      uint32
+	CurrentLBA     uint64
+	BackupLBA      uint64
+	FirstUsableLBA uint64
+	LastUsableLBA  uint64
+	DiskGUID       GUID
+	EntriesLBA     uint64
+	EntryCount     uint32
+	EntrySize      uint32
+	EntriesCRC     uint32
+}
+
+// ParseGPTHeader parses and validates the GPT header held in data, which
+// must be a complete sector.
+func ParseGPTHeader(data []byte) (GPTHeader, error) {
+	if len(data) < gptHeaderMinSize || string(data[0:8]) != gptSignature {
+		return GPTHeader{}, ErrInvalidHeader
+	}
+	h := GPTHeader{
+		Revision:       binary.LittleEndian.Uint32(data[0x08:]),
+		HeaderSize:     binary.LittleEndian.Uint32(data[0x0C:]),
+		HeaderCRC:      binary.LittleEndian.Uint32(data[0x10:]),
+		CurrentLBA:     binary.LittleEndian.Uint64(data[0x18:]),
+		BackupLBA:      binary.LittleEndian.Uint64(data[0x20:]),
+		FirstUsableLBA: binary.LittleEndian.Uint64(data[0x28:]),
+		LastUsableLBA:  binary.LittleEndian.Uint64(data[0x30:]),
+		EntriesLBA:     binary.LittleEndian.Uint64(data[0x48:]),
+		EntryCount:     binary.LittleEndian.Uint32(data[0x50:]),
+		EntrySize:      binary.LittleEndian.Uint32(data[0x54:]),
+		EntriesCRC:     binary.LittleEndian.Uint32(data[0x58:]),
+	}
+	copy(h.DiskGUID[:], data[0x38:0x48])
+
+	if h.HeaderSize < gptHeaderMinSize || int(h.HeaderSize) > len(data) {
+		return GPTHeader{}, ErrInvalidHeader
+	}
+	if h.EntrySize < gptEntryMinSize || h.EntrySize%8 != 0 {
+		return GPTHeader{}, ErrInvalidHeader
+	}
+	if uint64(h.EntryCount)*uint64(h.EntrySize) > gptEntriesMaxSize {
+		return GPTHeader{}, ErrInvalidHeader
+	}
+
+	header := make([]byte, h.HeaderSize)
+	copy(header, data)
+	binary.LittleEndian.PutUint32(header[0x10:], 0)
+	if crc32.ChecksumIEEE(header) != h.HeaderCRC {
+		return GPTHeader{}, ErrHeaderCRC
+	}
+
+	return h, nil
+}
+
+// readGPT reads the GUID partition table of the disk held by r.
+func readGPT(r io.ReaderAt, size int64) (*Table, error) {
+	var firstErr error
+	for _, sectorSize := range []int64{512, 4096} {
+		t := &Table{Scheme: GPT, SectorSize: sectorSize}
+		err := t.readGPT(r, size)
+		if err == nil {
+			return t, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// readGPT reads the primary and backup headers and the partitions of a
+// GUID partition table that uses t.SectorSize.
+func (t *Table) readGPT(r io.ReaderAt, size int64) error {
+	entries, err := t.readPrimary(r)
+
+	backupLBA := uint64(size/t.SectorSize) - 1
+	if t.Primary != nil {
+		backupLBA = t.Primary.BackupLBA
+	} else if size < 2*t.SectorSize {
+		return err
+	}
+	if backup, berr := t.readHeader(r, backupLBA); berr == nil {
+		t.Backup = &backup
+	}
+
+	if err == nil {
+		return t.parseEntries(r, entries, *t.Primary)
+	}
+	if t.Backup == nil {
+		return err
+	}
+	if entries, err = t.readEntries(r, *t.Backup); err != nil {
+		return err
+	}
+	return t.parseEntries(r, entries, *t.Backup)
+}
+
+// readPrimary reads the primary header and its partition entry array.
+func (t *Table) readPrimary(r io.ReaderAt) ([]byte, error) {
+	h, err := t.readHeader(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	t.Primary = &h
+	return t.readEntries(r, h)
+}
+
+func (t *Table) readHeader(r io.ReaderAt, lba uint64) (GPTHeader, error) {
+	sector := make([]byte, t.SectorSize)
+	if _, err := r.ReadAt(sector, int64(lba)*t.SectorSize); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return GPTHeader{}, ErrInvalidHeader
+		}
+		return GPTHeader{}, err
+	}
+	h, err := ParseGPTHeader(sector)
+	if err != nil {
+		return GPTHeader{}, err
+	}
+	if h.CurrentLBA != lba {
+		return GPTHeader{}, ErrInvalidHeader
+	}
+	return h, nil
+}
+
+func (t *Table) readEntries(r io.ReaderAt, h GPTHeader) ([]byte, error) {
+	entries := make([]byte, int(h.EntryCount)*int(h.EntrySize))
+	if _, err := r.ReadAt(entries, int64(h.EntriesLBA)*t.SectorSize); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrInvalidHeader
+		}
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(entries) != h.EntriesCRC {
+		return nil, ErrEntriesCRC
+	}
+	return entries, nil
+}
+
+func (t *Table) parseEntries(r io.ReaderAt, entries []byte, h GPTHeader) error {
+	for i := 0; i < int(h.EntryCount); i++ {
+		b := entries[i*int(h.EntrySize):]
+		var typ GUID
+		copy(typ[:], b[0:16])
+		if typ.IsZero() {
+			continue
+		}
+		first := binary.LittleEndian.Uint64(b[0x20:])
+		last := binary.LittleEndian.Uint64(b[0x28:])
+		if last < first {
+			return ErrInvalidHeader
+		}
+		p := Partition{
+			Number:     i + 1,
+			Start:      int64(first) * t.SectorSize,
+			Size:       int64(last-first+1) * t.SectorSize,
+			TypeGUID:   typ,
+			Attributes: binary.LittleEndian.Uint64(b[0x30:]),
+			Name:       utf16le.DecodeTerminated(b[0x38:0x80]),
+			r:          r,
+		}
+		copy(p.GUID[:], b[0x10:0x20])
+		t.Partitions = append(t.Partitions, p)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package partition
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidGUID is returned when a GUID cannot be parsed.
+var ErrInvalidGUID = errors.New("invalid GUID")
+
+// Partition type GUIDs.
+var (
+	TypeUnused            = GUID{}
+	TypeEFISystem         = MustParseGUID("C12A7328-F81F-11D2-BA4B-00A0C93EC93B")
+	TypeMicrosoftReserved = MustParseGUID("E3C9E316-0B5C-4DB8-817D-F92DF00215AE")
+	TypeBasicData         = MustParseGUID("EBD0A0A2-B9E5-4433-87C0-68B6B72699C7")
+	TypeLDMMetadata       = MustParseGUID("5808C8AA-7E8F-42E0-85D2-E1E90434CFB3")
+	TypeLDMData           = MustParseGUID("AF9B60A0-1431-4F62-BC68-3311714A69AD")
+	TypeWindowsRecovery   = MustParseGUID("DE94BBA4-06D1-4D40-A16A-BFD50179D6AC")
+	TypeStorageSpaces     = MustParseGUID("E75CAF8F-F680-4CEE-AFA3-B001E56EFC2D")
+	TypeLinuxFilesystem   = MustParseGUID("0FC63DAF-8483-4772-8E79-3D69D8477DE4")
+	TypeLinuxSwap         = MustParseGUID("0657FD6D-A4AB-43C4-84E5-0933C84B4F4F")
+	TypeLinuxLVM          = MustParseGUID("E6D6D379-F507-44C2-A23C-238F2A3DF928")
+	TypeAppleHFS          = MustParseGUID("48465300-0000-11AA-AA11-00306543ECAC")
+	TypeAppleAPFS         = MustParseGUID("7C3457EF-0000-11AA-AA11-00306543ECAC")
+	TypeBIOSBoot          = MustParseGUID("21686148-6449-6E6F-744E-656564454649")
+)
+
+var typeNames = map[GUID]string{
+	TypeEFISystem:         "EFI system",
+	TypeMicrosoftReserved: "Microsoft reserved",
+	TypeBasicData:         "Basic data",
+	TypeLDMMetadata:       "LDM metadata",
+	TypeLDMData:           "LDM data",
+	TypeWindowsRecovery:   "Windows recovery",
+	TypeStorageSpaces:     "Storage Spaces",
+	TypeLinuxFilesystem:   "Linux filesystem",
+	TypeLinuxSwap:         "Linux swap",
+	TypeLinuxLVM:          "Linux LVM",
+	TypeAppleHFS:          "Apple HFS+",
+	TypeAppleAPFS:         "Apple APFS",
+	TypeBIOSBoot:          "BIOS boot",
+}
+
+// GUID is a globally unique identifier as it is stored on disk, with its
+// first three fields in little-endian byte order.
+type GUID [16]byte
+
+// ParseGUID parses a GUID in the form XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX.
+// Surrounding braces are optional.
+func ParseGUID(s string) (GUID, error) {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return GUID{}, ErrInvalidGUID
+	}
+	b, err := hex.DecodeString(s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:36])
+	if err != nil {
+		return GUID{}, ErrInvalidGUID
+	}
+	var g GUID
+	binary.LittleEndian.PutUint32(g[0:], binary.BigEndian.Uint32(b[0:]))
+	binary.LittleEndian.PutUint16(g[4:], binary.BigEndian.Uint16(b[4:]))
+	binary.LittleEndian.PutUint16(g[6:], binary.BigEndian.Uint16(b[6:]))
+	copy(g[8:], b[8:])
+	return g, nil
+}
+
+// MustParseGUID parses s as a GUID and panics if it is invalid. It is
+// intended for the initialization of package variables.
+func MustParseGUID(s string) GUID {
+	g, err := ParseGUID(s)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// IsZero returns true if the GUID is zero.
+func (g GUID) IsZero() bool {
+	return g == GUID{}
+}
+
+// String returns a string representation of the GUID. It will adhere to
+// this pattern:
+//
+//	{XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX}
+func (g GUID) String() string {
+	s := strings.ToUpper(hex.EncodeToString([]byte{
+		g[3], g[2], g[1], g[0], g[5], g[4], g[7], g[6],
+		g[8], g[9], g[10], g[11], g[12], g[13], g[14], g[15],
+	}))
+	return "{" + s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32] + "}"
+}
+
+// TypeName returns a description of a partition type GUID. An empty string
+// is returned for types that are not known.
+func TypeName(g GUID) string {
+	return typeNames[g]
+}
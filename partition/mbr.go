@@ -0,0 +1,161 @@
+package partition
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+const (
+	mbrSectorSize = 512
+	maxLogical    = 128 // Maximum number of logical partitions followed in an extended partition
+)
+
+// MBR partition types.
+const (
+	MBREmpty       = 0x00
+	MBRFAT12       = 0x01
+	MBRFAT16Small  = 0x04
+	MBRExtended    = 0x05
+	MBRFAT16       = 0x06
+	MBRIFS         = 0x07 // NTFS, exFAT and ReFS
+	MBRFAT32       = 0x0B
+	MBRFAT32LBA    = 0x0C
+	MBRFAT16LBA    = 0x0E
+	MBRExtendedLBA = 0x0F
+	MBRRecovery    = 0x27
+	MBRDynamic     = 0x42 // Windows dynamic disk (LDM)
+	MBRLinuxSwap   = 0x82
+	MBRLinux       = 0x83
+	MBRLinuxExt    = 0x85
+	MBRLinuxLVM    = 0x8E
+	MBRProtective  = 0xEE
+	MBREFI         = 0xEF
+)
+
+type mbrEntry struct {
+	status  byte
+	typ     byte
+	lba     uint32
+	sectors uint32
+}
+
+type mbr struct {
+	signature uint32
+	entries   [4]mbrEntry
+}
+
+// parseMBR parses a master boot record. Boot sectors that hold a file
+// system rather than a partition table are rejected with ErrNoTable.
+func parseMBR(sector []byte) (m mbr, err error) {
+	if sector[510] != 0x55 || sector[511] != 0xAA || detect(sector) != Unknown {
+		return mbr{}, ErrNoTable
+	}
+	m.signature = binary.LittleEndian.Uint32(sector[0x1B8:])
+	for i := range m.entries {
+		b := sector[0x1BE+i*16:]
+		e := mbrEntry{
+			status:  b[0],
+			typ:     b[4],
+			lba:     binary.LittleEndian.Uint32(b[8:]),
+			sectors: binary.LittleEndian.Uint32(b[12:]),
+		}
+		if e.status != 0x00 && e.status != 0x80 {
+			return mbr{}, ErrNoTable
+		}
+		m.entries[i] = e
+	}
+	return m, nil
+}
+
+// protective returns true if m is a protective MBR for a GPT disk.
+func (m mbr) protective() bool {
+	for _, e := range m.entries {
+		if e.typ == MBRProtective {
+			return true
+		}
+	}
+	return false
+}
+
+func (e mbrEntry) empty() bool {
+	return e.typ == MBREmpty || e.sectors == 0
+}
+
+func (e mbrEntry) extended() bool {
+	return e.typ == MBRExtended || e.typ == MBRExtendedLBA || e.typ == MBRLinuxExt
+}
+
+// readMBR returns the partitions described by m. Extended partitions are
+// not returned themselves; the logical partitions within them are.
+func readMBR(r io.ReaderAt, m mbr) (*Table, error) {
+	t := &Table{
+		Scheme:        MBR,
+		SectorSize:    mbrSectorSize,
+		DiskSignature: m.signature,
+	}
+	for i, e := range m.entries {
+		switch {
+		case e.empty():
+		case e.extended():
+			if err := t.readExtended(r, e); err != nil {
+				return nil, err
+			}
+		default:
+			t.Partitions = append(t.Partitions, t.mbrPartition(r, i+1, e, 0))
+		}
+	}
+	return t, nil
+}
+
+// readExtended follows the chain of extended boot records within ext and
+// adds the logical partitions it describes to t.
+//
+// The first entry of each extended boot record is relative to the record
+// itself. The second entry, which links to the next record, is relative to
+// the start of the extended partition.
+func (t *Table) readExtended(r io.ReaderAt, ext mbrEntry) error {
+	sector := make([]byte, mbrSectorSize)
+	seen := make(map[uint32]bool)
+	ebr := ext.lba
+	for number := 5; number < 5+maxLogical; number++ {
+		if seen[ebr] {
+			return ErrInvalidEBR
+		}
+		seen[ebr] = true
+
+		if _, err := r.ReadAt(sector, int64(ebr)*mbrSectorSize); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return ErrInvalidEBR
+			}
+			return err
+		}
+		m, err := parseMBR(sector)
+		if err != nil {
+			return ErrInvalidEBR
+		}
+
+		if e := m.entries[0]; !e.empty() {
+			p := t.mbrPartition(r, number, e, ebr)
+			p.Logical = true
+			t.Partitions = append(t.Partitions, p)
+		}
+
+		next := m.entries[1]
+		if next.empty() || !next.extended() {
+			return nil
+		}
+		ebr = ext.lba + next.lba
+	}
+	return nil
+}
+
+func (t *Table) mbrPartition(r io.ReaderAt, number int, e mbrEntry, base uint32) Partition {
+	return Partition{
+		Number:   number,
+		Start:    (int64(base) + int64(e.lba)) * t.SectorSize,
+		Size:     int64(e.sectors) * t.SectorSize,
+		Type:     e.typ,
+		Bootable: e.status == 0x80,
+		r:        r,
+	}
+}
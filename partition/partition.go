@@ -0,0 +1,139 @@
+package partition
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	// ErrNoTable is returned when a disk does not hold an MBR or GPT
+	// partition table.
+	ErrNoTable = errors.New("no partition table found")
+
+	// ErrInvalidHeader is returned when a GPT header is malformed.
+	ErrInvalidHeader = errors.New("invalid GPT header")
+
+	// ErrHeaderCRC is returned when a GPT header fails its CRC check.
+	ErrHeaderCRC = errors.New("GPT header CRC mismatch")
+
+	// ErrEntriesCRC is returned when a GPT partition entry array fails its
+	// CRC check.
+	ErrEntriesCRC = errors.New("GPT partition entry array CRC mismatch")
+
+	// ErrInvalidEBR is returned when an extended boot record in an MBR
+	// extended partition is malformed or forms a loop.
+	ErrInvalidEBR = errors.New("invalid extended boot record")
+)
+
+// Scheme identifies a partitioning scheme.
+type Scheme int
+
+// Partitioning schemes.
+const (
+	MBR Scheme = iota + 1
+	GPT
+)
+
+// String returns a string representation of the scheme.
+func (s Scheme) String() string {
+	switch s {
+	case MBR:
+		return "MBR"
+	case GPT:
+		return "GPT"
+	default:
+		return "Unknown"
+	}
+}
+
+// Table is a partition table read from a disk.
+type Table struct {
+	Scheme     Scheme
+	SectorSize int64
+
+	// DiskSignature is the disk signature held by the MBR.
+	DiskSignature uint32
+
+	// Primary and Backup hold the GPT headers. A header is nil if it is
+	// missing or fails validation. The partitions are taken from the
+	// primary header's entry array when it is valid, and from the backup
+	// otherwise.
+	Primary *GPTHeader
+	Backup  *GPTHeader
+
+	Partitions []Partition
+}
+
+// Partition is a partition within a disk. It is an io.ReaderAt that is
+// bounded to the partition's extent.
+type Partition struct {
+	// Number is the one-based number of the partition. MBR primary
+	// partitions are numbered by their slot in the table, and logical
+	// partitions are numbered from 5 in the order of their extended boot
+	// records. GPT partitions are numbered by their index in the entry
+	// array.
+	Number int
+
+	Start int64 // Offset of the partition from the start of the disk in bytes
+	Size  int64 // Size of the partition in bytes
+
+	// MBR fields
+	Type     byte // MBR partition type
+	Bootable bool
+	Logical  bool
+
+	// GPT fields
+	TypeGUID   GUID
+	GUID       GUID
+	Name       string
+	Attributes uint64
+
+	r io.ReaderAt
+}
+
+// ReadAt reads len(b) bytes from the partition at offset off.
+func (p Partition) ReadAt(b []byte, off int64) (int, error) {
+	return p.Reader().ReadAt(b, off)
+}
+
+// Reader returns a section reader for the partition.
+func (p Partition) Reader() *io.SectionReader {
+	return io.NewSectionReader(p.r, p.Start, p.Size)
+}
+
+// Filesystem identifies the file system held by the partition. GPT
+// partitions that belong to a Storage Spaces pool are identified by their
+// partition type.
+func (p Partition) Filesystem() (Filesystem, error) {
+	if p.TypeGUID == TypeStorageSpaces {
+		return StorageSpaces, nil
+	}
+	return Detect(p)
+}
+
+// Read reads the partition table of the disk held by r. The size of the
+// disk in bytes is used to locate the backup GPT header.
+//
+// GPT disks with 512 and 4096 byte sectors are recognized. MBR disks are
+// assumed to use 512 byte sectors.
+//
+// If the disk holds a file system but no partition table, ErrNoTable is
+// returned.
+func Read(r io.ReaderAt, size int64) (*Table, error) {
+	sector := make([]byte, mbrSectorSize)
+	if _, err := r.ReadAt(sector, 0); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNoTable
+		}
+		return nil, err
+	}
+
+	m, err := parseMBR(sector)
+	if err != nil {
+		return nil, err
+	}
+	if m.protective() {
+		return readGPT(r, size)
+	}
+	return readMBR(r, m)
+}
@@ -0,0 +1,343 @@
+package partition_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/gentlemanautomaton/volmgmt/partition"
+)
+
+// bootSector returns a boot sector with the given OEM identifier.
+func bootSector(oem string) []byte {
+	b := make([]byte, 512)
+	b[0], b[1], b[2] = 0xEB, 0x52, 0x90
+	copy(b[3:11], oem)
+	b[510], b[511] = 0x55, 0xAA
+	return b
+}
+
+// fatBootSector returns a FAT boot sector with one sector per cluster, two
+// FATs of fatSize sectors and the given number of clusters. FAT32 volumes
+// have no fixed root directory. The file system type label is left blank.
+func fatBootSector(clusters, fatSize int, fat32 bool) []byte {
+	b := bootSector("MSWIN4.1")
+	binary.LittleEndian.PutUint16(b[0x0B:], 512)
+	b[0x0D] = 1
+	b[0x10] = 2
+	reserved, rootEntries := 1, 512
+	if fat32 {
+		reserved, rootEntries = 32, 0
+		binary.LittleEndian.PutUint32(b[0x24:], uint32(fatSize))
+	} else {
+		binary.LittleEndian.PutUint16(b[0x16:], uint16(fatSize))
+	}
+	binary.LittleEndian.PutUint16(b[0x0E:], uint16(reserved))
+	binary.LittleEndian.PutUint16(b[0x11:], uint16(rootEntries))
+	total := reserved + 2*fatSize + rootEntries*32/512 + clusters
+	if total < 0x10000 {
+		binary.LittleEndian.PutUint16(b[0x13:], uint16(total))
+	} else {
+		binary.LittleEndian.PutUint32(b[0x20:], uint32(total))
+	}
+	return b
+}
+
+type mbrEntry struct {
+	status  byte
+	typ     byte
+	lba     uint32
+	sectors uint32
+}
+
+// putMBR writes a master boot record to the sector at lba.
+func putMBR(disk []byte, lba int, signature uint32, entries ...mbrEntry) {
+	sector := disk[lba*512 : (lba+1)*512]
+	binary.LittleEndian.PutUint32(sector[0x1B8:], signature)
+	for i, e := range entries {
+		b := sector[0x1BE+i*16:]
+		b[0] = e.status
+		b[4] = e.typ
+		binary.LittleEndian.PutUint32(b[8:], e.lba)
+		binary.LittleEndian.PutUint32(b[12:], e.sectors)
+	}
+	sector[510], sector[511] = 0x55, 0xAA
+}
+
+type gptEntry struct {
+	typ   partition.GUID
+	guid  partition.GUID
+	first uint64
+	last  uint64
+	attr  uint64
+	name  string
+}
+
+// newGPT returns a disk of the given number of sectors with a protective
+// MBR and primary and backup GUID partition tables.
+func newGPT(sectorSize, sectors int, entries ...gptEntry) []byte {
+	disk := make([]byte, sectorSize*sectors)
+	putMBR(disk, 0, 0, mbrEntry{typ: partition.MBRProtective, lba: 1, sectors: uint32(sectors - 1)})
+
+	const count, size = 128, 128
+	array := make([]byte, count*size)
+	for i, e := range entries {
+		b := array[i*size:]
+		copy(b[0:], e.typ[:])
+		copy(b[0x10:], e.guid[:])
+		binary.LittleEndian.PutUint64(b[0x20:], e.first)
+		binary.LittleEndian.PutUint64(b[0x28:], e.last)
+		binary.LittleEndian.PutUint64(b[0x30:], e.attr)
+		for j, c := range utf16.Encode([]rune(e.name)) {
+			binary.LittleEndian.PutUint16(b[0x38+j*2:], c)
+		}
+	}
+	arraySectors := len(array) / sectorSize
+	last := uint64(sectors - 1)
+
+	header := func(current, backup, entriesLBA uint64) {
+		h := disk[int(current)*sectorSize:][:92]
+		copy(h, "EFI PART")
+		binary.LittleEndian.PutUint32(h[0x08:], 0x00010000)
+		binary.LittleEndian.PutUint32(h[0x0C:], 92)
+		binary.LittleEndian.PutUint64(h[0x18:], current)
+		binary.LittleEndian.PutUint64(h[0x20:], backup)
+		binary.LittleEndian.PutUint64(h[0x28:], uint64(2+arraySectors))
+		binary.LittleEndian.PutUint64(h[0x30:], last-1-uint64(arraySectors))
+		copy(h[0x38:], diskGUID[:])
+		binary.LittleEndian.PutUint64(h[0x48:], entriesLBA)
+		binary.LittleEndian.PutUint32(h[0x50:], count)
+		binary.LittleEndian.PutUint32(h[0x54:], size)
+		binary.LittleEndian.PutUint32(h[0x58:], crc32.ChecksumIEEE(array))
+		binary.LittleEndian.PutUint32(h[0x10:], crc32.ChecksumIEEE(h))
+		copy(disk[int(entriesLBA)*sectorSize:], array)
+	}
+	header(1, last, 2)
+	header(last, 1, last-uint64(arraySectors))
+	return disk
+}
+
+var (
+	diskGUID = partition.MustParseGUID("{6E0B6A3C-0A8B-4C39-9E5D-2C6B7B4C2F10}")
+	partGUID = partition.MustParseGUID("{0B6F3E5A-77A2-4B7E-8C5D-9D8B1E2F3A4B}")
+)
+
+func TestGUID(t *testing.T) {
+	want := []byte{0xA2, 0xA0, 0xD0, 0xEB, 0xE5, 0xB9, 0x33, 0x44, 0x87, 0xC0, 0x68, 0xB6, 0xB7, 0x26, 0x99, 0xC7}
+	if g := partition.TypeBasicData; !bytes.Equal(g[:], want) {
+		t.Errorf("TypeBasicData = % X, want % X", g[:], want)
+	}
+	if got, want := partition.TypeBasicData.String(), "{EBD0A0A2-B9E5-4433-87C0-68B6B72699C7}"; got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+	for _, s := range []string{"", "EBD0A0A2-B9E5-4433-87C0-68B6B72699C", "EBD0A0A2-B9E5-4433-87C0+68B6B72699C7", "XBD0A0A2-B9E5-4433-87C0-68B6B72699C7"} {
+		if _, err := partition.ParseGUID(s); err != partition.ErrInvalidGUID {
+			t.Errorf("ParseGUID(%q) returned %v, want %v", s, err, partition.ErrInvalidGUID)
+		}
+	}
+	if got := partition.TypeName(partition.TypeEFISystem); got != "EFI system" {
+		t.Errorf("TypeName(TypeEFISystem) = %q", got)
+	}
+}
+
+func TestMBR(t *testing.T) {
+	disk := make([]byte, 512*400)
+	putMBR(disk, 0, 0xCAFEF00D,
+		mbrEntry{status: 0x80, typ: partition.MBRIFS, lba: 2, sectors: 100},
+		mbrEntry{typ: partition.MBRFAT32LBA, lba: 102, sectors: 50},
+		mbrEntry{typ: partition.MBRExtendedLBA, lba: 200, sectors: 200},
+	)
+	// First logical partition, with a link to the next extended boot record
+	putMBR(disk, 200, 0,
+		mbrEntry{typ: partition.MBRLinux, lba: 1, sectors: 49},
+		mbrEntry{typ: partition.MBRExtended, lba: 100, sectors: 100},
+	)
+	putMBR(disk, 300, 0, mbrEntry{typ: partition.MBRIFS, lba: 4, sectors: 96})
+
+	copy(disk[2*512:], bootSector("NTFS    "))
+	copy(disk[102*512:], fatBootSector(70000, 600, true))
+	copy(disk[304*512:], bootSector("-FVE-FS-"))
+
+	table, err := partition.Read(bytes.NewReader(disk), int64(len(disk)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Scheme != partition.MBR || table.DiskSignature != 0xCAFEF00D {
+		t.Fatalf("scheme %s, signature %08X", table.Scheme, table.DiskSignature)
+	}
+
+	type result struct {
+		number   int
+		start    int64
+		size     int64
+		typ      byte
+		bootable bool
+		logical  bool
+		fs       partition.Filesystem
+	}
+	want := []result{
+		{1, 2 * 512, 100 * 512, partition.MBRIFS, true, false, partition.NTFS},
+		{2, 102 * 512, 50 * 512, partition.MBRFAT32LBA, false, false, partition.FAT32},
+		{5, 201 * 512, 49 * 512, partition.MBRLinux, false, true, partition.Unknown},
+		{6, 304 * 512, 96 * 512, partition.MBRIFS, false, true, partition.BitLocker},
+	}
+	if len(table.Partitions) != len(want) {
+		t.Fatalf("found %d partitions, want %d", len(table.Partitions), len(want))
+	}
+	for i, p := range table.Partitions {
+		fs, err := p.Filesystem()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := result{p.Number, p.Start, p.Size, p.Type, p.Bootable, p.Logical, fs}
+		if got != want[i] {
+			t.Errorf("partition %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+
+	// Reads are bounded to the partition
+	p := table.Partitions[1]
+	buf := make([]byte, 1024)
+	if n, err := p.ReadAt(buf, p.Size-512); n != 512 || err == nil {
+		t.Errorf("ReadAt at end of partition returned %d, %v", n, err)
+	}
+}
+
+func TestDetectFAT(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		sector []byte
+		want   partition.Filesystem
+	}{
+		{"FAT12", fatBootSector(4084, 12, false), partition.FAT12},
+		{"FAT16 minimum", fatBootSector(4085, 12, false), partition.FAT16},
+		{"FAT16 maximum", fatBootSector(65524, 256, false), partition.FAT16},
+		{"FAT32 minimum", fatBootSector(65525, 512, true), partition.FAT32},
+		{"FAT32", fatBootSector(1000000, 7813, true), partition.FAT32},
+		{"no sector size", func() []byte {
+			b := fatBootSector(4084, 12, false)
+			binary.LittleEndian.PutUint16(b[0x0B:], 0)
+			return b
+		}(), partition.Unknown},
+		{"no FAT", fatBootSector(4084, 0, false), partition.Unknown},
+		{"no clusters", fatBootSector(0, 12, false), partition.Unknown},
+	} {
+		got, err := partition.Detect(bytes.NewReader(tt.sector))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: Detect returned %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	// The file system type label does not override the cluster count
+	b := fatBootSector(4084, 12, false)
+	copy(b[0x36:], "FAT16   ")
+	if got, _ := partition.Detect(bytes.NewReader(b)); got != partition.FAT12 {
+		t.Errorf("Detect with a FAT16 label returned %s, want %s", got, partition.FAT12)
+	}
+}
+
+func TestMBRLoop(t *testing.T) {
+	disk := make([]byte, 512*100)
+	putMBR(disk, 0, 0, mbrEntry{typ: partition.MBRExtended, lba: 10, sectors: 90})
+	putMBR(disk, 10, 0,
+		mbrEntry{typ: partition.MBRLinux, lba: 1, sectors: 9},
+		mbrEntry{typ: partition.MBRExtended, lba: 0, sectors: 10},
+	)
+	if _, err := partition.Read(bytes.NewReader(disk), int64(len(disk))); err != partition.ErrInvalidEBR {
+		t.Fatalf("Read returned %v, want %v", err, partition.ErrInvalidEBR)
+	}
+}
+
+func TestNoTable(t *testing.T) {
+	for _, disk := range [][]byte{
+		make([]byte, 4096),
+		append(bootSector("NTFS    "), make([]byte, 4096)...),
+		append(bootSector("EXFAT   "), make([]byte, 4096)...),
+	} {
+		if _, err := partition.Read(bytes.NewReader(disk), int64(len(disk))); err != partition.ErrNoTable {
+			t.Errorf("Read returned %v, want %v", err, partition.ErrNoTable)
+		}
+	}
+}
+
+func TestGPT(t *testing.T) {
+	for _, sectorSize := range []int{512, 4096} {
+		disk := newGPT(sectorSize, 256,
+			gptEntry{typ: partition.TypeEFISystem, guid: partGUID, first: 40, last: 59, attr: partition.AttrRequired, name: "EFI system partition"},
+			gptEntry{typ: partition.TypeBasicData, guid: diskGUID, first: 60, last: 199, attr: partition.AttrNoDriveLetter, name: "Données"},
+			gptEntry{typ: partition.TypeStorageSpaces, first: 200, last: 219, name: "Storage pool"},
+		)
+		copy(disk[60*sectorSize:], bootSector("EXFAT   "))
+
+		table, err := partition.Read(bytes.NewReader(disk), int64(len(disk)))
+		if err != nil {
+			t.Fatalf("%d: %v", sectorSize, err)
+		}
+		if table.Scheme != partition.GPT || table.SectorSize != int64(sectorSize) {
+			t.Fatalf("%d: scheme %s, sector size %d", sectorSize, table.Scheme, table.SectorSize)
+		}
+		if table.Primary == nil || table.Backup == nil {
+			t.Fatalf("%d: missing header: primary %v, backup %v", sectorSize, table.Primary, table.Backup)
+		}
+		if table.Primary.DiskGUID != diskGUID || table.Backup.CurrentLBA != 255 {
+			t.Errorf("%d: unexpected headers: %+v, %+v", sectorSize, table.Primary, table.Backup)
+		}
+		if len(table.Partitions) != 3 {
+			t.Fatalf("%d: found %d partitions, want 3", sectorSize, len(table.Partitions))
+		}
+
+		efi, data := table.Partitions[0], table.Partitions[1]
+		if efi.Number != 1 || efi.TypeGUID != partition.TypeEFISystem || efi.GUID != partGUID || efi.Name != "EFI system partition" || efi.Attributes != partition.AttrRequired {
+			t.Errorf("%d: unexpected EFI partition: %+v", sectorSize, efi)
+		}
+		if efi.Start != int64(40*sectorSize) || efi.Size != int64(20*sectorSize) {
+			t.Errorf("%d: EFI partition at %d, size %d", sectorSize, efi.Start, efi.Size)
+		}
+		if data.Number != 2 || data.Name != "Données" || data.Attributes != partition.AttrNoDriveLetter {
+			t.Errorf("%d: unexpected data partition: %+v", sectorSize, data)
+		}
+		if fs, err := data.Filesystem(); err != nil || fs != partition.ExFAT {
+			t.Errorf("%d: data partition file system %s, %v", sectorSize, fs, err)
+		}
+		if fs, err := table.Partitions[2].Filesystem(); err != nil || fs != partition.StorageSpaces {
+			t.Errorf("%d: pool partition file system %s, %v", sectorSize, fs, err)
+		}
+	}
+}
+
+func TestGPTBackup(t *testing.T) {
+	disk := newGPT(512, 256, gptEntry{typ: partition.TypeBasicData, first: 40, last: 199, name: "Data"})
+	disk[512+0x20] ^= 0xFF // Corrupt the primary header
+
+	table, err := partition.Read(bytes.NewReader(disk), int64(len(disk)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Primary != nil || table.Backup == nil {
+		t.Fatalf("primary %v, backup %v", table.Primary, table.Backup)
+	}
+	if len(table.Partitions) != 1 || table.Partitions[0].Name != "Data" {
+		t.Fatalf("unexpected partitions: %+v", table.Partitions)
+	}
+
+	if _, err := partition.ParseGPTHeader(disk[512:1024]); err != partition.ErrHeaderCRC {
+		t.Errorf("ParseGPTHeader returned %v, want %v", err, partition.ErrHeaderCRC)
+	}
+}
+
+func TestGPTEntriesCRC(t *testing.T) {
+	disk := newGPT(512, 256, gptEntry{typ: partition.TypeBasicData, first: 40, last: 199, name: "Data"})
+	disk[2*512+0x38] = 'X'   // Corrupt the primary entry array
+	disk[223*512+0x38] = 'X' // Corrupt the backup entry array
+
+	_, err := partition.Read(bytes.NewReader(disk), int64(len(disk)))
+	if !errors.Is(err, partition.ErrEntriesCRC) {
+		t.Fatalf("Read returned %v, want %v", err, partition.ErrEntriesCRC)
+	}
+}
@@ -0,0 +1,13 @@
+// Package reparse decodes the reparse point data attached to files and
+// directories on NTFS and ReFS volumes.
+//
+// Reparse data is held in a REPARSE_DATA_BUFFER, which begins with a tag
+// that identifies the file system filter that owns it. Parse decodes the
+// buffer into a Point whose concrete type depends on the tag. The data of
+// tags that are not understood is retained as a Raw point.
+//
+// Reparse data is read from live files with Get, or from the
+// $REPARSE_POINT attribute of an MFT entry with FromEntry.
+//
+// https://learn.microsoft.com/en-us/windows/win32/fileio/reparse-point-tags
+package reparse
@@ -0,0 +1,31 @@
+package reparse
+
+import (
+	"fmt"
+
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+)
+
+// FromEntry decodes the $REPARSE_POINT attribute of an MFT entry that was
+// read from v. It returns ErrNotReparsePoint if the entry has no reparse
+// point.
+//
+// If v is nil, as it is for entries read from an extracted $MFT file, only
+// resident reparse data can be decoded.
+func FromEntry(v *ntfs.Volume, e ntfs.MFTEntry) (Point, error) {
+	attr, ok := e.ReparsePoint()
+	if !ok {
+		return nil, ErrNotReparsePoint
+	}
+	data := attr.Value
+	if attr.NonResident {
+		if v == nil {
+			return nil, fmt.Errorf("MFT record %d: %s: %w", e.Number, attr.Type, ErrNonResident)
+		}
+		var err error
+		if data, err = v.ReparseData(e); err != nil {
+			return nil, fmt.Errorf("MFT record %d: %w", e.Number, err)
+		}
+	}
+	return Parse(data)
+}
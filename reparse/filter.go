@@ -0,0 +1,151 @@
+package reparse
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/utf16le"
+	"github.com/gentlemanautomaton/volmgmt/partition"
+)
+
+// WOFProvider identifies the Windows Overlay Filter provider that backs a
+// file.
+type WOFProvider uint32
+
+// Windows Overlay Filter providers.
+const (
+	WOFProviderWIM  WOFProvider = 1 // The file is backed by a WIM image
+	WOFProviderFile WOFProvider = 2 // The file is compressed in its own stream
+)
+
+// String returns the name of the provider.
+func (p WOFProvider) String() string {
+	switch p {
+	case WOFProviderWIM:
+		return "WIM"
+	case WOFProviderFile:
+		return "File"
+	}
+	return fmt.Sprintf("provider(%d)", uint32(p))
+}
+
+// WOFAlgorithm identifies the compression algorithm of a file that is
+// compressed by the Windows Overlay Filter.
+type WOFAlgorithm uint32
+
+// Windows Overlay Filter compression algorithms.
+const (
+	WOFXpress4K  WOFAlgorithm = 0
+	WOFLZX       WOFAlgorithm = 1
+	WOFXpress8K  WOFAlgorithm = 2
+	WOFXpress16K WOFAlgorithm = 3
+)
+
+// String returns the name of the algorithm.
+func (a WOFAlgorithm) String() string {
+	switch a {
+	case WOFXpress4K:
+		return "XPRESS4K"
+	case WOFLZX:
+		return "LZX"
+	case WOFXpress8K:
+		return "XPRESS8K"
+	case WOFXpress16K:
+		return "XPRESS16K"
+	}
+	return fmt.Sprintf("algorithm(%d)", uint32(a))
+}
+
+// WOF is a file backed by the Windows Overlay Filter. Files compressed
+// with compact.exe use the file provider, which stores the compressed data
+// in the WofCompressedData stream.
+type WOF struct {
+	Header
+	Version         uint32
+	Provider        WOFProvider
+	ProviderVersion uint32
+
+	// File provider only
+	Algorithm WOFAlgorithm
+
+	// WIM provider only
+	Flags        uint32
+	DataSourceID int64
+	ResourceHash [20]byte
+}
+
+// parseWOF parses the data of a Windows Overlay Filter reparse point,
+// which is a WOF_EXTERNAL_INFO followed by provider-specific information.
+func parseWOF(h Header, data []byte) (*WOF, error) {
+	if len(data) < 16 {
+		return nil, ErrTruncated
+	}
+	w := &WOF{
+		Header:          h,
+		Version:         binary.LittleEndian.Uint32(data[0:]),
+		Provider:        WOFProvider(binary.LittleEndian.Uint32(data[4:])),
+		ProviderVersion: binary.LittleEndian.Uint32(data[8:]),
+	}
+	switch w.Provider {
+	case WOFProviderFile:
+		w.Algorithm = WOFAlgorithm(binary.LittleEndian.Uint32(data[12:]))
+	case WOFProviderWIM:
+		if len(data) < 44 {
+			return nil, ErrTruncated
+		}
+		w.Flags = binary.LittleEndian.Uint32(data[12:])
+		w.DataSourceID = int64(binary.LittleEndian.Uint64(data[16:]))
+		copy(w.ResourceHash[:], data[24:44])
+	}
+	return w, nil
+}
+
+// WCI is a file or directory that is projected into a container by the
+// Windows Container Isolation filter.
+type WCI struct {
+	Header
+	Version    uint32
+	LookupGUID partition.GUID
+	Name       string // Name of the file in the container's layers
+}
+
+// parseWCI parses the data of a Windows Container Isolation reparse point.
+// Its layout is undocumented.
+func parseWCI(h Header, data []byte) (*WCI, error) {
+	if len(data) < 26 {
+		return nil, ErrTruncated
+	}
+	w := &WCI{
+		Header:  h,
+		Version: binary.LittleEndian.Uint32(data[0:]),
+	}
+	copy(w.LookupGUID[:], data[8:24])
+	length := int(binary.LittleEndian.Uint16(data[24:]))
+	if 26+length > len(data) {
+		return nil, ErrTruncated
+	}
+	w.Name = utf16le.Decode(data[26 : 26+length])
+	return w, nil
+}
+
+// Dedup is a file whose contents have been moved into the chunk store of
+// the Data Deduplication service. Its data is undocumented and is not
+// decoded.
+type Dedup struct {
+	Header
+	Data []byte
+}
+
+// Cloud is a cloud files placeholder, such as a OneDrive file that is not
+// stored locally. Its data is private to the cloud files filter and is not
+// decoded.
+type Cloud struct {
+	Header
+	Data []byte
+}
+
+// Provider returns the four bits that distinguish the cloud files tags,
+// which sync providers use to identify the kind of placeholder.
+func (c *Cloud) Provider() uint8 {
+	return uint8(c.ReparseTag>>12) & 0xF
+}
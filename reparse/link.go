@@ -0,0 +1,159 @@
+package reparse
+
+import (
+	"encoding/binary"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/utf16le"
+)
+
+// Symbolic link flags.
+const (
+	SymlinkRelative = 0x00000001 // The substitute name is a relative path
+)
+
+// Symlink is a symbolic link.
+type Symlink struct {
+	Header
+	SubstituteName string // The path used by the file system
+	PrintName      string // The path displayed to users
+	Flags          uint32
+}
+
+// Relative returns true if the link target is relative to the directory
+// that contains the link.
+func (s *Symlink) Relative() bool {
+	return s.Flags&SymlinkRelative != 0
+}
+
+// Target returns the path the link refers to, in the Win32 namespace.
+func (s *Symlink) Target() string {
+	if s.PrintName != "" {
+		return s.PrintName
+	}
+	return stripNT(s.SubstituteName)
+}
+
+// MountPoint is a directory junction or volume mount point.
+type MountPoint struct {
+	Header
+	SubstituteName string // The path used by the file system
+	PrintName      string // The path displayed to users, often empty
+}
+
+// Target returns the path the junction refers to, in the Win32 namespace.
+// Volume mount points refer to a volume name such as
+// \\?\Volume{GUID}\.
+func (m *MountPoint) Target() string {
+	if m.Volume() {
+		return `\\?\` + strings.TrimPrefix(m.SubstituteName, `\??\`)
+	}
+	if m.PrintName != "" {
+		return m.PrintName
+	}
+	return stripNT(m.SubstituteName)
+}
+
+// Volume returns true if the mount point refers to a volume rather than a
+// directory.
+func (m *MountPoint) Volume() bool {
+	return strings.HasPrefix(m.SubstituteName, `\??\Volume{`)
+}
+
+// AppExecLink is an app execution alias, which launches a packaged app
+// from a path such as %LOCALAPPDATA%\Microsoft\WindowsApps\winget.exe.
+type AppExecLink struct {
+	Header
+	Version   uint32
+	PackageID string
+	AppID     string // Application user model ID
+	Path      string // Path of the executable within the package
+	Extra     []string
+}
+
+// Target returns the path of the executable that is launched.
+func (a *AppExecLink) Target() string {
+	return a.Path
+}
+
+// parseSymlink parses the data of a symbolic link.
+func parseSymlink(h Header, data []byte) (*Symlink, error) {
+	if len(data) < 12 {
+		return nil, ErrTruncated
+	}
+	sub, printName, err := names(data, data[12:])
+	if err != nil {
+		return nil, err
+	}
+	return &Symlink{
+		Header:         h,
+		SubstituteName: sub,
+		PrintName:      printName,
+		Flags:          binary.LittleEndian.Uint32(data[8:]),
+	}, nil
+}
+
+// parseMountPoint parses the data of a junction or mount point.
+func parseMountPoint(h Header, data []byte) (*MountPoint, error) {
+	if len(data) < 8 {
+		return nil, ErrTruncated
+	}
+	sub, printName, err := names(data, data[8:])
+	if err != nil {
+		return nil, err
+	}
+	return &MountPoint{
+		Header:         h,
+		SubstituteName: sub,
+		PrintName:      printName,
+	}, nil
+}
+
+// names reads the substitute and printName names described by the header at
+// the start of data from the path buffer.
+func names(data, buffer []byte) (sub, printName string, err error) {
+	subOffset := int(binary.LittleEndian.Uint16(data[0:]))
+	subLength := int(binary.LittleEndian.Uint16(data[2:]))
+	printOffset := int(binary.LittleEndian.Uint16(data[4:]))
+	printLength := int(binary.LittleEndian.Uint16(data[6:]))
+	if subOffset+subLength > len(buffer) || printOffset+printLength > len(buffer) {
+		return "", "", ErrTruncated
+	}
+	sub = utf16le.Decode(buffer[subOffset : subOffset+subLength])
+	printName = utf16le.Decode(buffer[printOffset : printOffset+printLength])
+	return sub, printName, nil
+}
+
+// parseAppExecLink parses the data of an app execution alias, which holds
+// a version followed by a list of null-terminated strings.
+func parseAppExecLink(h Header, data []byte) (*AppExecLink, error) {
+	if len(data) < 4 {
+		return nil, ErrTruncated
+	}
+	a := &AppExecLink{
+		Header:  h,
+		Version: binary.LittleEndian.Uint32(data),
+	}
+	var fields []string
+	for rest := data[4:]; len(rest) >= 2; {
+		end := 0
+		for end+1 < len(rest) && (rest[end] != 0 || rest[end+1] != 0) {
+			end += 2
+		}
+		fields = append(fields, utf16le.Decode(rest[:end]))
+		if end+2 > len(rest) {
+			break
+		}
+		rest = rest[end+2:]
+	}
+	if len(fields) < 3 {
+		return nil, ErrTruncated
+	}
+	a.PackageID, a.AppID, a.Path = fields[0], fields[1], fields[2]
+	for _, f := range fields[3:] {
+		if f != "" {
+			a.Extra = append(a.Extra, f)
+		}
+	}
+	return a, nil
+}
@@ -0,0 +1,115 @@
+package reparse
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/partition"
+)
+
+var (
+	// ErrTruncated is returned when reparse data is shorter than its
+	// structure requires.
+	ErrTruncated = errors.New("reparse data is truncated")
+
+	// ErrNotReparsePoint is returned when a file does not have a reparse
+	// point.
+	ErrNotReparsePoint = errors.New("file is not a reparse point")
+
+	// ErrNonResident is returned when reparse data is stored outside of an
+	// MFT entry and the volume holding it is not available.
+	ErrNonResident = errors.New("reparse data is not resident")
+)
+
+// MaxSize is the largest reparse data buffer that a file system will store.
+const MaxSize = 16 * 1024
+
+// Point is decoded reparse point data. Its concrete type is one of the
+// types in this package, depending on its tag.
+type Point interface {
+	Tag() Tag
+}
+
+// Link is implemented by reparse points that redirect to another path.
+type Link interface {
+	Point
+	Target() string
+}
+
+// Header holds the fields common to all reparse points.
+type Header struct {
+	ReparseTag Tag
+	GUID       partition.GUID // Zero for tags owned by Microsoft
+}
+
+// Tag returns the reparse tag.
+func (h Header) Tag() Tag {
+	return h.ReparseTag
+}
+
+// Raw is reparse data that is not decoded by this package.
+type Raw struct {
+	Header
+	Data []byte
+}
+
+// Parse decodes a REPARSE_DATA_BUFFER, or a REPARSE_GUID_DATA_BUFFER for
+// tags that are not owned by Microsoft. The returned point may refer to
+// data.
+//
+// If data is well formed but its tag-specific contents cannot be decoded,
+// a Raw point is returned.
+func Parse(data []byte) (Point, error) {
+	if len(data) < 8 {
+		return nil, ErrTruncated
+	}
+	h := Header{ReparseTag: Tag(binary.LittleEndian.Uint32(data[0:]))}
+	length := int(binary.LittleEndian.Uint16(data[4:]))
+	data = data[8:]
+	if !h.ReparseTag.Microsoft() {
+		if len(data) < 16 {
+			return nil, ErrTruncated
+		}
+		copy(h.GUID[:], data[:16])
+		data = data[16:]
+	}
+	if length > len(data) {
+		return nil, ErrTruncated
+	}
+	data = data[:length]
+
+	var (
+		p   Point
+		err error
+	)
+	switch {
+	case h.ReparseTag == TagSymlink:
+		p, err = parseSymlink(h, data)
+	case h.ReparseTag == TagMountPoint:
+		p, err = parseMountPoint(h, data)
+	case h.ReparseTag == TagAppExecLink:
+		p, err = parseAppExecLink(h, data)
+	case h.ReparseTag == TagWOF:
+		p, err = parseWOF(h, data)
+	case h.ReparseTag == TagWCI || h.ReparseTag == TagWCI1:
+		p, err = parseWCI(h, data)
+	case h.ReparseTag == TagDedup:
+		p = &Dedup{Header: h, Data: data}
+	case h.ReparseTag.Cloud():
+		p = &Cloud{Header: h, Data: data}
+	}
+	if p == nil || err != nil {
+		return &Raw{Header: h, Data: data}, nil
+	}
+	return p, nil
+}
+
+// stripNT removes the \??\ prefix of an NT namespace path, which is how
+// link targets are stored.
+func stripNT(path string) string {
+	if strings.HasPrefix(path, `\??\UNC\`) {
+		return `\\` + path[8:]
+	}
+	return strings.TrimPrefix(path, `\??\`)
+}
@@ -0,0 +1,213 @@
+package reparse_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/ntfstest"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/partition"
+	"github.com/gentlemanautomaton/volmgmt/reparse"
+)
+
+func utf16Bytes(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, len(u)*2)
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[i*2:], c)
+	}
+	return b
+}
+
+// buffer encodes a REPARSE_DATA_BUFFER holding data.
+func buffer(tag reparse.Tag, data []byte) []byte {
+	b := make([]byte, 8+len(data))
+	binary.LittleEndian.PutUint32(b[0:], uint32(tag))
+	binary.LittleEndian.PutUint16(b[4:], uint16(len(data)))
+	copy(b[8:], data)
+	return b
+}
+
+// linkData encodes the data of a symbolic link or mount point. Flags are
+// only included for symbolic links.
+func linkData(tag reparse.Tag, sub, printName string, flags uint32) []byte {
+	subData, printData := utf16Bytes(sub), utf16Bytes(printName)
+	header := 8
+	if tag == reparse.TagSymlink {
+		header = 12
+	}
+	b := make([]byte, header)
+	binary.LittleEndian.PutUint16(b[0:], 0)
+	binary.LittleEndian.PutUint16(b[2:], uint16(len(subData)))
+	binary.LittleEndian.PutUint16(b[4:], uint16(len(subData)+2))
+	binary.LittleEndian.PutUint16(b[6:], uint16(len(printData)))
+	if tag == reparse.TagSymlink {
+		binary.LittleEndian.PutUint32(b[8:], flags)
+	}
+	b = append(b, subData...)
+	b = append(b, 0, 0)
+	b = append(b, printData...)
+	return append(b, 0, 0)
+}
+
+func TestParseSymlink(t *testing.T) {
+	p, err := reparse.Parse(buffer(reparse.TagSymlink, linkData(reparse.TagSymlink, `\??\C:\Windows\System32`, `C:\Windows\System32`, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, ok := p.(*reparse.Symlink)
+	if !ok {
+		t.Fatalf("Parse returned %T, want *Symlink", p)
+	}
+	if s.Tag() != reparse.TagSymlink || s.Relative() || s.SubstituteName != `\??\C:\Windows\System32` || s.Target() != `C:\Windows\System32` {
+		t.Errorf("Symlink = %+v, Target = %q", s, s.Target())
+	}
+
+	p, err = reparse.Parse(buffer(reparse.TagSymlink, linkData(reparse.TagSymlink, `..\lib`, ``, reparse.SymlinkRelative)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := p.(*reparse.Symlink); !s.Relative() || s.Target() != `..\lib` {
+		t.Errorf("relative Symlink = %+v, Target = %q", s, s.Target())
+	}
+}
+
+func TestParseMountPoint(t *testing.T) {
+	tests := []struct {
+		sub, printName, target string
+		volume                 bool
+	}{
+		{`\??\C:\Users\Public`, `C:\Users\Public`, `C:\Users\Public`, false},
+		{`\??\UNC\server\share`, ``, `\\server\share`, false},
+		{`\??\Volume{01234567-89ab-cdef-0123-456789abcdef}\`, ``, `\\?\Volume{01234567-89ab-cdef-0123-456789abcdef}\`, true},
+	}
+	for _, tt := range tests {
+		p, err := reparse.Parse(buffer(reparse.TagMountPoint, linkData(reparse.TagMountPoint, tt.sub, tt.printName, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, ok := p.(*reparse.MountPoint)
+		if !ok {
+			t.Fatalf("Parse returned %T, want *MountPoint", p)
+		}
+		if m.Target() != tt.target || m.Volume() != tt.volume {
+			t.Errorf("%s: Target = %q, Volume = %t, want %q, %t", tt.sub, m.Target(), m.Volume(), tt.target, tt.volume)
+		}
+		var _ reparse.Link = m
+	}
+}
+
+func TestParseAppExecLink(t *testing.T) {
+	data := []byte{3, 0, 0, 0}
+	for _, s := range []string{"Microsoft.DesktopAppInstaller_8wekyb3d8bbwe", "Microsoft.DesktopAppInstaller_8wekyb3d8bbwe!winget", `C:\Program Files\WindowsApps\winget.exe`, "0"} {
+		data = append(data, utf16Bytes(s)...)
+		data = append(data, 0, 0)
+	}
+	p, err := reparse.Parse(buffer(reparse.TagAppExecLink, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, ok := p.(*reparse.AppExecLink)
+	if !ok {
+		t.Fatalf("Parse returned %T, want *AppExecLink", p)
+	}
+	if a.Version != 3 || a.AppID != "Microsoft.DesktopAppInstaller_8wekyb3d8bbwe!winget" || a.Target() != `C:\Program Files\WindowsApps\winget.exe` || len(a.Extra) != 1 {
+		t.Errorf("AppExecLink = %+v", a)
+	}
+}
+
+func TestParseWOF(t *testing.T) {
+	data := make([]byte, 16)
+	binary.LittleEndian.PutUint32(data[0:], 1)
+	binary.LittleEndian.PutUint32(data[4:], uint32(reparse.WOFProviderFile))
+	binary.LittleEndian.PutUint32(data[8:], 1)
+	binary.LittleEndian.PutUint32(data[12:], uint32(reparse.WOFLZX))
+	p, err := reparse.Parse(buffer(reparse.TagWOF, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, ok := p.(*reparse.WOF)
+	if !ok {
+		t.Fatalf("Parse returned %T, want *WOF", p)
+	}
+	if w.Provider != reparse.WOFProviderFile || w.Algorithm != reparse.WOFLZX || w.Algorithm.String() != "LZX" {
+		t.Errorf("WOF = %+v", w)
+	}
+}
+
+func TestParseCloud(t *testing.T) {
+	tag := reparse.TagCloud | 0x3000
+	p, err := reparse.Parse(buffer(tag, []byte{1, 2, 3, 4}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := p.(*reparse.Cloud)
+	if !ok {
+		t.Fatalf("Parse returned %T, want *Cloud", p)
+	}
+	if c.Provider() != 3 || len(c.Data) != 4 || tag.String() != "CLOUD_3" {
+		t.Errorf("Cloud = %+v (%s)", c, tag)
+	}
+}
+
+func TestParseThirdParty(t *testing.T) {
+	guid := partition.MustParseGUID("{01234567-89AB-CDEF-0123-456789ABCDEF}")
+	data := make([]byte, 8+16+3)
+	binary.LittleEndian.PutUint32(data[0:], 0x00000123)
+	binary.LittleEndian.PutUint16(data[4:], 3)
+	copy(data[8:], guid[:])
+	copy(data[24:], "abc")
+
+	p, err := reparse.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := p.(*reparse.Raw)
+	if !ok {
+		t.Fatalf("Parse returned %T, want *Raw", p)
+	}
+	if r.Tag().Microsoft() || r.GUID != guid || string(r.Data) != "abc" {
+		t.Errorf("Raw = %+v", r)
+	}
+
+	if _, err := reparse.Parse(data[:20]); !errors.Is(err, reparse.ErrTruncated) {
+		t.Errorf("Parse of truncated data returned %v, want ErrTruncated", err)
+	}
+}
+
+func TestFromEntry(t *testing.T) {
+	img := ntfstest.NewImage(64, ntfstest.Run{LCN: 4, Length: 12})
+	img.Set(40, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse | ntfs.RecordDirectory,
+		Attrs: [][]byte{
+			ntfstest.ResidentAttr(ntfs.AttrReparsePoint, "", buffer(reparse.TagMountPoint, linkData(reparse.TagMountPoint, `\??\D:\Data`, `D:\Data`, 0))),
+		},
+	})
+	v, err := ntfs.Open(img.Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := v.Entry(40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := reparse.FromEntry(v, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link, ok := p.(reparse.Link); !ok || link.Target() != `D:\Data` {
+		t.Errorf("FromEntry returned %+v", p)
+	}
+
+	e, err = v.Entry(ntfs.RecordRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reparse.FromEntry(v, e); !errors.Is(err, reparse.ErrNotReparsePoint) {
+		t.Errorf("FromEntry of the root directory returned %v, want ErrNotReparsePoint", err)
+	}
+}
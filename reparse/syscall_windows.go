@@ -0,0 +1,44 @@
+package reparse
+
+import (
+	"errors"
+	"syscall"
+
+	"github.com/gentlemanautomaton/volmgmt/fsctl"
+	"golang.org/x/sys/windows"
+)
+
+// Get reads and decodes the reparse point of the file or directory open in
+// handle. The handle must have been opened with
+// FILE_FLAG_OPEN_REPARSE_POINT, otherwise the reparse point is followed.
+//
+// It returns ErrNotReparsePoint if the file has no reparse point.
+func Get(handle syscall.Handle) (Point, error) {
+	buffer := make([]byte, MaxSize)
+	var length uint32
+	err := syscall.DeviceIoControl(handle, fsctl.GetReparsePoint, nil, 0, &buffer[0], uint32(len(buffer)), &length, nil)
+	if err != nil {
+		if errors.Is(err, windows.ERROR_NOT_A_REPARSE_POINT) {
+			return nil, ErrNotReparsePoint
+		}
+		return nil, err
+	}
+	return Parse(buffer[:length])
+}
+
+// GetPath reads and decodes the reparse point of the file or directory at
+// path without following it.
+func GetPath(path string) (Point, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	const shareMode = syscall.FILE_SHARE_READ | syscall.FILE_SHARE_WRITE | syscall.FILE_SHARE_DELETE
+	const flags = syscall.FILE_FLAG_BACKUP_SEMANTICS | syscall.FILE_FLAG_OPEN_REPARSE_POINT
+	handle, err := syscall.CreateFile(p, 0, shareMode, nil, syscall.OPEN_EXISTING, flags, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.CloseHandle(handle)
+	return Get(handle)
+}
@@ -0,0 +1,117 @@
+package reparse
+
+import "fmt"
+
+// Tag identifies the owner and format of reparse point data.
+//
+// https://learn.microsoft.com/en-us/windows/win32/fileio/reparse-point-tags
+type Tag uint32
+
+// Reparse point tags.
+const (
+	TagMountPoint      Tag = 0xA0000003 // Junctions and volume mount points
+	TagHSM             Tag = 0xC0000004
+	TagDriveExtender   Tag = 0x80000005
+	TagHSM2            Tag = 0x80000006
+	TagSIS             Tag = 0x80000007
+	TagWIM             Tag = 0x80000008
+	TagCSV             Tag = 0x80000009
+	TagDFS             Tag = 0x8000000A
+	TagSymlink         Tag = 0xA000000C
+	TagDFSR            Tag = 0x80000012
+	TagDedup           Tag = 0x80000013
+	TagNFS             Tag = 0x80000014
+	TagFileInfo        Tag = 0x80000015
+	TagWOF             Tag = 0x80000017 // Windows Overlay Filter, used for compact files
+	TagWCI             Tag = 0x80000018 // Windows Container Isolation
+	TagWCI1            Tag = 0x90001018
+	TagGlobalReparse   Tag = 0xA0000019
+	TagCloud           Tag = 0x9000001A // Cloud files placeholders, such as OneDrive
+	TagAppExecLink     Tag = 0x8000001B
+	TagProjFS          Tag = 0x9000001C
+	TagLXSymlink       Tag = 0xA000001D
+	TagStorageSync     Tag = 0x8000001E
+	TagWCITombstone    Tag = 0xA000001F
+	TagUnhandled       Tag = 0x80000020
+	TagOneDrive        Tag = 0x80000021
+	TagProjFSTombstone Tag = 0xA0000022
+	TagAFUnix          Tag = 0x80000023
+	TagLXFIFO          Tag = 0x80000024
+	TagLXCHR           Tag = 0x80000025
+	TagLXBLK           Tag = 0x80000026
+	TagWCILink         Tag = 0xA0000027
+	TagWCILink1        Tag = 0xA0001027
+)
+
+// cloudMask removes the bits that distinguish the sixteen cloud files tags
+// from one another.
+const cloudMask = 0xFFFF0FFF
+
+var tagNames = map[Tag]string{
+	TagMountPoint:      "MOUNT_POINT",
+	TagHSM:             "HSM",
+	TagDriveExtender:   "DRIVE_EXTENDER",
+	TagHSM2:            "HSM2",
+	TagSIS:             "SIS",
+	TagWIM:             "WIM",
+	TagCSV:             "CSV",
+	TagDFS:             "DFS",
+	TagSymlink:         "SYMLINK",
+	TagDFSR:            "DFSR",
+	TagDedup:           "DEDUP",
+	TagNFS:             "NFS",
+	TagFileInfo:        "FILE_PLACEHOLDER",
+	TagWOF:             "WOF",
+	TagWCI:             "WCI",
+	TagWCI1:            "WCI_1",
+	TagGlobalReparse:   "GLOBAL_REPARSE",
+	TagCloud:           "CLOUD",
+	TagAppExecLink:     "APPEXECLINK",
+	TagProjFS:          "PROJFS",
+	TagLXSymlink:       "LX_SYMLINK",
+	TagStorageSync:     "STORAGE_SYNC",
+	TagWCITombstone:    "WCI_TOMBSTONE",
+	TagUnhandled:       "UNHANDLED",
+	TagOneDrive:        "ONEDRIVE",
+	TagProjFSTombstone: "PROJFS_TOMBSTONE",
+	TagAFUnix:          "AF_UNIX",
+	TagLXFIFO:          "LX_FIFO",
+	TagLXCHR:           "LX_CHR",
+	TagLXBLK:           "LX_BLK",
+	TagWCILink:         "WCI_LINK",
+	TagWCILink1:        "WCI_LINK_1",
+}
+
+// String returns the name of the tag, without its IO_REPARSE_TAG_ prefix.
+func (t Tag) String() string {
+	if name, ok := tagNames[t]; ok {
+		return name
+	}
+	if t.Cloud() {
+		return fmt.Sprintf("CLOUD_%X", (uint32(t)>>12)&0xF)
+	}
+	return fmt.Sprintf("tag(%#08x)", uint32(t))
+}
+
+// Microsoft returns true if the tag is owned by Microsoft. The data of
+// other tags is preceded by a GUID that identifies its owner.
+func (t Tag) Microsoft() bool {
+	return t&0x80000000 != 0
+}
+
+// NameSurrogate returns true if the tag marks a file or directory that
+// stands in for another named entity, as links do.
+func (t Tag) NameSurrogate() bool {
+	return t&0x20000000 != 0
+}
+
+// Directory returns true if the tag can be set on a directory that holds
+// files.
+func (t Tag) Directory() bool {
+	return t&0x10000000 != 0
+}
+
+// Cloud returns true if the tag is one of the cloud files tags.
+func (t Tag) Cloud() bool {
+	return t&cloudMask == TagCloud
+}
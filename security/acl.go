@@ -0,0 +1,186 @@
+package security
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gentlemanautomaton/volmgmt/partition"
+)
+
+// ACEType is the type of an access control entry.
+type ACEType uint8
+
+// Access control entry types.
+const (
+	AccessAllowed               ACEType = 0x00
+	AccessDenied                ACEType = 0x01
+	SystemAudit                 ACEType = 0x02
+	SystemAlarm                 ACEType = 0x03
+	AccessAllowedCompound       ACEType = 0x04
+	AccessAllowedObject         ACEType = 0x05
+	AccessDeniedObject          ACEType = 0x06
+	SystemAuditObject           ACEType = 0x07
+	SystemAlarmObject           ACEType = 0x08
+	AccessAllowedCallback       ACEType = 0x09
+	AccessDeniedCallback        ACEType = 0x0A
+	AccessAllowedCallbackObject ACEType = 0x0B
+	AccessDeniedCallbackObject  ACEType = 0x0C
+	SystemAuditCallback         ACEType = 0x0D
+	SystemAlarmCallback         ACEType = 0x0E
+	SystemAuditCallbackObject   ACEType = 0x0F
+	SystemAlarmCallbackObject   ACEType = 0x10
+	SystemMandatoryLabel        ACEType = 0x11
+	SystemResourceAttribute     ACEType = 0x12
+	SystemScopedPolicyID        ACEType = 0x13
+)
+
+// Object returns true if entries of the type carry object type GUIDs.
+func (t ACEType) Object() bool {
+	switch t {
+	case AccessAllowedObject, AccessDeniedObject, SystemAuditObject, SystemAlarmObject,
+		AccessAllowedCallbackObject, AccessDeniedCallbackObject, SystemAuditCallbackObject, SystemAlarmCallbackObject:
+		return true
+	}
+	return false
+}
+
+// String returns the SDDL abbreviation of the type.
+func (t ACEType) String() string {
+	if s, ok := aceTypeStrings[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("0x%x", uint8(t))
+}
+
+var aceTypeStrings = map[ACEType]string{
+	AccessAllowed:               "A",
+	AccessDenied:                "D",
+	SystemAudit:                 "AU",
+	SystemAlarm:                 "AL",
+	AccessAllowedObject:         "OA",
+	AccessDeniedObject:          "OD",
+	SystemAuditObject:           "OU",
+	SystemAlarmObject:           "OL",
+	AccessAllowedCallback:       "XA",
+	AccessDeniedCallback:        "XD",
+	AccessAllowedCallbackObject: "ZA",
+	SystemAuditCallback:         "XU",
+	SystemMandatoryLabel:        "ML",
+	SystemResourceAttribute:     "RA",
+	SystemScopedPolicyID:        "SP",
+}
+
+// Access control entry flags.
+const (
+	ObjectInherit      = 0x01
+	ContainerInherit   = 0x02
+	NoPropagateInherit = 0x04
+	InheritOnly        = 0x08
+	Inherited          = 0x10
+	SuccessfulAccess   = 0x40
+	FailedAccess       = 0x80
+)
+
+// Object access control entry flags, which indicate the presence of the
+// object type GUIDs.
+const (
+	ObjectTypePresent          = 0x1
+	InheritedObjectTypePresent = 0x2
+)
+
+// ACE is an access control entry.
+type ACE struct {
+	Type  ACEType
+	Flags uint8
+	Mask  uint32
+	SID   SID
+
+	// Object entries only
+	ObjectFlags         uint32
+	ObjectType          partition.GUID
+	InheritedObjectType partition.GUID
+
+	// ApplicationData holds any data that follows the security identifier,
+	// such as the condition of a callback entry.
+	ApplicationData []byte
+}
+
+// ACL is an access control list.
+type ACL struct {
+	Revision uint8
+	Entries  []ACE
+}
+
+// ParseACL parses a binary access control list from the start of data.
+func ParseACL(data []byte) (ACL, error) {
+	if len(data) < 8 {
+		return ACL{}, ErrTruncated
+	}
+	size := int(binary.LittleEndian.Uint16(data[2:]))
+	count := int(binary.LittleEndian.Uint16(data[4:]))
+	if size < 8 || size > len(data) {
+		return ACL{}, ErrTruncated
+	}
+	acl := ACL{Revision: data[0]}
+	for offset, i := 8, 0; i < count; i++ {
+		if offset+4 > size {
+			return ACL{}, ErrTruncated
+		}
+		length := int(binary.LittleEndian.Uint16(data[offset+2:]))
+		if length < 4 || offset+length > size {
+			return ACL{}, ErrTruncated
+		}
+		ace, err := parseACE(data[offset : offset+length])
+		if err != nil {
+			return ACL{}, fmt.Errorf("ACE %d: %w", i, err)
+		}
+		acl.Entries = append(acl.Entries, ace)
+		offset += length
+	}
+	return acl, nil
+}
+
+// parseACE parses an access control entry.
+func parseACE(data []byte) (ACE, error) {
+	ace := ACE{
+		Type:  ACEType(data[0]),
+		Flags: data[1],
+	}
+	if len(data) < 8 {
+		return ACE{}, ErrTruncated
+	}
+	ace.Mask = binary.LittleEndian.Uint32(data[4:])
+	rest := data[8:]
+
+	if ace.Type.Object() {
+		if len(rest) < 4 {
+			return ACE{}, ErrTruncated
+		}
+		ace.ObjectFlags = binary.LittleEndian.Uint32(rest)
+		rest = rest[4:]
+		if ace.ObjectFlags&ObjectTypePresent != 0 {
+			if len(rest) < 16 {
+				return ACE{}, ErrTruncated
+			}
+			copy(ace.ObjectType[:], rest)
+			rest = rest[16:]
+		}
+		if ace.ObjectFlags&InheritedObjectTypePresent != 0 {
+			if len(rest) < 16 {
+				return ACE{}, ErrTruncated
+			}
+			copy(ace.InheritedObjectType[:], rest)
+			rest = rest[16:]
+		}
+	}
+
+	sid, n, err := ParseSID(rest)
+	if err != nil {
+		return ACE{}, err
+	}
+	ace.SID = sid
+	if len(rest) > n {
+		ace.ApplicationData = rest[n:]
+	}
+	return ace, nil
+}
@@ -0,0 +1,113 @@
+package security
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrNotSelfRelative is returned when a security descriptor is not in
+// self-relative form.
+var ErrNotSelfRelative = errors.New("security descriptor is not self-relative")
+
+// Control holds the control flags of a security descriptor.
+type Control uint16
+
+// Security descriptor control flags.
+const (
+	OwnerDefaulted       Control = 0x0001
+	GroupDefaulted       Control = 0x0002
+	DACLPresent          Control = 0x0004
+	DACLDefaulted        Control = 0x0008
+	SACLPresent          Control = 0x0010
+	SACLDefaulted        Control = 0x0020
+	DACLAutoInheritReq   Control = 0x0100
+	SACLAutoInheritReq   Control = 0x0200
+	DACLAutoInherited    Control = 0x0400
+	SACLAutoInherited    Control = 0x0800
+	DACLProtected        Control = 0x1000
+	SACLProtected        Control = 0x2000
+	ResourceManagerValid Control = 0x4000
+	SelfRelative         Control = 0x8000
+)
+
+// Descriptor is a security descriptor.
+type Descriptor struct {
+	Revision uint8
+	Control  Control
+	Owner    *SID // Nil if the descriptor has no owner
+	Group    *SID // Nil if the descriptor has no primary group
+	DACL     *ACL // Nil if the descriptor has no discretionary ACL
+	SACL     *ACL // Nil if the descriptor has no system ACL
+}
+
+// Parse parses a security descriptor in self-relative form, which is how
+// descriptors are stored on disk.
+func Parse(data []byte) (Descriptor, error) {
+	if len(data) < 20 {
+		return Descriptor{}, ErrTruncated
+	}
+	d := Descriptor{
+		Revision: data[0],
+		Control:  Control(binary.LittleEndian.Uint16(data[2:])),
+	}
+	if d.Control&SelfRelative == 0 {
+		return Descriptor{}, ErrNotSelfRelative
+	}
+	ownerOffset := binary.LittleEndian.Uint32(data[4:])
+	groupOffset := binary.LittleEndian.Uint32(data[8:])
+	saclOffset := binary.LittleEndian.Uint32(data[12:])
+	daclOffset := binary.LittleEndian.Uint32(data[16:])
+
+	var err error
+	if d.Owner, err = parseSIDAt(data, ownerOffset); err != nil {
+		return Descriptor{}, fmt.Errorf("owner: %w", err)
+	}
+	if d.Group, err = parseSIDAt(data, groupOffset); err != nil {
+		return Descriptor{}, fmt.Errorf("group: %w", err)
+	}
+	if d.Control&SACLPresent != 0 {
+		if d.SACL, err = parseACLAt(data, saclOffset); err != nil {
+			return Descriptor{}, fmt.Errorf("SACL: %w", err)
+		}
+	}
+	if d.Control&DACLPresent != 0 {
+		if d.DACL, err = parseACLAt(data, daclOffset); err != nil {
+			return Descriptor{}, fmt.Errorf("DACL: %w", err)
+		}
+	}
+	return d, nil
+}
+
+// parseSIDAt parses the security identifier at offset within data. It
+// returns nil if offset is zero.
+func parseSIDAt(data []byte, offset uint32) (*SID, error) {
+	if offset == 0 {
+		return nil, nil
+	}
+	if uint64(offset) >= uint64(len(data)) {
+		return nil, ErrTruncated
+	}
+	sid, _, err := ParseSID(data[offset:])
+	if err != nil {
+		return nil, err
+	}
+	return &sid, nil
+}
+
+// parseACLAt parses the access control list at offset within data. A
+// present ACL with an offset of zero is a null ACL, which is returned as
+// nil.
+func parseACLAt(data []byte, offset uint32) (*ACL, error) {
+	if offset == 0 {
+		return nil, nil
+	}
+	if uint64(offset) >= uint64(len(data)) {
+		return nil, ErrTruncated
+	}
+	acl, err := ParseACL(data[offset:])
+	if err != nil {
+		return nil, err
+	}
+	return &acl, nil
+}
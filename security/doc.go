@@ -0,0 +1,10 @@
+// Package security decodes Windows security descriptors, access control
+// lists and security identifiers from their self-relative binary form.
+//
+// It does not depend on the Windows API, so descriptors read from disk
+// images, such as those held in the $Secure file of an NTFS volume, can be
+// examined on any platform. Descriptors can be rendered in the Security
+// Descriptor Definition Language (SDDL).
+//
+// https://learn.microsoft.com/en-us/windows/win32/secauthz/security-descriptor-string-format
+package security
@@ -0,0 +1,180 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/partition"
+)
+
+// abbreviation is the SDDL abbreviation of a set of access rights.
+type abbreviation struct {
+	mask uint32
+	s    string
+}
+
+// SDDL rights abbreviations, in the order they are written. File rights
+// that match one of the combined file access masks are written as that
+// mask instead.
+var rightStrings = []abbreviation{
+	{0x10000000, "GA"},
+	{0x80000000, "GR"},
+	{0x40000000, "GW"},
+	{0x20000000, "GX"},
+	{0x00020000, "RC"},
+	{0x00010000, "SD"},
+	{0x00040000, "WD"},
+	{0x00080000, "WO"},
+	{0x00000001, "CC"},
+	{0x00000002, "DC"},
+	{0x00000004, "LC"},
+	{0x00000008, "SW"},
+	{0x00000010, "RP"},
+	{0x00000020, "WP"},
+	{0x00000040, "DT"},
+	{0x00000080, "LO"},
+	{0x00000100, "CR"},
+}
+
+// Combined file access masks.
+var fileRights = map[uint32]string{
+	0x001F01FF: "FA",
+	0x00120089: "FR",
+	0x00120116: "FW",
+	0x001200A0: "FX",
+}
+
+// Rights of mandatory label entries.
+var labelRights = []abbreviation{
+	{0x1, "NW"},
+	{0x2, "NR"},
+	{0x4, "NX"},
+}
+
+// SDDL abbreviations of access control entry flags.
+var aceFlagStrings = []struct {
+	flag uint8
+	s    string
+}{
+	{ObjectInherit, "OI"},
+	{ContainerInherit, "CI"},
+	{NoPropagateInherit, "NP"},
+	{InheritOnly, "IO"},
+	{Inherited, "ID"},
+	{SuccessfulAccess, "SA"},
+	{FailedAccess, "FA"},
+}
+
+// SDDL returns the descriptor in the Security Descriptor Definition
+// Language, such as O:BAG:SYD:PAI(A;OICI;FA;;;SY)(A;OICI;FA;;;BA).
+//
+// The conditions of callback entries and the values of resource attribute
+// entries are omitted.
+func (d Descriptor) SDDL() string {
+	var b strings.Builder
+	if d.Owner != nil {
+		b.WriteString("O:")
+		b.WriteString(d.Owner.alias())
+	}
+	if d.Group != nil {
+		b.WriteString("G:")
+		b.WriteString(d.Group.alias())
+	}
+	if d.Control&DACLPresent != 0 {
+		b.WriteString("D:")
+		writeACL(&b, d.DACL, d.Control&DACLProtected != 0, d.Control&DACLAutoInheritReq != 0, d.Control&DACLAutoInherited != 0)
+	}
+	if d.Control&SACLPresent != 0 {
+		b.WriteString("S:")
+		writeACL(&b, d.SACL, d.Control&SACLProtected != 0, d.Control&SACLAutoInheritReq != 0, d.Control&SACLAutoInherited != 0)
+	}
+	return b.String()
+}
+
+// String returns the descriptor in the Security Descriptor Definition
+// Language.
+func (d Descriptor) String() string {
+	return d.SDDL()
+}
+
+// writeACL writes the flags and entries of an access control list.
+func writeACL(b *strings.Builder, acl *ACL, protected, autoInheritReq, autoInherited bool) {
+	if protected {
+		b.WriteString("P")
+	}
+	if autoInheritReq {
+		b.WriteString("AR")
+	}
+	if autoInherited {
+		b.WriteString("AI")
+	}
+	if acl == nil {
+		b.WriteString("NO_ACCESS_CONTROL")
+		return
+	}
+	for _, ace := range acl.Entries {
+		b.WriteString(ace.SDDL())
+	}
+}
+
+// SDDL returns the entry in the Security Descriptor Definition Language,
+// such as (A;OICI;FA;;;SY).
+func (ace ACE) SDDL() string {
+	var b strings.Builder
+	b.WriteByte('(')
+	b.WriteString(ace.Type.String())
+	b.WriteByte(';')
+	for _, f := range aceFlagStrings {
+		if ace.Flags&f.flag != 0 {
+			b.WriteString(f.s)
+		}
+	}
+	b.WriteByte(';')
+	b.WriteString(ace.rights())
+	b.WriteByte(';')
+	if ace.ObjectFlags&ObjectTypePresent != 0 {
+		b.WriteString(guidString(ace.ObjectType))
+	}
+	b.WriteByte(';')
+	if ace.ObjectFlags&InheritedObjectTypePresent != 0 {
+		b.WriteString(guidString(ace.InheritedObjectType))
+	}
+	b.WriteByte(';')
+	b.WriteString(ace.SID.alias())
+	b.WriteByte(')')
+	return b.String()
+}
+
+// rights returns the access mask of the entry in SDDL form. Masks that
+// cannot be expressed with abbreviations are written in hexadecimal.
+func (ace ACE) rights() string {
+	if ace.Type == SystemMandatoryLabel {
+		return maskString(ace.Mask, labelRights)
+	}
+	if s, ok := fileRights[ace.Mask]; ok {
+		return s
+	}
+	return maskString(ace.Mask, rightStrings)
+}
+
+// maskString returns mask as a series of abbreviations.
+func maskString(mask uint32, known []abbreviation) string {
+	var s string
+	remaining := mask
+	for _, r := range known {
+		if mask&r.mask != 0 {
+			s += r.s
+			remaining &^= r.mask
+		}
+	}
+	if remaining != 0 {
+		return fmt.Sprintf("0x%x", mask)
+	}
+	return s
+}
+
+// guidString returns a GUID in the form used by SDDL, which is lowercase
+// and has no braces.
+func guidString(g partition.GUID) string {
+	return strings.ToLower(strings.Trim(g.String(), "{}"))
+}
@@ -0,0 +1,128 @@
+package security_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/security"
+)
+
+func mustSID(t *testing.T, s string) security.SID {
+	t.Helper()
+	sid, err := security.ParseSIDString(s)
+	if err != nil {
+		t.Fatalf("ParseSIDString(%q): %v", s, err)
+	}
+	return sid
+}
+
+// ace encodes an access control entry with no object types.
+func ace(typ security.ACEType, flags uint8, mask uint32, sid security.SID) []byte {
+	sidData := sid.Bytes()
+	b := make([]byte, 8, 8+len(sidData))
+	b[0] = byte(typ)
+	b[1] = flags
+	binary.LittleEndian.PutUint16(b[2:], uint16(8+len(sidData)))
+	binary.LittleEndian.PutUint32(b[4:], mask)
+	return append(b, sidData...)
+}
+
+// acl encodes an access control list.
+func acl(entries ...[]byte) []byte {
+	b := make([]byte, 8)
+	b[0] = 2
+	for _, e := range entries {
+		b = append(b, e...)
+	}
+	binary.LittleEndian.PutUint16(b[2:], uint16(len(b)))
+	binary.LittleEndian.PutUint16(b[4:], uint16(len(entries)))
+	return b
+}
+
+// descriptor encodes a self-relative security descriptor.
+func descriptor(control security.Control, owner, group security.SID, dacl []byte) []byte {
+	b := make([]byte, 20)
+	b[0] = 1
+	binary.LittleEndian.PutUint16(b[2:], uint16(control|security.SelfRelative))
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(b)))
+	b = append(b, owner.Bytes()...)
+	binary.LittleEndian.PutUint32(b[8:], uint32(len(b)))
+	b = append(b, group.Bytes()...)
+	if dacl != nil {
+		binary.LittleEndian.PutUint32(b[16:], uint32(len(b)))
+		b = append(b, dacl...)
+	}
+	return b
+}
+
+func TestSID(t *testing.T) {
+	tests := []struct {
+		s    string
+		name string
+	}{
+		{"S-1-5-18", `NT AUTHORITY\SYSTEM`},
+		{"S-1-5-32-544", `BUILTIN\Administrators`},
+		{"S-1-5-21-1004336348-1177238915-682003330-500", "Administrator"},
+		{"S-1-5-21-1004336348-1177238915-682003330-1001", ""},
+	}
+	for _, tt := range tests {
+		sid := mustSID(t, tt.s)
+		parsed, n, err := security.ParseSID(sid.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(sid.Bytes()) || !parsed.Equal(sid) || parsed.String() != tt.s {
+			t.Errorf("ParseSID(%s) = %s (%d bytes)", tt.s, parsed, n)
+		}
+		if name, _ := sid.Name(); name != tt.name {
+			t.Errorf("%s: Name = %q, want %q", tt.s, name, tt.name)
+		}
+	}
+
+	if _, err := security.ParseSIDString("S-1"); !errors.Is(err, security.ErrInvalidSID) {
+		t.Errorf("ParseSIDString of an invalid SID returned %v", err)
+	}
+}
+
+func TestDescriptor(t *testing.T) {
+	system := mustSID(t, "S-1-5-18")
+	admins := mustSID(t, "S-1-5-32-544")
+	users := mustSID(t, "S-1-5-32-545")
+	owner := mustSID(t, "S-1-3-0")
+	user := mustSID(t, "S-1-5-21-1004336348-1177238915-682003330-1001")
+
+	const oici = security.ObjectInherit | security.ContainerInherit
+	data := descriptor(security.DACLPresent|security.DACLProtected|security.DACLAutoInherited, admins, system, acl(
+		ace(security.AccessAllowed, oici, 0x001F01FF, system),
+		ace(security.AccessAllowed, oici, 0x001F01FF, admins),
+		ace(security.AccessAllowed, oici|security.InheritOnly, 0x10000000, owner),
+		ace(security.AccessAllowed, security.Inherited, 0x001200A9, users),
+		ace(security.AccessDenied, 0, 0x00010000|0x00040000, user),
+	))
+
+	d, err := security.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Owner == nil || !d.Owner.Equal(admins) || d.Group == nil || !d.Group.Equal(system) {
+		t.Errorf("Owner = %v, Group = %v", d.Owner, d.Group)
+	}
+	if d.DACL == nil || len(d.DACL.Entries) != 5 || d.SACL != nil {
+		t.Fatalf("DACL = %+v, SACL = %+v", d.DACL, d.SACL)
+	}
+
+	want := "O:BAG:SYD:PAI(A;OICI;FA;;;SY)(A;OICI;FA;;;BA)(A;OICIIO;GA;;;CO)(A;ID;0x1200a9;;;BU)(D;;SDWD;;;S-1-5-21-1004336348-1177238915-682003330-1001)"
+	if got := d.SDDL(); got != want {
+		t.Errorf("SDDL\n got: %s\nwant: %s", got, want)
+	}
+
+	null := descriptor(security.DACLPresent, admins, system, nil)
+	if d, err := security.Parse(null); err != nil || d.SDDL() != "O:BAG:SYD:NO_ACCESS_CONTROL" {
+		t.Errorf("SDDL of a null DACL = %q (%v)", d.SDDL(), err)
+	}
+
+	if _, err := security.Parse(data[:len(data)-4]); !errors.Is(err, security.ErrTruncated) {
+		t.Errorf("Parse of truncated data returned %v, want ErrTruncated", err)
+	}
+}
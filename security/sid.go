@@ -0,0 +1,229 @@
+package security
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrTruncated is returned when security data is shorter than its
+// structure requires.
+var ErrTruncated = errors.New("security data is truncated")
+
+// ErrInvalidSID is returned when a security identifier cannot be parsed.
+var ErrInvalidSID = errors.New("invalid security identifier")
+
+// SID is a security identifier.
+type SID struct {
+	Revision       uint8
+	Authority      uint64 // 48-bit identifier authority
+	SubAuthorities []uint32
+}
+
+// ParseSID parses a binary security identifier from the start of data. It
+// returns the identifier and its length in bytes.
+func ParseSID(data []byte) (SID, int, error) {
+	if len(data) < 8 {
+		return SID{}, 0, ErrTruncated
+	}
+	count := int(data[1])
+	length := 8 + count*4
+	if len(data) < length {
+		return SID{}, 0, ErrTruncated
+	}
+	sid := SID{
+		Revision:       data[0],
+		SubAuthorities: make([]uint32, count),
+	}
+	for _, b := range data[2:8] {
+		sid.Authority = sid.Authority<<8 | uint64(b)
+	}
+	for i := range sid.SubAuthorities {
+		sid.SubAuthorities[i] = binary.LittleEndian.Uint32(data[8+i*4:])
+	}
+	return sid, length, nil
+}
+
+// ParseSIDString parses a security identifier in its string form, such as
+// S-1-5-32-544.
+func ParseSIDString(s string) (SID, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) < 3 || !strings.EqualFold(parts[0], "S") {
+		return SID{}, ErrInvalidSID
+	}
+	revision, err := strconv.ParseUint(parts[1], 10, 8)
+	if err != nil {
+		return SID{}, ErrInvalidSID
+	}
+	authority, err := strconv.ParseUint(parts[2], 0, 48)
+	if err != nil {
+		return SID{}, ErrInvalidSID
+	}
+	sid := SID{Revision: uint8(revision), Authority: authority}
+	for _, part := range parts[3:] {
+		sub, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return SID{}, ErrInvalidSID
+		}
+		sid.SubAuthorities = append(sid.SubAuthorities, uint32(sub))
+	}
+	return sid, nil
+}
+
+// Bytes returns the binary form of the security identifier.
+func (sid SID) Bytes() []byte {
+	b := make([]byte, 8+len(sid.SubAuthorities)*4)
+	b[0] = sid.Revision
+	b[1] = uint8(len(sid.SubAuthorities))
+	for i := 0; i < 6; i++ {
+		b[7-i] = byte(sid.Authority >> (8 * i))
+	}
+	for i, sub := range sid.SubAuthorities {
+		binary.LittleEndian.PutUint32(b[8+i*4:], sub)
+	}
+	return b
+}
+
+// String returns the security identifier in its string form, such as
+// S-1-5-32-544.
+func (sid SID) String() string {
+	var b strings.Builder
+	b.WriteString("S-")
+	b.WriteString(strconv.FormatUint(uint64(sid.Revision), 10))
+	b.WriteByte('-')
+	if sid.Authority >= 1<<32 {
+		b.WriteString("0x")
+		b.WriteString(strings.ToUpper(strconv.FormatUint(sid.Authority, 16)))
+	} else {
+		b.WriteString(strconv.FormatUint(sid.Authority, 10))
+	}
+	for _, sub := range sid.SubAuthorities {
+		b.WriteByte('-')
+		b.WriteString(strconv.FormatUint(uint64(sub), 10))
+	}
+	return b.String()
+}
+
+// Equal returns true if sid and other are the same security identifier.
+func (sid SID) Equal(other SID) bool {
+	if sid.Revision != other.Revision || sid.Authority != other.Authority || len(sid.SubAuthorities) != len(other.SubAuthorities) {
+		return false
+	}
+	for i := range sid.SubAuthorities {
+		if sid.SubAuthorities[i] != other.SubAuthorities[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Name returns the name of a well-known security identifier, such as
+// BUILTIN\Administrators. It returns false for other identifiers, which
+// can only be named by the system that issued them.
+func (sid SID) Name() (string, bool) {
+	s := sid.String()
+	if name, ok := wellKnownNames[s]; ok {
+		return name, true
+	}
+	if strings.HasPrefix(s, "S-1-5-21-") && len(sid.SubAuthorities) == 5 {
+		// Well-known accounts and groups of a domain or computer
+		switch sid.SubAuthorities[4] {
+		case 500:
+			return "Administrator", true
+		case 501:
+			return "Guest", true
+		case 512:
+			return "Domain Admins", true
+		case 513:
+			return "Domain Users", true
+		}
+	}
+	return "", false
+}
+
+// alias returns the two letter SDDL alias of the security identifier, or
+// its string form if it does not have one.
+func (sid SID) alias() string {
+	s := sid.String()
+	if a, ok := sidAliases[s]; ok {
+		return a
+	}
+	return s
+}
+
+// SDDL aliases of well-known security identifiers.
+var sidAliases = map[string]string{
+	"S-1-1-0":      "WD",
+	"S-1-3-0":      "CO",
+	"S-1-3-1":      "CG",
+	"S-1-3-4":      "OW",
+	"S-1-5-2":      "NU",
+	"S-1-5-4":      "IU",
+	"S-1-5-6":      "SU",
+	"S-1-5-7":      "AN",
+	"S-1-5-9":      "ED",
+	"S-1-5-10":     "PS",
+	"S-1-5-11":     "AU",
+	"S-1-5-12":     "RC",
+	"S-1-5-18":     "SY",
+	"S-1-5-19":     "LS",
+	"S-1-5-20":     "NS",
+	"S-1-5-32-544": "BA",
+	"S-1-5-32-545": "BU",
+	"S-1-5-32-546": "BG",
+	"S-1-5-32-547": "PU",
+	"S-1-5-32-548": "AO",
+	"S-1-5-32-549": "SO",
+	"S-1-5-32-550": "PO",
+	"S-1-5-32-551": "BO",
+	"S-1-5-32-552": "RE",
+	"S-1-5-32-554": "RU",
+	"S-1-5-32-555": "RD",
+	"S-1-5-32-556": "NO",
+	"S-1-5-32-558": "MU",
+	"S-1-5-32-559": "LU",
+	"S-1-5-32-568": "IS",
+	"S-1-5-32-569": "CY",
+	"S-1-5-32-573": "ER",
+	"S-1-15-2-1":   "AC",
+	"S-1-16-4096":  "LW",
+	"S-1-16-8192":  "ME",
+	"S-1-16-8448":  "MP",
+	"S-1-16-12288": "HI",
+	"S-1-16-16384": "SI",
+}
+
+// Names of well-known security identifiers.
+var wellKnownNames = map[string]string{
+	"S-1-0-0":      "NULL SID",
+	"S-1-1-0":      "Everyone",
+	"S-1-2-0":      "LOCAL",
+	"S-1-3-0":      "CREATOR OWNER",
+	"S-1-3-1":      "CREATOR GROUP",
+	"S-1-3-4":      "OWNER RIGHTS",
+	"S-1-5-2":      `NT AUTHORITY\NETWORK`,
+	"S-1-5-4":      `NT AUTHORITY\INTERACTIVE`,
+	"S-1-5-6":      `NT AUTHORITY\SERVICE`,
+	"S-1-5-7":      `NT AUTHORITY\ANONYMOUS LOGON`,
+	"S-1-5-9":      `NT AUTHORITY\ENTERPRISE DOMAIN CONTROLLERS`,
+	"S-1-5-10":     `NT AUTHORITY\SELF`,
+	"S-1-5-11":     `NT AUTHORITY\Authenticated Users`,
+	"S-1-5-12":     `NT AUTHORITY\RESTRICTED`,
+	"S-1-5-18":     `NT AUTHORITY\SYSTEM`,
+	"S-1-5-19":     `NT AUTHORITY\LOCAL SERVICE`,
+	"S-1-5-20":     `NT AUTHORITY\NETWORK SERVICE`,
+	"S-1-5-32-544": `BUILTIN\Administrators`,
+	"S-1-5-32-545": `BUILTIN\Users`,
+	"S-1-5-32-546": `BUILTIN\Guests`,
+	"S-1-5-32-547": `BUILTIN\Power Users`,
+	"S-1-5-32-551": `BUILTIN\Backup Operators`,
+	"S-1-5-32-555": `BUILTIN\Remote Desktop Users`,
+	"S-1-15-2-1":   `APPLICATION PACKAGE AUTHORITY\ALL APPLICATION PACKAGES`,
+	"S-1-15-2-2":   `APPLICATION PACKAGE AUTHORITY\ALL RESTRICTED APPLICATION PACKAGES`,
+	"S-1-16-4096":  `Mandatory Label\Low Mandatory Level`,
+	"S-1-16-8192":  `Mandatory Label\Medium Mandatory Level`,
+	"S-1-16-12288": `Mandatory Label\High Mandatory Level`,
+	"S-1-16-16384": `Mandatory Label\System Mandatory Level`,
+	"S-1-5-80-956008885-3418522649-1831038044-1853292631-2271478464": `NT SERVICE\TrustedInstaller`,
+}
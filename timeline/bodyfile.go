@@ -0,0 +1,69 @@
+package timeline
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// WriteBodyfile writes entries to w in the Sleuthkit bodyfile format, which
+// can be turned into a timeline with mactime. Each entry produces one line:
+//
+//	MD5|name|inode|mode_as_string|UID|GID|size|atime|mtime|ctime|crtime
+//
+// Timestamps are written as whole seconds since the Unix epoch. Names are
+// suffixed to identify $FILE_NAME and change journal entries and the
+// entries of deleted files. Any | in a name is replaced so that each line
+// has exactly 11 fields.
+func WriteBodyfile(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		mode := "r/rrwxrwxrwx"
+		if e.Directory() {
+			mode = "d/drwxrwxrwx"
+		}
+		_, err := fmt.Fprintf(bw, "0|%s|%s|%s|0|0|%d|%d|%d|%d|%d\n",
+			bodyName(e), inode(e), mode, e.Size,
+			unix(e.Times.Accessed), unix(e.Times.Modified), unix(e.Times.Changed), unix(e.Times.Born))
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// bodyName returns the name of e as it is written to a bodyfile.
+func bodyName(e Entry) string {
+	name := strings.ReplaceAll(e.Path, "|", "_")
+	switch e.Source {
+	case SourceFileName:
+		name += " ($FILE_NAME)"
+	case SourceJournal:
+		name += " ($UsnJrnl: " + e.Reason.Join(",", usn.ReasonFormatBasic) + ")"
+	}
+	if e.Deleted {
+		name += " (deleted)"
+	}
+	return name
+}
+
+// inode returns the MFT segment and sequence numbers of e, which is how
+// NTFS files are commonly identified in bodyfiles.
+func inode(e Entry) string {
+	if !e.ID.IsInt64() {
+		return e.ID.String()
+	}
+	return fmt.Sprintf("%d-%d", e.ID.Segment(), e.ID.Sequence())
+}
+
+// unix returns t in seconds since the Unix epoch, or zero if t is not set.
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
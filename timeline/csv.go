@@ -0,0 +1,47 @@
+package timeline
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// csvHeader holds the column names of CSV timelines.
+var csvHeader = []string{"time", "macb", "source", "type", "path", "id", "size", "attributes", "deleted", "usn"}
+
+// WriteCSV writes events to w as a CSV timeline with a header row. Times
+// are written in UTC in RFC 3339 format with 100 nanosecond precision. The
+// type column holds the reasons of change journal events.
+func WriteCSV(w io.Writer, events []Event) error {
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range events {
+		var reason string
+		if e.Source == SourceJournal {
+			reason = e.Reason.Join("|", usn.ReasonFormatBasic)
+		}
+		err := cw.Write([]string{
+			e.Time.UTC().Format("2006-01-02T15:04:05.0000000Z07:00"),
+			e.MACB.String(),
+			e.Source.String(),
+			reason,
+			e.Path,
+			e.ID.String(),
+			strconv.FormatUint(e.Size, 10),
+			e.Attributes.Join("", fileattr.FormatCode),
+			strconv.FormatBool(e.Deleted),
+			strconv.FormatInt(int64(e.USN), 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
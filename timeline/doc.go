@@ -0,0 +1,12 @@
+// Package timeline merges file system activity from several sources into a
+// single chronological view.
+//
+// Change journal records and the $STANDARD_INFORMATION and $FILE_NAME
+// timestamps of master file table entries are collected as entries. Each
+// entry holds up to four timestamps and can be expanded into events, one
+// for each distinct point in time, in the manner of the Sleuthkit's mactime
+// tool.
+//
+// Entries can be written as a Sleuthkit bodyfile and events can be written
+// as a CSV timeline.
+package timeline
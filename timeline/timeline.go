@@ -0,0 +1,322 @@
+package timeline
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// Source identifies where a timeline entry came from.
+type Source uint8
+
+// Timeline sources.
+const (
+	SourceJournal             Source = iota + 1 // Change journal record
+	SourceStandardInformation                   // $STANDARD_INFORMATION timestamps
+	SourceFileName                              // $FILE_NAME timestamps
+)
+
+// String returns a short name for the source.
+func (s Source) String() string {
+	switch s {
+	case SourceJournal:
+		return "$J"
+	case SourceStandardInformation:
+		return "$SI"
+	case SourceFileName:
+		return "$FN"
+	default:
+		return "unknown"
+	}
+}
+
+// MACB is a set of flags that identify which timestamps of an entry an
+// event represents.
+type MACB uint8
+
+// Timestamp flags.
+const (
+	Modified MACB = 1 << iota
+	Accessed
+	Changed
+	Born
+)
+
+// String returns the flags in the mactime style, such as "m.cb".
+func (m MACB) String() string {
+	b := []byte("....")
+	for i, c := range "macb" {
+		if m&(1<<i) != 0 {
+			b[i] = byte(c)
+		}
+	}
+	return string(b)
+}
+
+// Entry is a single piece of evidence with up to four timestamps.
+type Entry struct {
+	Source     Source
+	ID         fileref.ID
+	Path       string
+	Size       uint64
+	Attributes fileattr.Value
+	Deleted    bool       // The entry belongs to a deleted file
+	Reason     usn.Reason // Journal entries only
+	USN        usn.USN
+	Times      ntfs.MACB // Journal entries only set Changed
+}
+
+// Directory returns true if the entry describes a directory.
+func (e Entry) Directory() bool {
+	return e.Attributes.Match(fileattr.Directory)
+}
+
+// Event is a point in time at which one or more of an entry's timestamps
+// were recorded.
+type Event struct {
+	Time time.Time
+	MACB MACB
+	Entry
+}
+
+// Options control which entries and events are included in a timeline.
+type Options struct {
+	// After and Before limit events to a range of time, inclusive. Zero
+	// values leave the range unbounded.
+	After  time.Time
+	Before time.Time
+
+	// Filter excludes entries whose records do not match. Master file table
+	// entries are matched in their usn.Record form.
+	Filter usn.Filter
+
+	// Format transforms the volume-relative paths of entries.
+	Format usn.PathFormatter
+}
+
+// Timeline collects entries from several sources. It is not safe for
+// concurrent use.
+type Timeline struct {
+	opts    Options
+	entries []Entry
+}
+
+// New returns an empty timeline with the given options.
+func New(opts Options) *Timeline {
+	return &Timeline{opts: opts}
+}
+
+// AddRecord adds a change journal record to the timeline. The record's path
+// should already be resolved.
+func (t *Timeline) AddRecord(r usn.Record) {
+	if !t.opts.Filter.Match(r) {
+		return
+	}
+	path := r.Path
+	if path == "" {
+		path = r.FileName
+	}
+	t.add(Entry{
+		Source:     SourceJournal,
+		ID:         r.FileReferenceNumber,
+		Path:       t.opts.Format.Format(path),
+		Attributes: r.FileAttributes,
+		Reason:     r.Reason,
+		USN:        r.USN,
+		Times:      ntfs.MACB{Changed: r.TimeStamp},
+	})
+}
+
+// AddEntry adds the $STANDARD_INFORMATION and $FILE_NAME timestamps of a
+// master file table entry to the timeline. Paths are resolved with filer,
+// which may be nil.
+//
+// A $FILE_NAME entry is added for each of the file's names, except for DOS
+// 8.3 names.
+func (t *Timeline) AddEntry(e *ntfs.MFTEntry, filer usn.Filer) {
+	record := e.Record()
+	record.Path = resolve(filer, record)
+	if !t.opts.Filter.Match(record) {
+		return
+	}
+
+	var size uint64
+	if s, ok := e.Stream(""); ok {
+		size = s.Size
+	}
+
+	if e.StandardInformation != nil {
+		t.add(Entry{
+			Source:     SourceStandardInformation,
+			ID:         record.FileReferenceNumber,
+			Path:       t.opts.Format.Format(record.Path),
+			Size:       size,
+			Attributes: record.FileAttributes,
+			Deleted:    e.Deleted(),
+			USN:        record.USN,
+			Times:      e.StandardInformation.MACB(),
+		})
+	}
+
+	for _, fn := range e.FileNames {
+		if fn.Namespace == ntfs.NamespaceDOS && len(e.FileNames) > 1 {
+			continue
+		}
+		named := record
+		named.ParentFileReferenceNumber = fn.Parent
+		named.FileName = fn.Name
+		t.add(Entry{
+			Source:     SourceFileName,
+			ID:         record.FileReferenceNumber,
+			Path:       t.opts.Format.Format(resolve(filer, named)),
+			Size:       size,
+			Attributes: record.FileAttributes,
+			Deleted:    e.Deleted(),
+			USN:        record.USN,
+			Times:      fn.MACB(),
+		})
+	}
+}
+
+// resolve returns the path of r.
+func resolve(filer usn.Filer, r usn.Record) string {
+	if filer == nil {
+		return r.FileName
+	}
+	return filer.Path(r)
+}
+
+// add adds e to the timeline if at least one of its timestamps is within
+// range.
+func (t *Timeline) add(e Entry) {
+	for _, ts := range []time.Time{e.Times.Modified, e.Times.Accessed, e.Times.Changed, e.Times.Born} {
+		if t.inRange(ts) {
+			t.entries = append(t.entries, e)
+			return
+		}
+	}
+}
+
+// inRange returns true if ts is set and within the range of the timeline.
+func (t *Timeline) inRange(ts time.Time) bool {
+	if ts.IsZero() {
+		return false
+	}
+	if !t.opts.After.IsZero() && ts.Before(t.opts.After) {
+		return false
+	}
+	if !t.opts.Before.IsZero() && ts.After(t.opts.Before) {
+		return false
+	}
+	return true
+}
+
+// ReadJournal adds the records produced by iter to the timeline. Records
+// without a path have their paths resolved with filer, which may be nil.
+// It returns when iter returns io.EOF or an error, or when ctx is
+// cancelled.
+func (t *Timeline) ReadJournal(ctx context.Context, iter usn.Iter, filer usn.Filer) error {
+	var (
+		buffer  = make([]byte, 1<<20)
+		records []usn.Record
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var err error
+		records, err = iter.Next(buffer, records[:0])
+		for _, r := range records {
+			if r.Path == "" {
+				r.Path = resolve(filer, r)
+			}
+			t.AddRecord(r)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ReadMFT adds the entries of a master file table to the timeline,
+// including the entries of deleted files. Paths are resolved with filer,
+// which may be nil. Records that cannot be read are skipped.
+func (t *Timeline) ReadMFT(ctx context.Context, mft *ntfs.MFT, filer usn.Filer) error {
+	count := mft.RecordCount()
+	for n := uint64(0); n < count; n++ {
+		if n%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		e, err := mft.Entry(n)
+		if err != nil || e.Header.BaseRecord != 0 || len(e.FileNames) == 0 {
+			continue
+		}
+		t.AddEntry(&e, filer)
+	}
+	return nil
+}
+
+// Len returns the number of entries in the timeline.
+func (t *Timeline) Len() int {
+	return len(t.entries)
+}
+
+// Entries returns the entries of the timeline in the order they were
+// added.
+func (t *Timeline) Entries() []Entry {
+	return t.entries
+}
+
+// Events expands the entries of the timeline into events and returns them
+// in chronological order. Timestamps of an entry that share the same time
+// are combined into a single event. Events with the same time are ordered
+// by path and then by source.
+func (t *Timeline) Events() []Event {
+	var events []Event
+	for _, e := range t.entries {
+		var times [4]time.Time
+		var flags [4]MACB
+		n := 0
+		for i, ts := range []time.Time{e.Times.Modified, e.Times.Accessed, e.Times.Changed, e.Times.Born} {
+			if !t.inRange(ts) {
+				continue
+			}
+			j := 0
+			for j < n && !times[j].Equal(ts) {
+				j++
+			}
+			if j == n {
+				times[n] = ts
+				n++
+			}
+			flags[j] |= 1 << i
+		}
+		for j := 0; j < n; j++ {
+			events = append(events, Event{Time: times[j], MACB: flags[j], Entry: e})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c < 0
+		}
+		return a.Source < b.Source
+	})
+	return events
+}
@@ -0,0 +1,199 @@
+package timeline_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/timeline"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+)
+
+var (
+	t0 = time.Date(2022, 3, 1, 10, 0, 0, 500, time.UTC)
+	t1 = t0.Add(time.Hour)
+	t2 = t0.Add(2 * time.Hour)
+	t3 = t0.Add(3 * time.Hour)
+
+	root    = fileref.NewSegment(5, 5)
+	docs    = fileref.NewSegment(30, 1)
+	report  = fileref.NewSegment(31, 2)
+	deleted = fileref.NewSegment(32, 3)
+)
+
+// testFiler resolves paths for the files used in these tests.
+func testFiler(id fileref.ID) (usn.Record, error) {
+	switch id {
+	case root:
+		return usn.Record{FileReferenceNumber: root, ParentFileReferenceNumber: root, FileName: "."}, nil
+	case docs:
+		return usn.Record{FileReferenceNumber: docs, ParentFileReferenceNumber: root, FileName: "Docs"}, nil
+	}
+	return usn.Record{}, usn.ErrNotFound
+}
+
+func reportEntry() *ntfs.MFTEntry {
+	return &ntfs.MFTEntry{
+		Number: 31,
+		Header: ntfs.RecordHeader{Sequence: 2, Flags: ntfs.RecordInUse},
+		StandardInformation: &ntfs.StandardInformation{
+			Created: t0, Modified: t2, Changed: t2, Accessed: t3,
+			Attributes: fileattr.Archive,
+		},
+		FileNames: []ntfs.FileName{
+			{Parent: docs, Created: t0, Modified: t0, Changed: t0, Accessed: t0, Namespace: ntfs.NamespaceDOS, Name: "REPORT~1.DOC"},
+			{Parent: docs, Created: t0, Modified: t0, Changed: t0, Accessed: t0, Namespace: ntfs.NamespaceWin32, Name: "report.docx"},
+		},
+		Streams: []ntfs.Stream{{Size: 1234}},
+	}
+}
+
+func deletedEntry() *ntfs.MFTEntry {
+	return &ntfs.MFTEntry{
+		Number: 32,
+		Header: ntfs.RecordHeader{Sequence: 4},
+		StandardInformation: &ntfs.StandardInformation{
+			Created: t1, Modified: t1, Changed: t1, Accessed: t1,
+		},
+		FileNames: []ntfs.FileName{
+			{Parent: docs, Created: t1, Modified: t1, Changed: t1, Accessed: t1, Namespace: ntfs.NamespaceWin32DOS, Name: "old.txt"},
+		},
+	}
+}
+
+func journalRecord() usn.Record {
+	return usn.Record{
+		FileReferenceNumber:       report,
+		ParentFileReferenceNumber: docs,
+		USN:                       4096,
+		TimeStamp:                 t1,
+		Reason:                    usn.ReasonDataExtend | usn.ReasonClose,
+		FileAttributes:            fileattr.Archive,
+		FileName:                  "report.docx",
+		Path:                      `Docs\report.docx`,
+	}
+}
+
+func TestEvents(t *testing.T) {
+	tl := timeline.New(timeline.Options{})
+	tl.AddEntry(reportEntry(), testFiler)
+	tl.AddRecord(journalRecord())
+
+	if tl.Len() != 3 {
+		t.Fatalf("Len = %d, want 3 ($SI, $FN and $J)", tl.Len())
+	}
+
+	type event struct {
+		time   time.Time
+		macb   string
+		source timeline.Source
+	}
+	want := []event{
+		{t0, "...b", timeline.SourceStandardInformation},
+		{t0, "macb", timeline.SourceFileName},
+		{t1, "..c.", timeline.SourceJournal},
+		{t2, "m.c.", timeline.SourceStandardInformation},
+		{t3, ".a..", timeline.SourceStandardInformation},
+	}
+	events := tl.Events()
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, e := range events {
+		got := event{e.Time, e.MACB.String(), e.Source}
+		if got != want[i] {
+			t.Errorf("event %d = %v, want %v", i, got, want[i])
+		}
+		if e.Path != `Docs\report.docx` {
+			t.Errorf("event %d path = %q", i, e.Path)
+		}
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	tl := timeline.New(timeline.Options{After: t1, Before: t2})
+	tl.AddEntry(reportEntry(), testFiler)
+	tl.AddEntry(deletedEntry(), testFiler)
+	tl.AddRecord(journalRecord())
+
+	// The $FN entry of the report is entirely out of range
+	if tl.Len() != 4 {
+		t.Errorf("Len = %d, want 4", tl.Len())
+	}
+	for _, e := range tl.Events() {
+		if e.Time.Before(t1) || e.Time.After(t2) {
+			t.Errorf("event at %v is out of range", e.Time)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	filter, err := usnfilter.Parse("ext = txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tl := timeline.New(timeline.Options{Filter: filter})
+	tl.AddEntry(reportEntry(), testFiler)
+	tl.AddEntry(deletedEntry(), testFiler)
+	tl.AddRecord(journalRecord())
+
+	for _, e := range tl.Entries() {
+		if e.Path != `Docs\old.txt` || !e.Deleted || e.ID != deleted {
+			t.Errorf("unexpected entry %+v", e)
+		}
+	}
+	if tl.Len() != 2 {
+		t.Errorf("Len = %d, want 2", tl.Len())
+	}
+}
+
+func TestWriteBodyfile(t *testing.T) {
+	tl := timeline.New(timeline.Options{})
+	tl.AddEntry(reportEntry(), testFiler)
+	tl.AddEntry(deletedEntry(), testFiler)
+	tl.AddRecord(journalRecord())
+
+	var buf bytes.Buffer
+	if err := timeline.WriteBodyfile(&buf, tl.Entries()); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := []string{
+		"0|Docs\\report.docx|31-2|r/rrwxrwxrwx|0|0|1234|1646139600|1646136000|1646136000|1646128800",
+		"0|Docs\\report.docx ($FILE_NAME)|31-2|r/rrwxrwxrwx|0|0|1234|1646128800|1646128800|1646128800|1646128800",
+		"0|Docs\\old.txt (deleted)|32-3|r/rrwxrwxrwx|0|0|0|1646132400|1646132400|1646132400|1646132400",
+		"0|Docs\\old.txt ($FILE_NAME) (deleted)|32-3|r/rrwxrwxrwx|0|0|0|1646132400|1646132400|1646132400|1646132400",
+		"0|Docs\\report.docx ($UsnJrnl: DataExtend,Close)|31-2|r/rrwxrwxrwx|0|0|0|0|0|1646132400|0",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(want), buf.String())
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d:\n got %s\nwant %s", i, lines[i], want[i])
+		}
+		if fields := strings.Count(lines[i], "|") + 1; fields != 11 {
+			t.Errorf("line %d has %d fields, want 11", i, fields)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	tl := timeline.New(timeline.Options{})
+	tl.AddRecord(journalRecord())
+
+	var buf bytes.Buffer
+	if err := timeline.WriteCSV(&buf, tl.Events()); err != nil {
+		t.Fatal(err)
+	}
+	want := "time,macb,source,type,path,id,size,attributes,deleted,usn\r\n" +
+		"2022-03-01T11:00:00.0000005Z,..c.,$J,DataExtend|Close,Docs\\report.docx," + report.String() + ",0,A,false,4096\r\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
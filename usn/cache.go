@@ -8,8 +8,14 @@ import (
 	"github.com/gentlemanautomaton/volmgmt/fileref"
 )
 
-// ErrNotFound is returned by cache.Filer when a record cannot be found.
-var ErrNotFound = errors.New("not found")
+var (
+	// ErrNotFound is returned by cache.Filer when a record cannot be found.
+	ErrNotFound = errors.New("not found")
+
+	// ErrReused is returned by cache.Filer when a record cannot be found
+	// because its MFT segment has since been reused by another file.
+	ErrReused = errors.New("MFT segment was reused by another file")
+)
 
 /*
 // FileTable describes a mutable repository of file table records.
@@ -29,15 +35,22 @@ const fileNameSizeEstimate = 32 * 2
 const cacheBufferSize = 8 + (recordV3Size+fileNameSizeEstimate)*2048 // USN + 2048 records
 
 // Cache is a usn change journal cache.
+//
+// The cache holds at most one record for each MFT segment. When a segment
+// is reused by a new file, the record for the previous file is evicted so
+// that its name is not attached to the paths of unrelated files.
 type Cache struct {
-	m      map[fileref.ID]Record
-	buffer [cacheBufferSize]byte
+	m        map[fileref.ID]Record
+	segments map[uint64]fileref.ID // Maps MFT segments to their current file reference numbers
+	format   PathFormatter
+	buffer   [cacheBufferSize]byte
 }
 
 // NewCache prepares a new cache object.
 func NewCache() *Cache {
 	return &Cache{
-		m: make(map[fileref.ID]Record),
+		m:        make(map[fileref.ID]Record),
+		segments: make(map[uint64]fileref.ID),
 	}
 }
 
@@ -59,8 +72,7 @@ func (c *Cache) ReadFrom(ctx context.Context, iter Iter) error {
 			return err
 		}
 		for i := range records {
-			frn := records[i].FileReferenceNumber
-			c.m[frn] = records[i]
+			c.Set(records[i])
 		}
 	}
 }
@@ -72,8 +84,37 @@ func (c *Cache) Get(frn fileref.ID) (record Record, ok bool) {
 }
 
 // Set updates the record for the given file reference number.
+//
+// If the cache holds a record for an older file in the same MFT segment,
+// that record is evicted. If the cache holds a record for a newer file in
+// the same segment, r is stale and is discarded.
 func (c *Cache) Set(r Record) {
-	c.m[r.FileReferenceNumber] = r
+	frn := r.FileReferenceNumber
+	if frn.IsInt64() {
+		segment := frn.Segment()
+		if current, ok := c.segments[segment]; ok && current != frn {
+			if current.NewerSequence(frn) {
+				return
+			}
+			delete(c.m, current)
+		}
+		c.segments[segment] = frn
+	}
+	c.m[frn] = r
+}
+
+// Reused reports whether the MFT segment referred to by frn has been reused
+// by a newer file. If it has, the file reference number of the newer file
+// is returned.
+func (c *Cache) Reused(frn fileref.ID) (current fileref.ID, reused bool) {
+	if !frn.IsInt64() {
+		return fileref.ID{}, false
+	}
+	current, ok := c.segments[frn.Segment()]
+	if !ok || current == frn || !current.NewerSequence(frn) {
+		return fileref.ID{}, false
+	}
+	return current, true
 }
 
 // Size returns the number of records in the cache
@@ -81,11 +122,24 @@ func (c *Cache) Size() int {
 	return len(c.m)
 }
 
+// SetPathFormatter causes the paths of records returned by Records to be
+// transformed by f. Pass nil to return volume-relative paths.
+func (c *Cache) SetPathFormatter(f PathFormatter) {
+	c.format = f
+}
+
 // Filer is a Filer that uses the cache to retrieve values.
+//
+// If frn refers to an MFT segment that has been reused by a newer file,
+// ErrReused is returned.
 func (c *Cache) Filer(frn fileref.ID) (record Record, err error) {
 	record, ok := c.m[frn]
 	if !ok {
-		err = ErrNotFound
+		if _, reused := c.Reused(frn); reused {
+			err = ErrReused
+		} else {
+			err = ErrNotFound
+		}
 	}
 	return
 }
@@ -96,15 +150,7 @@ func (c *Cache) Records() []Record {
 	filer := Filer(c.Filer)
 	records := make([]Record, 0, len(c.m))
 	for _, record := range c.m {
-		record.Path = record.FileName
-		if !record.ParentFileReferenceNumber.IsZero() {
-			parents, pErr := filer.Parents(record)
-			if pErr == nil {
-				for p := range parents {
-					record.Path = parents[p].FileName + `\` + record.Path
-				}
-			}
-		}
+		record.Path = c.format.Format(filer.Path(record))
 		records = append(records, record)
 	}
 	return records
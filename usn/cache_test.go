@@ -0,0 +1,68 @@
+package usn_test
+
+import (
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+func dirRecord(frn, parent fileref.ID, name string) usn.Record {
+	return usn.Record{
+		FileReferenceNumber:       frn,
+		ParentFileReferenceNumber: parent,
+		FileAttributes:            fileattr.Directory,
+		FileName:                  name,
+	}
+}
+
+func TestCacheSegmentReuse(t *testing.T) {
+	var (
+		root    = fileref.NewSegment(5, 5)
+		oldDir  = fileref.NewSegment(100, 1)
+		newDir  = fileref.NewSegment(100, 2)
+		oldFile = usn.Record{ParentFileReferenceNumber: oldDir, FileName: "old.txt"}
+		newFile = usn.Record{ParentFileReferenceNumber: newDir, FileName: "new.txt"}
+	)
+
+	cache := usn.NewCache()
+	cache.Set(dirRecord(root, root, "."))
+	cache.Set(dirRecord(oldDir, root, "Deleted"))
+
+	filer := usn.Filer(cache.Filer)
+	if got, want := filer.Path(oldFile), `Deleted\old.txt`; got != want {
+		t.Fatalf("path before reuse is %q, want %q", got, want)
+	}
+
+	// The segment is reused by a new directory
+	cache.Set(dirRecord(newDir, root, "Created"))
+
+	if got := cache.Size(); got != 2 {
+		t.Errorf("cache holds %d records after reuse, want 2", got)
+	}
+	if current, reused := cache.Reused(oldDir); !reused || current != newDir {
+		t.Errorf("Reused(%s) = %s, %t, want %s, true", oldDir, current, reused, newDir)
+	}
+	if _, reused := cache.Reused(newDir); reused {
+		t.Errorf("Reused(%s) reported reuse of the current file", newDir)
+	}
+	if _, err := cache.Filer(oldDir); err != usn.ErrReused {
+		t.Errorf("Filer(%s) returned %v, want %v", oldDir, err, usn.ErrReused)
+	}
+	if got, want := filer.Path(oldFile), `old.txt`; got != want {
+		t.Errorf("path of orphaned file is %q, want %q", got, want)
+	}
+	if got, want := filer.Path(newFile), `Created\new.txt`; got != want {
+		t.Errorf("path of new file is %q, want %q", got, want)
+	}
+
+	// Stale records for older files are discarded
+	cache.Set(dirRecord(oldDir, root, "Deleted"))
+	if _, err := cache.Filer(newDir); err != nil {
+		t.Errorf("stale record evicted the current one: %v", err)
+	}
+	if _, ok := cache.Get(oldDir); ok {
+		t.Error("stale record was stored in the cache")
+	}
+}
@@ -3,11 +3,7 @@ package usn
 import (
 	"errors"
 	"io"
-	"syscall"
 	"unsafe"
-
-	"github.com/gentlemanautomaton/volmgmt/hsync"
-	"github.com/gentlemanautomaton/volmgmt/volumeapi"
 )
 
 var (
@@ -30,53 +26,31 @@ var (
 // TODO: Attempt to merge Enumerator and Cursor into one type.
 type Cursor struct {
 	data       RawJournalData
-	h          *hsync.Handle
+	dev        Device
 	usn        USN
 	processor  Processor
 	reasonMask Reason
 	filter     Filter
 	filer      Filer
+	format     PathFormatter
 	total      Stats
 	filtered   Stats
 	// TODO: Consider adding some sort of buffer (or let the user provide one)
 }
 
-// NewCursor returns a USN change journal cursor for the volume described by
-// path. Only records matching the provided reason mask will be returned.
-//
-// If filer is non-nil, it will be used to return records with a populated
-// path field.
+// NewCursorWithDevice returns a USN Journal cursor for the volume served by
+// device.
 //
-// TODO: Make processors, filters and filers fulfill a CursorOption interface, then
-// accept a variadic set of options.
-func NewCursor(path string, reasonMask Reason, processor Processor, filter Filter, filer Filer) (cursor *Cursor, err error) {
-	const (
-		access = syscall.GENERIC_READ
-		mode   = syscall.FILE_SHARE_READ | syscall.FILE_SHARE_WRITE
-	)
-
-	h, err := volumeapi.Handle(path, access, mode)
-	if err != nil {
-		return nil, err
-	}
-
-	return NewCursorWithHandle(hsync.New(h), processor, reasonMask, filter, filer)
-}
-
-// NewCursorWithHandle returns a USN Journal cursor for the volume with the
-// given handle.
-//
-// When the cursor is closed its associated handle will also be closed. When
-// providing an existing handle that will be used elsewhere be sure to
-// clone it first.
-func NewCursorWithHandle(handle *hsync.Handle, processor Processor, reasonMask Reason, filter Filter, filer Filer) (*Cursor, error) {
-	data, err := QueryJournal(handle.Handle())
+// When the cursor is closed its device will also be closed. When providing a
+// device that will be used elsewhere be sure to clone it first.
+func NewCursorWithDevice(device Device, processor Processor, reasonMask Reason, filter Filter, filer Filer) (*Cursor, error) {
+	data, err := device.QueryJournal()
 	if err != nil {
 		return nil, err
 	}
 
 	return &Cursor{
-		h:          handle,
+		dev:        device,
 		data:       data,
 		processor:  processor,
 		reasonMask: reasonMask,
@@ -101,7 +75,7 @@ func (c *Cursor) Read(p []byte) (n int, err error) {
 		MinMajorVersion: 2,
 		MaxMajorVersion: 3,
 	}
-	length, err := ReadJournal(c.h.Handle(), opts, p)
+	length, err := c.dev.ReadJournal(opts, p)
 	n = int(length)
 	if err == nil && length >= 8 {
 		// Check the next USN that was returned at the start of the buffer. If it
@@ -240,9 +214,18 @@ func (c *Cursor) Stats() (total, filtered Stats) {
 	return
 }
 
+// SetPathFormatter causes the paths of records returned by Next to be
+// transformed by f. Filters are applied before formatting takes place. Pass
+// nil to return volume-relative paths.
+//
+// Paths are only populated when the cursor has a filer.
+func (c *Cursor) SetPathFormatter(f PathFormatter) {
+	c.format = f
+}
+
 // Close releases any resources consumed by the journal.
 func (c *Cursor) Close() {
-	c.h.Close()
+	c.dev.Close()
 }
 
 // process performs record post-processing after it has been marshaled.
@@ -250,13 +233,7 @@ func (c *Cursor) process(record *Record, filter Filter, filer Filer) (matched bo
 	c.processor.Process(*record)
 
 	if filer != nil && !record.ParentFileReferenceNumber.IsZero() {
-		record.Path = record.FileName
-		parents, pErr := filer.Parents(*record)
-		if pErr == nil {
-			for p := range parents {
-				record.Path = parents[p].FileName + `\` + record.Path
-			}
-		}
+		record.Path = filer.Path(*record)
 	}
 
 	c.total.Add(record)
@@ -264,6 +241,7 @@ func (c *Cursor) process(record *Record, filter Filter, filer Filer) (matched bo
 	if filter == nil || filter(*record) {
 		matched = true
 		c.filtered.Add(record)
+		record.Path = c.format.Format(record.Path)
 	}
 	return
 }
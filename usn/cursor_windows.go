@@ -0,0 +1,40 @@
+package usn
+
+import (
+	"syscall"
+
+	"github.com/gentlemanautomaton/volmgmt/hsync"
+	"github.com/gentlemanautomaton/volmgmt/volumeapi"
+)
+
+// NewCursor returns a USN change journal cursor for the volume described by
+// path. Only records matching the provided reason mask will be returned.
+//
+// If filer is non-nil, it will be used to return records with a populated
+// path field.
+//
+// TODO: Make processors, filters and filers fulfill a CursorOption interface, then
+// accept a variadic set of options.
+func NewCursor(path string, reasonMask Reason, processor Processor, filter Filter, filer Filer) (cursor *Cursor, err error) {
+	const (
+		access = syscall.GENERIC_READ
+		mode   = syscall.FILE_SHARE_READ | syscall.FILE_SHARE_WRITE
+	)
+
+	h, err := volumeapi.Handle(path, access, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCursorWithHandle(hsync.New(h), processor, reasonMask, filter, filer)
+}
+
+// NewCursorWithHandle returns a USN Journal cursor for the volume with the
+// given handle.
+//
+// When the cursor is closed its associated handle will also be closed. When
+// providing an existing handle that will be used elsewhere be sure to
+// clone it first.
+func NewCursorWithHandle(handle *hsync.Handle, processor Processor, reasonMask Reason, filter Filter, filer Filer) (*Cursor, error) {
+	return NewCursorWithDevice(handleDevice{h: handle}, processor, reasonMask, filter, filer)
+}
@@ -0,0 +1,23 @@
+package usn
+
+// Device carries out change journal requests for a volume. Journals,
+// cursors, enumerators and monitors make all of their requests through a
+// device, so volumes that are not mounted by the operating system can be
+// read through the same types as live volumes.
+//
+// ReadJournal and EnumData fill buffer in the form returned by the
+// FSCTL_READ_USN_JOURNAL and FSCTL_ENUM_USN_DATA control codes: a 64 bit
+// update sequence number or file reference number that continues the
+// request, followed by records. EnumData returns io.EOF when there are no
+// more records to enumerate.
+//
+// A device and its clones share the underlying volume, which is released
+// once all of them have been closed.
+type Device interface {
+	CreateJournal(maxSize, allocDelta uint64) error
+	QueryJournal() (RawJournalData, error)
+	ReadJournal(opts RawReadOptions, buffer []byte) (length uint32, err error)
+	EnumData(opts RawEnumOptions, buffer []byte) (length uint32, err error)
+	Clone() Device
+	Close() error
+}
@@ -0,0 +1,41 @@
+package usn
+
+import (
+	"io"
+	"syscall"
+
+	"github.com/gentlemanautomaton/volmgmt/hsync"
+)
+
+// handleDevice is a device that issues control codes to a volume handle.
+type handleDevice struct {
+	h *hsync.Handle
+}
+
+func (d handleDevice) CreateJournal(maxSize, allocDelta uint64) error {
+	return CreateJournal(d.h.Handle(), maxSize, allocDelta)
+}
+
+func (d handleDevice) QueryJournal() (RawJournalData, error) {
+	return QueryJournal(d.h.Handle())
+}
+
+func (d handleDevice) ReadJournal(opts RawReadOptions, buffer []byte) (uint32, error) {
+	return ReadJournal(d.h.Handle(), opts, buffer)
+}
+
+func (d handleDevice) EnumData(opts RawEnumOptions, buffer []byte) (uint32, error) {
+	length, err := EnumData(d.h.Handle(), opts, buffer)
+	if err == syscall.ERROR_HANDLE_EOF {
+		err = io.EOF
+	}
+	return length, err
+}
+
+func (d handleDevice) Clone() Device {
+	return handleDevice{h: d.h.Clone()}
+}
+
+func (d handleDevice) Close() error {
+	return d.h.Close()
+}
@@ -2,11 +2,7 @@ package usn
 
 import (
 	"io"
-	"syscall"
 	"unsafe"
-
-	"github.com/gentlemanautomaton/volmgmt/hsync"
-	"github.com/gentlemanautomaton/volmgmt/volumeapi"
 )
 
 // Enumerator reads records from a master file table.
@@ -14,43 +10,26 @@ import (
 // TODO: Attempt to merge Enumerator and Cursor into one type.
 type Enumerator struct {
 	data   RawJournalData
-	h      *hsync.Handle
+	dev    Device
 	pos    int64 // File reference number or USN
 	filter Filter
 	low    USN
 	high   USN
 }
 
-// NewEnumerator returns a master file table enumerator for the volume described
-// by path.
-func NewEnumerator(path string, filter Filter, low, high USN) (enumerator *Enumerator, err error) {
-	const (
-		access = syscall.GENERIC_READ
-		mode   = syscall.FILE_SHARE_READ | syscall.FILE_SHARE_WRITE
-	)
-
-	h, err := volumeapi.Handle(path, access, mode)
-	if err != nil {
-		return nil, err
-	}
-
-	return NewEnumeratorWithHandle(hsync.New(h), filter, low, high)
-}
-
-// NewEnumeratorWithHandle returns a master file table enumerator for the
-// volume with the given handle.
+// NewEnumeratorWithDevice returns a master file table enumerator for the
+// volume served by device.
 //
-// When the enumerator is closed its associated handle will also be closed. When
-// providing an existing handle that will be used elsewhere be sure to
-// clone it first.
-func NewEnumeratorWithHandle(handle *hsync.Handle, filter Filter, low, high USN) (*Enumerator, error) {
-	data, err := QueryJournal(handle.Handle())
+// When the enumerator is closed its device will also be closed. When
+// providing a device that will be used elsewhere be sure to clone it first.
+func NewEnumeratorWithDevice(device Device, filter Filter, low, high USN) (*Enumerator, error) {
+	data, err := device.QueryJournal()
 	if err != nil {
 		return nil, err
 	}
 
 	return &Enumerator{
-		h:      handle,
+		dev:    device,
 		data:   data,
 		filter: filter,
 		low:    low,
@@ -71,7 +50,7 @@ func (e *Enumerator) Read(p []byte) (n int, err error) {
 		MinMajorVersion:          2,
 		MaxMajorVersion:          3,
 	}
-	length, err := EnumData(e.h.Handle(), opts, p)
+	length, err := e.dev.EnumData(opts, p)
 	n = int(length)
 	if err == nil && length >= 8 {
 		// Check the next USN that was returned at the start of the buffer. If it
@@ -92,7 +71,7 @@ func (e *Enumerator) Read(p []byte) (n int, err error) {
 			return 0, io.EOF
 		}
 	}
-	if err == syscall.ERROR_HANDLE_EOF {
+	if err == io.EOF {
 		return 0, io.EOF
 	}
 	return
@@ -136,5 +115,5 @@ func (e *Enumerator) Next(buffer []byte, data []Record) (records []Record, err e
 
 // Close releases any resources consumed by the enumerator.
 func (e *Enumerator) Close() {
-	e.h.Close()
+	e.dev.Close()
 }
@@ -0,0 +1,34 @@
+package usn
+
+import (
+	"syscall"
+
+	"github.com/gentlemanautomaton/volmgmt/hsync"
+	"github.com/gentlemanautomaton/volmgmt/volumeapi"
+)
+
+// NewEnumerator returns a master file table enumerator for the volume described
+// by path.
+func NewEnumerator(path string, filter Filter, low, high USN) (enumerator *Enumerator, err error) {
+	const (
+		access = syscall.GENERIC_READ
+		mode   = syscall.FILE_SHARE_READ | syscall.FILE_SHARE_WRITE
+	)
+
+	h, err := volumeapi.Handle(path, access, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEnumeratorWithHandle(hsync.New(h), filter, low, high)
+}
+
+// NewEnumeratorWithHandle returns a master file table enumerator for the
+// volume with the given handle.
+//
+// When the enumerator is closed its associated handle will also be closed. When
+// providing an existing handle that will be used elsewhere be sure to
+// clone it first.
+func NewEnumeratorWithHandle(handle *hsync.Handle, filter Filter, low, high USN) (*Enumerator, error) {
+	return NewEnumeratorWithDevice(handleDevice{h: handle}, filter, low, high)
+}
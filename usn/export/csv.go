@@ -0,0 +1,114 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// CSVEncoder writes records as comma-separated values. A header row that
+// names each column is written before the first record.
+type CSVEncoder struct {
+	w      *csv.Writer
+	fields []Field
+	row    []string
+	header bool
+}
+
+// NewCSVEncoder returns an encoder that writes records to w as CSV. Only
+// the given fields will be written, in the order given. If fields is empty,
+// all fields will be written.
+func NewCSVEncoder(w io.Writer, fields ...Field) *CSVEncoder {
+	if len(fields) == 0 {
+		fields = Fields
+	}
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true // RFC 4180
+	return &CSVEncoder{
+		w:      cw,
+		fields: fields,
+		row:    make([]string, len(fields)),
+	}
+}
+
+// Encode writes r to the stream as a single row. Rows are buffered until
+// Flush is called.
+func (e *CSVEncoder) Encode(r usn.Record) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	for i, field := range e.fields {
+		e.row[i] = text(&r, field)
+	}
+	return e.w.Write(e.row)
+}
+
+// Flush writes any buffered rows to the underlying writer. If no records
+// have been encoded the header row is written.
+func (e *CSVEncoder) Flush() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *CSVEncoder) writeHeader() error {
+	if e.header {
+		return nil
+	}
+	e.header = true
+	for i, field := range e.fields {
+		e.row[i] = string(field)
+	}
+	return e.w.Write(e.row)
+}
+
+// CSVDecoder reads records from comma-separated values. The first row must
+// be a header row that names each column.
+type CSVDecoder struct {
+	r      *csv.Reader
+	fields []Field
+}
+
+// NewCSVDecoder returns a decoder that reads CSV records from r.
+func NewCSVDecoder(r io.Reader) *CSVDecoder {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	return &CSVDecoder{r: cr}
+}
+
+// Decode reads the next row from the stream and stores it in r.
+func (d *CSVDecoder) Decode(r *usn.Record) error {
+	if d.fields == nil {
+		header, err := d.r.Read()
+		if err != nil {
+			return err
+		}
+		fields := make([]Field, len(header))
+		for i, name := range header {
+			fields[i] = Field(strings.ToLower(strings.TrimSpace(name)))
+			if !fields[i].Valid() {
+				return fmt.Errorf("%w: %s", ErrUnknownField, name)
+			}
+		}
+		d.fields = fields
+	}
+
+	row, err := d.r.Read()
+	if err != nil {
+		return err
+	}
+
+	*r = usn.Record{}
+	for i, field := range d.fields {
+		if err := setText(r, field, row[i]); err != nil {
+			line, _ := d.r.FieldPos(i)
+			return fmt.Errorf("line %d: %s: %v", line, field, err)
+		}
+	}
+	return nil
+}
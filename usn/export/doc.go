@@ -0,0 +1,37 @@
+// Package export encodes and decodes USN records in formats that are
+// suitable for processing by other programs.
+//
+// Records can be written as newline-delimited JSON (NDJSON), with one
+// object per line, or as CSV as described by RFC 4180, with a header row
+// that names each column. Both formats share a single schema, which is
+// identified by SchemaVersion. Within a schema version fields may be added
+// but existing fields will not be renamed, removed or changed.
+//
+// # Schema
+//
+// The fields of schema version 1 are listed below, along with the form
+// they take in JSON. In CSV every field is written as text; numbers are
+// written in decimal and lists are joined with |.
+//
+//	usn          number    the update sequence number of the record
+//	time         string    the time of the record in RFC 3339 form, in UTC with nanoseconds
+//	id           string    the file reference number, in decimal
+//	parent_id    string    the parent file reference number, in decimal
+//	reason       [string]  the reason codes, as basic names such as "FileCreate"
+//	source       [string]  the source information, as names such as "DataManagement"
+//	attributes   [string]  the file attributes, as names such as "Hidden"
+//	security_id  number    the security identifier of the record
+//	version      string    the major and minor version of the record, such as "3.0"
+//	name         string    the file name
+//	path         string    the resolved path of the file
+//
+// Flags that do not have a name are written as hexadecimal values such as
+// "0x00000100". Identifiers are written as strings because 128-bit values
+// cannot be represented by JSON numbers.
+//
+// Each JSON object also includes a "schema" member that holds the schema
+// version. Decoders reject records written with a newer schema version.
+//
+// Encoders can be limited to a subset of fields. Decoders accept any subset
+// of fields, leaving the others at their zero values.
+package export
@@ -0,0 +1,138 @@
+package export_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usn/export"
+	"github.com/gentlemanautomaton/volmgmt/usnsource"
+)
+
+var records = []usn.Record{
+	{
+		MajorVersion:              3,
+		FileReferenceNumber:       fileref.NewSegment(0x1234, 3),
+		ParentFileReferenceNumber: fileref.NewSegment(5, 5),
+		USN:                       123456789,
+		TimeStamp:                 time.Date(2021, 3, 4, 5, 6, 7, 891011121, time.UTC),
+		Reason:                    usn.ReasonFileCreate | usn.ReasonClose,
+		SourceInfo:                usnsource.Local,
+		FileAttributes:            fileattr.Archive | fileattr.Hidden,
+		FileName:                  `report, "final".docx`,
+		Path:                      `Users\Public\report, "final".docx`,
+	},
+	{
+		MajorVersion:              2,
+		FileReferenceNumber:       fileref.New128(-1, 7),
+		ParentFileReferenceNumber: fileref.New64(5),
+		USN:                       1,
+		TimeStamp:                 time.Date(1999, 12, 31, 23, 59, 59, 0, time.UTC),
+		Reason:                    usn.ReasonRename | 0x01000000,
+		SourceInfo:                usnsource.DataManagement | usnsource.AuxilaryData,
+		SecurityID:                42,
+		FileAttributes:            fileattr.Directory,
+		FileName:                  "multi\nline",
+	},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []export.Format{export.JSON, export.CSV} {
+		t.Run(format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			enc, err := export.NewEncoder(format, &buf)
+			if err != nil {
+				t.Fatalf("NewEncoder returned an error: %v", err)
+			}
+			for _, record := range records {
+				if err := enc.Encode(record); err != nil {
+					t.Fatalf("Encode returned an error: %v", err)
+				}
+			}
+			if err := enc.Flush(); err != nil {
+				t.Fatalf("Flush returned an error: %v", err)
+			}
+
+			dec, err := export.NewDecoder(format, &buf)
+			if err != nil {
+				t.Fatalf("NewDecoder returned an error: %v", err)
+			}
+			for i, want := range records {
+				var got usn.Record
+				if err := dec.Decode(&got); err != nil {
+					t.Fatalf("record %d: Decode returned an error: %v", i, err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("record %d:\ngot  %+v\nwant %+v", i, got, want)
+				}
+			}
+			var extra usn.Record
+			if err := dec.Decode(&extra); err != io.EOF {
+				t.Errorf("Decode at end of stream returned %v, want %v", err, io.EOF)
+			}
+		})
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	var buf bytes.Buffer
+	enc := export.NewJSONEncoder(&buf)
+	if err := enc.Encode(records[0]); err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"schema":1,"usn":123456789,"time":"2021-03-04T05:06:07.891011121Z","id":"844424930136628",` +
+		`"parent_id":"1407374883553285","reason":["FileCreate","Close"],"source":[],"attributes":["Hidden","Archive"],` +
+		`"security_id":0,"version":"3.0","name":"report, \"final\".docx","path":"Users\\Public\\report, \"final\".docx"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestFieldSelection(t *testing.T) {
+	fields, err := export.ParseFields("id, Path")
+	if err != nil {
+		t.Fatalf("ParseFields returned an error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	enc := export.NewCSVEncoder(&buf, fields...)
+	if err := enc.Encode(records[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	const want = "id,path\r\n844424930136628,\"Users\\Public\\report, \"\"final\"\".docx\"\r\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+
+	if _, err := export.ParseFields("id,bogus"); !errors.Is(err, export.ErrUnknownField) {
+		t.Errorf("ParseFields with an unknown field returned %v", err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	var r usn.Record
+	dec := export.NewJSONDecoder(strings.NewReader(`{"schema":2,"id":"5"}`))
+	if err := dec.Decode(&r); !errors.Is(err, export.ErrUnsupportedSchema) {
+		t.Errorf("newer schema returned %v, want %v", err, export.ErrUnsupportedSchema)
+	}
+
+	csvDec := export.NewCSVDecoder(strings.NewReader("id,bogus\r\n5,6\r\n"))
+	if err := csvDec.Decode(&r); !errors.Is(err, export.ErrUnknownField) {
+		t.Errorf("unknown column returned %v, want %v", err, export.ErrUnknownField)
+	}
+
+	csvDec = export.NewCSVDecoder(strings.NewReader("reason\r\nBogus\r\n"))
+	if err := csvDec.Decode(&r); err == nil {
+		t.Error("invalid reason did not return an error")
+	}
+}
@@ -0,0 +1,213 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnsource"
+)
+
+// SchemaVersion is the version of the schema used by the encoders in this
+// package.
+const SchemaVersion = 1
+
+// ErrUnknownField is returned when a field name is not part of the schema.
+var ErrUnknownField = errors.New("unknown field")
+
+// Field identifies a field within the export schema.
+type Field string
+
+// Schema fields.
+const (
+	FieldUSN        Field = "usn"
+	FieldTime       Field = "time"
+	FieldID         Field = "id"
+	FieldParentID   Field = "parent_id"
+	FieldReason     Field = "reason"
+	FieldSource     Field = "source"
+	FieldAttributes Field = "attributes"
+	FieldSecurityID Field = "security_id"
+	FieldVersion    Field = "version"
+	FieldName       Field = "name"
+	FieldPath       Field = "path"
+)
+
+// Fields lists every field in the schema, in the order in which they are
+// written by default.
+var Fields = []Field{
+	FieldUSN,
+	FieldTime,
+	FieldID,
+	FieldParentID,
+	FieldReason,
+	FieldSource,
+	FieldAttributes,
+	FieldSecurityID,
+	FieldVersion,
+	FieldName,
+	FieldPath,
+}
+
+// ParseFields interprets s as a comma-separated list of field names. If s
+// is empty, all fields are returned.
+func ParseFields(s string) ([]Field, error) {
+	if strings.TrimSpace(s) == "" {
+		return Fields, nil
+	}
+	var fields []Field
+	for _, name := range strings.Split(s, ",") {
+		field := Field(strings.ToLower(strings.TrimSpace(name)))
+		if !field.Valid() {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownField, name)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// Valid returns true if f is part of the schema.
+func (f Field) Valid() bool {
+	for _, field := range Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the value of field f within r in its JSON form.
+func value(r *usn.Record, f Field) any {
+	switch f {
+	case FieldUSN:
+		return int64(r.USN)
+	case FieldTime:
+		return r.TimeStamp.UTC().Format(time.RFC3339Nano)
+	case FieldID:
+		return r.FileReferenceNumber.String()
+	case FieldParentID:
+		return r.ParentFileReferenceNumber.String()
+	case FieldReason:
+		return flagNames(uint32(r.Reason), func(flag uint32) (string, bool) {
+			name, ok := usn.ReasonFormatBasic[usn.Reason(flag)]
+			return name, ok
+		})
+	case FieldSource:
+		return flagNames(uint32(r.SourceInfo), func(flag uint32) (string, bool) {
+			name, ok := usnsource.FormatGo[usnsource.Info(flag)]
+			return name, ok
+		})
+	case FieldAttributes:
+		return flagNames(uint32(r.FileAttributes), func(flag uint32) (string, bool) {
+			name, ok := fileattr.FormatGo[fileattr.Value(flag)]
+			return name, ok
+		})
+	case FieldSecurityID:
+		return r.SecurityID
+	case FieldVersion:
+		return fmt.Sprintf("%d.%d", r.MajorVersion, r.MinorVersion)
+	case FieldName:
+		return r.FileName
+	case FieldPath:
+		return r.Path
+	default:
+		return nil
+	}
+}
+
+// text returns the value of field f within r in its CSV form.
+func text(r *usn.Record, f Field) string {
+	switch v := value(r, f).(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, "|")
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	default:
+		return ""
+	}
+}
+
+// setText parses s as the CSV form of field f and stores it in r.
+func setText(r *usn.Record, f Field, s string) error {
+	switch f {
+	case FieldUSN:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		r.USN = usn.USN(n)
+	case FieldTime:
+		if s == "" {
+			r.TimeStamp = time.Time{}
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+		r.TimeStamp = t
+	case FieldID:
+		return r.FileReferenceNumber.UnmarshalText([]byte(s))
+	case FieldParentID:
+		return r.ParentFileReferenceNumber.UnmarshalText([]byte(s))
+	case FieldReason:
+		return r.Reason.UnmarshalText([]byte(s))
+	case FieldSource:
+		return r.SourceInfo.UnmarshalText([]byte(s))
+	case FieldAttributes:
+		return r.FileAttributes.UnmarshalText([]byte(s))
+	case FieldSecurityID:
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return err
+		}
+		r.SecurityID = uint32(n)
+	case FieldVersion:
+		major, minor, ok := strings.Cut(s, ".")
+		if !ok {
+			return fmt.Errorf("invalid record version: %s", s)
+		}
+		maj, err := strconv.ParseUint(major, 10, 16)
+		if err != nil {
+			return err
+		}
+		min, err := strconv.ParseUint(minor, 10, 16)
+		if err != nil {
+			return err
+		}
+		r.MajorVersion, r.MinorVersion = uint16(maj), uint16(min)
+	case FieldName:
+		r.FileName = s
+	case FieldPath:
+		r.Path = s
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownField, f)
+	}
+	return nil
+}
+
+// flagNames returns the names of each flag set in v. Flags without a name
+// are returned as hexadecimal values.
+func flagNames(v uint32, lookup func(flag uint32) (string, bool)) []string {
+	names := []string{}
+	for i := 0; i < 32; i++ {
+		flag := uint32(1) << uint32(i)
+		if v&flag == 0 {
+			continue
+		}
+		if name, ok := lookup(flag); ok {
+			names = append(names, name)
+		} else {
+			names = append(names, fmt.Sprintf("0x%08x", flag))
+		}
+	}
+	return names
+}
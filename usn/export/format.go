@@ -0,0 +1,99 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// ErrTextFormat is returned by NewEncoder and NewDecoder for the text
+// format, which is written by each command in its own way.
+var ErrTextFormat = errors.New("the text format is not handled by the export package")
+
+// Format identifies an output format.
+type Format int
+
+// Output formats.
+const (
+	Text Format = iota // Human-readable text
+	JSON               // Newline-delimited JSON
+	CSV                // Comma-separated values with a header row
+)
+
+// ParseFormat interprets the given string as an output format.
+func ParseFormat(format string) (Format, error) {
+	switch strings.ToLower(format) {
+	case "", "text", "txt":
+		return Text, nil
+	case "json", "ndjson", "jsonl":
+		return JSON, nil
+	case "csv":
+		return CSV, nil
+	default:
+		return Text, fmt.Errorf("unsupported or unknown output format: %s", format)
+	}
+}
+
+// String returns a string representation of the format.
+func (f Format) String() string {
+	switch f {
+	case Text:
+		return "text"
+	case JSON:
+		return "json"
+	case CSV:
+		return "csv"
+	default:
+		return fmt.Sprintf("format(%d)", int(f))
+	}
+}
+
+// Encoder writes records to an output stream.
+type Encoder interface {
+	// Encode writes r to the stream.
+	Encode(r usn.Record) error
+
+	// Flush writes any buffered data to the underlying writer.
+	Flush() error
+}
+
+// Decoder reads records from an input stream.
+type Decoder interface {
+	// Decode reads the next record from the stream and stores it in r.
+	// It returns io.EOF when there are no more records.
+	Decode(r *usn.Record) error
+}
+
+// NewEncoder returns an encoder that writes records to w in the given
+// format. Only the given fields will be written. If fields is empty, all
+// fields will be written.
+func NewEncoder(format Format, w io.Writer, fields ...Field) (Encoder, error) {
+	switch format {
+	case JSON:
+		return NewJSONEncoder(w, fields...), nil
+	case CSV:
+		return NewCSVEncoder(w, fields...), nil
+	case Text:
+		return nil, ErrTextFormat
+	default:
+		return nil, fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// NewDecoder returns a decoder that reads records from r in the given
+// format.
+func NewDecoder(format Format, r io.Reader) (Decoder, error) {
+	switch format {
+	case JSON:
+		return NewJSONDecoder(r), nil
+	case CSV:
+		return NewCSVDecoder(r), nil
+	case Text:
+		return nil, ErrTextFormat
+	default:
+		return nil, fmt.Errorf("unsupported input format: %s", format)
+	}
+}
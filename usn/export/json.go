@@ -0,0 +1,129 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// ErrUnsupportedSchema is returned when a record was written with a schema
+// version that is newer than SchemaVersion.
+var ErrUnsupportedSchema = errors.New("unsupported export schema version")
+
+// JSONEncoder writes records as newline-delimited JSON objects.
+type JSONEncoder struct {
+	w      io.Writer
+	fields []Field
+	buf    bytes.Buffer
+}
+
+// NewJSONEncoder returns an encoder that writes records to w as
+// newline-delimited JSON. Only the given fields will be written. If fields
+// is empty, all fields will be written.
+func NewJSONEncoder(w io.Writer, fields ...Field) *JSONEncoder {
+	if len(fields) == 0 {
+		fields = Fields
+	}
+	return &JSONEncoder{w: w, fields: fields}
+}
+
+// Encode writes r to the stream as a single line of JSON.
+func (e *JSONEncoder) Encode(r usn.Record) error {
+	e.buf.Reset()
+	fmt.Fprintf(&e.buf, `{"schema":%d`, SchemaVersion)
+	for _, field := range e.fields {
+		data, err := json.Marshal(value(&r, field))
+		if err != nil {
+			return err
+		}
+		e.buf.WriteString(`,"`)
+		e.buf.WriteString(string(field))
+		e.buf.WriteString(`":`)
+		e.buf.Write(data)
+	}
+	e.buf.WriteString("}\n")
+	_, err := e.w.Write(e.buf.Bytes())
+	return err
+}
+
+// Flush does nothing. Each record is written to the underlying writer when
+// it is encoded.
+func (e *JSONEncoder) Flush() error {
+	return nil
+}
+
+// JSONDecoder reads records from a stream of JSON objects.
+type JSONDecoder struct {
+	d *json.Decoder
+}
+
+// NewJSONDecoder returns a decoder that reads JSON records from r.
+func NewJSONDecoder(r io.Reader) *JSONDecoder {
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	return &JSONDecoder{d: d}
+}
+
+// Decode reads the next JSON object from the stream and stores it in r.
+// Members that are not part of the schema are ignored.
+func (d *JSONDecoder) Decode(r *usn.Record) error {
+	var members map[string]json.RawMessage
+	if err := d.d.Decode(&members); err != nil {
+		return err
+	}
+
+	if raw, ok := members["schema"]; ok {
+		var version int
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return fmt.Errorf("schema: %v", err)
+		}
+		if version > SchemaVersion {
+			return fmt.Errorf("%w: %d", ErrUnsupportedSchema, version)
+		}
+	}
+
+	*r = usn.Record{}
+	for name, raw := range members {
+		field := Field(name)
+		if !field.Valid() {
+			continue
+		}
+		s, err := rawText(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if err := setText(r, field, s); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// rawText converts a JSON value into the CSV form of a field.
+func rawText(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return "", nil
+	}
+	switch raw[0] {
+	case '"':
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	case '[':
+		var list []string
+		err := json.Unmarshal(raw, &list)
+		return strings.Join(list, "|"), err
+	case 'n':
+		return "", nil
+	default:
+		var n json.Number
+		err := json.Unmarshal(raw, &n)
+		return n.String(), err
+	}
+}
@@ -23,3 +23,21 @@ func (f Filer) Parents(r Record) (records []Record, err error) {
 	}
 	return
 }
+
+// Path returns the volume-relative path of r by joining the file names of
+// its parents. If r has no parent, or its parents cannot be retrieved
+// because of an error such as ErrReused, its file name is returned.
+func (f Filer) Path(r Record) string {
+	path := r.FileName
+	if r.ParentFileReferenceNumber.IsZero() {
+		return path
+	}
+	parents, err := f.Parents(r)
+	if err != nil {
+		return path
+	}
+	for p := range parents {
+		path = parents[p].FileName + `\` + path
+	}
+	return path
+}
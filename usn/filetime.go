@@ -0,0 +1,37 @@
+package usn
+
+import (
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/filetime"
+)
+
+// Filetime is a Windows FILETIME, a count of 100-nanosecond intervals since
+// January 1, 1601 UTC. It has the same layout as windows.Filetime but is
+// available on all platforms.
+type Filetime struct {
+	LowDateTime  uint32
+	HighDateTime uint32
+}
+
+// NewFiletime returns the FILETIME for t. The zero time produces a zero
+// FILETIME.
+func NewFiletime(t time.Time) Filetime {
+	v := filetime.FromTime(t)
+	return Filetime{LowDateTime: uint32(v), HighDateTime: uint32(v >> 32)}
+}
+
+// Nanoseconds returns ft as the number of nanoseconds since the Unix epoch.
+func (ft Filetime) Nanoseconds() int64 {
+	return filetime.UnixNano(ft.Uint64())
+}
+
+// Time returns ft as a time. A zero FILETIME produces a zero time.
+func (ft Filetime) Time() time.Time {
+	return filetime.ToTime(ft.Uint64())
+}
+
+// Uint64 returns ft as a single 64-bit value.
+func (ft Filetime) Uint64() uint64 {
+	return uint64(ft.HighDateTime)<<32 | uint64(ft.LowDateTime)
+}
@@ -2,45 +2,25 @@ package usn
 
 import (
 	"errors"
-	"syscall"
 
 	"github.com/gentlemanautomaton/volmgmt/fileref"
-	"github.com/gentlemanautomaton/volmgmt/hsync"
-	"github.com/gentlemanautomaton/volmgmt/volumeapi"
 )
 
 const mftBufSize = 8192
 
 // MFT provides access to the master file table.
 type MFT struct {
-	h *hsync.Handle
+	dev Device
 }
 
-// NewMFT returns a master file table accessor for the volume with the given
-// path.
-func NewMFT(path string) (*MFT, error) {
-	const (
-		access = syscall.GENERIC_READ
-		mode   = syscall.FILE_SHARE_READ | syscall.FILE_SHARE_WRITE
-	)
-
-	h, err := volumeapi.Handle(path, access, mode)
-	if err != nil {
-		return nil, err
-	}
-
-	return NewMFTWithHandle(hsync.New(h)), nil
-}
-
-// NewMFTWithHandle returns a master file table accessor for the volume with the
-// given handle.
+// NewMFTWithDevice returns a master file table accessor for the volume
+// served by device.
 //
-// When the journal is closed its associated handle will also be closed. When
-// providing an existing handle that will be used elsewhere be sure to
-// clone it first.
-func NewMFTWithHandle(handle *hsync.Handle) *MFT {
+// When the MFT is closed its device will also be closed. When providing a
+// device that will be used elsewhere be sure to clone it first.
+func NewMFTWithDevice(device Device) *MFT {
 	return &MFT{
-		h: handle,
+		dev: device,
 	}
 }
 
@@ -64,7 +44,7 @@ func (mft *MFT) File(id fileref.ID) (record Record, err error) {
 		b      = buffer[:]
 	)
 
-	length, err := EnumData(mft.h.Handle(), opts, b)
+	length, err := mft.dev.EnumData(opts, b)
 	if err != nil {
 		return
 	}
@@ -87,10 +67,10 @@ func (mft *MFT) File(id fileref.ID) (record Record, err error) {
 //
 // To enumerate all records within the MFT, provide Min and Max as values.
 func (mft *MFT) Enumerate(filter Filter, low, high USN) (*Enumerator, error) {
-	return NewEnumeratorWithHandle(mft.h.Clone(), filter, low, high)
+	return NewEnumeratorWithDevice(mft.dev.Clone(), filter, low, high)
 }
 
 // Close releases any resources consumed by the MFT.
 func (mft *MFT) Close() {
-	mft.h.Close()
+	mft.dev.Close()
 }
@@ -0,0 +1,34 @@
+package usn
+
+import (
+	"syscall"
+
+	"github.com/gentlemanautomaton/volmgmt/hsync"
+	"github.com/gentlemanautomaton/volmgmt/volumeapi"
+)
+
+// NewMFT returns a master file table accessor for the volume with the given
+// path.
+func NewMFT(path string) (*MFT, error) {
+	const (
+		access = syscall.GENERIC_READ
+		mode   = syscall.FILE_SHARE_READ | syscall.FILE_SHARE_WRITE
+	)
+
+	h, err := volumeapi.Handle(path, access, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMFTWithHandle(hsync.New(h)), nil
+}
+
+// NewMFTWithHandle returns a master file table accessor for the volume with the
+// given handle.
+//
+// When the journal is closed its associated handle will also be closed. When
+// providing an existing handle that will be used elsewhere be sure to
+// clone it first.
+func NewMFTWithHandle(handle *hsync.Handle) *MFT {
+	return NewMFTWithDevice(handleDevice{h: handle})
+}
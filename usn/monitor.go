@@ -5,11 +5,7 @@ import (
 	"fmt"
 	"io"
 	"sync"
-	"syscall"
 	"time"
-
-	"github.com/gentlemanautomaton/volmgmt/hsync"
-	"github.com/gentlemanautomaton/volmgmt/volumeapi"
 )
 
 const (
@@ -40,41 +36,25 @@ type Monitor struct {
 	mft *MFT // Used by m.run without acquiring a lock when it's running
 
 	mutex     sync.RWMutex
-	h         *hsync.Handle // Cloned for each cursor when it's created
+	dev       Device // Cloned for each cursor when it's created
 	listeners []chan Record
+	format    PathFormatter
 	sigstop   chan struct{} // nil when not running, close to stop m.run
 	stopped   chan struct{} // nil when not running, closed by m.run when exited
 	closed    bool
 }
 
-// NewMonitor returns a USN change journal monitor for the volume described by
-// path.
-func NewMonitor(path string) (monitor *Monitor, err error) {
-	const (
-		access = syscall.GENERIC_READ
-		mode   = syscall.FILE_SHARE_READ | syscall.FILE_SHARE_WRITE
-	)
-
-	h, err := volumeapi.Handle(path, access, mode)
-	if err != nil {
-		return nil, err
-	}
-
-	return NewMonitorWithHandle(hsync.New(h)), nil
-}
-
-// NewMonitorWithHandle returns a USN journal monitor for the volume with the
-// given handle. The returned monitor will be inactive until it has been
-// started by a call to Run().
+// NewMonitorWithDevice returns a USN journal monitor for the volume served
+// by device. The returned monitor will be inactive until it has been started
+// by a call to Run().
 //
-// When the monitor is closed its associated handle will also be closed. When
-// providing an existing handle that will be used elsewhere be sure to
-// clone it first.
+// When the monitor is closed its device will also be closed. When providing
+// a device that will be used elsewhere be sure to clone it first.
 //
 // It is the caller's responsibility to close the monitor when finished with it.
-func NewMonitorWithHandle(handle *hsync.Handle) *Monitor {
+func NewMonitorWithDevice(device Device) *Monitor {
 	return &Monitor{
-		h: handle,
+		dev: device,
 	}
 }
 
@@ -113,16 +93,17 @@ func (m *Monitor) Run(start USN, interval time.Duration, reasonMask Reason, proc
 	}
 
 	if m.mft == nil {
-		m.mft = NewMFTWithHandle(m.h.Clone())
+		m.mft = NewMFTWithDevice(m.dev.Clone())
 	}
 
-	cursor, err := NewCursorWithHandle(m.h.Clone(), processor, reasonMask, filter, filer)
+	cursor, err := NewCursorWithDevice(m.dev.Clone(), processor, reasonMask, filter, filer)
 	if err != nil {
-		errC <- fmt.Errorf("unable to create cursor for volume handle: %v", err)
+		errC <- fmt.Errorf("unable to create cursor for volume: %v", err)
 		return errC
 	}
 
 	cursor.usn = start
+	cursor.format = m.format
 
 	m.sigstop = make(chan struct{})
 	m.stopped = make(chan struct{})
@@ -179,6 +160,17 @@ func (m *Monitor) run(interval time.Duration, cursor *Cursor, sigstop, stopped c
 	}
 }
 
+// SetPathFormatter causes the paths of records broadcast by the monitor to
+// be transformed by f. Filters are applied before formatting takes place.
+// Pass nil to broadcast volume-relative paths.
+//
+// The formatter takes effect the next time the monitor is started.
+func (m *Monitor) SetPathFormatter(f PathFormatter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.format = f
+}
+
 // Stop will cause the monitor to stop observing the USN journal.
 func (m *Monitor) Stop() error {
 	m.mutex.Lock()
@@ -225,6 +217,7 @@ func (m *Monitor) Close() error {
 		m.mft.Close()
 		m.mft = nil
 	}
+	m.dev.Close()
 
 	for _, listener := range m.listeners {
 		close(listener)
@@ -0,0 +1,37 @@
+package usn
+
+import (
+	"syscall"
+
+	"github.com/gentlemanautomaton/volmgmt/hsync"
+	"github.com/gentlemanautomaton/volmgmt/volumeapi"
+)
+
+// NewMonitor returns a USN change journal monitor for the volume described by
+// path.
+func NewMonitor(path string) (monitor *Monitor, err error) {
+	const (
+		access = syscall.GENERIC_READ
+		mode   = syscall.FILE_SHARE_READ | syscall.FILE_SHARE_WRITE
+	)
+
+	h, err := volumeapi.Handle(path, access, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMonitorWithHandle(hsync.New(h)), nil
+}
+
+// NewMonitorWithHandle returns a USN journal monitor for the volume with the
+// given handle. The returned monitor will be inactive until it has been
+// started by a call to Run().
+//
+// When the monitor is closed its associated handle will also be closed. When
+// providing an existing handle that will be used elsewhere be sure to
+// clone it first.
+//
+// It is the caller's responsibility to close the monitor when finished with it.
+func NewMonitorWithHandle(handle *hsync.Handle) *Monitor {
+	return NewMonitorWithDevice(handleDevice{h: handle})
+}
@@ -0,0 +1,148 @@
+package offline
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+var (
+	// ErrNoJournal is returned when a volume does not have a change
+	// journal.
+	ErrNoJournal = errors.New("volume does not have a change journal")
+
+	// ErrJournalID is returned when a read request names a change journal
+	// other than the one on the volume.
+	ErrJournalID = errors.New("journal ID does not match the volume's change journal")
+
+	// ErrReadOnly is returned when a request would modify the volume.
+	ErrReadOnly = errors.New("offline volumes are read-only")
+
+	// ErrClosed is returned when a device is already closed.
+	ErrClosed = errors.New("device already closed")
+)
+
+// maxUSN is the largest update sequence number reported by Windows.
+const maxUSN = 0x7FFFFFFFFFFF0000
+
+// Device serves change journal requests from an NTFS volume held in an
+// io.ReaderAt. It implements usn.Device.
+type Device struct {
+	v      *volume
+	closed bool
+}
+
+// volume is the state shared by a device and its clones.
+type volume struct {
+	mutex  sync.Mutex
+	refs   int
+	closer io.Closer // Nil if the volume was provided by the caller
+
+	vol *ntfs.Volume
+
+	once       sync.Once
+	journal    *ntfs.Journal
+	journalErr error
+}
+
+// Open opens the NTFS volume image or block device at path.
+//
+// The returned device must be closed when it is no longer needed.
+func Open(path string) (*Device, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	d, err := NewDevice(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	d.v.closer = f
+	return d, nil
+}
+
+// NewDevice returns a device for the NTFS volume held in r. Volumes that
+// do not have a change journal can be enumerated but not queried.
+//
+// The change journal is located the first time that it is needed, so
+// devices that are only used to enumerate the master file table do not
+// look for it.
+func NewDevice(r io.ReaderAt) (*Device, error) {
+	vol, err := ntfs.Open(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Device{v: &volume{refs: 1, vol: vol}}, nil
+}
+
+// openJournal returns the change journal of the volume, opening it on
+// first use. It returns ErrNoJournal if the volume does not have one.
+func (v *volume) openJournal() (*ntfs.Journal, error) {
+	v.once.Do(func() {
+		v.journal, v.journalErr = v.vol.Journal()
+		if errors.Is(v.journalErr, ntfs.ErrNotFound) {
+			v.journalErr = ErrNoJournal
+		}
+	})
+	return v.journal, v.journalErr
+}
+
+// Volume returns the NTFS volume served by the device.
+func (d *Device) Volume() *ntfs.Volume {
+	return d.v.vol
+}
+
+// CreateJournal returns ErrReadOnly.
+func (d *Device) CreateJournal(maxSize, allocDelta uint64) error {
+	return ErrReadOnly
+}
+
+// QueryJournal returns information about the change journal. It returns
+// ErrNoJournal if the volume does not have one.
+func (d *Device) QueryJournal() (usn.RawJournalData, error) {
+	j, err := d.v.openJournal()
+	if err != nil {
+		return usn.RawJournalData{}, err
+	}
+	return usn.RawJournalData{
+		JournalID:                j.ID,
+		FirstUSN:                 j.FirstUSN,
+		NextUSN:                  j.NextUSN,
+		LowestValidUSN:           j.LowestValidUSN,
+		MaxUSN:                   maxUSN,
+		MaximumSize:              j.MaximumSize,
+		AllocationDelta:          j.AllocationDelta,
+		MinSupportedMajorVersion: 2,
+		MaxSupportedMajorVersion: 4,
+	}, nil
+}
+
+// Clone returns an independent copy of d that shares its volume. When
+// finished with a clone, it is the caller's responsibility to close it.
+func (d *Device) Clone() usn.Device {
+	d.v.mutex.Lock()
+	defer d.v.mutex.Unlock()
+	d.v.refs++
+	return &Device{v: d.v}
+}
+
+// Close releases the device. The file opened by Open is closed when the
+// device and all of its clones have been closed.
+func (d *Device) Close() error {
+	d.v.mutex.Lock()
+	defer d.v.mutex.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+	d.closed = true
+	d.v.refs--
+	if d.v.refs == 0 && d.v.closer != nil {
+		return d.v.closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+// Package offline reads the change journals of NTFS volumes that are not
+// mounted by Windows, such as disk images and block devices on Linux.
+//
+// The volume is read with the ntfs package. Journal query data is derived
+// from the $Max stream of $Extend\$UsnJrnl, journal records are read from
+// its $J stream and master file table enumeration is served from the $MFT.
+// A Device answers the same requests as a live volume handle, so the
+// journals, cursors, enumerators and caches of the usn package work
+// unchanged.
+//
+// The volume is assumed not to change while it is read. Volumes that are
+// mounted read-only, or not at all, satisfy this.
+package offline
@@ -0,0 +1,59 @@
+package offline
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// segmentMask selects the record number of a file reference number.
+const segmentMask = 1<<48 - 1
+
+// EnumData fills buffer with records for the files in the master file
+// table, in the same form as FSCTL_ENUM_USN_DATA. Enumeration begins at the
+// MFT record named by opts.StartFileReferenceNumber, whose sequence number
+// is ignored. Only files whose USN lies between opts.Low and opts.High,
+// inclusive, are returned.
+//
+// Records are produced by ntfs.MFTEntry.Record. Extension records, records
+// that are not in use and records without file names are skipped. It
+// returns io.EOF when there are no more records.
+func (d *Device) EnumData(opts usn.RawEnumOptions, buffer []byte) (uint32, error) {
+	if len(buffer) < 8 {
+		return 0, usn.ErrInsufficientBuffer
+	}
+
+	mft := d.v.vol.MFT()
+	count := mft.RecordCount()
+	next := uint64(opts.StartFileReferenceNumber) & segmentMask
+	n := 8
+
+	for ; next < count; next++ {
+		e, err := mft.Entry(next)
+		if err != nil || !e.InUse() || e.Header.BaseRecord != 0 || len(e.FileNames) == 0 {
+			continue
+		}
+		record := e.Record()
+		if record.USN < opts.Low || record.USN > opts.High {
+			continue
+		}
+		data, err := record.MarshalBinary()
+		if err != nil {
+			continue
+		}
+		if n+len(data) > len(buffer) {
+			if n == 8 {
+				return 0, usn.ErrInsufficientBuffer
+			}
+			break
+		}
+		n += copy(buffer[n:], data)
+	}
+
+	if n == 8 {
+		return 0, io.EOF
+	}
+	binary.LittleEndian.PutUint64(buffer, next)
+	return uint32(n), nil
+}
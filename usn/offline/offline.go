@@ -0,0 +1,64 @@
+package offline
+
+import "github.com/gentlemanautomaton/volmgmt/usn"
+
+// NewJournal returns a USN Journal accessor for the NTFS volume image or
+// block device at path. It is the offline equivalent of usn.NewJournal.
+func NewJournal(path string) (*usn.Journal, error) {
+	d, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return usn.NewJournalWithDevice(d), nil
+}
+
+// NewCursor returns a USN change journal cursor for the NTFS volume image or
+// block device at path. It is the offline equivalent of usn.NewCursor.
+func NewCursor(path string, reasonMask usn.Reason, processor usn.Processor, filter usn.Filter, filer usn.Filer) (*usn.Cursor, error) {
+	d, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := usn.NewCursorWithDevice(d, processor, reasonMask, filter, filer)
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewMFT returns a master file table accessor for the NTFS volume image or
+// block device at path. It is the offline equivalent of usn.NewMFT.
+func NewMFT(path string) (*usn.MFT, error) {
+	d, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return usn.NewMFTWithDevice(d), nil
+}
+
+// NewEnumerator returns a master file table enumerator for the NTFS volume
+// image or block device at path. It is the offline equivalent of
+// usn.NewEnumerator.
+func NewEnumerator(path string, filter usn.Filter, low, high usn.USN) (*usn.Enumerator, error) {
+	d, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	e, err := usn.NewEnumeratorWithDevice(d, filter, low, high)
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// NewMonitor returns a USN change journal monitor for the NTFS volume image
+// or block device at path. It is the offline equivalent of usn.NewMonitor.
+func NewMonitor(path string) (*usn.Monitor, error) {
+	d, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return usn.NewMonitorWithDevice(d), nil
+}
@@ -0,0 +1,244 @@
+package offline_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/internal/ntfstest"
+	"github.com/gentlemanautomaton/volmgmt/ntfs"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usn/offline"
+)
+
+const journalID = 0x01D7_7123_4567_89AB
+
+var (
+	root    = fileref.NewSegment(ntfs.RecordRoot, ntfs.RecordRoot)
+	users   = fileref.NewSegment(41, 1)
+	report  = fileref.NewSegment(42, 2)
+	oldFile = fileref.NewSegment(43, 3)
+	when    = time.Date(2022, 2, 2, 10, 30, 0, 0, time.UTC)
+)
+
+// purged is the size of the region at the start of $J that has been
+// deallocated.
+const purged = 2 * ntfstest.ClusterSize
+
+// newImage returns an image whose change journal holds three records in
+// its last two clusters, after a purged region of two clusters.
+func newImage(journal bool) *ntfstest.Image {
+	img := ntfstest.NewImage(64, ntfstest.Run{LCN: 4, Length: 12})
+	img.Set(41, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse | ntfs.RecordDirectory,
+		Attrs: [][]byte{
+			ntfstest.StdInfoAttr(when, when, when, when, 0, 0x100, purged),
+			ntfstest.FileNameAttr(root, "Users", ntfs.NamespaceWin32DOS, 0),
+		},
+	})
+	img.Set(42, ntfstest.Record{
+		Sequence: 2,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.StdInfoAttr(when, when, when, when, fileattr.Archive, 0x101, purged+96),
+			ntfstest.FileNameAttr(users, "report.docx", ntfs.NamespaceWin32DOS, fileattr.Archive),
+		},
+	})
+	if !journal {
+		return img
+	}
+
+	j := make([]byte, purged+2*ntfstest.ClusterSize)
+	page := append(
+		ntfstest.EncodeJournalRecord(ntfstest.JournalRecord{ID: report, Parent: users, USN: purged, Time: when, Reason: uint32(usn.ReasonFileCreate), Attrs: fileattr.Archive, Name: "report.docx"}),
+		ntfstest.EncodeJournalRecord(ntfstest.JournalRecord{ID: report, Parent: users, USN: purged + 96, Time: when, Reason: uint32(usn.ReasonFileCreate | usn.ReasonClose), Attrs: fileattr.Archive, Name: "report.docx"})...,
+	)
+	copy(j[purged:], page)
+	copy(j[purged+ntfstest.ClusterSize:], ntfstest.EncodeJournalRecord(ntfstest.JournalRecord{ID: oldFile, Parent: users, USN: purged + ntfstest.ClusterSize, Time: when, Reason: uint32(usn.ReasonFileDelete | usn.ReasonClose), Name: "old.txt"}))
+	img.WriteClusters(60, j[purged:])
+
+	info := make([]byte, 32)
+	binary.LittleEndian.PutUint64(info[0x00:], 32<<20)
+	binary.LittleEndian.PutUint64(info[0x08:], 8<<20)
+	binary.LittleEndian.PutUint64(info[0x10:], journalID)
+	binary.LittleEndian.PutUint64(info[0x18:], purged)
+
+	extend := fileref.NewSegment(ntfs.RecordExtend, ntfs.RecordExtend)
+	img.Set(40, ntfstest.Record{
+		Sequence: 1,
+		Flags:    ntfs.RecordInUse,
+		Attrs: [][]byte{
+			ntfstest.FileNameAttr(extend, "$UsnJrnl", ntfs.NamespaceWin32DOS, fileattr.Hidden|fileattr.System),
+			ntfstest.ResidentAttr(ntfs.AttrData, "$Max", info),
+			ntfstest.NonResidentAttr(ntfs.AttrData, "$J", []ntfstest.Run{{LCN: -1, Length: 2}, {LCN: 60, Length: 2}}, uint64(len(j)), uint64(len(j)), ntfs.AttrFlagSparse, 0),
+		},
+	})
+	return img
+}
+
+func newJournal(t *testing.T) *usn.Journal {
+	t.Helper()
+	d, err := offline.NewDevice(newImage(true).Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return usn.NewJournalWithDevice(d)
+}
+
+// readCursor reads all of the records available to a cursor.
+func readCursor(t *testing.T, c *usn.Cursor, bufSize int) []usn.Record {
+	t.Helper()
+	var records []usn.Record
+	buffer := make([]byte, bufSize)
+	for i := 0; ; i++ {
+		if i > 100 {
+			t.Fatal("cursor did not reach the end of the journal")
+		}
+		batch, err := c.Next(buffer)
+		records = append(records, batch...)
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	j := newJournal(t)
+	defer j.Close()
+
+	data, err := j.Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.JournalID != journalID || data.FirstUSN != purged || data.NextUSN != purged+2*ntfstest.ClusterSize {
+		t.Errorf("journal %X, first %d, next %d", data.JournalID, data.FirstUSN, data.NextUSN)
+	}
+	if data.MaximumSize != 32<<20 || data.AllocationDelta != 8<<20 || data.LowestValidUSN != purged {
+		t.Errorf("max %d, delta %d, lowest %d", data.MaximumSize, data.AllocationDelta, data.LowestValidUSN)
+	}
+	if err := j.Create(0, 0); !errors.Is(err, offline.ErrReadOnly) {
+		t.Errorf("Create returned %v, want %v", err, offline.ErrReadOnly)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	j := newJournal(t)
+	defer j.Close()
+
+	cache, err := j.Cache(context.Background(), nil, usn.Min, usn.Max)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		mask    usn.Reason
+		bufSize int
+		want    []string
+	}{
+		{usn.ReasonAny, 65536, []string{`Users\report.docx`, `Users\report.docx`, `Users\old.txt`}},
+		{usn.ReasonAny, 128, []string{`Users\report.docx`, `Users\report.docx`, `Users\old.txt`}},
+		{usn.ReasonFileDelete, 65536, []string{`Users\old.txt`}},
+	} {
+		c, err := j.Cursor(nil, tt.mask, nil, cache.Filer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records := readCursor(t, c, tt.bufSize)
+		c.Close()
+
+		var got []string
+		for _, r := range records {
+			got = append(got, r.Path)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("mask %v, buffer %d: read %q, want %q", tt.mask, tt.bufSize, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("mask %v, buffer %d: record %d = %q, want %q", tt.mask, tt.bufSize, i, got[i], tt.want[i])
+			}
+		}
+		if len(records) > 0 && !records[0].TimeStamp.Equal(when) {
+			t.Errorf("time stamp %v, want %v", records[0].TimeStamp, when)
+		}
+	}
+}
+
+func TestMFT(t *testing.T) {
+	j := newJournal(t)
+	defer j.Close()
+
+	mft := j.MFT()
+	defer mft.Close()
+
+	record, err := mft.File(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.FileReferenceNumber != report || record.ParentFileReferenceNumber != users || record.FileName != "report.docx" || record.USN != purged+96 {
+		t.Errorf("File returned %+v", record)
+	}
+
+	// The enumeration is limited to the given range of USNs
+	e, err := mft.Enumerate(nil, purged+1, usn.Max)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	var names []string
+	buffer := make([]byte, 4096)
+	for {
+		records, err := e.Next(buffer, nil)
+		for _, r := range records {
+			names = append(names, r.FileName)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(names) != 1 || names[0] != "report.docx" {
+		t.Errorf("enumerated %q", names)
+	}
+}
+
+func TestNoJournal(t *testing.T) {
+	d, err := offline.NewDevice(newImage(false).Reader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if _, err := d.QueryJournal(); !errors.Is(err, offline.ErrNoJournal) {
+		t.Errorf("QueryJournal returned %v, want %v", err, offline.ErrNoJournal)
+	}
+}
+
+func TestNewJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "volume.img")
+	if err := os.WriteFile(path, newImage(true).Bytes(t), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := offline.NewCursor(path, usn.ReasonAny, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if records := readCursor(t, c, 65536); len(records) != 3 {
+		t.Errorf("read %d records, want 3", len(records))
+	}
+}
@@ -0,0 +1,100 @@
+package offline
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// blockSize is the amount of $J data that is read at a time.
+const blockSize = 64 << 10
+
+// ReadJournal fills buffer with the records of the change journal that
+// begin at opts.StartUSN and match opts.ReasonMask, in the same form as
+// FSCTL_READ_USN_JOURNAL. Reading starts at the first record that has not
+// been purged if StartUSN precedes it.
+//
+// Records are read until buffer is full or the end of the journal is
+// reached, so a result that holds no records marks the end of the journal.
+// The Timeout and BytesToWaitFor options are ignored.
+func (d *Device) ReadJournal(opts usn.RawReadOptions, buffer []byte) (uint32, error) {
+	j, err := d.v.openJournal()
+	if err != nil {
+		return 0, err
+	}
+	if opts.JournalID != 0 && opts.JournalID != j.ID {
+		return 0, ErrJournalID
+	}
+	if len(buffer) < 8 {
+		return 0, usn.ErrInsufficientBuffer
+	}
+
+	minVersion, maxVersion := opts.MinMajorVersion, opts.MaxMajorVersion
+	if maxVersion == 0 {
+		// Version 0 requests receive version 2 records
+		minVersion, maxVersion = 2, 2
+	}
+
+	jr := j.Reader()
+	if opts.StartUSN > j.FirstUSN {
+		jr.Seek(opts.StartUSN)
+	}
+
+	n := 8
+	full := false
+	err = jr.NextRaw(make([]byte, blockSize), func(_ usn.USN, record []byte) bool {
+		if !match(record, opts, minVersion, maxVersion) {
+			return true
+		}
+		if n+len(record) > len(buffer) {
+			full = true
+			return false
+		}
+		n += copy(buffer[n:], record)
+		return true
+	})
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if full && n == 8 {
+		return 0, usn.ErrInsufficientBuffer
+	}
+
+	binary.LittleEndian.PutUint64(buffer, uint64(jr.Offset()))
+	return uint32(n), nil
+}
+
+// match returns true if the raw record should be returned by a read
+// request.
+func match(record []byte, opts usn.RawReadOptions, minVersion, maxVersion uint16) bool {
+	version := binary.LittleEndian.Uint16(record[4:])
+	if version < minVersion || version > maxVersion {
+		return false
+	}
+
+	// The reason follows the file reference numbers, the USN and, in all
+	// versions but 4, the time stamp
+	var offset int
+	switch version {
+	case 2:
+		offset = 0x28
+	case 3:
+		offset = 0x38
+	case 4:
+		offset = 0x30
+	default:
+		return false
+	}
+	if len(record) < offset+4 {
+		return false
+	}
+	reason := usn.Reason(binary.LittleEndian.Uint32(record[offset:]))
+	if reason&opts.ReasonMask == 0 {
+		return false
+	}
+	if opts.ReturnOnlyOnClose != 0 && reason&usn.ReasonClose == 0 {
+		return false
+	}
+	return true
+}
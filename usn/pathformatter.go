@@ -0,0 +1,15 @@
+package usn
+
+// PathFormatter transforms the volume-relative path of a record into some
+// other form, such as an absolute path. It is applied after filtering, so
+// filters always operate on volume-relative paths.
+type PathFormatter func(path string) string
+
+// Format returns f(path) if f is non-nil and path is not empty. Otherwise it
+// returns path unchanged.
+func (f PathFormatter) Format(path string) string {
+	if f == nil || path == "" {
+		return path
+	}
+	return f(path)
+}
@@ -1,8 +1,11 @@
 package usn
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/number"
 )
 
 // Reason describes a kind of USN record, that is the reason for which it was
@@ -129,34 +132,60 @@ var ReasonFormatShort = ReasonFormat{
 
 // ParseReason interprets the given string as one or more reason codes and
 // returns a uint32 representing their combined bitmask.
+//
+// Codes are separated by | or commas. Each code may be a name from any of
+// the reason formats, a Go constant name such as ReasonFileCreate, or a
+// numeric value in decimal or in hexadecimal with a 0x prefix. The aliases
+// move, all, any and * are also accepted. Names are case-insensitive.
 func ParseReason(reason string) (code Reason, err error) {
-	reason = strings.ToLower(reason)
-	reason = strings.Replace(reason, ",", "|", -1)
-	parts := strings.Split(reason, "|")
+	parts := strings.FieldsFunc(reason, func(r rune) bool { return r == '|' || r == ',' })
+	if len(parts) == 0 {
+		return 0, errors.New("no reason codes specified")
+	}
 
 	for _, part := range parts {
-		switch part {
-		case "overwrite", "dataoverwrite", "reasondataoverwrite", "usn_reason_data_overwrite":
-			code |= ReasonDataOverwrite
-		case "extend", "dataextend", "reasondataextend", "usn_reason_data_extend":
-			code |= ReasonDataExtend
-		case "truncation", "datatruncation", "reasondatatruncation", "usn_reason_data_truncation":
-			code |= ReasonDataTruncation
-		case "create", "filecreate", "reasonfilecreate", "usn_reason_file_create":
-			code |= ReasonFileCreate
-		case "delete", "filedelete", "reasonfiledelete", "usn_reason_file_delete":
-			code |= ReasonFileDelete
-		case "move":
-			code |= ReasonRenameOldName
-			code |= ReasonRenameNewName
-		case "all", "any", "*":
-			code |= ReasonAny
-		default:
-			err = fmt.Errorf("unsupported or unknown reason code: %s", part)
+		part = strings.TrimSpace(part)
+		value, ok := parseReasonCode(part)
+		if !ok {
+			return 0, fmt.Errorf("unsupported or unknown reason code: %s", part)
+		}
+		code |= value
+	}
+
+	return code, nil
+}
+
+// parseReasonCode interprets a single reason code.
+func parseReasonCode(s string) (Reason, bool) {
+	if n, err := number.ParseUint32(s); err == nil {
+		return Reason(n), true
+	}
+
+	switch strings.ToLower(s) {
+	case "move":
+		return ReasonRename, true
+	case "all", "any", "*":
+		return ReasonAny, true
+	}
+
+	for _, format := range []ReasonFormat{ReasonFormatConstant, ReasonFormatBasic, ReasonFormatShort} {
+		for value, name := range format {
+			if strings.EqualFold(name, s) {
+				return value, true
+			}
+		}
+	}
+
+	// Go constant names are the basic names with a Reason prefix
+	if len(s) > 6 && strings.EqualFold(s[:6], "reason") {
+		for value, name := range ReasonFormatBasic {
+			if strings.EqualFold(name, s[6:]) {
+				return value, true
+			}
 		}
 	}
 
-	return
+	return 0, false
 }
 
 // String returns a string representation of the reason code using a default
@@ -211,3 +240,42 @@ func (r Reason) Match(c Reason) bool {
 func (r Reason) Rename() bool {
 	return r.Match(ReasonRenameOldName | ReasonRenameNewName)
 }
+
+// MarshalText returns the reason codes as a list of C-style constant names
+// separated by |. Bits without a name are written as a hexadecimal value.
+// A reason of zero is written as 0.
+func (r Reason) MarshalText() ([]byte, error) {
+	if r == 0 {
+		return []byte("0"), nil
+	}
+
+	var known Reason
+	for code := range ReasonFormatConstant {
+		known |= code
+	}
+
+	text := r.Join("|", ReasonFormatConstant)
+	if unknown := r &^ known; unknown != 0 {
+		if text != "" {
+			text += "|"
+		}
+		text += fmt.Sprintf("0x%08x", uint32(unknown))
+	}
+
+	return []byte(text), nil
+}
+
+// UnmarshalText parses text as a set of reason codes, as described by
+// ParseReason. Empty text is interpreted as zero.
+func (r *Reason) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*r = 0
+		return nil
+	}
+	code, err := ParseReason(string(text))
+	if err != nil {
+		return err
+	}
+	*r = code
+	return nil
+}
@@ -0,0 +1,66 @@
+package usn_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+func TestParseReason(t *testing.T) {
+	tests := []struct {
+		input string
+		want  usn.Reason
+	}{
+		{"create", usn.ReasonFileCreate},
+		{"FileCreate|Close", usn.ReasonFileCreate | usn.ReasonClose},
+		{"USN_REASON_FILE_DELETE", usn.ReasonFileDelete},
+		{"ReasonSecurityChange", usn.ReasonSecurityChange},
+		{"move", usn.ReasonRename},
+		{"Rename, Truncation", usn.ReasonRename | usn.ReasonDataTruncation},
+		{"*", usn.ReasonAny},
+		{"0x80000100", usn.ReasonFileCreate | usn.ReasonClose},
+		{"0256", usn.ReasonFileCreate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := usn.ParseReason(tt.input)
+			if err != nil {
+				t.Fatalf("ParseReason returned an error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	for _, input := range []string{"", "bogus", "create|bogus"} {
+		if _, err := usn.ParseReason(input); err == nil {
+			t.Errorf("ParseReason(%q) did not return an error", input)
+		}
+	}
+}
+
+func TestReasonTextRoundTrip(t *testing.T) {
+	reasons := []usn.Reason{
+		0,
+		usn.ReasonClose,
+		usn.ReasonRename | usn.ReasonFileCreate,
+		usn.ReasonAny,
+	}
+
+	for _, r := range reasons {
+		data, err := json.Marshal(r)
+		if err != nil {
+			t.Fatalf("Marshal(%s) returned an error: %v", r, err)
+		}
+		var got usn.Reason
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal(%s) returned an error: %v", data, err)
+		}
+		if got != r {
+			t.Errorf("%s: round trip produced %#x, want %#x", data, uint32(got), uint32(r))
+		}
+	}
+}
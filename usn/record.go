@@ -1,16 +1,16 @@
 package usn
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"time"
+	"unicode/utf16"
 	"unsafe"
 
 	"github.com/gentlemanautomaton/volmgmt/fileattr"
 	"github.com/gentlemanautomaton/volmgmt/fileref"
 	"github.com/gentlemanautomaton/volmgmt/usnsource"
-
-	"golang.org/x/sys/windows"
 )
 
 const (
@@ -60,7 +60,7 @@ type Record struct {
 	TimeStamp                 time.Time
 	Reason                    Reason
 	SourceInfo                usnsource.Info
-	SecurityID                uint32 // This always seems to be zero, and is probably useless
+	SecurityID                uint32 // Index into $Secure, see ntfs/secure; always zero in live journal records
 	FileAttributes            fileattr.Value
 	FileName                  string
 	Path                      string
@@ -96,6 +96,52 @@ func (r *Record) UnmarshalBinary(data []byte) error {
 	}
 }
 
+// MarshalBinary encodes the record in the form returned by the change
+// journal. Records whose file reference numbers fit in 64 bits are encoded
+// as version 2 records and others as version 3 records. The record's
+// version and length fields are ignored, as is its path.
+func (r Record) MarshalBinary() ([]byte, error) {
+	name := utf16.Encode([]rune(r.FileName))
+
+	// The fields that follow the file reference numbers are the same in
+	// both versions, and are followed by the file name
+	v2 := r.FileReferenceNumber.IsInt64() && r.ParentFileReferenceNumber.IsInt64()
+	major, refSize := uint16(2), 8
+	if !v2 {
+		major, refSize = 3, 16
+	}
+	pos := recordHeaderSize + 2*refSize
+	nameOffset := pos + 36
+	length := (nameOffset + len(name)*2 + 7) &^ 7
+	if length > MaxRecordSize {
+		return nil, ErrRecordLengthExceedsMax
+	}
+
+	data := make([]byte, length)
+	binary.LittleEndian.PutUint32(data[0:], uint32(length))
+	binary.LittleEndian.PutUint16(data[4:], major)
+	if v2 {
+		binary.LittleEndian.PutUint64(data[8:], uint64(r.FileReferenceNumber.Int64()))
+		binary.LittleEndian.PutUint64(data[16:], uint64(r.ParentFileReferenceNumber.Int64()))
+	} else {
+		id, parent := r.FileReferenceNumber.LittleEndian(), r.ParentFileReferenceNumber.LittleEndian()
+		copy(data[8:], id[:])
+		copy(data[24:], parent[:])
+	}
+	binary.LittleEndian.PutUint64(data[pos:], uint64(r.USN))
+	binary.LittleEndian.PutUint64(data[pos+8:], NewFiletime(r.TimeStamp).Uint64())
+	binary.LittleEndian.PutUint32(data[pos+16:], uint32(r.Reason))
+	binary.LittleEndian.PutUint32(data[pos+20:], uint32(r.SourceInfo))
+	binary.LittleEndian.PutUint32(data[pos+24:], r.SecurityID)
+	binary.LittleEndian.PutUint32(data[pos+28:], uint32(r.FileAttributes))
+	binary.LittleEndian.PutUint16(data[pos+32:], uint16(len(name)*2))
+	binary.LittleEndian.PutUint16(data[pos+34:], uint16(nameOffset))
+	for i, c := range name {
+		binary.LittleEndian.PutUint16(data[nameOffset+i*2:], c)
+	}
+	return data, nil
+}
+
 // unmarshal2 assumes the header has already been umarshalled.
 func (r *Record) unmarshal2(data []byte) error {
 	if err := r.validateSize(data, recordV2Size); err != nil {
@@ -175,7 +221,7 @@ type RawRecordV1 struct {
 	FileReferenceNumber       int64
 	ParentFileReferenceNumber int64
 	USN                       USN
-	TimeStamp                 windows.Filetime
+	TimeStamp                 Filetime
 }
 
 // RawRecordV2 represents the raw form of a version 2 change journal record.
@@ -184,7 +230,7 @@ type RawRecordV2 struct {
 	FileReferenceNumber       int64
 	ParentFileReferenceNumber int64
 	USN                       USN
-	TimeStamp                 windows.Filetime
+	TimeStamp                 Filetime
 	Reason                    Reason
 	SourceInfo                usnsource.Info
 	SecurityID                uint32
@@ -200,7 +246,7 @@ type RawRecordV3 struct {
 	FileReferenceNumber       [16]byte
 	ParentFileReferenceNumber [16]byte
 	USN                       USN
-	TimeStamp                 windows.Filetime
+	TimeStamp                 Filetime
 	Reason                    Reason
 	SourceInfo                usnsource.Info
 	SecurityID                uint32
@@ -0,0 +1,56 @@
+package usn_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+func TestRecordMarshalBinary(t *testing.T) {
+	when := time.Date(2024, 5, 6, 7, 8, 9, 100, time.UTC)
+	for _, want := range []usn.Record{
+		{
+			MajorVersion:              2,
+			FileReferenceNumber:       fileref.NewSegment(41, 3),
+			ParentFileReferenceNumber: fileref.NewSegment(5, 5),
+			USN:                       4096,
+			TimeStamp:                 when,
+			Reason:                    usn.ReasonFileCreate | usn.ReasonClose,
+			SecurityID:                0x102,
+			FileAttributes:            fileattr.Archive,
+			FileName:                  "résumé.docx",
+		},
+		{
+			MajorVersion:              3,
+			FileReferenceNumber:       fileref.New128(41, 7),
+			ParentFileReferenceNumber: fileref.New128(5, 7),
+			USN:                       8192,
+			TimeStamp:                 when,
+			Reason:                    usn.ReasonDataExtend,
+			FileName:                  "data.bin",
+		},
+	} {
+		data, err := want.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data)%8 != 0 {
+			t.Errorf("%s: length %d is not aligned", want.FileName, len(data))
+		}
+		var got usn.Record
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%s: %v", want.FileName, err)
+		}
+		if int(got.RecordLength) != len(data) {
+			t.Errorf("%s: record length %d, want %d", want.FileName, got.RecordLength, len(data))
+		}
+		got.RecordLength = 0
+		got.TimeStamp = got.TimeStamp.UTC()
+		if got != want {
+			t.Errorf("got %+v\nwant %+v", got, want)
+		}
+	}
+}
@@ -1,11 +1,20 @@
 package usn
 
 import (
-	"syscall"
-	"unsafe"
+	"encoding/binary"
+	"unicode/utf16"
 )
 
+// utf16BytesToString decodes little-endian UTF-16 data into a string. The
+// string ends at the first null character, if any.
 func utf16BytesToString(s []byte) string {
-	p := (*[0xffff]uint16)(unsafe.Pointer(&s[0]))
-	return syscall.UTF16ToString(p[:len(s)/2])
+	u := make([]uint16, len(s)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(s[i*2:])
+		if u[i] == 0 {
+			u = u[:i]
+			break
+		}
+	}
+	return string(utf16.Decode(u))
 }
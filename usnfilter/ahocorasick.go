@@ -0,0 +1,113 @@
+package usnfilter
+
+// ahoCorasick is an Aho-Corasick automaton that reports whether a string
+// contains any of a set of byte patterns.
+//
+// The automaton is stored as a fully resolved transition table, so matching
+// costs a single table lookup per input byte regardless of the number of
+// patterns. To keep the table small, bytes that do not appear in any pattern
+// share a single equivalence class.
+type ahoCorasick struct {
+	classes  [256]uint16 // Maps each byte to its equivalence class
+	stride   int         // Number of equivalence classes
+	next     []int32     // Transitions, indexed by state*stride+class
+	accept   []bool      // States at which at least one pattern ends
+	matchAll bool        // Set when the empty pattern is present
+}
+
+// newAhoCorasick builds an automaton for the given patterns. It returns nil
+// if patterns is empty.
+func newAhoCorasick(patterns []string) *ahoCorasick {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	ac := &ahoCorasick{}
+
+	// Assign equivalence classes. Class zero is shared by every byte that
+	// does not appear in a pattern.
+	ac.stride = 1
+	for _, pattern := range patterns {
+		for i := 0; i < len(pattern); i++ {
+			if c := pattern[i]; ac.classes[c] == 0 {
+				ac.classes[c] = uint16(ac.stride)
+				ac.stride++
+			}
+		}
+	}
+
+	// Build the trie, with -1 marking missing transitions
+	ac.addState()
+	for _, pattern := range patterns {
+		if pattern == "" {
+			ac.matchAll = true
+			continue
+		}
+		state := int32(0)
+		for i := 0; i < len(pattern); i++ {
+			t := int(state)*ac.stride + int(ac.classes[pattern[i]])
+			if ac.next[t] < 0 {
+				ac.next[t] = ac.addState()
+			}
+			state = ac.next[t]
+		}
+		ac.accept[state] = true
+	}
+
+	// Resolve failure links breadth-first, replacing missing transitions
+	// with the transitions of the failure state
+	fail := make([]int32, len(ac.accept))
+	queue := make([]int32, 0, len(ac.accept))
+	for c := 0; c < ac.stride; c++ {
+		if s := ac.next[c]; s < 0 {
+			ac.next[c] = 0
+		} else {
+			queue = append(queue, s)
+		}
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		base := int(state) * ac.stride
+		fallback := int(fail[state]) * ac.stride
+		for c := 0; c < ac.stride; c++ {
+			s := ac.next[base+c]
+			if s < 0 {
+				ac.next[base+c] = ac.next[fallback+c]
+				continue
+			}
+			fail[s] = ac.next[fallback+c]
+			if ac.accept[fail[s]] {
+				ac.accept[s] = true
+			}
+			queue = append(queue, s)
+		}
+	}
+
+	return ac
+}
+
+// addState appends a new state with no transitions and returns its index.
+func (ac *ahoCorasick) addState() int32 {
+	state := int32(len(ac.accept))
+	for c := 0; c < ac.stride; c++ {
+		ac.next = append(ac.next, -1)
+	}
+	ac.accept = append(ac.accept, false)
+	return state
+}
+
+// Contains reports whether s contains at least one of the patterns.
+func (ac *ahoCorasick) Contains(s string) bool {
+	if ac.matchAll {
+		return true
+	}
+	state := int32(0)
+	for i := 0; i < len(s); i++ {
+		state = ac.next[int(state)*ac.stride+int(ac.classes[s[i]])]
+		if ac.accept[state] {
+			return true
+		}
+	}
+	return false
+}
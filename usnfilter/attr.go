@@ -0,0 +1,30 @@
+package usnfilter
+
+import (
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// AttrAny returns a filter that returns true when a record has at least one
+// of the given file attributes.
+func AttrAny(attr fileattr.Value) usn.Filter {
+	return func(record usn.Record) bool {
+		return record.FileAttributes&attr != 0
+	}
+}
+
+// AttrAll returns a filter that returns true when a record has all of the
+// given file attributes.
+func AttrAll(attr fileattr.Value) usn.Filter {
+	return func(record usn.Record) bool {
+		return record.FileAttributes.Match(attr)
+	}
+}
+
+// AttrNone returns a filter that returns true when a record has none of the
+// given file attributes.
+func AttrNone(attr fileattr.Value) usn.Filter {
+	return func(record usn.Record) bool {
+		return record.FileAttributes&attr == 0
+	}
+}
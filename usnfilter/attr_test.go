@@ -0,0 +1,33 @@
+package usnfilter_test
+
+import (
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+)
+
+func TestAttr(t *testing.T) {
+	record := usn.Record{FileAttributes: fileattr.Hidden | fileattr.System}
+	tests := []struct {
+		name   string
+		filter usn.Filter
+		want   bool
+	}{
+		{"any-match", usnfilter.AttrAny(fileattr.Hidden | fileattr.Temporary), true},
+		{"any-miss", usnfilter.AttrAny(fileattr.Temporary | fileattr.Offline), false},
+		{"all-match", usnfilter.AttrAll(fileattr.Hidden | fileattr.System), true},
+		{"all-miss", usnfilter.AttrAll(fileattr.Hidden | fileattr.Archive), false},
+		{"none-match", usnfilter.AttrNone(fileattr.ReparsePoint | fileattr.Offline), true},
+		{"none-miss", usnfilter.AttrNone(fileattr.ReparsePoint | fileattr.System), false},
+		{"isdir", usnfilter.IsDir, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(record); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
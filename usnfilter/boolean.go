@@ -37,10 +37,10 @@ func Or(filters ...usn.Filter) usn.Filter {
 	return func(record usn.Record) bool {
 		for _, filter := range filters {
 			if filter.Match(record) {
-				return false
+				return true
 			}
 		}
-		return true
+		return false
 	}
 }
 
@@ -0,0 +1,40 @@
+package usnfilter_test
+
+import (
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+)
+
+func TestBoolean(t *testing.T) {
+	var (
+		yes usn.Filter = func(usn.Record) bool { return true }
+		no  usn.Filter = func(usn.Record) bool { return false }
+	)
+	tests := []struct {
+		name   string
+		filter usn.Filter
+		want   bool
+	}{
+		{"and-true", usnfilter.And(yes, yes), true},
+		{"and-false", usnfilter.And(yes, no), false},
+		{"and-nil", usnfilter.And(nil, yes), true},
+		{"and-empty", usnfilter.And(), true},
+		{"or-first", usnfilter.Or(yes, no), true},
+		{"or-last", usnfilter.Or(no, yes), true},
+		{"or-false", usnfilter.Or(no, no), false},
+		{"or-nil", usnfilter.Or(nil, no), false},
+		{"or-empty", usnfilter.Or(), true},
+		{"not-true", usnfilter.Not(yes), false},
+		{"not-false", usnfilter.Not(no), true},
+		{"nested", usnfilter.And(usnfilter.Or(no, yes), usnfilter.Not(usnfilter.Or(no, no))), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(usn.Record{}); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
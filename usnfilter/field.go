@@ -0,0 +1,220 @@
+package usnfilter
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/araddon/dateparse"
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnsource"
+)
+
+// errUnsupportedOp is returned by a field compiler when a field does not
+// support the requested operator.
+var errUnsupportedOp = errors.New("unsupported operator")
+
+// fieldCompiler compiles a comparison for a particular field into a filter.
+type fieldCompiler func(op, value string) (usn.Filter, error)
+
+// fields maps field names to their compilers.
+var fields = map[string]fieldCompiler{
+	"reason": compileReason,
+	"source": compileSource,
+	"attr":   compileAttr,
+	"path":   compileString(recordPath),
+	"name":   compileString(recordName),
+	"ext":    compileExt,
+	"time":   compileTime,
+	"id":     compileID(recordID),
+	"parent": compileID(recordParent),
+}
+
+func recordPath(record usn.Record) string {
+	if record.Path == "" {
+		return record.FileName
+	}
+	return record.Path
+}
+
+func recordName(record usn.Record) string {
+	return record.FileName
+}
+
+func recordExt(record usn.Record) string {
+	return extension(record.FileName)
+}
+
+func recordID(record usn.Record) fileref.ID {
+	return record.FileReferenceNumber
+}
+
+func recordParent(record usn.Record) fileref.ID {
+	return record.ParentFileReferenceNumber
+}
+
+func compileReason(op, value string) (usn.Filter, error) {
+	reason, err := usn.ParseReason(value)
+	if err != nil {
+		return nil, err
+	}
+	return compileFlags(op, ReasonAll(reason), ReasonAny(reason))
+}
+
+func compileSource(op, value string) (usn.Filter, error) {
+	info, err := usnsource.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	return compileFlags(op, SourceAll(info), SourceAny(info))
+}
+
+func compileAttr(op, value string) (usn.Filter, error) {
+	attr, err := fileattr.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	return compileFlags(op, AttrAll(attr), AttrAny(attr))
+}
+
+// compileFlags applies an operator to a pair of flag filters. Equality
+// operators use the filter that requires all flags and match operators use
+// the filter that requires any of them.
+func compileFlags(op string, all, some usn.Filter) (usn.Filter, error) {
+	switch op {
+	case "=":
+		return all, nil
+	case "!=":
+		return Not(all), nil
+	case "~":
+		return some, nil
+	case "!~":
+		return Not(some), nil
+	default:
+		return nil, errUnsupportedOp
+	}
+}
+
+// compileString returns a compiler for a string field.
+func compileString(field func(usn.Record) string) fieldCompiler {
+	return func(op, value string) (usn.Filter, error) {
+		switch op {
+		case "=", "!=":
+			filter := func(record usn.Record) bool {
+				return strings.EqualFold(field(record), value)
+			}
+			if op == "!=" {
+				return Not(filter), nil
+			}
+			return filter, nil
+		case "~", "!~":
+			re, err := regexp.Compile("(?i)" + value)
+			if err != nil {
+				return nil, err
+			}
+			filter := func(record usn.Record) bool {
+				return re.MatchString(field(record))
+			}
+			if op == "!~" {
+				return Not(filter), nil
+			}
+			return filter, nil
+		case "glob":
+			g, err := CompileGlob(value)
+			if err != nil {
+				return nil, err
+			}
+			return func(record usn.Record) bool {
+				return g.Match(field(record))
+			}, nil
+		default:
+			return nil, errUnsupportedOp
+		}
+	}
+}
+
+// compileExt compiles a comparison for the ext field. A leading dot in the
+// value is ignored.
+func compileExt(op, value string) (usn.Filter, error) {
+	switch op {
+	case "=", "!=":
+		value = strings.TrimPrefix(value, ".")
+	}
+	return compileString(recordExt)(op, value)
+}
+
+func compileTime(op, value string) (usn.Filter, error) {
+	switch op {
+	case "=", "!=", "<", "<=", ">", ">=":
+	default:
+		return nil, errUnsupportedOp
+	}
+	t, err := dateparse.ParseIn(value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "=":
+		return func(record usn.Record) bool { return record.TimeStamp.Equal(t) }, nil
+	case "!=":
+		return func(record usn.Record) bool { return !record.TimeStamp.Equal(t) }, nil
+	case "<":
+		return func(record usn.Record) bool { return record.TimeStamp.Before(t) }, nil
+	case "<=":
+		return Before(t), nil
+	case ">":
+		return func(record usn.Record) bool { return record.TimeStamp.After(t) }, nil
+	default:
+		return After(t), nil
+	}
+}
+
+// compileID returns a compiler for a file identifier field.
+func compileID(field func(usn.Record) fileref.ID) fieldCompiler {
+	return func(op, value string) (usn.Filter, error) {
+		var match func(int) bool
+		switch op {
+		case "=":
+			match = func(c int) bool { return c == 0 }
+		case "!=":
+			match = func(c int) bool { return c != 0 }
+		case "<":
+			match = func(c int) bool { return c < 0 }
+		case "<=":
+			match = func(c int) bool { return c <= 0 }
+		case ">":
+			match = func(c int) bool { return c > 0 }
+		case ">=":
+			match = func(c int) bool { return c >= 0 }
+		default:
+			return nil, errUnsupportedOp
+		}
+		id, err := fileref.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(record usn.Record) bool {
+			return match(compareID(field(record), id))
+		}, nil
+	}
+}
+
+// compareID orders file identifiers by the values that fileref.Parse reads
+// and ID.String writes. Identifiers that fit in 64 bits are signed and sort
+// before all larger identifiers, which are unsigned.
+func compareID(a, b fileref.ID) int {
+	switch a64, b64 := a.IsInt64(), b.IsInt64(); {
+	case a64 && b64:
+		return cmp.Compare(a.Int64(), b.Int64())
+	case a64:
+		return -1
+	case b64:
+		return 1
+	}
+	return bytes.Compare(a[:], b[:])
+}
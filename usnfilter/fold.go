@@ -0,0 +1,33 @@
+package usnfilter
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// foldRune returns the smallest rune that is equivalent to r under simple
+// case folding. Two runes fold to the same value exactly when
+// strings.EqualFold considers them equal.
+func foldRune(r rune) rune {
+	if r < utf8.RuneSelf {
+		if 'a' <= r && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		return r
+	}
+	folded := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < folded {
+			folded = f
+		}
+	}
+	return folded
+}
+
+// fold returns s with each of its runes case folded by foldRune. Every
+// case-insensitive comparison made by this package folds strings this way,
+// so that a pattern behaves the same no matter which filter it is used in.
+func fold(s string) string {
+	return strings.Map(foldRune, s)
+}
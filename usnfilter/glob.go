@@ -0,0 +1,199 @@
+package usnfilter
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// ErrEmptyPattern is returned when an empty wildcard pattern is compiled.
+var ErrEmptyPattern = errors.New("empty wildcard pattern")
+
+// Glob is a compiled Windows-style wildcard pattern.
+//
+// Patterns are made up of path segments separated by backslashes. Forward
+// slashes are treated as backslashes. Within a segment a * matches any
+// sequence of characters and a ? matches exactly one character, but neither
+// will match a separator. A segment consisting solely of ** matches zero or
+// more whole segments, which allows patterns to span directories:
+//
+//	*.tmp                  any file with a .tmp extension, at any depth
+//	Users\*\Desktop\*.lnk  shortcuts on the desktop of any user
+//	**\node_modules\**     everything within any node_modules directory
+//
+// Patterns that do not contain a separator are matched against the last
+// segment of a path, which means they match files of that name in any
+// directory. Patterns that contain a separator are matched against the
+// whole path. Leading separators are ignored because record paths are
+// relative to the root of the volume.
+//
+// All matching is case-insensitive, with cases folded as they are by
+// strings.EqualFold.
+type Glob struct {
+	pattern  string
+	segments []globSegment
+	basename bool // Match against the last path segment only
+}
+
+// globSegment is a single segment of a compiled wildcard pattern.
+type globSegment struct {
+	runes     []rune
+	recursive bool // **
+	literal   bool // Contains no wildcards
+}
+
+// CompileGlob compiles a Windows-style wildcard pattern.
+func CompileGlob(pattern string) (*Glob, error) {
+	normalized := strings.Trim(strings.ReplaceAll(pattern, "/", `\`), `\`)
+	if normalized == "" {
+		return nil, ErrEmptyPattern
+	}
+
+	g := &Glob{
+		pattern:  pattern,
+		basename: !strings.Contains(normalized, `\`),
+	}
+
+	for _, part := range strings.Split(normalized, `\`) {
+		if part == "" {
+			continue
+		}
+		if part == "**" {
+			// Collapse consecutive recursive segments
+			if n := len(g.segments); n > 0 && g.segments[n-1].recursive {
+				continue
+			}
+			g.segments = append(g.segments, globSegment{recursive: true})
+			continue
+		}
+		part = strings.ReplaceAll(part, "**", "*")
+		g.segments = append(g.segments, globSegment{
+			runes:   foldRunes(part),
+			literal: !strings.ContainsAny(part, "*?"),
+		})
+	}
+
+	// A pattern made up only of recursive segments matches everything,
+	// which requires it to be matched against the whole path
+	if len(g.segments) == 1 && g.segments[0].recursive {
+		g.basename = false
+	}
+
+	return g, nil
+}
+
+// MustCompileGlob is like CompileGlob but panics if the pattern cannot be
+// compiled.
+func MustCompileGlob(pattern string) *Glob {
+	g, err := CompileGlob(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// String returns the pattern that g was compiled from.
+func (g *Glob) String() string {
+	return g.pattern
+}
+
+// Match reports whether path matches the pattern.
+func (g *Glob) Match(path string) bool {
+	path = strings.Trim(strings.ReplaceAll(path, "/", `\`), `\`)
+	if g.basename {
+		if i := strings.LastIndexByte(path, '\\'); i >= 0 {
+			path = path[i+1:]
+		}
+		return matchSegment(g.segments[0], foldRunes(path))
+	}
+	return g.matchPath(splitPath(path))
+}
+
+// matchPath reports whether the given path segments match the pattern.
+func (g *Glob) matchPath(parts []string) bool {
+	return matchSegments(g.segments, parts)
+}
+
+// matchSegments reports whether parts matches the pattern segments.
+func matchSegments(segments []globSegment, parts []string) bool {
+	for len(segments) > 0 {
+		seg := segments[0]
+		if seg.recursive {
+			rest := segments[1:]
+			if len(rest) == 0 {
+				return true
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 || !matchSegment(seg, foldRunes(parts[0])) {
+			return false
+		}
+		segments, parts = segments[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// matchSegment reports whether name matches a single pattern segment. Both
+// must already be case folded.
+func matchSegment(seg globSegment, name []rune) bool {
+	pattern := seg.runes
+	if seg.literal {
+		return string(pattern) == string(name)
+	}
+
+	// Iterative wildcard matching that backtracks to the most recent star
+	var p, n, starP, starN = 0, 0, -1, 0
+	for n < len(name) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == name[n]):
+			p++
+			n++
+		case p < len(pattern) && pattern[p] == '*':
+			starP, starN = p, n
+			p++
+		case starP >= 0:
+			starN++
+			p, n = starP+1, starN
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// foldRunes returns the case folded runes of s.
+func foldRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = foldRune(r)
+	}
+	return runes
+}
+
+// splitPath splits a backslash-separated path into its non-empty segments.
+func splitPath(path string) []string {
+	return strings.FieldsFunc(path, func(r rune) bool { return r == '\\' })
+}
+
+// PathGlob returns a filter that returns true when a record's path matches
+// the given wildcard pattern. If the record does not specify a path its
+// filename will be used instead. See Glob for a description of the pattern
+// syntax.
+func PathGlob(pattern string) (usn.Filter, error) {
+	g, err := CompileGlob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return func(record usn.Record) bool {
+		return g.Match(recordPath(record))
+	}, nil
+}
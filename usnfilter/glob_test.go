@@ -0,0 +1,93 @@
+package usnfilter_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+)
+
+func TestGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{`*.tmp`, `a.tmp`, true},
+		{`*.tmp`, `Users\Bob\AppData\a.TMP`, true},
+		{`*.tmp`, `a.tmp.bak`, false},
+		{`?.txt`, `a.txt`, true},
+		{`?.txt`, `ab.txt`, false},
+		{`a*b*c`, `aXXbYYc`, true},
+		{`a*b*c`, `aXXbYY`, false},
+		{`Users\*\Desktop\*.lnk`, `users\bob\desktop\app.lnk`, true},
+		{`Users\*\Desktop\*.lnk`, `Users\Bob\Sub\Desktop\app.lnk`, false},
+		{`Users\*.lnk`, `Users\Bob\app.lnk`, false},
+		{`\Windows\*`, `Windows\notepad.exe`, true},
+		{`Windows/System32/*`, `Windows\System32\x.dll`, true},
+		{`**\node_modules\**`, `src\node_modules\a\b.js`, true},
+		{`**\node_modules\**`, `node_modules`, true},
+		{`**\node_modules\**`, `src\node_module\a.js`, false},
+		{`Users\**\*.docx`, `Users\a.docx`, true},
+		{`Users\**\*.docx`, `Users\x\y\z\a.docx`, true},
+		{`Users\**\*.docx`, `Other\a.docx`, false},
+		{`**\**\a`, `x\a`, true},
+		{`ÄBC*`, `äbcd`, true},
+		{`**`, `a.txt`, true},
+		{`**`, `Users\Bob\a.txt`, true},
+		{`\**\`, `Users\Bob`, true},
+		{"\u212Aelvin", `kelvin`, true},
+		{`STRASSE`, `straße`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			g, err := usnfilter.CompileGlob(tt.pattern)
+			if err != nil {
+				t.Fatalf("CompileGlob returned an error: %v", err)
+			}
+			if got := g.Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) = %t, want %t", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGlobEmpty(t *testing.T) {
+	for _, pattern := range []string{"", `\`, "//"} {
+		if _, err := usnfilter.CompileGlob(pattern); err != usnfilter.ErrEmptyPattern {
+			t.Errorf("CompileGlob(%q) returned %v, want %v", pattern, err, usnfilter.ErrEmptyPattern)
+		}
+	}
+}
+
+func TestCaseFolding(t *testing.T) {
+	// Every predicate should agree with strings.EqualFold
+	pairs := [][2]string{
+		{"report.docx", "REPORT.DOCX"},
+		{"\u212Aelvin.txt", "kelvin.txt"},
+		{"\u017Fort.txt", "SORT.TXT"},
+		{"Äpfel.txt", "äPFEL.TXT"},
+		{"straße.txt", "STRASSE.TXT"},
+	}
+
+	for _, pair := range pairs {
+		pattern, name := pair[0], pair[1]
+		record := usn.Record{FileName: name, Path: `Users\` + name}
+		want := strings.EqualFold(pattern, name)
+
+		glob := usnfilter.MustCompileGlob(pattern)
+		matcher := usnfilter.CompileMatcher(usnfilter.Patterns{Names: []string{pattern}})
+		results := map[string]bool{
+			"Glob":         glob.Match(record.Path),
+			"Matcher":      matcher.Match(record),
+			"PathContains": usnfilter.PathContains(pattern)(record),
+		}
+		for predicate, got := range results {
+			if got != want {
+				t.Errorf("%s: %q against %q = %t, want %t", predicate, pattern, name, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package usnfilter
+
+import (
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// FileID returns a filter that returns true when a record's file reference
+// number is one of the given identifiers.
+func FileID(ids ...fileref.ID) usn.Filter {
+	set := idSet(ids)
+	return func(record usn.Record) bool {
+		_, ok := set[record.FileReferenceNumber]
+		return ok
+	}
+}
+
+// ParentID returns a filter that returns true when a record's parent file
+// reference number is one of the given identifiers.
+func ParentID(ids ...fileref.ID) usn.Filter {
+	set := idSet(ids)
+	return func(record usn.Record) bool {
+		_, ok := set[record.ParentFileReferenceNumber]
+		return ok
+	}
+}
+
+// Subtree returns a filter that returns true when a record is the directory
+// identified by dir or is located anywhere beneath it. The ancestors of each
+// record are retrieved from filer.
+func Subtree(dir fileref.ID, filer usn.Filer) usn.Filter {
+	return func(record usn.Record) bool {
+		if record.FileReferenceNumber == dir || record.ParentFileReferenceNumber == dir {
+			return true
+		}
+		parents, err := filer.Parents(record)
+		if err != nil {
+			return false
+		}
+		for i := range parents {
+			if parents[i].FileReferenceNumber == dir {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func idSet(ids []fileref.ID) map[fileref.ID]struct{} {
+	set := make(map[fileref.ID]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,81 @@
+package usnfilter_test
+
+import (
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+)
+
+func TestFileID(t *testing.T) {
+	record := usn.Record{
+		FileReferenceNumber:       fileref.New64(100),
+		ParentFileReferenceNumber: fileref.New64(50),
+	}
+	tests := []struct {
+		name   string
+		filter usn.Filter
+		want   bool
+	}{
+		{"id-match", usnfilter.FileID(fileref.New64(1), fileref.New64(100)), true},
+		{"id-miss", usnfilter.FileID(fileref.New64(50)), false},
+		{"id-empty", usnfilter.FileID(), false},
+		{"parent-match", usnfilter.ParentID(fileref.New64(50)), true},
+		{"parent-miss", usnfilter.ParentID(fileref.New64(100)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(record); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubtree(t *testing.T) {
+	// 5 (root) -> 10 (Users) -> 20 (Public) -> 30 (file.txt)
+	//          -> 11 (Windows) -> 31 (notepad.exe)
+	cache := usn.NewCache()
+	dir := func(id, parent int64, name string) usn.Record {
+		return usn.Record{
+			FileReferenceNumber:       fileref.New64(id),
+			ParentFileReferenceNumber: fileref.New64(parent),
+			FileAttributes:            fileattr.Directory,
+			FileName:                  name,
+		}
+	}
+	cache.Set(dir(5, 5, "."))
+	cache.Set(dir(10, 5, "Users"))
+	cache.Set(dir(11, 5, "Windows"))
+	cache.Set(dir(20, 10, "Public"))
+
+	file := func(id, parent int64) usn.Record {
+		return usn.Record{
+			FileReferenceNumber:       fileref.New64(id),
+			ParentFileReferenceNumber: fileref.New64(parent),
+		}
+	}
+
+	users := usnfilter.Subtree(fileref.New64(10), cache.Filer)
+	tests := []struct {
+		name   string
+		record usn.Record
+		want   bool
+	}{
+		{"self", dir(10, 5, "Users"), true},
+		{"child", dir(20, 10, "Public"), true},
+		{"grandchild", file(30, 20), true},
+		{"sibling", file(31, 11), false},
+		{"root", dir(5, 5, "."), false},
+		{"orphan", file(32, 99), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := users.Match(tt.record); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
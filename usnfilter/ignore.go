@@ -0,0 +1,161 @@
+package usnfilter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// IgnoreList is a compiled list of exclusion patterns in the style of a
+// .gitignore file.
+//
+// Each line of an ignore file holds one pattern. Blank lines and lines
+// beginning with # are ignored; a leading \ allows a pattern to begin with
+// a literal # or !. Trailing white space is removed.
+//
+// Patterns use the wildcard syntax described by Glob, with these additions:
+//
+//   - A leading ! negates the pattern, re-including anything matched by an
+//     earlier pattern. The last matching pattern wins.
+//   - A trailing separator limits the pattern to directories.
+//   - A pattern with a separator at its beginning or middle is anchored to
+//     the root of the volume. Other patterns match at any depth.
+//
+// As with git, when a directory is excluded everything within it is
+// excluded too, and files within an excluded directory cannot be
+// re-included by a negated pattern.
+type IgnoreList struct {
+	rules []ignoreRule
+}
+
+// ignoreRule is a single compiled line of an ignore file.
+type ignoreRule struct {
+	glob    *Glob
+	negate  bool
+	dirOnly bool
+}
+
+// ParseIgnore reads an ignore list from r.
+func ParseIgnore(r io.Reader) (*IgnoreList, error) {
+	var list IgnoreList
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		rule, ok, err := parseIgnoreLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if ok {
+			list.rules = append(list.rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// LoadIgnoreFile reads an ignore list from the file at path.
+func LoadIgnoreFile(path string) (*IgnoreList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list, err := ParseIgnore(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return list, nil
+}
+
+// parseIgnoreLine parses a single line of an ignore file. It returns false
+// if the line does not contain a pattern.
+func parseIgnoreLine(line string) (rule ignoreRule, ok bool, err error) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || line[0] == '#' {
+		return ignoreRule{}, false, nil
+	}
+
+	switch {
+	case line[0] == '!':
+		rule.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\#`), strings.HasPrefix(line, `\!`):
+		line = line[1:]
+	}
+
+	line = strings.ReplaceAll(line, "/", `\`)
+	if strings.HasSuffix(line, `\`) {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, `\`)
+	}
+
+	// Patterns without a separator match at any depth
+	if !strings.Contains(line, `\`) {
+		line = `**\` + line
+	}
+
+	rule.glob, err = CompileGlob(line)
+	if err != nil {
+		return ignoreRule{}, false, err
+	}
+
+	return rule, true, nil
+}
+
+// Len returns the number of patterns in the list.
+func (list *IgnoreList) Len() int {
+	if list == nil {
+		return 0
+	}
+	return len(list.rules)
+}
+
+// Match reports whether path is excluded by the list. The path is relative
+// to the root of the volume. If isDir is true the path refers to a
+// directory.
+func (list *IgnoreList) Match(path string, isDir bool) bool {
+	if list.Len() == 0 {
+		return false
+	}
+
+	parts := splitPath(strings.ReplaceAll(path, "/", `\`))
+	for i := 1; i < len(parts); i++ {
+		if list.match(parts[:i], true) {
+			return true
+		}
+	}
+	return list.match(parts, isDir)
+}
+
+// match evaluates the rules against a single path, ignoring its parents.
+func (list *IgnoreList) match(parts []string, isDir bool) bool {
+	excluded := false
+	for _, rule := range list.rules {
+		if excluded != rule.negate {
+			// This rule cannot change the outcome
+			continue
+		}
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.glob.matchPath(parts) {
+			excluded = !rule.negate
+		}
+	}
+	return excluded
+}
+
+// Filter returns a filter that returns true when a record is excluded by
+// the list. If the record does not specify a path its filename will be used
+// instead.
+func (list *IgnoreList) Filter() usn.Filter {
+	return func(record usn.Record) bool {
+		return list.Match(recordPath(record), IsDir(record))
+	}
+}
@@ -0,0 +1,89 @@
+package usnfilter_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+)
+
+const ignoreFile = `
+# Temporary files
+*.tmp
+!keep.tmp
+
+# Build output anywhere
+obj/
+node_modules/
+!node_modules/important.js
+
+# Anchored to the root
+/Windows/Temp
+build/out
+\#literal
+`
+
+func TestIgnoreList(t *testing.T) {
+	list, err := usnfilter.ParseIgnore(strings.NewReader(ignoreFile))
+	if err != nil {
+		t.Fatalf("ParseIgnore returned an error: %v", err)
+	}
+	if got, want := list.Len(), 8; got != want {
+		t.Fatalf("list has %d rules, want %d", got, want)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{`a.tmp`, false, true},
+		{`Users\x\B.TMP`, false, true},
+		{`Users\x\keep.tmp`, false, false},
+		{`Users\x\a.txt`, false, false},
+		{`src\obj`, true, true},
+		{`src\obj`, false, false},
+		{`src\obj\a.dll`, false, true},
+		{`node_modules\important.js`, false, true},
+		{`Windows\Temp`, true, true},
+		{`Windows\Temp\x.log`, false, true},
+		{`Users\Windows\Temp`, true, false},
+		{`build\out\x`, false, true},
+		{`src\build\out`, false, false},
+		{`#literal`, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := list.Match(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("Match(%q, %t) = %t, want %t", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreListFilter(t *testing.T) {
+	list, err := usnfilter.ParseIgnore(strings.NewReader("cache/\n"))
+	if err != nil {
+		t.Fatalf("ParseIgnore returned an error: %v", err)
+	}
+	filter := list.Filter()
+
+	dir := usn.Record{FileName: "Cache", FileAttributes: fileattr.Directory}
+	if !filter.Match(dir) {
+		t.Error("directory record without a path was not excluded")
+	}
+	file := usn.Record{FileName: "cache", FileAttributes: fileattr.Archive}
+	if filter.Match(file) {
+		t.Error("file record was excluded by a directory pattern")
+	}
+}
+
+func TestIgnoreListEmpty(t *testing.T) {
+	var list *usnfilter.IgnoreList
+	if list.Match(`a\b`, false) {
+		t.Error("nil list matched a path")
+	}
+}
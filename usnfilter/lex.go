@@ -0,0 +1,162 @@
+package usnfilter
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind identifies the kind of a token in a filter expression.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenAnd
+	tokenOr
+	tokenNot
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEOF:
+		return "end of expression"
+	case tokenWord:
+		return "word"
+	case tokenString:
+		return "string"
+	case tokenOp:
+		return "operator"
+	case tokenLParen:
+		return "'('"
+	case tokenRParen:
+		return "')'"
+	case tokenAnd:
+		return "'and'"
+	case tokenOr:
+		return "'or'"
+	case tokenNot:
+		return "'not'"
+	default:
+		return "unknown token"
+	}
+}
+
+// token is a lexical token within a filter expression.
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int // Byte offset of the token within the expression
+}
+
+// lex breaks expr into a sequence of tokens. The last token is always
+// tokenEOF.
+//
+// Words are runs of characters that are not white space, parentheses,
+// quotes or operator characters. Strings are enclosed in single or double
+// quotes. Single-quoted strings are taken literally. Within double-quoted
+// strings a backslash followed by a double quote is an escaped quote; all
+// other backslashes are preserved so that Windows paths and regular
+// expressions can be written naturally.
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(expr) {
+		r, size := utf8.DecodeRuneInString(expr[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, value: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, value: ")", pos: i})
+			i++
+		case r == '"' || r == '\'':
+			value, end, err := lexString(expr, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value, pos: i})
+			i = end
+		case strings.HasPrefix(expr[i:], "&&"):
+			tokens = append(tokens, token{kind: tokenAnd, value: "&&", pos: i})
+			i += 2
+		case strings.HasPrefix(expr[i:], "||"):
+			tokens = append(tokens, token{kind: tokenOr, value: "||", pos: i})
+			i += 2
+		case isOpChar(r):
+			op := lexOp(expr[i:])
+			if op == "!" {
+				tokens = append(tokens, token{kind: tokenNot, value: op, pos: i})
+			} else {
+				tokens = append(tokens, token{kind: tokenOp, value: op, pos: i})
+			}
+			i += len(op)
+		default:
+			start := i
+			for i < len(expr) {
+				r, size := utf8.DecodeRuneInString(expr[i:])
+				if unicode.IsSpace(r) || r == '(' || r == ')' || r == '"' || r == '\'' || isOpChar(r) {
+					break
+				}
+				i += size
+			}
+			word := expr[start:i]
+			switch strings.ToLower(word) {
+			case "and":
+				tokens = append(tokens, token{kind: tokenAnd, value: word, pos: start})
+			case "or":
+				tokens = append(tokens, token{kind: tokenOr, value: word, pos: start})
+			case "not":
+				tokens = append(tokens, token{kind: tokenNot, value: word, pos: start})
+			default:
+				tokens = append(tokens, token{kind: tokenWord, value: word, pos: start})
+			}
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(expr)})
+	return tokens, nil
+}
+
+// lexString reads a quoted string that starts at expr[start]. It returns the
+// unquoted value and the offset of the first byte following the string.
+func lexString(expr string, start int) (value string, end int, err error) {
+	quote := expr[start]
+	var b strings.Builder
+	for i := start + 1; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case quote == '"' && c == '\\' && i+1 < len(expr) && expr[i+1] == '"':
+			b.WriteByte('"')
+			i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, &SyntaxError{Expr: expr, Pos: start, Msg: "unterminated string"}
+}
+
+// lexOp returns the operator at the start of s.
+func lexOp(s string) string {
+	for _, op := range []string{"!=", "!~", "<=", ">=", "==", "=", "~", "<", ">", "!"} {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return s[:1]
+}
+
+func isOpChar(r rune) bool {
+	switch r {
+	case '=', '!', '~', '<', '>':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package usnfilter
+
+import (
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// Patterns is a set of patterns that can be compiled into a Matcher.
+type Patterns struct {
+	Substrings []string // Matched anywhere within the path
+	Extensions []string // Matched against the file name's extension, with or without a leading dot
+	Names      []string // Matched against the whole file name
+}
+
+// Matcher is a compiled set of patterns that can be matched against large
+// numbers of records efficiently. It is intended for large exclusion lists
+// that would be slow to evaluate as a chain of individual filters.
+//
+// Substrings are matched with an Aho-Corasick automaton, so the cost of
+// matching a record does not grow with the number of substrings.
+// Extensions and names are looked up in hash sets. Each record's path is
+// case folded once, no matter how many patterns there are.
+//
+// All matching is case-insensitive. A Matcher is safe for concurrent use.
+type Matcher struct {
+	substrings *ahoCorasick
+	exts       map[string]struct{}
+	names      map[string]struct{}
+}
+
+// CompileMatcher compiles the given patterns into a matcher.
+func CompileMatcher(p Patterns) *Matcher {
+	m := &Matcher{}
+
+	if len(p.Substrings) > 0 {
+		folded := make([]string, len(p.Substrings))
+		for i, substr := range p.Substrings {
+			folded[i] = fold(substr)
+		}
+		m.substrings = newAhoCorasick(folded)
+	}
+
+	if len(p.Extensions) > 0 {
+		m.exts = make(map[string]struct{}, len(p.Extensions))
+		for _, ext := range p.Extensions {
+			m.exts[fold(strings.TrimPrefix(ext, "."))] = struct{}{}
+		}
+	}
+
+	if len(p.Names) > 0 {
+		m.names = make(map[string]struct{}, len(p.Names))
+		for _, name := range p.Names {
+			m.names[fold(name)] = struct{}{}
+		}
+	}
+
+	return m
+}
+
+// Match returns true if the record matches at least one of the patterns.
+// If the record does not specify a path its filename will be used instead.
+func (m *Matcher) Match(record usn.Record) bool {
+	path := fold(recordPath(record))
+
+	if m.substrings != nil && m.substrings.Contains(path) {
+		return true
+	}
+
+	if m.exts == nil && m.names == nil {
+		return false
+	}
+
+	// The file name is the last element of the folded path
+	name := path
+	if i := strings.LastIndexByte(name, '\\'); i >= 0 {
+		name = name[i+1:]
+	}
+
+	if m.names != nil {
+		if _, ok := m.names[name]; ok {
+			return true
+		}
+	}
+
+	if m.exts != nil {
+		if _, ok := m.exts[extension(name)]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Filter returns a filter that returns true when a record matches at least
+// one of the patterns.
+func (m *Matcher) Filter() usn.Filter {
+	return m.Match
+}
+
+// MatchAny returns a filter that returns true when a record matches at least
+// one of the given patterns. It is equivalent to CompileMatcher(p).Filter().
+func MatchAny(p Patterns) usn.Filter {
+	return CompileMatcher(p).Filter()
+}
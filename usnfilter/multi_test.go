@@ -0,0 +1,126 @@
+package usnfilter_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+)
+
+func TestMatcher(t *testing.T) {
+	m := usnfilter.CompileMatcher(usnfilter.Patterns{
+		Substrings: []string{`\AppData\Local\Temp\`, "secret", "she", "hers"},
+		Extensions: []string{".TMP", "bak"},
+		Names:      []string{"Thumbs.db", "desktop.ini"},
+	})
+
+	tests := []struct {
+		record usn.Record
+		want   bool
+	}{
+		{usn.Record{Path: `Users\bob\appdata\local\temp\x.txt`}, true},
+		{usn.Record{Path: `Users\bob\TopSecret.docx`}, true},
+		{usn.Record{Path: `Users\ushers.txt`}, true},
+		{usn.Record{Path: `Users\bob\a.tmp`}, true},
+		{usn.Record{Path: `Users\bob\a.BAK`}, true},
+		{usn.Record{Path: `Users\bob\a.bak.txt`}, false},
+		{usn.Record{Path: `Users\bob\THUMBS.DB`}, true},
+		{usn.Record{Path: `Users\bob\thumbs.db.old`}, false},
+		{usn.Record{FileName: "desktop.ini"}, true},
+		{usn.Record{FileName: "notes.txt"}, false},
+		{usn.Record{Path: `Users\bob\AppData\Local\notes.txt`}, false},
+		{usn.Record{Path: `Users\h\e\r\s.txt`}, false},
+	}
+
+	for _, tt := range tests {
+		name := tt.record.Path
+		if name == "" {
+			name = tt.record.FileName
+		}
+		t.Run(name, func(t *testing.T) {
+			if got := m.Match(tt.record); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcherEmpty(t *testing.T) {
+	if usnfilter.MatchAny(usnfilter.Patterns{}).Match(usn.Record{Path: `a\b`}) {
+		t.Error("empty matcher matched a record")
+	}
+	if !usnfilter.MatchAny(usnfilter.Patterns{Substrings: []string{""}}).Match(usn.Record{Path: `a\b`}) {
+		t.Error("empty substring did not match a record")
+	}
+}
+
+// TestMatcherPathContains verifies that a matcher agrees with an equivalent
+// chain of PathContains filters.
+func TestMatcherPathContains(t *testing.T) {
+	substrings := benchmarkSubstrings(500)
+	m := usnfilter.MatchAny(usnfilter.Patterns{Substrings: substrings})
+	chain := pathContainsChain(substrings)
+	for _, record := range benchmarkRecords() {
+		if got, want := m.Match(record), chain.Match(record); got != want {
+			t.Errorf("%s: matcher returned %t, PathContains returned %t", record.Path, got, want)
+		}
+	}
+}
+
+func benchmarkSubstrings(n int) []string {
+	substrings := make([]string, n)
+	for i := range substrings {
+		substrings[i] = fmt.Sprintf(`\Project%04d\Confidential`, i)
+	}
+	return substrings
+}
+
+func benchmarkRecords() []usn.Record {
+	paths := []string{
+		`Windows\System32\drivers\etc\hosts`,
+		`Users\bob\AppData\Local\Microsoft\Windows\INetCache\IE\container.dat`,
+		`Users\alice\Documents\Project0042\confidential\plan.docx`,
+		`Shares\Engineering\Project9999\Public\readme.txt`,
+		`Program Files\Common Files\microsoft shared\ink\tipband.dll`,
+		`Users\Public\Project0499\CONFIDENTIAL\budget.xlsx`,
+	}
+	records := make([]usn.Record, len(paths))
+	for i, path := range paths {
+		records[i] = usn.Record{Path: path, FileName: path[strings.LastIndexByte(path, '\\')+1:]}
+	}
+	return records
+}
+
+func pathContainsChain(substrings []string) usn.Filter {
+	filters := make([]usn.Filter, len(substrings))
+	for i, substr := range substrings {
+		filters[i] = usnfilter.PathContains(substr)
+	}
+	return usnfilter.Or(filters...)
+}
+
+func BenchmarkMatcher(b *testing.B) {
+	for _, n := range []int{10, 1000, 5000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			benchmarkFilter(b, usnfilter.MatchAny(usnfilter.Patterns{Substrings: benchmarkSubstrings(n)}))
+		})
+	}
+}
+
+func BenchmarkPathContainsOr(b *testing.B) {
+	for _, n := range []int{10, 1000, 5000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			benchmarkFilter(b, pathContainsChain(benchmarkSubstrings(n)))
+		})
+	}
+}
+
+func benchmarkFilter(b *testing.B, filter usn.Filter) {
+	records := benchmarkRecords()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.Match(records[i%len(records)])
+	}
+}
@@ -0,0 +1,52 @@
+package usnfilter
+
+import (
+	"strings"
+	"unicode/utf16"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// Ext returns a filter that returns true when a record's file name has one
+// of the given extensions. Extensions may be supplied with or without a
+// leading dot. The match is case-insensitive.
+//
+// An empty extension matches file names that do not have one.
+func Ext(exts ...string) usn.Filter {
+	set := make(map[string]struct{}, len(exts))
+	for _, ext := range exts {
+		set[fold(strings.TrimPrefix(ext, "."))] = struct{}{}
+	}
+	return func(record usn.Record) bool {
+		_, ok := set[fold(extension(record.FileName))]
+		return ok
+	}
+}
+
+// NameLength returns a filter that returns true when the length of a
+// record's file name is between min and max, inclusive. Lengths are
+// measured in UTF-16 code units, which is how NTFS measures them. A max of
+// zero or less leaves the upper bound unlimited.
+func NameLength(min, max int) usn.Filter {
+	return func(record usn.Record) bool {
+		n := utf16Len(record.FileName)
+		return n >= min && (max <= 0 || n <= max)
+	}
+}
+
+// extension returns the extension of name without a leading dot. If name
+// has no extension it returns an empty string.
+func extension(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[i+1:]
+	}
+	return ""
+}
+
+// utf16Len returns the number of UTF-16 code units needed to encode s.
+func utf16Len(s string) (n int) {
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
@@ -0,0 +1,58 @@
+package usnfilter_test
+
+import (
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+)
+
+func TestExt(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   usn.Filter
+		fileName string
+		want     bool
+	}{
+		{"match", usnfilter.Ext("tmp", "log"), "debug.log", true},
+		{"dot", usnfilter.Ext(".tmp"), "x.tmp", true},
+		{"case", usnfilter.Ext("TMP"), "x.Tmp", true},
+		{"last", usnfilter.Ext("gz"), "archive.tar.gz", true},
+		{"inner", usnfilter.Ext("tar"), "archive.tar.gz", false},
+		{"miss", usnfilter.Ext("tmp"), "x.txt", false},
+		{"none", usnfilter.Ext("tmp"), "Makefile", false},
+		{"empty", usnfilter.Ext(""), "Makefile", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(usn.Record{FileName: tt.fileName}); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNameLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max int
+		fileName string
+		want     bool
+	}{
+		{"inside", 1, 10, "abc.txt", true},
+		{"min", 7, 10, "abc.txt", true},
+		{"max", 1, 7, "abc.txt", true},
+		{"short", 8, 10, "abc.txt", false},
+		{"long", 1, 6, "abc.txt", false},
+		{"unbounded", 200, 0, string(make([]byte, 300)), true},
+		{"surrogate", 2, 2, "\U0001F600", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := usnfilter.NameLength(tt.min, tt.max)
+			if got := filter.Match(usn.Record{FileName: tt.fileName}); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
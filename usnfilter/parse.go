@@ -0,0 +1,242 @@
+package usnfilter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// SyntaxError is returned when a filter expression cannot be parsed.
+type SyntaxError struct {
+	Expr string // The expression being parsed
+	Pos  int    // Byte offset of the error within the expression
+	Msg  string // Description of the error
+}
+
+// Error returns a description of the error and its position.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("filter expression: %s at position %d", e.Msg, e.Pos+1)
+}
+
+// Parse compiles a filter expression into a filter.
+//
+// An expression is a set of comparisons joined by boolean operators:
+//
+//	reason ~ create|delete and not (path ~ '\\Windows\\' or ext = tmp)
+//
+// Each comparison names a field, an operator and a value. The following
+// fields are supported:
+//
+//	reason  the reason codes of the record
+//	source  the source information of the record
+//	attr    the file attributes of the record
+//	path    the path of the record, or its file name if it has no path
+//	name    the file name of the record
+//	ext     the extension of the record's file name, without a leading dot
+//	time    the timestamp of the record
+//	id      the file reference number of the record
+//	parent  the parent file reference number of the record
+//
+// Flag fields (reason, source and attr) support =, !=, ~ and !~. An =
+// comparison matches when the record has all of the given flags and a ~
+// comparison matches when it has any of them. Flags are parsed by
+// usn.ParseReason, usnsource.Parse and fileattr.Parse respectively, so any
+// name or number accepted by those functions may be used. Multiple flags
+// can be joined with | or commas.
+//
+// String fields (path, name and ext) support =, !=, ~ (regular expression),
+// !~ and glob. Glob patterns use Windows wildcard syntax as described by
+// Glob. All string comparisons are case-insensitive.
+//
+// The time field supports =, !=, <, <=, > and >=. Times are interpreted
+// in the local time zone unless they specify one.
+//
+// Identifier fields (id and parent) support =, !=, <, <=, > and >=.
+// Identifiers may be written in decimal or in hexadecimal with a 0x prefix.
+//
+// Comparisons can be combined with and, or and not, which may also be
+// written as &&, || and !. Parentheses control grouping. Without them not
+// binds most tightly, followed by and, then or.
+//
+// Values containing white space, parentheses, quotes or operator characters
+// must be quoted. Single-quoted values are taken literally. In double-quoted
+// values \" is an escaped quote; all other backslashes are preserved.
+//
+// If expr is empty or contains only white space, a nil filter is returned.
+// Errors are of type *SyntaxError.
+func Parse(expr string) (usn.Filter, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{expr: expr, tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, nil
+	}
+
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, "unexpected %s", describe(tok))
+	}
+
+	return filter, nil
+}
+
+// MustParse is like Parse but panics if the expression cannot be parsed.
+func MustParse(expr string) usn.Filter {
+	filter, err := Parse(expr)
+	if err != nil {
+		panic(err)
+	}
+	return filter
+}
+
+// parser is a recursive descent parser for filter expressions.
+type parser struct {
+	expr   string
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *parser) errorf(tok token, format string, args ...any) error {
+	return &SyntaxError{Expr: p.expr, Pos: tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseOr parses a sequence of and expressions joined by or.
+func (p *parser) parseOr() (usn.Filter, error) {
+	filter, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	filters := []usn.Filter{filter}
+	for p.peek().kind == tokenOr {
+		p.advance()
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, next)
+	}
+	if len(filters) == 1 {
+		return filter, nil
+	}
+	return Or(filters...), nil
+}
+
+// parseAnd parses a sequence of unary expressions joined by and.
+func (p *parser) parseAnd() (usn.Filter, error) {
+	filter, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	filters := []usn.Filter{filter}
+	for p.peek().kind == tokenAnd {
+		p.advance()
+		next, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, next)
+	}
+	if len(filters) == 1 {
+		return filter, nil
+	}
+	return And(filters...), nil
+}
+
+// parseUnary parses a negated expression, a parenthesized expression or
+// a comparison.
+func (p *parser) parseUnary() (usn.Filter, error) {
+	switch tok := p.peek(); tok.kind {
+	case tokenNot:
+		p.advance()
+		filter, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(filter), nil
+	case tokenLParen:
+		p.advance()
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing.kind != tokenRParen {
+			return nil, p.errorf(closing, "expected ')' to close '(' at position %d, found %s", tok.pos+1, describe(closing))
+		}
+		p.advance()
+		return filter, nil
+	case tokenWord:
+		return p.parseComparison()
+	default:
+		return nil, p.errorf(tok, "expected field name, found %s", describe(tok))
+	}
+}
+
+// parseComparison parses a field, an operator and a value.
+func (p *parser) parseComparison() (usn.Filter, error) {
+	fieldTok := p.advance()
+	field, ok := fields[strings.ToLower(fieldTok.value)]
+	if !ok {
+		return nil, p.errorf(fieldTok, "unknown field %q", fieldTok.value)
+	}
+
+	opTok := p.advance()
+	var op string
+	switch {
+	case opTok.kind == tokenOp:
+		op = opTok.value
+		if op == "==" {
+			op = "="
+		}
+	case opTok.kind == tokenWord && strings.EqualFold(opTok.value, "glob"):
+		op = "glob"
+	default:
+		return nil, p.errorf(opTok, "expected operator after %q, found %s", fieldTok.value, describe(opTok))
+	}
+
+	valueTok := p.advance()
+	if valueTok.kind != tokenWord && valueTok.kind != tokenString {
+		return nil, p.errorf(valueTok, "expected value after %q, found %s", op, describe(valueTok))
+	}
+
+	filter, err := field(op, valueTok.value)
+	if err != nil {
+		if err == errUnsupportedOp {
+			return nil, p.errorf(opTok, "operator %q is not supported by field %q", op, fieldTok.value)
+		}
+		return nil, p.errorf(valueTok, "invalid %s value %q: %v", strings.ToLower(fieldTok.value), valueTok.value, err)
+	}
+	return filter, nil
+}
+
+// describe returns a description of tok that is suitable for use in error
+// messages.
+func describe(tok token) string {
+	switch tok.kind {
+	case tokenEOF, tokenLParen, tokenRParen:
+		return tok.kind.String()
+	case tokenString:
+		return fmt.Sprintf("string %q", tok.value)
+	default:
+		return fmt.Sprintf("%s %q", tok.kind, tok.value)
+	}
+}
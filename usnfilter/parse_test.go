@@ -0,0 +1,121 @@
+package usnfilter_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"github.com/gentlemanautomaton/volmgmt/fileref"
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+	"github.com/gentlemanautomaton/volmgmt/usnsource"
+)
+
+var sample = usn.Record{
+	FileReferenceNumber:       fileref.New64(0x2a),
+	ParentFileReferenceNumber: fileref.New64(5),
+	TimeStamp:                 time.Date(2020, 6, 15, 12, 0, 0, 0, time.UTC),
+	Reason:                    usn.ReasonFileCreate | usn.ReasonClose,
+	SourceInfo:                usnsource.DataManagement,
+	FileAttributes:            fileattr.Archive | fileattr.Hidden,
+	FileName:                  "Report.DOCX",
+	Path:                      `Users\Public\Report.DOCX`,
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`reason = create`, true},
+		{`reason = create|close`, true},
+		{`reason = USN_REASON_FILE_DELETE`, false},
+		{`reason != delete`, true},
+		{`reason = create|delete`, false},
+		{`reason ~ create|delete`, true},
+		{`reason !~ delete|rename`, true},
+		{`attr ~ Hidden,System`, true},
+		{`source = DataManagement`, true},
+		{`source = local`, false},
+		{`attr = H`, true},
+		{`attr = Hidden,System`, false},
+		{`path = 'users\public\report.docx'`, true},
+		{`path ~ "^Users\\Public"`, true},
+		{`path !~ Windows`, true},
+		{`path glob 'users\*.docx'`, false},
+		{`path glob 'users\**\*.docx'`, true},
+		{`path glob '*.docx'`, true},
+		{`name glob "report.do?x"`, true},
+		{`name = report.docx`, true},
+		{`ext = .docx`, true},
+		{`ext = docx and name = report.docx`, true},
+		{`ext = tmp or name = report.docx`, true},
+		{`not ext = tmp`, true},
+		{`!(ext = docx)`, false},
+		{`ext = tmp || (reason = create && attr = A)`, true},
+		{`time > 2020-01-01`, true},
+		{`time < "2020-06-15T12:00:00Z"`, false},
+		{`time <= "2020-06-15T12:00:00Z"`, true},
+		{`id = 42`, true},
+		{`id = 0x2A`, true},
+		{`id > 42`, false},
+		{`parent = 5 and id >= 0x2a`, true},
+		{`id > -1`, true},
+		{`id < 18446744073709551616`, true},
+		{`NAME == REPORT.DOCX AND NOT PARENT = 6`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			filter, err := usnfilter.Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse returned an error: %v", err)
+			}
+			if got := filter.Match(sample); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	filter, err := usnfilter.Parse("   ")
+	if err != nil || filter != nil {
+		t.Errorf("Parse of empty expression returned %v, %v", filter, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+	}{
+		{`bogus = 1`, 0},
+		{`name`, 4},
+		{`name =`, 6},
+		{`name = "abc`, 7},
+		{`(name = a`, 9},
+		{`name = a)`, 8},
+		{`name < a`, 5},
+		{`id ~ x`, 3},
+		{`time glob x`, 5},
+		{`reason = nonsense`, 9},
+		{`path ~ "("`, 7},
+		{`name = a and`, 12},
+		{`name = a name = b`, 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := usnfilter.Parse(tt.expr)
+			var syntaxErr *usnfilter.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expected a syntax error, got %v", err)
+			}
+			if syntaxErr.Pos != tt.pos {
+				t.Errorf("error position is %d, want %d (%v)", syntaxErr.Pos, tt.pos, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package usnfilter
+
+import "github.com/gentlemanautomaton/volmgmt/usn"
+
+// ReasonAny returns a filter that returns true when a record has at least
+// one of the given reason codes.
+func ReasonAny(reason usn.Reason) usn.Filter {
+	return func(record usn.Record) bool {
+		return record.Reason&reason != 0
+	}
+}
+
+// ReasonAll returns a filter that returns true when a record has all of the
+// given reason codes.
+func ReasonAll(reason usn.Reason) usn.Filter {
+	return func(record usn.Record) bool {
+		return record.Reason.Match(reason)
+	}
+}
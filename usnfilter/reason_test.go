@@ -0,0 +1,31 @@
+package usnfilter_test
+
+import (
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+)
+
+func TestReason(t *testing.T) {
+	record := usn.Record{Reason: usn.ReasonDataExtend | usn.ReasonClose}
+	tests := []struct {
+		name   string
+		filter usn.Filter
+		want   bool
+	}{
+		{"any-one", usnfilter.ReasonAny(usn.ReasonClose), true},
+		{"any-some", usnfilter.ReasonAny(usn.ReasonFileCreate | usn.ReasonDataExtend), true},
+		{"any-none", usnfilter.ReasonAny(usn.ReasonFileCreate | usn.ReasonFileDelete), false},
+		{"all-one", usnfilter.ReasonAll(usn.ReasonDataExtend), true},
+		{"all-both", usnfilter.ReasonAll(usn.ReasonDataExtend | usn.ReasonClose), true},
+		{"all-some", usnfilter.ReasonAll(usn.ReasonDataExtend | usn.ReasonFileDelete), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(record); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package usnfilter
+
+import (
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnsource"
+)
+
+// SourceLocal is a filter that returns true when a record was produced by
+// a local user or application, as opposed to the operating system or a
+// replication service.
+func SourceLocal(record usn.Record) bool {
+	return record.SourceInfo == usnsource.Local
+}
+
+// SourceAny returns a filter that returns true when a record has at least
+// one of the given source information codes.
+//
+// Records produced by defragmentation, antivirus scans and other operating
+// system services carry usnsource.DataManagement. They can be excluded like
+// so:
+//
+//	usnfilter.Not(usnfilter.SourceAny(usnsource.DataManagement))
+func SourceAny(info usnsource.Info) usn.Filter {
+	return func(record usn.Record) bool {
+		return record.SourceInfo&info != 0
+	}
+}
+
+// SourceAll returns a filter that returns true when a record has all of the
+// given source information codes. If info is usnsource.Local the filter
+// behaves like SourceLocal.
+func SourceAll(info usnsource.Info) usn.Filter {
+	if info == usnsource.Local {
+		return SourceLocal
+	}
+	return func(record usn.Record) bool {
+		return record.SourceInfo.Match(info)
+	}
+}
@@ -0,0 +1,36 @@
+package usnfilter_test
+
+import (
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+	"github.com/gentlemanautomaton/volmgmt/usnsource"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter usn.Filter
+		info   usnsource.Info
+		want   bool
+	}{
+		{"local-local", usnfilter.SourceLocal, usnsource.Local, true},
+		{"local-os", usnfilter.SourceLocal, usnsource.DataManagement, false},
+		{"any-match", usnfilter.SourceAny(usnsource.DataManagement | usnsource.AuxilaryData), usnsource.AuxilaryData, true},
+		{"any-miss", usnfilter.SourceAny(usnsource.DataManagement), usnsource.ReplicationManagement, false},
+		{"any-local", usnfilter.SourceAny(usnsource.DataManagement), usnsource.Local, false},
+		{"all-match", usnfilter.SourceAll(usnsource.DataManagement), usnsource.DataManagement | usnsource.AuxilaryData, true},
+		{"all-miss", usnfilter.SourceAll(usnsource.DataManagement | usnsource.AuxilaryData), usnsource.DataManagement, false},
+		{"all-local", usnfilter.SourceAll(usnsource.Local), usnsource.DataManagement, false},
+		{"exclude-noise", usnfilter.Not(usnfilter.SourceAny(usnsource.DataManagement)), usnsource.DataManagement, false},
+		{"exclude-keep", usnfilter.Not(usnfilter.SourceAny(usnsource.DataManagement)), usnsource.Local, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(usn.Record{SourceInfo: tt.info}); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,13 +9,15 @@ import (
 // PathContains returns a filter that returns true when a record's path contains
 // the given substring. If the record does not specify a path its filename will
 // be used instead. The match is case-insensitive.
+//
+// To match against a large number of substrings, use a Matcher instead.
 func PathContains(substr string) usn.Filter {
-	ci := strings.ToLower(substr)
+	ci := fold(substr)
 	return func(record usn.Record) bool {
 		if record.Path == "" {
-			return strings.Contains(strings.ToLower(record.FileName), ci)
+			return strings.Contains(fold(record.FileName), ci)
 		}
-		return strings.Contains(strings.ToLower(record.Path), ci)
+		return strings.Contains(fold(record.Path), ci)
 	}
 }
 
@@ -23,11 +25,11 @@ func PathContains(substr string) usn.Filter {
 // with the given prefix. If the record does not specify a path its filename
 // will be used instead. The match is case-insensitive.
 func PathPrefix(prefix string) usn.Filter {
-	ci := strings.ToLower(prefix)
+	ci := fold(prefix)
 	return func(record usn.Record) bool {
 		if record.Path == "" {
-			return strings.HasPrefix(strings.ToLower(record.FileName), ci)
+			return strings.HasPrefix(fold(record.FileName), ci)
 		}
-		return strings.HasPrefix(strings.ToLower(record.Path), ci)
+		return strings.HasPrefix(fold(record.Path), ci)
 	}
 }
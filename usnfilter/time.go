@@ -0,0 +1,41 @@
+package usnfilter
+
+import (
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+)
+
+// After returns a filter that returns true when a record's timestamp is at
+// or after t.
+func After(t time.Time) usn.Filter {
+	return func(record usn.Record) bool {
+		return !record.TimeStamp.Before(t)
+	}
+}
+
+// Before returns a filter that returns true when a record's timestamp is at
+// or before t.
+func Before(t time.Time) usn.Filter {
+	return func(record usn.Record) bool {
+		return !record.TimeStamp.After(t)
+	}
+}
+
+// TimeRange returns a filter that returns true when a record's timestamp
+// falls between start and end, inclusive. A zero value for start or end
+// leaves that side of the range unbounded. If both are zero a nil filter is
+// returned.
+func TimeRange(start, end time.Time) usn.Filter {
+	switch {
+	case start.IsZero() && end.IsZero():
+		return nil
+	case start.IsZero():
+		return Before(end)
+	case end.IsZero():
+		return After(start)
+	}
+	return func(record usn.Record) bool {
+		return !record.TimeStamp.Before(start) && !record.TimeStamp.After(end)
+	}
+}
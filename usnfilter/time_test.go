@@ -0,0 +1,44 @@
+package usnfilter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+)
+
+func TestTime(t *testing.T) {
+	var (
+		t1 = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		t2 = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+		t3 = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+	tests := []struct {
+		name   string
+		filter usn.Filter
+		when   time.Time
+		want   bool
+	}{
+		{"after-later", usnfilter.After(t1), t2, true},
+		{"after-equal", usnfilter.After(t2), t2, true},
+		{"after-earlier", usnfilter.After(t3), t2, false},
+		{"before-earlier", usnfilter.Before(t3), t2, true},
+		{"before-equal", usnfilter.Before(t2), t2, true},
+		{"before-later", usnfilter.Before(t1), t2, false},
+		{"range-inside", usnfilter.TimeRange(t1, t3), t2, true},
+		{"range-start", usnfilter.TimeRange(t1, t3), t1, true},
+		{"range-end", usnfilter.TimeRange(t1, t3), t3, true},
+		{"range-outside", usnfilter.TimeRange(t1, t2), t3, false},
+		{"range-open-start", usnfilter.TimeRange(time.Time{}, t2), t1, true},
+		{"range-open-end", usnfilter.TimeRange(t2, time.Time{}), t1, false},
+		{"range-unbounded", usnfilter.TimeRange(time.Time{}, time.Time{}), t1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(usn.Record{TimeStamp: tt.when}); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package usnfilter
+
+import "github.com/gentlemanautomaton/volmgmt/usn"
+
+// Version returns a filter that returns true when a record's major version
+// is one of the given versions.
+func Version(major ...uint16) usn.Filter {
+	return func(record usn.Record) bool {
+		for _, v := range major {
+			if record.MajorVersion == v {
+				return true
+			}
+		}
+		return false
+	}
+}
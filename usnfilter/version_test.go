@@ -0,0 +1,29 @@
+package usnfilter_test
+
+import (
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/usnfilter"
+)
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  usn.Filter
+		version uint16
+		want    bool
+	}{
+		{"match", usnfilter.Version(2), 2, true},
+		{"set", usnfilter.Version(2, 3), 3, true},
+		{"miss", usnfilter.Version(3), 2, false},
+		{"empty", usnfilter.Version(), 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(usn.Record{MajorVersion: tt.version}); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package usnsource
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/number"
+)
+
+// Parse interprets s as a set of source information codes.
+//
+// Codes are separated by | or commas. Each code may be a name from any of
+// the source information formats or a numeric value in decimal or in
+// hexadecimal with a 0x prefix. Names are case-insensitive.
+func Parse(s string) (info Info, err error) {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '|' || r == ',' })
+	if len(parts) == 0 {
+		return 0, errors.New("no source information specified")
+	}
+
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		code, ok := parseCode(part)
+		if !ok {
+			return 0, fmt.Errorf("unknown source information code: %s", part)
+		}
+		info |= code
+	}
+
+	return info, nil
+}
+
+// parseCode interprets a single source information name or number.
+func parseCode(s string) (Info, bool) {
+	if n, err := number.ParseUint32(s); err == nil {
+		return Info(n), true
+	}
+
+	for _, format := range []Format{FormatC, FormatGo, FormatShort} {
+		for code, name := range format {
+			if strings.EqualFold(name, s) {
+				return code, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// MarshalText returns the source information as a list of short names
+// separated by |. Bits without a name are written as a hexadecimal value.
+func (info Info) MarshalText() ([]byte, error) {
+	var known Info
+	for code := range FormatShort {
+		known |= code
+	}
+
+	text := info.Join("|", FormatShort)
+	if unknown := info &^ known; unknown != 0 {
+		if text != "" {
+			text += "|"
+		}
+		text += fmt.Sprintf("0x%08x", uint32(unknown))
+	}
+
+	return []byte(text), nil
+}
+
+// UnmarshalText parses text as a set of source information codes, as
+// described by Parse. Empty text is interpreted as Local.
+func (info *Info) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*info = Local
+		return nil
+	}
+	code, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*info = code
+	return nil
+}
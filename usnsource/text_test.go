@@ -0,0 +1,61 @@
+package usnsource_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/usnsource"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  usnsource.Info
+	}{
+		{"LOCAL", usnsource.Local},
+		{"os", usnsource.DataManagement},
+		{"DataManagement|AUX", usnsource.DataManagement | usnsource.AuxilaryData},
+		{"USN_SOURCE_CLIENT_REPLICATION_MANAGEMENT", usnsource.ClientReplicationManagement},
+		{"0x4", usnsource.ReplicationManagement},
+		{"010", usnsource.AuxilaryData | usnsource.ClientReplicationManagement},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := usnsource.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse returned an error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := usnsource.Parse("bogus"); err == nil {
+		t.Error("Parse accepted an unknown code")
+	}
+}
+
+func TestTextRoundTrip(t *testing.T) {
+	values := []usnsource.Info{
+		usnsource.Local,
+		usnsource.DataManagement,
+		usnsource.AuxilaryData | usnsource.ClientReplicationManagement,
+		usnsource.ReplicationManagement | 0x100,
+	}
+
+	for _, info := range values {
+		data, err := json.Marshal(info)
+		if err != nil {
+			t.Fatalf("Marshal(%s) returned an error: %v", info, err)
+		}
+		var got usnsource.Info
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal(%s) returned an error: %v", data, err)
+		}
+		if got != info {
+			t.Errorf("%s: round trip produced %#x, want %#x", data, uint32(got), uint32(info))
+		}
+	}
+}
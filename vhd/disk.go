@@ -0,0 +1,370 @@
+package vhd
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/utf16le"
+)
+
+const (
+	maxTableSize = 64 << 20 // Largest block allocation table that we're willing to allocate
+	maxDepth     = 32       // Maximum length of a differencing disk chain
+)
+
+var (
+	// ErrNoParent is returned when data is read from a differencing disk
+	// whose parent has not been provided.
+	ErrNoParent = errors.New("differencing disk has no parent")
+
+	// ErrParentNotFound is returned by OpenFile when the parent of a
+	// differencing disk cannot be located.
+	ErrParentNotFound = errors.New("parent disk not found")
+
+	// ErrParentMismatch is returned by OpenFile when the parent of a
+	// differencing disk does not have the unique ID that it expects.
+	ErrParentMismatch = errors.New("parent disk does not match differencing disk")
+)
+
+// Disk is a virtual hard disk. It is an io.ReaderAt over the virtual disk's
+// contents.
+type Disk struct {
+	Footer Footer
+	Header *Header // Nil for fixed disks
+
+	r       io.ReaderAt
+	bat     []uint32
+	parent  io.ReaderAt
+	closers []io.Closer
+}
+
+// Open opens the VHD image held by r. The size of the image in bytes is
+// required to locate its footer.
+//
+// If the image is a differencing disk, its parent must be provided by
+// calling SetParent before data is read from blocks that are not present in
+// the image. OpenFile locates parents automatically.
+func Open(r io.ReaderAt, size int64) (*Disk, error) {
+	if size < footerSize {
+		return nil, ErrNotVHD
+	}
+
+	footer, err := readFooter(r, size-footerSize)
+	if err != nil {
+		// Dynamic disks keep a copy of the footer at the start of the file
+		var ferr error
+		if footer, ferr = readFooter(r, 0); ferr != nil || footer.DiskType == Fixed {
+			return nil, err
+		}
+	}
+
+	d := &Disk{Footer: footer, r: r}
+	switch footer.DiskType {
+	case Fixed:
+		if int64(footer.CurrentSize) > size-footerSize {
+			return nil, ErrUnsupported
+		}
+		return d, nil
+	case Dynamic, Differencing:
+	default:
+		return nil, ErrUnsupported
+	}
+
+	data := make([]byte, headerSize)
+	if err := readFull(r, data, int64(footer.DataOffset)); err != nil {
+		return nil, err
+	}
+	header, err := ParseHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	d.Header = &header
+
+	blocks := (footer.CurrentSize + uint64(header.BlockSize) - 1) / uint64(header.BlockSize)
+	if uint64(header.MaxTableEntries) < blocks || uint64(header.MaxTableEntries)*4 > maxTableSize {
+		return nil, ErrUnsupported
+	}
+	table := make([]byte, header.MaxTableEntries*4)
+	if err := readFull(r, table, int64(header.TableOffset)); err != nil {
+		return nil, err
+	}
+	d.bat = make([]uint32, header.MaxTableEntries)
+	for i := range d.bat {
+		d.bat[i] = binary.BigEndian.Uint32(table[i*4:])
+	}
+
+	return d, nil
+}
+
+// OpenFile opens the VHD image at path. If the image is a differencing
+// disk, its parents are located with their parent locators and opened as
+// well.
+//
+// The returned disk must be closed when it is no longer needed.
+func OpenFile(path string) (*Disk, error) {
+	return openFile(path, 0)
+}
+
+func openFile(path string, depth int) (*Disk, error) {
+	if depth > maxDepth {
+		return nil, ErrUnsupported
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	d, err := Open(f, fi.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	d.closers = append(d.closers, f)
+
+	if d.Footer.DiskType == Differencing {
+		if err := d.openParent(path, depth); err != nil {
+			d.Close()
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// openParent locates and opens the parent of a differencing disk at path.
+func (d *Disk) openParent(path string, depth int) error {
+	locations, err := d.ParentPaths()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	var candidates []string
+	for _, location := range locations {
+		p := filepath.FromSlash(strings.ReplaceAll(location, `\`, "/"))
+		if !filepath.IsAbs(p) && !isWindowsAbs(location) {
+			p = filepath.Join(dir, p)
+		}
+		candidates = append(candidates, p)
+	}
+	for _, location := range append(locations, d.Header.ParentName) {
+		if location == "" {
+			continue
+		}
+		base := location[strings.LastIndexAny(location, `\/`)+1:]
+		candidates = append(candidates, filepath.Join(dir, base))
+	}
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err != nil {
+			continue
+		}
+		parent, err := openFile(candidate, depth+1)
+		if err != nil {
+			return err
+		}
+		if parent.Footer.UniqueID != d.Header.ParentUniqueID {
+			parent.Close()
+			return ErrParentMismatch
+		}
+		d.SetParent(parent)
+		d.closers = append(d.closers, parent)
+		return nil
+	}
+
+	return ErrParentNotFound
+}
+
+// isWindowsAbs returns true if path is an absolute Windows path.
+func isWindowsAbs(path string) bool {
+	return len(path) >= 3 && path[1] == ':' && (path[2] == '\\' || path[2] == '/') || strings.HasPrefix(path, `\\`)
+}
+
+// Size returns the size of the virtual disk in bytes.
+func (d *Disk) Size() int64 {
+	return int64(d.Footer.CurrentSize)
+}
+
+// Parent returns the parent of a differencing disk, or nil if it has not
+// been provided.
+func (d *Disk) Parent() io.ReaderAt {
+	return d.parent
+}
+
+// SetParent provides the parent of a differencing disk. Data for blocks and
+// sectors that are not present in d is read from parent.
+func (d *Disk) SetParent(parent io.ReaderAt) {
+	d.parent = parent
+}
+
+// ParentPaths returns the locations of the parent of a differencing disk
+// that are recorded in its parent locators. Relative paths are returned
+// before absolute paths.
+func (d *Disk) ParentPaths() ([]string, error) {
+	if d.Header == nil {
+		return nil, nil
+	}
+
+	var relative, absolute []string
+	for _, l := range d.Header.ParentLocators {
+		if l.DataLength > 64<<10 {
+			continue
+		}
+		data := make([]byte, l.DataLength)
+		if err := readFull(d.r, data, int64(l.DataOffset)); err != nil {
+			return nil, err
+		}
+		switch l.Platform {
+		case PlatformWindowsRelative:
+			relative = append(relative, utf16le.DecodeTerminated(data))
+		case PlatformWindowsAbsolute:
+			absolute = append(absolute, utf16le.DecodeTerminated(data))
+		case PlatformMacURL:
+			if utf8.Valid(data) {
+				absolute = append(absolute, strings.TrimPrefix(strings.TrimRight(string(data), "\x00"), "file://"))
+			}
+		}
+	}
+	return append(relative, absolute...), nil
+}
+
+// Close closes the files opened by OpenFile, including those of parent
+// disks.
+func (d *Disk) Close() (err error) {
+	for _, c := range d.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	d.closers = nil
+	return
+}
+
+// ReadAt reads len(b) bytes from the virtual disk at offset off.
+func (d *Disk) ReadAt(b []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("vhd: negative offset")
+	}
+	size := d.Size()
+	if off >= size {
+		return 0, io.EOF
+	}
+	if remaining := size - off; int64(len(b)) > remaining {
+		b = b[:remaining]
+		err = io.EOF
+	}
+
+	if d.Header == nil {
+		n, rerr := d.r.ReadAt(b, off)
+		if rerr != nil {
+			return n, rerr
+		}
+		return n, err
+	}
+
+	blockSize := int64(d.Header.BlockSize)
+	for n < len(b) {
+		block := (off + int64(n)) / blockSize
+		within := (off + int64(n)) % blockSize
+		chunk := b[n:]
+		if int64(len(chunk)) > blockSize-within {
+			chunk = chunk[:blockSize-within]
+		}
+		if rerr := d.readBlock(chunk, block, within, off+int64(n)); rerr != nil {
+			return n, rerr
+		}
+		n += len(chunk)
+	}
+	return n, err
+}
+
+// readBlock reads b from the given offset within a block. The offset of
+// the data within the virtual disk is needed to read from the parent.
+func (d *Disk) readBlock(b []byte, block, within, off int64) error {
+	sector := d.bat[block]
+	if sector == unallocated {
+		return d.readParent(b, off)
+	}
+
+	start := int64(sector)*sectorSize + d.Header.bitmapSize()
+	if d.Footer.DiskType != Differencing {
+		return readFull(d.r, b, start+within)
+	}
+
+	// Sectors of a differencing disk that are not marked in the block's
+	// bitmap are read from the parent
+	bitmap := make([]byte, d.Header.bitmapSize())
+	if err := readFull(d.r, bitmap, int64(sector)*sectorSize); err != nil {
+		return err
+	}
+	present := func(pos int64) bool {
+		s := pos / sectorSize
+		return bitmap[s/8]&(0x80>>uint(s%8)) != 0
+	}
+	for i := int64(0); i < int64(len(b)); {
+		pos := within + i
+		state := present(pos)
+		end := i + sectorSize - pos%sectorSize
+		for end < int64(len(b)) && present(within+end) == state {
+			end += sectorSize
+		}
+		if end > int64(len(b)) {
+			end = int64(len(b))
+		}
+		var err error
+		if state {
+			err = readFull(d.r, b[i:end], start+pos)
+		} else {
+			err = d.readParent(b[i:end], off+i)
+		}
+		if err != nil {
+			return err
+		}
+		i = end
+	}
+	return nil
+}
+
+// readParent reads b from the parent disk. Dynamic disks have no parent, so
+// zeros are returned.
+func (d *Disk) readParent(b []byte, off int64) error {
+	if d.Footer.DiskType != Differencing {
+		clear(b)
+		return nil
+	}
+	if d.parent == nil {
+		return ErrNoParent
+	}
+	return readFull(d.parent, b, off)
+}
+
+// readFull reads len(b) bytes from r at offset off. A short read is
+// reported as io.ErrUnexpectedEOF, because it indicates a truncated image.
+func readFull(r io.ReaderAt, b []byte, off int64) error {
+	n, err := r.ReadAt(b, off)
+	if n == len(b) {
+		return nil
+	}
+	if err == io.EOF || err == nil {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func readFooter(r io.ReaderAt, off int64) (Footer, error) {
+	data := make([]byte, footerSize)
+	if err := readFull(r, data, off); err != nil {
+		return Footer{}, err
+	}
+	return ParseFooter(data)
+}
@@ -0,0 +1,10 @@
+// Package vhd reads virtual hard disk images in the legacy VHD format used
+// by Virtual PC and Hyper-V.
+//
+// Fixed, dynamic and differencing disks are supported. An opened Disk is an
+// io.ReaderAt over the virtual disk's contents, which can be handed to the
+// partition package or to a file system reader without first converting the
+// image to a raw disk.
+//
+// https://learn.microsoft.com/en-us/windows/win32/vstor/about-vhd
+package vhd
@@ -0,0 +1,119 @@
+package vhd
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/partition"
+)
+
+const footerSize = 512
+
+var (
+	// ErrNotVHD is returned when a file is not a VHD image.
+	ErrNotVHD = errors.New("not a VHD image")
+
+	// ErrChecksum is returned when a VHD structure fails its checksum.
+	ErrChecksum = errors.New("VHD checksum mismatch")
+
+	// ErrUnsupported is returned when a VHD image uses a disk type or
+	// layout that is not supported.
+	ErrUnsupported = errors.New("unsupported VHD image")
+)
+
+// epoch is the reference time for VHD timestamps.
+var epoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// DiskType identifies the type of a virtual hard disk.
+type DiskType uint32
+
+// Disk types.
+const (
+	Fixed        DiskType = 2
+	Dynamic      DiskType = 3
+	Differencing DiskType = 4
+)
+
+// String returns a string representation of the disk type.
+func (t DiskType) String() string {
+	switch t {
+	case Fixed:
+		return "Fixed"
+	case Dynamic:
+		return "Dynamic"
+	case Differencing:
+		return "Differencing"
+	default:
+		return "Unknown"
+	}
+}
+
+// Geometry is the cylinder, head and sector geometry of a virtual disk.
+type Geometry struct {
+	Cylinders       uint16
+	Heads           uint8
+	SectorsPerTrack uint8
+}
+
+// Footer is the footer that ends every VHD image.
+type Footer struct {
+	Features       uint32
+	Version        uint32
+	DataOffset     uint64 // Offset of the dynamic disk header
+	Created        time.Time
+	CreatorApp     string
+	CreatorVersion uint32
+	CreatorHostOS  string
+	OriginalSize   uint64
+	CurrentSize    uint64
+	Geometry       Geometry
+	DiskType       DiskType
+	Checksum       uint32
+	UniqueID       partition.GUID
+	SavedState     bool
+}
+
+// ParseFooter parses and validates the VHD footer held in data.
+func ParseFooter(data []byte) (Footer, error) {
+	if len(data) < footerSize || string(data[0:8]) != "conectix" {
+		return Footer{}, ErrNotVHD
+	}
+	f := Footer{
+		Features:       binary.BigEndian.Uint32(data[8:]),
+		Version:        binary.BigEndian.Uint32(data[12:]),
+		DataOffset:     binary.BigEndian.Uint64(data[16:]),
+		Created:        epoch.Add(time.Duration(binary.BigEndian.Uint32(data[24:])) * time.Second),
+		CreatorApp:     string(data[28:32]),
+		CreatorVersion: binary.BigEndian.Uint32(data[32:]),
+		CreatorHostOS:  string(data[36:40]),
+		OriginalSize:   binary.BigEndian.Uint64(data[40:]),
+		CurrentSize:    binary.BigEndian.Uint64(data[48:]),
+		Geometry: Geometry{
+			Cylinders:       binary.BigEndian.Uint16(data[56:]),
+			Heads:           data[58],
+			SectorsPerTrack: data[59],
+		},
+		DiskType:   DiskType(binary.BigEndian.Uint32(data[60:])),
+		Checksum:   binary.BigEndian.Uint32(data[64:]),
+		SavedState: data[84] != 0,
+	}
+	copy(f.UniqueID[:], data[68:84])
+	if checksum(data[:footerSize], 64) != f.Checksum {
+		return Footer{}, ErrChecksum
+	}
+	return f, nil
+}
+
+// checksum returns the one's complement of the sum of the bytes in data,
+// excluding the 4-byte checksum field at offset.
+func checksum(data []byte, offset int) uint32 {
+	var sum uint32
+	for i, b := range data {
+		if i >= offset && i < offset+4 {
+			continue
+		}
+		sum += uint32(b)
+	}
+	return ^sum
+}
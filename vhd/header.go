@@ -0,0 +1,105 @@
+package vhd
+
+import (
+	"encoding/binary"
+	"time"
+	"unicode/utf16"
+
+	"github.com/gentlemanautomaton/volmgmt/partition"
+)
+
+const (
+	headerSize  = 1024
+	sectorSize  = 512
+	unallocated = 0xFFFFFFFF // Block allocation table entry for blocks that are not present
+)
+
+// Parent locator platform codes.
+const (
+	PlatformWindowsRelative = "W2ru" // Relative Windows path in UTF-16
+	PlatformWindowsAbsolute = "W2ku" // Absolute Windows path in UTF-16
+	PlatformMacAlias        = "Mac " // Mac OS alias
+	PlatformMacURL          = "MacX" // File URL in UTF-8
+)
+
+// ParentLocator describes where the parent of a differencing disk may be
+// found.
+type ParentLocator struct {
+	Platform   string
+	DataSpace  uint32 // Sectors reserved for the locator data
+	DataLength uint32 // Length of the locator data in bytes
+	DataOffset uint64 // Offset of the locator data in the file
+}
+
+// Header is the header of a dynamic or differencing disk.
+type Header struct {
+	TableOffset     uint64 // Offset of the block allocation table
+	Version         uint32
+	MaxTableEntries uint32
+	BlockSize       uint32
+	Checksum        uint32
+	ParentUniqueID  partition.GUID
+	ParentModified  time.Time
+	ParentName      string
+	ParentLocators  []ParentLocator
+}
+
+// ParseHeader parses and validates the dynamic disk header held in data.
+func ParseHeader(data []byte) (Header, error) {
+	if len(data) < headerSize || string(data[0:8]) != "cxsparse" {
+		return Header{}, ErrNotVHD
+	}
+	h := Header{
+		TableOffset:     binary.BigEndian.Uint64(data[16:]),
+		Version:         binary.BigEndian.Uint32(data[24:]),
+		MaxTableEntries: binary.BigEndian.Uint32(data[28:]),
+		BlockSize:       binary.BigEndian.Uint32(data[32:]),
+		Checksum:        binary.BigEndian.Uint32(data[36:]),
+		ParentModified:  epoch.Add(time.Duration(binary.BigEndian.Uint32(data[56:])) * time.Second),
+		ParentName:      utf16BE(data[64:576]),
+	}
+	copy(h.ParentUniqueID[:], data[40:56])
+	if checksum(data[:headerSize], 36) != h.Checksum {
+		return Header{}, ErrChecksum
+	}
+	if h.BlockSize < sectorSize || h.BlockSize&(h.BlockSize-1) != 0 {
+		return Header{}, ErrUnsupported
+	}
+
+	for i := 0; i < 8; i++ {
+		b := data[576+i*24:]
+		l := ParentLocator{
+			Platform:   string(b[0:4]),
+			DataSpace:  binary.BigEndian.Uint32(b[4:]),
+			DataLength: binary.BigEndian.Uint32(b[8:]),
+			DataOffset: binary.BigEndian.Uint64(b[16:]),
+		}
+		if l.Platform == "\x00\x00\x00\x00" || l.DataLength == 0 {
+			continue
+		}
+		h.ParentLocators = append(h.ParentLocators, l)
+	}
+
+	return h, nil
+}
+
+// bitmapSize returns the size of the sector bitmap that precedes the data
+// in each block, rounded up to a whole sector.
+func (h Header) bitmapSize() int64 {
+	bits := int64(h.BlockSize / sectorSize)
+	bytes := (bits + 7) / 8
+	return (bytes + sectorSize - 1) / sectorSize * sectorSize
+}
+
+// utf16BE decodes NUL-terminated big-endian UTF-16 data into a string.
+func utf16BE(data []byte) string {
+	u := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		c := binary.BigEndian.Uint16(data[i:])
+		if c == 0 {
+			break
+		}
+		u = append(u, c)
+	}
+	return string(utf16.Decode(u))
+}
@@ -0,0 +1,252 @@
+package vhd_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/gentlemanautomaton/volmgmt/partition"
+	"github.com/gentlemanautomaton/volmgmt/vhd"
+)
+
+const blockSize = 4096
+
+var (
+	parentID = partition.MustParseGUID("{11111111-2222-3333-4444-555555555555}")
+	childID  = partition.MustParseGUID("{66666666-7777-8888-9999-AAAAAAAAAAAA}")
+)
+
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for _, b := range data {
+		sum += uint32(b)
+	}
+	return ^sum
+}
+
+func footer(typ vhd.DiskType, size uint64, dataOffset uint64, id partition.GUID) []byte {
+	f := make([]byte, 512)
+	copy(f, "conectix")
+	binary.BigEndian.PutUint32(f[8:], 2)
+	binary.BigEndian.PutUint32(f[12:], 0x00010000)
+	binary.BigEndian.PutUint64(f[16:], dataOffset)
+	binary.BigEndian.PutUint32(f[24:], 86400) // 2000-01-02
+	copy(f[28:], "test")
+	copy(f[36:], "Wi2k")
+	binary.BigEndian.PutUint64(f[40:], size)
+	binary.BigEndian.PutUint64(f[48:], size)
+	binary.BigEndian.PutUint32(f[60:], uint32(typ))
+	copy(f[68:], id[:])
+	binary.BigEndian.PutUint32(f[64:], checksum(f))
+	return f
+}
+
+// pattern returns n bytes of recognizable content.
+func pattern(n int, seed byte) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = seed + byte(i/512)
+	}
+	return b
+}
+
+// block is a block of a dynamic disk. Sectors whose bit is set in present
+// hold data.
+type block struct {
+	index   int
+	present uint8
+	data    []byte
+}
+
+// dynamic returns a dynamic or differencing VHD image of the given virtual
+// size. A differencing disk refers to its parent by a relative locator.
+func dynamic(typ vhd.DiskType, size int, id partition.GUID, blocks ...block) []byte {
+	entries := size / blockSize
+	var image bytes.Buffer
+	image.Write(footer(typ, uint64(size), 512, id))
+
+	header := make([]byte, 1024)
+	copy(header, "cxsparse")
+	binary.BigEndian.PutUint64(header[8:], 0xFFFFFFFFFFFFFFFF)
+	binary.BigEndian.PutUint64(header[16:], 1536)
+	binary.BigEndian.PutUint32(header[24:], 0x00010000)
+	binary.BigEndian.PutUint32(header[28:], uint32(entries))
+	binary.BigEndian.PutUint32(header[32:], blockSize)
+
+	table := bytes.Repeat([]byte{0xFF}, (entries*4+511)/512*512)
+	locator := 1536 + len(table)
+	next := locator
+	if typ == vhd.Differencing {
+		copy(header[40:], parentID[:])
+		name := utf16BE("parent.vhd")
+		copy(header[64:], name)
+		path := utf16LE(`.\parent.vhd`)
+		copy(header[576:], "W2ru")
+		binary.BigEndian.PutUint32(header[580:], 1)
+		binary.BigEndian.PutUint32(header[584:], uint32(len(path)))
+		binary.BigEndian.PutUint64(header[592:], uint64(locator))
+		next += 512
+	}
+	binary.BigEndian.PutUint32(header[36:], checksum(header))
+	image.Write(header)
+
+	for _, b := range blocks {
+		binary.BigEndian.PutUint32(table[b.index*4:], uint32(next/512))
+		next += 512 + blockSize
+	}
+	image.Write(table)
+	if typ == vhd.Differencing {
+		path := make([]byte, 512)
+		copy(path, utf16LE(`.\parent.vhd`))
+		image.Write(path)
+	}
+	for _, b := range blocks {
+		bitmap := make([]byte, 512)
+		bitmap[0] = b.present
+		image.Write(bitmap)
+		image.Write(b.data)
+	}
+	image.Write(footer(typ, uint64(size), 512, id))
+	return image.Bytes()
+}
+
+func utf16LE(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	return b
+}
+
+func utf16BE(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = binary.BigEndian.AppendUint16(b, c)
+	}
+	return b
+}
+
+func readAll(t *testing.T, d *vhd.Disk) []byte {
+	t.Helper()
+	b := make([]byte, d.Size())
+	if _, err := d.ReadAt(b, 0); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestFixed(t *testing.T) {
+	data := pattern(3*blockSize, 1)
+	image := append(append([]byte(nil), data...), footer(vhd.Fixed, uint64(len(data)), 0xFFFFFFFFFFFFFFFF, parentID)...)
+
+	d, err := vhd.Open(bytes.NewReader(image), int64(len(image)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Footer.DiskType != vhd.Fixed || d.Header != nil || d.Size() != int64(len(data)) {
+		t.Fatalf("type %s, header %v, size %d", d.Footer.DiskType, d.Header, d.Size())
+	}
+	if d.Footer.CreatorApp != "test" || d.Footer.Created.Day() != 2 || d.Footer.UniqueID != parentID {
+		t.Errorf("unexpected footer: %+v", d.Footer)
+	}
+	if !bytes.Equal(readAll(t, d), data) {
+		t.Error("fixed disk content differs")
+	}
+
+	image[len(image)-512+50]++
+	if _, err := vhd.Open(bytes.NewReader(image), int64(len(image))); err != vhd.ErrChecksum {
+		t.Errorf("Open returned %v, want %v", err, vhd.ErrChecksum)
+	}
+}
+
+func TestDynamic(t *testing.T) {
+	one := pattern(blockSize, 10)
+	image := dynamic(vhd.Dynamic, 4*blockSize, parentID, block{index: 2, present: 0xFF, data: one})
+
+	d, err := vhd.Open(bytes.NewReader(image), int64(len(image)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Footer.DiskType != vhd.Dynamic || d.Header == nil || d.Header.BlockSize != blockSize {
+		t.Fatalf("type %s, header %+v", d.Footer.DiskType, d.Header)
+	}
+
+	want := make([]byte, 4*blockSize)
+	copy(want[2*blockSize:], one)
+	if !bytes.Equal(readAll(t, d), want) {
+		t.Error("dynamic disk content differs")
+	}
+
+	// Reads that span blocks and the end of the disk
+	b := make([]byte, 2*blockSize)
+	n, err := d.ReadAt(b, 3*blockSize-100)
+	if n != blockSize+100 || err != io.EOF || !bytes.Equal(b[:n], want[3*blockSize-100:]) {
+		t.Errorf("ReadAt at end of disk returned %d, %v", n, err)
+	}
+
+	// The footer copy at the start of the file is used if the footer at
+	// the end is damaged
+	image[len(image)-1]++
+	if _, err := vhd.Open(bytes.NewReader(image), int64(len(image))); err != nil {
+		t.Errorf("Open with damaged footer returned %v", err)
+	}
+}
+
+func TestDifferencing(t *testing.T) {
+	dir := t.TempDir()
+	base := pattern(2*blockSize, 1)
+	parent := dynamic(vhd.Dynamic, 2*blockSize, parentID, block{index: 0, present: 0xFF, data: base[:blockSize]}, block{index: 1, present: 0xFF, data: base[blockSize:]})
+	if err := os.WriteFile(filepath.Join(dir, "parent.vhd"), parent, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The child holds sectors 0 and 3 of its second block
+	changed := pattern(blockSize, 100)
+	child := dynamic(vhd.Differencing, 2*blockSize, childID, block{index: 1, present: 0x90, data: changed})
+	path := filepath.Join(dir, "child.vhd")
+	if err := os.WriteFile(path, child, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := vhd.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if paths, err := d.ParentPaths(); err != nil || len(paths) != 1 || paths[0] != `.\parent.vhd` {
+		t.Errorf("ParentPaths() = %q, %v", paths, err)
+	}
+	if d.Header.ParentName != "parent.vhd" {
+		t.Errorf("ParentName = %q", d.Header.ParentName)
+	}
+
+	want := append([]byte(nil), base...)
+	copy(want[blockSize:blockSize+512], changed[0:512])
+	copy(want[blockSize+3*512:blockSize+4*512], changed[3*512:4*512])
+	if !bytes.Equal(readAll(t, d), want) {
+		t.Error("differencing disk content differs")
+	}
+
+	// Without a parent, reads of sectors that are not present fail
+	orphan, err := vhd.Open(bytes.NewReader(child), int64(len(child)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orphan.ReadAt(make([]byte, 512), 0); err != vhd.ErrNoParent {
+		t.Errorf("ReadAt without parent returned %v, want %v", err, vhd.ErrNoParent)
+	}
+
+	// A parent with a different unique ID is rejected
+	other := dynamic(vhd.Dynamic, 2*blockSize, childID)
+	if err := os.WriteFile(filepath.Join(dir, "parent.vhd"), other, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vhd.OpenFile(path); err != vhd.ErrParentMismatch {
+		t.Errorf("OpenFile returned %v, want %v", err, vhd.ErrParentMismatch)
+	}
+}
@@ -0,0 +1,456 @@
+package vhdx
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/utf16le"
+	"github.com/gentlemanautomaton/volmgmt/partition"
+)
+
+const (
+	maxMetadataSize = 16 << 20  // Largest metadata region that we're willing to allocate
+	maxTableSize    = 256 << 20 // Largest block allocation table that we're willing to allocate
+	maxDepth        = 32        // Maximum length of a differencing disk chain
+)
+
+// Block allocation table entry states.
+const (
+	blockNotPresent       = 0
+	blockUndefined        = 1
+	blockZero             = 2
+	blockUnmapped         = 3
+	blockFullyPresent     = 6
+	blockPartiallyPresent = 7
+)
+
+var (
+	// ErrNoParent is returned when data is read from a differencing disk
+	// whose parent has not been provided.
+	ErrNoParent = errors.New("differencing disk has no parent")
+
+	// ErrParentNotFound is returned by OpenFile when the parent of a
+	// differencing disk cannot be located.
+	ErrParentNotFound = errors.New("parent disk not found")
+
+	// ErrParentMismatch is returned by OpenFile when the parent of a
+	// differencing disk does not have the data write GUID that it expects.
+	ErrParentMismatch = errors.New("parent disk does not match differencing disk")
+)
+
+// Disk is a VHDX virtual hard disk. It is an io.ReaderAt over the virtual
+// disk's contents.
+type Disk struct {
+	Creator  string
+	Header   Header
+	Regions  []Region
+	Metadata Metadata
+
+	r       io.ReaderAt
+	bat     []uint64
+	parent  io.ReaderAt
+	closers []io.Closer
+}
+
+// Open opens the VHDX image held by r.
+//
+// If the image is a differencing disk, its parent must be provided by
+// calling SetParent before data is read from blocks that are not present in
+// the image. OpenFile locates parents automatically.
+func Open(r io.ReaderAt) (*Disk, error) {
+	ident := make([]byte, 520)
+	if err := readFull(r, ident, 0); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, ErrNotVHDX
+		}
+		return nil, err
+	}
+	if string(ident[0:8]) != "vhdxfile" {
+		return nil, ErrNotVHDX
+	}
+	d := &Disk{
+		Creator: strings.TrimRight(utf16le.Decode(ident[8:520]), "\x00"),
+		r:       r,
+	}
+
+	if err := d.readHeader(); err != nil {
+		return nil, err
+	}
+	if err := d.readRegions(); err != nil {
+		return nil, err
+	}
+
+	var bat, metadata *Region
+	for i, region := range d.Regions {
+		switch region.GUID {
+		case RegionBAT:
+			bat = &d.Regions[i]
+		case RegionMetadata:
+			metadata = &d.Regions[i]
+		default:
+			if region.Required {
+				return nil, ErrUnsupported
+			}
+		}
+	}
+	if bat == nil || metadata == nil || metadata.Length > maxMetadataSize || bat.Length > maxTableSize {
+		return nil, ErrUnsupported
+	}
+
+	data := make([]byte, metadata.Length)
+	if err := readFull(r, data, int64(metadata.FileOffset)); err != nil {
+		return nil, err
+	}
+	m, err := ParseMetadata(data)
+	if err != nil {
+		return nil, err
+	}
+	d.Metadata = m
+
+	// Payload blocks are interleaved with sector bitmap blocks, with one
+	// sector bitmap block following each chunk of payload blocks. A
+	// differencing disk also needs the sector bitmap entry of its last chunk.
+	blocks := (m.VirtualDiskSize + uint64(m.BlockSize) - 1) / uint64(m.BlockSize)
+	if blocks > 0 {
+		last := d.batIndex(blocks - 1)
+		if m.HasParent {
+			last = d.bitmapIndex(blocks - 1)
+		}
+		if last >= uint64(bat.Length/8) {
+			return nil, ErrUnsupported
+		}
+	}
+	table := make([]byte, bat.Length)
+	if err := readFull(r, table, int64(bat.FileOffset)); err != nil {
+		return nil, err
+	}
+	d.bat = make([]uint64, bat.Length/8)
+	for i := range d.bat {
+		d.bat[i] = binary.LittleEndian.Uint64(table[i*8:])
+	}
+
+	return d, nil
+}
+
+// readHeader reads both image headers and selects the current one.
+func (d *Disk) readHeader() error {
+	var (
+		found    bool
+		firstErr error
+	)
+	data := make([]byte, headerSize)
+	for _, offset := range []int64{header1Offset, header2Offset} {
+		err := readFull(d.r, data, offset)
+		var h Header
+		if err == nil {
+			h, err = ParseHeader(data)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if !found || h.SequenceNumber > d.Header.SequenceNumber {
+			d.Header = h
+			found = true
+		}
+	}
+	if !found {
+		return firstErr
+	}
+	return nil
+}
+
+// readRegions reads the region table, falling back to its copy if it is
+// damaged.
+func (d *Disk) readRegions() error {
+	var firstErr error
+	data := make([]byte, regionTableSize)
+	for _, offset := range []int64{region1Offset, region2Offset} {
+		err := readFull(d.r, data, offset)
+		if err == nil {
+			d.Regions, err = ParseRegionTable(data)
+		}
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// OpenFile opens the VHDX image at path. If the image is a differencing
+// disk, its parents are located with its parent locator and opened as
+// well.
+//
+// The returned disk must be closed when it is no longer needed.
+func OpenFile(path string) (*Disk, error) {
+	return openFile(path, 0)
+}
+
+func openFile(path string, depth int) (*Disk, error) {
+	if depth > maxDepth {
+		return nil, ErrUnsupported
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	d, err := Open(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	d.closers = append(d.closers, f)
+
+	if d.Metadata.HasParent {
+		if err := d.openParent(path, depth); err != nil {
+			d.Close()
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// openParent locates and opens the parent of a differencing disk at path.
+func (d *Disk) openParent(path string, depth int) error {
+	dir := filepath.Dir(path)
+	locations := d.ParentPaths()
+
+	var candidates []string
+	for _, location := range locations {
+		p := filepath.FromSlash(strings.ReplaceAll(location, `\`, "/"))
+		if !filepath.IsAbs(p) && !isWindowsAbs(location) {
+			p = filepath.Join(dir, p)
+		}
+		candidates = append(candidates, p)
+	}
+	for _, location := range locations {
+		base := location[strings.LastIndexAny(location, `\/`)+1:]
+		candidates = append(candidates, filepath.Join(dir, base))
+	}
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err != nil {
+			continue
+		}
+		parent, err := openFile(candidate, depth+1)
+		if err != nil {
+			return err
+		}
+		if !d.linked(parent) {
+			parent.Close()
+			return ErrParentMismatch
+		}
+		d.SetParent(parent)
+		d.closers = append(d.closers, parent)
+		return nil
+	}
+
+	return ErrParentNotFound
+}
+
+// linked returns true if parent has the data write GUID recorded in the
+// parent locator of d.
+func (d *Disk) linked(parent *Disk) bool {
+	for _, key := range []string{KeyParentLinkage, KeyParentLinkage2} {
+		if id, err := partition.ParseGUID(d.Metadata.ParentLocator[key]); err == nil && id == parent.Header.DataWriteGUID {
+			return true
+		}
+	}
+	return false
+}
+
+// isWindowsAbs returns true if path is an absolute Windows path.
+func isWindowsAbs(path string) bool {
+	return len(path) >= 3 && path[1] == ':' && (path[2] == '\\' || path[2] == '/') || strings.HasPrefix(path, `\\`)
+}
+
+// Size returns the size of the virtual disk in bytes.
+func (d *Disk) Size() int64 {
+	return int64(d.Metadata.VirtualDiskSize)
+}
+
+// LogPending returns true if the image holds log entries that have not been
+// applied. Reads do not reflect changes held in the log.
+func (d *Disk) LogPending() bool {
+	return !d.Header.LogGUID.IsZero()
+}
+
+// Parent returns the parent of a differencing disk, or nil if it has not
+// been provided.
+func (d *Disk) Parent() io.ReaderAt {
+	return d.parent
+}
+
+// SetParent provides the parent of a differencing disk. Data for blocks and
+// sectors that are not present in d is read from parent.
+func (d *Disk) SetParent(parent io.ReaderAt) {
+	d.parent = parent
+}
+
+// ParentPaths returns the locations of the parent of a differencing disk
+// that are recorded in its parent locator. The relative path is returned
+// before absolute paths.
+func (d *Disk) ParentPaths() []string {
+	var paths []string
+	for _, key := range []string{KeyRelativePath, KeyAbsoluteWin32Path, KeyVolumePath} {
+		if path := d.Metadata.ParentLocator[key]; path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// Close closes the files opened by OpenFile, including those of parent
+// disks.
+func (d *Disk) Close() (err error) {
+	for _, c := range d.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	d.closers = nil
+	return
+}
+
+// batIndex returns the index of the block allocation table entry for a
+// payload block.
+func (d *Disk) batIndex(block uint64) uint64 {
+	return block + block/d.Metadata.chunkRatio()
+}
+
+// bitmapIndex returns the index of the block allocation table entry for
+// the sector bitmap block that covers a payload block.
+func (d *Disk) bitmapIndex(block uint64) uint64 {
+	ratio := d.Metadata.chunkRatio()
+	return block/ratio*(ratio+1) + ratio
+}
+
+// ReadAt reads len(b) bytes from the virtual disk at offset off.
+func (d *Disk) ReadAt(b []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("vhdx: negative offset")
+	}
+	size := d.Size()
+	if off >= size {
+		return 0, io.EOF
+	}
+	if remaining := size - off; int64(len(b)) > remaining {
+		b = b[:remaining]
+		err = io.EOF
+	}
+
+	blockSize := int64(d.Metadata.BlockSize)
+	for n < len(b) {
+		pos := off + int64(n)
+		within := pos % blockSize
+		chunk := b[n:]
+		if int64(len(chunk)) > blockSize-within {
+			chunk = chunk[:blockSize-within]
+		}
+		if rerr := d.readBlock(chunk, uint64(pos/blockSize), within, pos); rerr != nil {
+			return n, rerr
+		}
+		n += len(chunk)
+	}
+	return n, err
+}
+
+// readBlock reads b from the given offset within a payload block. The
+// offset of the data within the virtual disk is needed to read from the
+// parent.
+func (d *Disk) readBlock(b []byte, block uint64, within, off int64) error {
+	entry := d.bat[d.batIndex(block)]
+	start := int64(entry>>20) << 20
+
+	switch entry & 7 {
+	case blockFullyPresent:
+		return readFull(d.r, b, start+within)
+	case blockPartiallyPresent:
+		if !d.Metadata.HasParent {
+			return ErrUnsupported
+		}
+	case blockNotPresent:
+		return d.readParent(b, off)
+	default:
+		clear(b)
+		return nil
+	}
+
+	// Sectors of a partially present block that are not marked in its
+	// sector bitmap are read from the parent
+	bitmapEntry := d.bat[d.bitmapIndex(block)]
+	if bitmapEntry&7 != blockFullyPresent {
+		return d.readParent(b, off)
+	}
+	sectorSize := int64(d.Metadata.LogicalSectorSize)
+	base := int64(block%d.Metadata.chunkRatio())*int64(d.Metadata.BlockSize) + within
+	first := base / sectorSize / 8 * 8
+	last := (base + int64(len(b)) - 1) / sectorSize
+	bitmap := make([]byte, (last-first)/8+1)
+	if err := readFull(d.r, bitmap, int64(bitmapEntry>>20)<<20+first/8); err != nil {
+		return err
+	}
+	present := func(i int64) bool {
+		s := (base+i)/sectorSize - first
+		return bitmap[s/8]&(1<<uint(s%8)) != 0
+	}
+
+	for i := int64(0); i < int64(len(b)); {
+		state := present(i)
+		end := i + sectorSize - (within+i)%sectorSize
+		for end < int64(len(b)) && present(end) == state {
+			end += sectorSize
+		}
+		if end > int64(len(b)) {
+			end = int64(len(b))
+		}
+		var err error
+		if state {
+			err = readFull(d.r, b[i:end], start+within+i)
+		} else {
+			err = d.readParent(b[i:end], off+i)
+		}
+		if err != nil {
+			return err
+		}
+		i = end
+	}
+	return nil
+}
+
+// readParent reads b from the parent disk. Disks without a parent return
+// zeros.
+func (d *Disk) readParent(b []byte, off int64) error {
+	if !d.Metadata.HasParent {
+		clear(b)
+		return nil
+	}
+	if d.parent == nil {
+		return ErrNoParent
+	}
+	return readFull(d.parent, b, off)
+}
+
+// readFull reads len(b) bytes from r at offset off. A short read is
+// reported as io.ErrUnexpectedEOF, because it indicates a truncated image.
+func readFull(r io.ReaderAt, b []byte, off int64) error {
+	n, err := r.ReadAt(b, off)
+	if n == len(b) {
+		return nil
+	}
+	if err == io.EOF || err == nil {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,14 @@
+// Package vhdx reads virtual hard disk images in the VHDX format used by
+// Hyper-V.
+//
+// Fixed, dynamic and differencing disks are supported. An opened Disk is an
+// io.ReaderAt over the virtual disk's contents, which can be handed to the
+// partition package or to a file system reader without first converting the
+// image to a raw disk.
+//
+// Pending log entries are not replayed. Images of disks that were not shut
+// down cleanly are read as they were before the log was applied, which is
+// reported by Disk.LogPending.
+//
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-vhdx
+package vhdx
@@ -0,0 +1,119 @@
+package vhdx
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+
+	"github.com/gentlemanautomaton/volmgmt/partition"
+)
+
+const (
+	headerSize      = 4 << 10
+	regionTableSize = 64 << 10
+	maxRegions      = 2047
+
+	header1Offset = 64 << 10
+	header2Offset = 128 << 10
+	region1Offset = 192 << 10
+	region2Offset = 256 << 10
+)
+
+var (
+	// ErrNotVHDX is returned when a file is not a VHDX image.
+	ErrNotVHDX = errors.New("not a VHDX image")
+
+	// ErrChecksum is returned when a VHDX structure fails its checksum.
+	ErrChecksum = errors.New("VHDX checksum mismatch")
+
+	// ErrUnsupported is returned when a VHDX image uses a feature or layout
+	// that is not supported.
+	ErrUnsupported = errors.New("unsupported VHDX image")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Region GUIDs.
+var (
+	RegionBAT      = partition.MustParseGUID("2DC27766-F623-4200-9D64-115E9BFD4A08")
+	RegionMetadata = partition.MustParseGUID("8B7CA206-4790-4B9A-B8FE-575F050F886E")
+)
+
+// Header is a VHDX image header. Each image holds two headers, and the
+// valid header with the greater sequence number is current.
+type Header struct {
+	SequenceNumber uint64
+	FileWriteGUID  partition.GUID
+	DataWriteGUID  partition.GUID
+	LogGUID        partition.GUID
+	LogVersion     uint16
+	Version        uint16
+	LogLength      uint32
+	LogOffset      uint64
+}
+
+// ParseHeader parses and validates the VHDX header held in data.
+func ParseHeader(data []byte) (Header, error) {
+	if len(data) < headerSize || string(data[0:4]) != "head" {
+		return Header{}, ErrNotVHDX
+	}
+	if !validChecksum(data[:headerSize]) {
+		return Header{}, ErrChecksum
+	}
+	h := Header{
+		SequenceNumber: binary.LittleEndian.Uint64(data[8:]),
+		LogVersion:     binary.LittleEndian.Uint16(data[64:]),
+		Version:        binary.LittleEndian.Uint16(data[66:]),
+		LogLength:      binary.LittleEndian.Uint32(data[68:]),
+		LogOffset:      binary.LittleEndian.Uint64(data[72:]),
+	}
+	copy(h.FileWriteGUID[:], data[16:32])
+	copy(h.DataWriteGUID[:], data[32:48])
+	copy(h.LogGUID[:], data[48:64])
+	if h.Version != 1 {
+		return Header{}, ErrUnsupported
+	}
+	return h, nil
+}
+
+// Region is an entry in the region table, which locates the block
+// allocation table and metadata within the image.
+type Region struct {
+	GUID       partition.GUID
+	FileOffset uint64
+	Length     uint32
+	Required   bool
+}
+
+// ParseRegionTable parses and validates the region table held in data.
+func ParseRegionTable(data []byte) ([]Region, error) {
+	if len(data) < regionTableSize || string(data[0:4]) != "regi" {
+		return nil, ErrNotVHDX
+	}
+	if !validChecksum(data[:regionTableSize]) {
+		return nil, ErrChecksum
+	}
+	count := binary.LittleEndian.Uint32(data[8:])
+	if count > maxRegions {
+		return nil, ErrUnsupported
+	}
+	regions := make([]Region, count)
+	for i := range regions {
+		b := data[16+i*32:]
+		copy(regions[i].GUID[:], b[0:16])
+		regions[i].FileOffset = binary.LittleEndian.Uint64(b[16:])
+		regions[i].Length = binary.LittleEndian.Uint32(b[24:])
+		regions[i].Required = binary.LittleEndian.Uint32(b[28:])&1 != 0
+	}
+	return regions, nil
+}
+
+// validChecksum verifies the CRC-32C checksum held at offset 4 of data,
+// which is calculated with the checksum field set to zero.
+func validChecksum(data []byte) bool {
+	want := binary.LittleEndian.Uint32(data[4:])
+	crc := crc32.Update(0, castagnoli, data[:4])
+	crc = crc32.Update(crc, castagnoli, []byte{0, 0, 0, 0})
+	crc = crc32.Update(crc, castagnoli, data[8:])
+	return crc == want
+}
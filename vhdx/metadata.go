@@ -0,0 +1,164 @@
+package vhdx
+
+import (
+	"encoding/binary"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/utf16le"
+	"github.com/gentlemanautomaton/volmgmt/partition"
+)
+
+const maxMetadataEntries = 2047
+
+// Metadata item GUIDs.
+var (
+	ItemFileParameters     = partition.MustParseGUID("CAA16737-FA36-4D43-B3B6-33F0AA44E76B")
+	ItemVirtualDiskSize    = partition.MustParseGUID("2FA54224-CD1B-4876-B211-5DBED83BF4B8")
+	ItemVirtualDiskID      = partition.MustParseGUID("BECA12AB-B2E6-4523-93EF-C309E000C746")
+	ItemLogicalSectorSize  = partition.MustParseGUID("8141BF1D-A96F-4709-BA47-F233A8FAAB5F")
+	ItemPhysicalSectorSize = partition.MustParseGUID("CDA348C7-445D-4471-9CC9-E9885251C556")
+	ItemParentLocator      = partition.MustParseGUID("A8D35F2D-B30B-454D-ABF7-D3D84834AB0C")
+
+	// LocatorVHDX is the parent locator type for VHDX parents.
+	LocatorVHDX = partition.MustParseGUID("B04AEFB7-D19E-4A81-B789-25B8E9445913")
+)
+
+// Parent locator keys.
+const (
+	KeyParentLinkage     = "parent_linkage"
+	KeyParentLinkage2    = "parent_linkage2"
+	KeyRelativePath      = "relative_path"
+	KeyVolumePath        = "volume_path"
+	KeyAbsoluteWin32Path = "absolute_win32_path"
+)
+
+// Metadata holds the metadata items of a VHDX image.
+type Metadata struct {
+	BlockSize            uint32
+	LeaveBlocksAllocated bool
+	HasParent            bool
+	VirtualDiskSize      uint64
+	VirtualDiskID        partition.GUID
+	LogicalSectorSize    uint32
+	PhysicalSectorSize   uint32
+
+	// ParentLocatorType and ParentLocator hold the parent locator of a
+	// differencing disk, which maps keys such as KeyRelativePath to their
+	// values.
+	ParentLocatorType partition.GUID
+	ParentLocator     map[string]string
+}
+
+// ParseMetadata parses the metadata region held in data.
+func ParseMetadata(data []byte) (Metadata, error) {
+	if len(data) < 32 || string(data[0:8]) != "metadata" {
+		return Metadata{}, ErrNotVHDX
+	}
+	count := int(binary.LittleEndian.Uint16(data[10:]))
+	if count > maxMetadataEntries || 32+count*32 > len(data) {
+		return Metadata{}, ErrUnsupported
+	}
+
+	var m Metadata
+	found := make(map[partition.GUID]bool)
+	for i := 0; i < count; i++ {
+		b := data[32+i*32:]
+		var id partition.GUID
+		copy(id[:], b[0:16])
+		offset := binary.LittleEndian.Uint32(b[16:])
+		length := binary.LittleEndian.Uint32(b[20:])
+		required := binary.LittleEndian.Uint32(b[24:])&4 != 0
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return Metadata{}, ErrUnsupported
+		}
+		item := data[offset : offset+length]
+
+		switch id {
+		case ItemFileParameters:
+			if len(item) < 8 {
+				return Metadata{}, ErrUnsupported
+			}
+			m.BlockSize = binary.LittleEndian.Uint32(item[0:])
+			flags := binary.LittleEndian.Uint32(item[4:])
+			m.LeaveBlocksAllocated = flags&1 != 0
+			m.HasParent = flags&2 != 0
+		case ItemVirtualDiskSize:
+			if len(item) < 8 {
+				return Metadata{}, ErrUnsupported
+			}
+			m.VirtualDiskSize = binary.LittleEndian.Uint64(item)
+		case ItemVirtualDiskID:
+			if len(item) < 16 {
+				return Metadata{}, ErrUnsupported
+			}
+			copy(m.VirtualDiskID[:], item)
+		case ItemLogicalSectorSize:
+			if len(item) < 4 {
+				return Metadata{}, ErrUnsupported
+			}
+			m.LogicalSectorSize = binary.LittleEndian.Uint32(item)
+		case ItemPhysicalSectorSize:
+			if len(item) < 4 {
+				return Metadata{}, ErrUnsupported
+			}
+			m.PhysicalSectorSize = binary.LittleEndian.Uint32(item)
+		case ItemParentLocator:
+			if err := m.parseParentLocator(item); err != nil {
+				return Metadata{}, err
+			}
+		default:
+			if required {
+				return Metadata{}, ErrUnsupported
+			}
+			continue
+		}
+		found[id] = true
+	}
+
+	for _, id := range []partition.GUID{ItemFileParameters, ItemVirtualDiskSize, ItemLogicalSectorSize} {
+		if !found[id] {
+			return Metadata{}, ErrUnsupported
+		}
+	}
+	if m.BlockSize < 1<<20 || m.BlockSize > 256<<20 || m.BlockSize&(m.BlockSize-1) != 0 {
+		return Metadata{}, ErrUnsupported
+	}
+	if m.LogicalSectorSize != 512 && m.LogicalSectorSize != 4096 {
+		return Metadata{}, ErrUnsupported
+	}
+	if m.HasParent && !found[ItemParentLocator] {
+		return Metadata{}, ErrUnsupported
+	}
+
+	return m, nil
+}
+
+func (m *Metadata) parseParentLocator(data []byte) error {
+	if len(data) < 20 {
+		return ErrUnsupported
+	}
+	copy(m.ParentLocatorType[:], data[0:16])
+	count := int(binary.LittleEndian.Uint16(data[18:]))
+	if 20+count*12 > len(data) {
+		return ErrUnsupported
+	}
+	m.ParentLocator = make(map[string]string, count)
+	for i := 0; i < count; i++ {
+		b := data[20+i*12:]
+		keyOffset := int(binary.LittleEndian.Uint32(b[0:]))
+		valueOffset := int(binary.LittleEndian.Uint32(b[4:]))
+		keyLength := int(binary.LittleEndian.Uint16(b[8:]))
+		valueLength := int(binary.LittleEndian.Uint16(b[10:]))
+		if keyOffset+keyLength > len(data) || valueOffset+valueLength > len(data) {
+			return ErrUnsupported
+		}
+		key := utf16le.Decode(data[keyOffset : keyOffset+keyLength])
+		m.ParentLocator[key] = utf16le.Decode(data[valueOffset : valueOffset+valueLength])
+	}
+	return nil
+}
+
+// chunkRatio returns the number of payload blocks that are described by
+// each sector bitmap block.
+func (m Metadata) chunkRatio() uint64 {
+	return (1 << 23) * uint64(m.LogicalSectorSize) / uint64(m.BlockSize)
+}
@@ -0,0 +1,288 @@
+package vhdx_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/gentlemanautomaton/volmgmt/partition"
+	"github.com/gentlemanautomaton/volmgmt/vhdx"
+)
+
+const (
+	mb        = 1 << 20
+	blockSize = mb
+	diskSize  = 4 * blockSize
+	ratio     = (1 << 23) * 512 / blockSize
+)
+
+const (
+	stateNotPresent       = 0
+	stateZero             = 2
+	stateFullyPresent     = 6
+	statePartiallyPresent = 7
+)
+
+var (
+	parentWrite = partition.MustParseGUID("{0D0D0D0D-1111-2222-3333-444444444444}")
+	childWrite  = partition.MustParseGUID("{0E0E0E0E-5555-6666-7777-888888888888}")
+)
+
+// image builds a VHDX image with a metadata region at 1 MiB, a block
+// allocation table at 2 MiB and payload from 4 MiB.
+type image struct {
+	data []byte
+	next int // Next free megabyte
+}
+
+func utf16LE(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	return b
+}
+
+func putChecksum(data []byte) {
+	binary.LittleEndian.PutUint32(data[4:], 0)
+	binary.LittleEndian.PutUint32(data[4:], crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)))
+}
+
+// newImage returns an image of a disk. If locator is not nil, the disk is
+// a differencing disk with the given parent locator entries.
+func newImage(dataWrite partition.GUID, locator map[string]string) *image {
+	img := &image{data: make([]byte, 4*mb), next: 4}
+	copy(img.data, "vhdxfile")
+	copy(img.data[8:], utf16LE("test"))
+
+	for i, offset := range []int{64 << 10, 128 << 10} {
+		h := img.data[offset : offset+4096]
+		copy(h, "head")
+		binary.LittleEndian.PutUint64(h[8:], uint64(i+1))
+		copy(h[32:], dataWrite[:])
+		binary.LittleEndian.PutUint16(h[66:], 1)
+		putChecksum(h)
+	}
+
+	for _, offset := range []int{192 << 10, 256 << 10} {
+		r := img.data[offset : offset+64<<10]
+		copy(r, "regi")
+		binary.LittleEndian.PutUint32(r[8:], 2)
+		copy(r[16:], vhdx.RegionBAT[:])
+		binary.LittleEndian.PutUint64(r[32:], 2*mb)
+		binary.LittleEndian.PutUint32(r[40:], mb)
+		binary.LittleEndian.PutUint32(r[44:], 1)
+		copy(r[48:], vhdx.RegionMetadata[:])
+		binary.LittleEndian.PutUint64(r[64:], mb)
+		binary.LittleEndian.PutUint32(r[72:], mb)
+		binary.LittleEndian.PutUint32(r[76:], 1)
+		putChecksum(r)
+	}
+
+	m := img.data[mb : 2*mb]
+	copy(m, "metadata")
+	var flags uint32
+	if locator != nil {
+		flags = 2
+	}
+	params := binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, blockSize), flags)
+	items := []struct {
+		id   partition.GUID
+		data []byte
+	}{
+		{vhdx.ItemFileParameters, params},
+		{vhdx.ItemVirtualDiskSize, binary.LittleEndian.AppendUint64(nil, diskSize)},
+		{vhdx.ItemLogicalSectorSize, binary.LittleEndian.AppendUint32(nil, 512)},
+		{vhdx.ItemPhysicalSectorSize, binary.LittleEndian.AppendUint32(nil, 4096)},
+	}
+	if locator != nil {
+		l := make([]byte, 20+len(locator)*12)
+		copy(l, vhdx.LocatorVHDX[:])
+		binary.LittleEndian.PutUint16(l[18:], uint16(len(locator)))
+		i := 0
+		for key, value := range locator {
+			k, v := utf16LE(key), utf16LE(value)
+			e := l[20+i*12:]
+			binary.LittleEndian.PutUint32(e[0:], uint32(len(l)))
+			binary.LittleEndian.PutUint32(e[4:], uint32(len(l)+len(k)))
+			binary.LittleEndian.PutUint16(e[8:], uint16(len(k)))
+			binary.LittleEndian.PutUint16(e[10:], uint16(len(v)))
+			l = append(append(l, k...), v...)
+			i++
+		}
+		items = append(items, struct {
+			id   partition.GUID
+			data []byte
+		}{vhdx.ItemParentLocator, l})
+	}
+	binary.LittleEndian.PutUint16(m[10:], uint16(len(items)))
+	offset := 64 << 10
+	for i, item := range items {
+		e := m[32+i*32:]
+		copy(e, item.id[:])
+		binary.LittleEndian.PutUint32(e[16:], uint32(offset))
+		binary.LittleEndian.PutUint32(e[20:], uint32(len(item.data)))
+		copy(m[offset:], item.data)
+		offset += len(item.data)
+	}
+
+	return img
+}
+
+// setEntry sets a block allocation table entry.
+func (img *image) setEntry(index int, state uint64, content []byte) {
+	entry := state
+	if content != nil {
+		entry |= uint64(img.next) << 20
+		img.data = append(img.data, make([]byte, mb)...)
+		copy(img.data[img.next*mb:], content)
+		img.next++
+	}
+	binary.LittleEndian.PutUint64(img.data[2*mb+index*8:], entry)
+}
+
+// pattern returns n bytes of recognizable content.
+func pattern(n int, seed byte) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = seed + byte(i/512)
+	}
+	return b
+}
+
+func readAll(t *testing.T, d *vhdx.Disk) []byte {
+	t.Helper()
+	b := make([]byte, d.Size())
+	if _, err := d.ReadAt(b, 0); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDynamic(t *testing.T) {
+	content := pattern(blockSize, 7)
+	img := newImage(parentWrite, nil)
+	img.setEntry(1, stateFullyPresent, content)
+	img.setEntry(2, stateZero, nil)
+
+	d, err := vhdx.Open(bytes.NewReader(img.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Creator != "test" || d.Header.SequenceNumber != 2 || d.LogPending() {
+		t.Errorf("creator %q, sequence %d, log pending %t", d.Creator, d.Header.SequenceNumber, d.LogPending())
+	}
+	if d.Size() != diskSize || d.Metadata.BlockSize != blockSize || d.Metadata.PhysicalSectorSize != 4096 || d.Metadata.HasParent {
+		t.Errorf("unexpected metadata: %+v", d.Metadata)
+	}
+
+	want := make([]byte, diskSize)
+	copy(want[blockSize:], content)
+	if !bytes.Equal(readAll(t, d), want) {
+		t.Error("dynamic disk content differs")
+	}
+
+	// A damaged header or region table is replaced by its copy
+	img.data[128<<10+100]++
+	img.data[192<<10+100]++
+	d, err = vhdx.Open(bytes.NewReader(img.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Header.SequenceNumber != 1 {
+		t.Errorf("sequence %d, want 1", d.Header.SequenceNumber)
+	}
+	if _, err := vhdx.ParseHeader(img.data[128<<10 : 132<<10]); err != vhdx.ErrChecksum {
+		t.Errorf("ParseHeader returned %v, want %v", err, vhdx.ErrChecksum)
+	}
+}
+
+func TestDifferencing(t *testing.T) {
+	dir := t.TempDir()
+
+	base := pattern(diskSize, 1)
+	parent := newImage(parentWrite, nil)
+	for i := 0; i < 4; i++ {
+		parent.setEntry(i, stateFullyPresent, base[i*blockSize:(i+1)*blockSize])
+	}
+	if err := os.WriteFile(filepath.Join(dir, "parent.vhdx"), parent.data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The child holds sectors 0 and 2 of its first block, all of its
+	// second block and zeros for its third block
+	changed := pattern(blockSize, 100)
+	bitmap := make([]byte, mb)
+	bitmap[0] = 0x05
+	child := newImage(childWrite, map[string]string{
+		vhdx.KeyParentLinkage:     parentWrite.String(),
+		vhdx.KeyRelativePath:      `.\parent.vhdx`,
+		vhdx.KeyAbsoluteWin32Path: `C:\VMs\parent.vhdx`,
+	})
+	child.setEntry(0, statePartiallyPresent, changed)
+	child.setEntry(1, stateFullyPresent, changed)
+	child.setEntry(2, stateZero, nil)
+	child.setEntry(3, stateNotPresent, nil)
+	child.setEntry(ratio, stateFullyPresent, bitmap)
+	path := filepath.Join(dir, "child.vhdx")
+	if err := os.WriteFile(path, child.data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := vhdx.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if paths := d.ParentPaths(); len(paths) != 2 || paths[0] != `.\parent.vhdx` {
+		t.Errorf("ParentPaths() = %q", paths)
+	}
+
+	want := append([]byte(nil), base...)
+	copy(want[0:512], changed[0:512])
+	copy(want[1024:1536], changed[1024:1536])
+	copy(want[blockSize:], changed)
+	clear(want[2*blockSize : 3*blockSize])
+	if got := readAll(t, d); !bytes.Equal(got, want) {
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("differencing disk content differs at offset %d", i)
+			}
+		}
+	}
+
+	// Without a parent, reads of sectors that are not present fail
+	orphan, err := vhdx.Open(bytes.NewReader(child.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orphan.ReadAt(make([]byte, 512), 512); err != vhdx.ErrNoParent {
+		t.Errorf("ReadAt without parent returned %v, want %v", err, vhdx.ErrNoParent)
+	}
+
+	// A block allocation table without room for the sector bitmap entry
+	// is rejected
+	short := append([]byte(nil), child.data...)
+	for _, offset := range []int{192 << 10, 256 << 10} {
+		r := short[offset : offset+64<<10]
+		binary.LittleEndian.PutUint32(r[40:], ratio*8)
+		putChecksum(r)
+	}
+	if _, err := vhdx.Open(bytes.NewReader(short)); err != vhdx.ErrUnsupported {
+		t.Errorf("Open with a short table returned %v, want %v", err, vhdx.ErrUnsupported)
+	}
+
+	// A parent with a different data write GUID is rejected
+	other := newImage(childWrite, nil)
+	if err := os.WriteFile(filepath.Join(dir, "parent.vhdx"), other.data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vhdx.OpenFile(path); err != vhdx.ErrParentMismatch {
+		t.Errorf("OpenFile returned %v, want %v", err, vhdx.ErrParentMismatch)
+	}
+}
@@ -0,0 +1,7 @@
+// Package volpath converts volume-relative file paths into absolute paths.
+//
+// Paths produced by the change journal and master file table are relative
+// to the root of the volume they came from. A Formatter turns them into
+// drive letter paths or volume GUID paths by consulting a MountTable, which
+// can be supplied by the caller or retrieved from a live volume.
+package volpath
@@ -0,0 +1,84 @@
+package volpath
+
+import (
+	"errors"
+	"strings"
+)
+
+// LongPathPrefix is the prefix that instructs the Windows file system APIs
+// to skip path normalization, which lifts the MAX_PATH length restriction.
+const LongPathPrefix = `\\?\`
+
+var (
+	// ErrNoVolumeName is returned when a formatter requires a volume name
+	// but one was not provided.
+	ErrNoVolumeName = errors.New("a volume name is required for the requested path mode")
+
+	// ErrNoMountPoint is returned when a drive letter formatter is requested
+	// for a volume that has no mount points.
+	ErrNoMountPoint = errors.New("the volume does not have a mount point")
+)
+
+// Formatter converts volume-relative paths into another form. The zero value
+// is a formatter that returns relative paths unchanged.
+type Formatter struct {
+	// Mode determines the form of the formatted paths.
+	Mode Mode
+
+	// Root is the absolute path of the volume's root directory for the
+	// formatter's mode, including a trailing separator. It is empty for
+	// relative formatters.
+	Root string
+
+	// LongPath causes absolute paths to include the long path prefix.
+	LongPath bool
+}
+
+// New returns a formatter for the volume with the given volume name.
+//
+// Drive letter formatters look up the volume's preferred mount point in
+// mounts. Volume name formatters use the volume name itself. Relative
+// formatters ignore both volumeName and mounts.
+//
+// If longPath is true absolute paths will include LongPathPrefix.
+func New(mode Mode, volumeName string, mounts MountTable, longPath bool) (Formatter, error) {
+	f := Formatter{Mode: mode, LongPath: longPath}
+	switch mode {
+	case Relative:
+	case DriveLetter:
+		if volumeName == "" {
+			return Formatter{}, ErrNoVolumeName
+		}
+		root, ok := mounts.MountPoint(volumeName)
+		if !ok {
+			return Formatter{}, ErrNoMountPoint
+		}
+		f.Root = root
+	case VolumeName:
+		if volumeName == "" {
+			return Formatter{}, ErrNoVolumeName
+		}
+		f.Root = addTrailingSeparator(volumeName)
+	default:
+		return Formatter{}, errors.New("unsupported path mode: " + mode.String())
+	}
+	return f, nil
+}
+
+// Format returns the formatted form of the given volume-relative path.
+//
+// Empty paths are returned unchanged.
+func (f Formatter) Format(path string) string {
+	if path == "" || f.Root == "" {
+		return path
+	}
+	abs := f.Root + strings.TrimLeft(path, `\`)
+	if f.LongPath && !strings.HasPrefix(abs, LongPathPrefix) && !strings.HasPrefix(abs, `\\.\`) {
+		if strings.HasPrefix(abs, `\\`) {
+			// UNC paths take the form \\?\UNC\server\share
+			return LongPathPrefix + `UNC\` + abs[2:]
+		}
+		return LongPathPrefix + abs
+	}
+	return abs
+}
@@ -0,0 +1,74 @@
+package volpath_test
+
+import (
+	"testing"
+
+	"github.com/gentlemanautomaton/volmgmt/volpath"
+)
+
+const (
+	volumeC = `\\?\Volume{11111111-2222-3333-4444-555555555555}\`
+	volumeD = `\\?\Volume{aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee}\`
+	volumeE = `\\?\Volume{99999999-9999-9999-9999-999999999999}\`
+)
+
+var mounts = volpath.MountTable{
+	volumeC: {`C:\`},
+	volumeD: {`E:\Mounts\Data\`, `D:\`},
+	volumeE: nil,
+}
+
+func TestFormatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     volpath.Mode
+		volume   string
+		longPath bool
+		input    string
+		want     string
+	}{
+		{"relative", volpath.Relative, volumeC, false, `Users\Public\a.txt`, `Users\Public\a.txt`},
+		{"relative-long", volpath.Relative, volumeC, true, `Users\Public\a.txt`, `Users\Public\a.txt`},
+		{"drive", volpath.DriveLetter, volumeC, false, `Users\Public\a.txt`, `C:\Users\Public\a.txt`},
+		{"drive-long", volpath.DriveLetter, volumeC, true, `Users\Public\a.txt`, `\\?\C:\Users\Public\a.txt`},
+		{"drive-preferred", volpath.DriveLetter, volumeD, false, `x\y`, `D:\x\y`},
+		{"drive-case", volpath.DriveLetter, `\\?\VOLUME{AAAAAAAA-BBBB-CCCC-DDDD-EEEEEEEEEEEE}`, false, `x`, `D:\x`},
+		{"volume", volpath.VolumeName, volumeC, false, `Users\a.txt`, volumeC + `Users\a.txt`},
+		{"volume-long", volpath.VolumeName, volumeC, true, `Users\a.txt`, volumeC + `Users\a.txt`},
+		{"volume-trim", volpath.VolumeName, volumeC[:len(volumeC)-1], false, `\Users\a.txt`, volumeC + `Users\a.txt`},
+		{"empty", volpath.DriveLetter, volumeC, true, ``, ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := volpath.New(tt.mode, tt.volume, mounts, tt.longPath)
+			if err != nil {
+				t.Fatalf("New returned an error: %v", err)
+			}
+			if got := f.Format(tt.input); got != tt.want {
+				t.Errorf("Format(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatterErrors(t *testing.T) {
+	if _, err := volpath.New(volpath.DriveLetter, volumeE, mounts, false); err != volpath.ErrNoMountPoint {
+		t.Errorf("unmounted volume: got %v, want %v", err, volpath.ErrNoMountPoint)
+	}
+	if _, err := volpath.New(volpath.VolumeName, "", mounts, false); err != volpath.ErrNoVolumeName {
+		t.Errorf("missing volume name: got %v, want %v", err, volpath.ErrNoVolumeName)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, mode := range []volpath.Mode{volpath.Relative, volpath.DriveLetter, volpath.VolumeName} {
+		parsed, err := volpath.ParseMode(mode.String())
+		if err != nil || parsed != mode {
+			t.Errorf("ParseMode(%q) = %v, %v", mode.String(), parsed, err)
+		}
+	}
+	if _, err := volpath.ParseMode("bogus"); err == nil {
+		t.Error("ParseMode accepted an unknown mode")
+	}
+}
@@ -0,0 +1,44 @@
+package volpath
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mode determines the form of the paths produced by a formatter.
+type Mode int
+
+// Path formatting modes.
+const (
+	Relative    Mode = iota // Users\Public\file.txt
+	DriveLetter             // C:\Users\Public\file.txt
+	VolumeName              // \\?\Volume{xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx}\Users\Public\file.txt
+)
+
+// ParseMode interprets the given string as a path formatting mode.
+func ParseMode(mode string) (Mode, error) {
+	switch strings.ToLower(mode) {
+	case "", "relative", "rel":
+		return Relative, nil
+	case "drive", "driveletter", "letter", "mount":
+		return DriveLetter, nil
+	case "volume", "volumename", "guid":
+		return VolumeName, nil
+	default:
+		return Relative, fmt.Errorf("unsupported or unknown path mode: %s", mode)
+	}
+}
+
+// String returns a string representation of the mode.
+func (m Mode) String() string {
+	switch m {
+	case Relative:
+		return "relative"
+	case DriveLetter:
+		return "drive"
+	case VolumeName:
+		return "volume"
+	default:
+		return fmt.Sprintf("mode(%d)", int(m))
+	}
+}
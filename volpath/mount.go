@@ -0,0 +1,78 @@
+package volpath
+
+import (
+	"sort"
+	"strings"
+)
+
+// MountTable maps volume names to the set of paths at which each volume is
+// mounted. Volume names are also known as volume GUID paths and are of this
+// form:
+//
+//	\\?\Volume{xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx}\
+//
+// Mount points are drive letter roots like "C:\" or folder mounts like
+// "D:\Mounts\Data\".
+type MountTable map[string][]string
+
+// MountPoints returns the mount points for the given volume name. Volume
+// names are compared without regard to case or trailing separators.
+func (t MountTable) MountPoints(volumeName string) []string {
+	key := normalizeVolumeName(volumeName)
+	for name, points := range t {
+		if normalizeVolumeName(name) == key {
+			return points
+		}
+	}
+	return nil
+}
+
+// MountPoint returns the preferred mount point for the given volume name.
+//
+// Drive letter roots are preferred over folder mounts. When there are
+// several candidates of the same kind the shortest one wins, with ties
+// broken alphabetically. If the volume has no mount points ok will be false.
+func (t MountTable) MountPoint(volumeName string) (mountPoint string, ok bool) {
+	points := append([]string(nil), t.MountPoints(volumeName)...)
+	if len(points) == 0 {
+		return "", false
+	}
+	sort.Slice(points, func(i, j int) bool {
+		a, b := points[i], points[j]
+		if ar, br := isDriveRoot(a), isDriveRoot(b); ar != br {
+			return ar
+		}
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return strings.ToUpper(a) < strings.ToUpper(b)
+	})
+	return addTrailingSeparator(points[0]), true
+}
+
+// isDriveRoot returns true if s is a drive letter root such as "C:\".
+func isDriveRoot(s string) bool {
+	switch len(s) {
+	case 2:
+		return s[1] == ':'
+	case 3:
+		return s[1] == ':' && s[2] == '\\'
+	default:
+		return false
+	}
+}
+
+// normalizeVolumeName returns an upper case copy of name with a single
+// trailing separator.
+func normalizeVolumeName(name string) string {
+	return addTrailingSeparator(strings.ToUpper(strings.TrimRight(name, `\`)))
+}
+
+// addTrailingSeparator adds a backslash to the end of the given string if it
+// isn't present already.
+func addTrailingSeparator(s string) string {
+	if s == "" || strings.HasSuffix(s, `\`) {
+		return s
+	}
+	return s + `\`
+}
@@ -1,3 +1,5 @@
+//go:build windows
+
 package volume
 
 import (
@@ -12,6 +14,7 @@ import (
 	"github.com/gentlemanautomaton/volmgmt/mountapi"
 	"github.com/gentlemanautomaton/volmgmt/storageapi"
 	"github.com/gentlemanautomaton/volmgmt/usn"
+	"github.com/gentlemanautomaton/volmgmt/volpath"
 	"github.com/gentlemanautomaton/volmgmt/volumeapi"
 )
 
@@ -199,6 +202,43 @@ func (v *Volume) Paths() ([]string, error) {
 	return volumeapi.GetVolumePathNamesForVolumeName(name)
 }
 
+// Mounts returns a mount table holding the volume's mount points.
+func (v *Volume) Mounts() (volpath.MountTable, error) {
+	name, err := v.Name()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := volumeapi.GetVolumePathNamesForVolumeName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return volpath.MountTable{name: paths}, nil
+}
+
+// PathFormatter returns a path formatter that converts volume-relative
+// paths on the volume into paths of the given mode.
+func (v *Volume) PathFormatter(mode volpath.Mode, longPath bool) (volpath.Formatter, error) {
+	if mode == volpath.Relative {
+		return volpath.Formatter{LongPath: longPath}, nil
+	}
+
+	name, err := v.Name()
+	if err != nil {
+		return volpath.Formatter{}, err
+	}
+
+	var mounts volpath.MountTable
+	if mode == volpath.DriveLetter {
+		if mounts, err = v.Mounts(); err != nil {
+			return volpath.Formatter{}, err
+		}
+	}
+
+	return volpath.New(mode, name, mounts, longPath)
+}
+
 // Handle returns the system handle of the volume.
 func (v *Volume) Handle() syscall.Handle {
 	return v.h.Handle()
@@ -0,0 +1,23 @@
+// Package vss reads Volume Shadow Copy snapshots directly from the blocks
+// of an NTFS volume.
+//
+// The Volume Shadow Copy Service preserves earlier versions of a volume by
+// copying each 16 KiB block to a store before it is first overwritten. The
+// stores and the catalog that lists them are held within the volume, so
+// the snapshots can be recovered from a disk image on any platform.
+//
+// Each Snapshot implements io.ReaderAt and presents the volume as it was
+// when the snapshot was created. It can be opened with the ntfs package
+// like any other volume. Blocks that were not preserved by the snapshot or
+// a later one are read from the current volume.
+//
+// The store bitmap, which marks the blocks that were unused when a snapshot
+// was created, is not consulted. Those blocks also read from the current
+// volume rather than as zeros, so free space may not match the volume as it
+// was at the time.
+//
+// The format is undocumented. This package follows the description
+// published by the libvshadow project.
+//
+// https://github.com/libyal/libvshadow/blob/main/documentation/Volume%20Shadow%20Snapshot%20(VSS)%20format.asciidoc
+package vss
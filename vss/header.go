@@ -0,0 +1,110 @@
+package vss
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gentlemanautomaton/volmgmt/partition"
+)
+
+var (
+	// ErrNoShadowCopies is returned when a volume does not have a volume
+	// shadow copy header.
+	ErrNoShadowCopies = errors.New("volume does not have shadow copies")
+
+	// ErrInvalidBlock is returned when a catalog or store block is damaged.
+	ErrInvalidBlock = errors.New("invalid volume shadow copy block")
+)
+
+// Identifier is the GUID found at the start of every volume shadow copy
+// header and block.
+var Identifier = partition.MustParseGUID("{3808876B-C176-4E48-B7AE-04046E6CC752}")
+
+// HeaderOffset is the offset of the volume shadow copy header within an
+// NTFS volume.
+const HeaderOffset = 0x1E00
+
+// BlockSize is the size of catalog blocks, store blocks and the volume
+// blocks they preserve.
+const BlockSize = 0x4000
+
+// blockHeaderSize is the size of the header of catalog and store blocks.
+const blockHeaderSize = 128
+
+// Record types.
+const (
+	recordVolumeHeader   = 1
+	recordCatalog        = 2
+	recordStoreIndex     = 3
+	recordStoreHeader    = 4
+	recordStoreRangeList = 5
+	recordStoreBitmap    = 6
+)
+
+// VolumeHeader is the volume shadow copy header of a volume.
+type VolumeHeader struct {
+	Version       uint32
+	CatalogOffset int64
+	MaxSize       int64
+	VolumeID      partition.GUID
+	StorageID     partition.GUID // The volume holding the stores
+}
+
+// ParseVolumeHeader parses the volume shadow copy header found at
+// HeaderOffset. It returns ErrNoShadowCopies if the header is absent or
+// the volume has no catalog.
+func ParseVolumeHeader(data []byte) (VolumeHeader, error) {
+	if len(data) < 0x60 {
+		return VolumeHeader{}, ErrNoShadowCopies
+	}
+	var id partition.GUID
+	copy(id[:], data)
+	if id != Identifier || binary.LittleEndian.Uint32(data[0x14:]) != recordVolumeHeader {
+		return VolumeHeader{}, ErrNoShadowCopies
+	}
+	h := VolumeHeader{
+		Version:       binary.LittleEndian.Uint32(data[0x10:]),
+		CatalogOffset: int64(binary.LittleEndian.Uint64(data[0x30:])),
+		MaxSize:       int64(binary.LittleEndian.Uint64(data[0x38:])),
+	}
+	copy(h.VolumeID[:], data[0x40:])
+	copy(h.StorageID[:], data[0x50:])
+	if h.CatalogOffset == 0 {
+		return VolumeHeader{}, ErrNoShadowCopies
+	}
+	return h, nil
+}
+
+// blockHeader is the header of a catalog or store block.
+type blockHeader struct {
+	Version        uint32
+	Type           uint32
+	RelativeOffset int64
+	CurrentOffset  int64
+	NextOffset     int64 // Zero for the last block of a list
+}
+
+// parseBlockHeader parses the header of a catalog or store block and
+// verifies that it has the expected record type.
+func parseBlockHeader(data []byte, typ uint32) (blockHeader, error) {
+	if len(data) < blockHeaderSize {
+		return blockHeader{}, ErrInvalidBlock
+	}
+	var id partition.GUID
+	copy(id[:], data)
+	h := blockHeader{
+		Version:        binary.LittleEndian.Uint32(data[0x10:]),
+		Type:           binary.LittleEndian.Uint32(data[0x14:]),
+		RelativeOffset: int64(binary.LittleEndian.Uint64(data[0x18:])),
+		CurrentOffset:  int64(binary.LittleEndian.Uint64(data[0x20:])),
+		NextOffset:     int64(binary.LittleEndian.Uint64(data[0x28:])),
+	}
+	if id != Identifier {
+		return blockHeader{}, ErrInvalidBlock
+	}
+	if h.Type != typ {
+		return blockHeader{}, fmt.Errorf("%w: record type %d, expected %d", ErrInvalidBlock, h.Type, typ)
+	}
+	return h, nil
+}
@@ -0,0 +1,282 @@
+package vss
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/utf16le"
+	"github.com/gentlemanautomaton/volmgmt/partition"
+)
+
+var errNegativeOffset = errors.New("negative offset")
+
+// Block descriptor flags.
+const (
+	blockForwarder = 0x1 // The block is held by a later store at another offset
+	blockOverlay   = 0x2 // The block holds some of the sectors of a volume block
+	blockNotUsed   = 0x4
+)
+
+const (
+	blockDescriptorSize = 32
+	sectorSize          = BlockSize / 32 // Each bit of an overlay bitmap covers a sector
+)
+
+// Snapshot is a shadow copy of a volume. It implements io.ReaderAt, which
+// reads the volume as it was when the snapshot was created. The store
+// bitmap is not consulted; see the package documentation.
+type Snapshot struct {
+	Index            int // Position in Volume.Snapshots
+	StoreID          partition.GUID
+	CopyID           partition.GUID // Shadow copy ID, as listed by vssadmin
+	SetID            partition.GUID
+	Created          time.Time
+	VolumeSize       int64
+	Attributes       uint32
+	OperatingMachine string
+	ServiceMachine   string
+
+	vol             *Volume
+	headerOffset    int64
+	blockListOffset int64
+	rangeListOffset int64
+	bitmapOffset    int64
+
+	once   sync.Once
+	blocks map[int64]*block
+	err    error
+}
+
+// block describes how a store holds a volume block.
+type block struct {
+	data      int64 // Offset of the preserved copy, if present
+	present   bool
+	forward   int64 // Offset of the block in the next store, if forwarded
+	forwarded bool
+	overlays  []overlay
+}
+
+// overlay holds some of the sectors of a volume block.
+type overlay struct {
+	data   int64
+	bitmap uint32
+}
+
+// readStoreHeader reads the store header, which identifies the snapshot.
+func (s *Snapshot) readStoreHeader(buf []byte) error {
+	if _, err := s.vol.r.ReadAt(buf, s.headerOffset); err != nil {
+		return fmt.Errorf("store header at %d: %w", s.headerOffset, err)
+	}
+	if _, err := parseBlockHeader(buf, recordStoreHeader); err != nil {
+		return fmt.Errorf("store header at %d: %w", s.headerOffset, err)
+	}
+	info := buf[blockHeaderSize:]
+	copy(s.CopyID[:], info[0x10:])
+	copy(s.SetID[:], info[0x20:])
+	s.Attributes = binary.LittleEndian.Uint32(info[0x38:])
+
+	rest := info[0x40:]
+	for _, name := range []*string{&s.OperatingMachine, &s.ServiceMachine} {
+		if len(rest) < 2 {
+			break
+		}
+		length := int(binary.LittleEndian.Uint16(rest))
+		if 2+length > len(rest) {
+			break
+		}
+		*name = utf16le.Decode(rest[2 : 2+length])
+		rest = rest[2+length:]
+	}
+	return nil
+}
+
+// load reads the block list of the store.
+func (s *Snapshot) load() error {
+	s.once.Do(func() {
+		s.blocks = make(map[int64]*block)
+		buf := make([]byte, BlockSize)
+		offset := s.blockListOffset
+		visited := make(map[int64]bool)
+		for offset != 0 {
+			if visited[offset] {
+				s.err = fmt.Errorf("store block list at %d: %w: loop in block list", offset, ErrInvalidBlock)
+				return
+			}
+			visited[offset] = true
+			if _, err := s.vol.r.ReadAt(buf, offset); err != nil {
+				s.err = fmt.Errorf("store block list at %d: %w", offset, err)
+				return
+			}
+			header, err := parseBlockHeader(buf, recordStoreIndex)
+			if err != nil {
+				s.err = fmt.Errorf("store block list at %d: %w", offset, err)
+				return
+			}
+			for pos := blockHeaderSize; pos+blockDescriptorSize <= BlockSize; pos += blockDescriptorSize {
+				s.addDescriptor(buf[pos : pos+blockDescriptorSize])
+			}
+			offset = header.NextOffset
+		}
+	})
+	return s.err
+}
+
+// addDescriptor adds a block descriptor from the store's block list.
+func (s *Snapshot) addDescriptor(d []byte) {
+	original := int64(binary.LittleEndian.Uint64(d[0x00:]))
+	relative := int64(binary.LittleEndian.Uint64(d[0x08:]))
+	data := int64(binary.LittleEndian.Uint64(d[0x10:]))
+	flags := binary.LittleEndian.Uint32(d[0x18:])
+	bitmap := binary.LittleEndian.Uint32(d[0x1C:])
+	if original == 0 && relative == 0 && data == 0 && flags == 0 {
+		return
+	}
+	if flags&blockNotUsed != 0 || original%BlockSize != 0 {
+		return
+	}
+
+	b, ok := s.blocks[original]
+	if !ok {
+		b = new(block)
+		s.blocks[original] = b
+	}
+	switch {
+	case flags&blockOverlay != 0:
+		b.overlays = append(b.overlays, overlay{data: data, bitmap: bitmap})
+	case flags&blockForwarder != 0:
+		b.forward, b.forwarded = relative, true
+	default:
+		b.data, b.present = data, true
+	}
+}
+
+// Size returns the size of the volume when the snapshot was created.
+func (s *Snapshot) Size() int64 {
+	return s.VolumeSize
+}
+
+// Blocks returns the offsets of the volume blocks whose contents were
+// preserved by the snapshot or a later one, in ascending order. These are
+// the only blocks whose contents may differ from the current volume.
+func (s *Snapshot) Blocks() ([]int64, error) {
+	seen := make(map[int64]bool)
+	for _, later := range s.vol.Snapshots[s.Index:] {
+		if err := later.load(); err != nil {
+			return nil, err
+		}
+		for offset := range later.blocks {
+			seen[offset] = true
+		}
+	}
+	offsets := make([]int64, 0, len(seen))
+	for offset := range seen {
+		if offset < s.VolumeSize {
+			offsets = append(offsets, offset)
+		}
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	return offsets, nil
+}
+
+// ReadAt reads len(p) bytes from the snapshot starting at byte offset off.
+func (s *Snapshot) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errNegativeOffset
+	}
+	if off >= s.VolumeSize {
+		return 0, io.EOF
+	}
+	if remaining := s.VolumeSize - off; int64(len(p)) > remaining {
+		p = p[:remaining]
+		err = io.EOF
+	}
+
+	var buf []byte
+	for n < len(p) {
+		pos := off + int64(n)
+		start := pos / BlockSize * BlockSize
+
+		// Whole blocks are read directly into p
+		if pos == start && len(p)-n >= BlockSize {
+			if rerr := s.readBlock(p[n:n+BlockSize], start); rerr != nil {
+				return n, rerr
+			}
+			n += BlockSize
+			continue
+		}
+
+		if buf == nil {
+			buf = make([]byte, BlockSize)
+		}
+		if rerr := s.readBlock(buf, start); rerr != nil {
+			return n, rerr
+		}
+		n += copy(p[n:], buf[pos-start:])
+	}
+	return n, err
+}
+
+// readBlock reads the volume block at offset as it was when the snapshot
+// was created.
+//
+// The block is looked up in the store of the snapshot and then in each
+// later store, because a block is preserved only by the first snapshot to
+// be created after it was last written. If no store holds it, the block
+// has not changed and is read from the volume.
+func (s *Snapshot) readBlock(buf []byte, offset int64) error {
+	var covered uint32 // Sectors that have been read from overlays
+
+	for _, store := range s.vol.Snapshots[s.Index:] {
+		if err := store.load(); err != nil {
+			return err
+		}
+		b, ok := store.blocks[offset]
+		if !ok {
+			continue
+		}
+		for _, o := range b.overlays {
+			for i := 0; i < 32; i++ {
+				bit := uint32(1) << i
+				if o.bitmap&bit == 0 || covered&bit != 0 {
+					continue
+				}
+				if _, err := s.vol.r.ReadAt(buf[i*sectorSize:(i+1)*sectorSize], o.data+int64(i*sectorSize)); err != nil {
+					return fmt.Errorf("store block at %d: %w", o.data, err)
+				}
+				covered |= bit
+			}
+		}
+		if b.present {
+			return s.readSectors(buf, b.data, covered)
+		}
+		if b.forwarded {
+			offset = b.forward
+		}
+	}
+	return s.readSectors(buf, offset, covered)
+}
+
+// readSectors reads the sectors of the block at offset that are not
+// covered into buf. Each run of consecutive sectors is read at once.
+func (s *Snapshot) readSectors(buf []byte, offset int64, covered uint32) error {
+	for i := 0; i < 32; {
+		if covered&(1<<i) != 0 {
+			i++
+			continue
+		}
+		end := i + 1
+		for end < 32 && covered&(1<<end) == 0 {
+			end++
+		}
+		if _, err := s.vol.r.ReadAt(buf[i*sectorSize:end*sectorSize], offset+int64(i*sectorSize)); err != nil && err != io.EOF {
+			return err
+		}
+		i = end
+	}
+	return nil
+}
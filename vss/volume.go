@@ -0,0 +1,117 @@
+package vss
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/filetime"
+	"github.com/gentlemanautomaton/volmgmt/partition"
+)
+
+// Catalog entry types.
+const (
+	catalogEmpty    = 0
+	catalogUnused   = 1
+	catalogInfo     = 2
+	catalogLocation = 3
+)
+
+const catalogEntrySize = 128
+
+// Volume provides access to the shadow copies of a volume.
+type Volume struct {
+	Header    VolumeHeader
+	Snapshots []*Snapshot // Oldest first
+
+	r io.ReaderAt
+}
+
+// Open reads the volume shadow copy header and catalog of the NTFS volume
+// held in r. It returns ErrNoShadowCopies if the volume has none.
+func Open(r io.ReaderAt) (*Volume, error) {
+	data := make([]byte, 512)
+	if _, err := r.ReadAt(data, HeaderOffset); err != nil {
+		return nil, fmt.Errorf("unable to read volume shadow copy header: %w", err)
+	}
+	header, err := ParseVolumeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Volume{Header: header, r: r}
+	if err := v.readCatalog(); err != nil {
+		return nil, err
+	}
+	if len(v.Snapshots) == 0 {
+		return nil, ErrNoShadowCopies
+	}
+	return v, nil
+}
+
+// readCatalog reads the catalog, which lists the stores of the volume.
+func (v *Volume) readCatalog() error {
+	snapshots := make(map[partition.GUID]*Snapshot)
+	get := func(id partition.GUID) *Snapshot {
+		s, ok := snapshots[id]
+		if !ok {
+			s = &Snapshot{StoreID: id, vol: v}
+			snapshots[id] = s
+		}
+		return s
+	}
+
+	block := make([]byte, BlockSize)
+	offset := v.Header.CatalogOffset
+	visited := make(map[int64]bool)
+	for offset != 0 {
+		if visited[offset] {
+			return fmt.Errorf("catalog block at %d: %w: loop in block list", offset, ErrInvalidBlock)
+		}
+		visited[offset] = true
+		if _, err := v.r.ReadAt(block, offset); err != nil {
+			return fmt.Errorf("catalog block at %d: %w", offset, err)
+		}
+		header, err := parseBlockHeader(block, recordCatalog)
+		if err != nil {
+			return fmt.Errorf("catalog block at %d: %w", offset, err)
+		}
+		for pos := blockHeaderSize; pos+catalogEntrySize <= BlockSize; pos += catalogEntrySize {
+			entry := block[pos : pos+catalogEntrySize]
+			var id partition.GUID
+			copy(id[:], entry[0x10:])
+			switch binary.LittleEndian.Uint64(entry) {
+			case catalogInfo:
+				s := get(id)
+				s.VolumeSize = int64(binary.LittleEndian.Uint64(entry[0x08:]))
+				s.Created = filetime.ToTime(binary.LittleEndian.Uint64(entry[0x30:]))
+			case catalogLocation:
+				s := get(id)
+				s.blockListOffset = int64(binary.LittleEndian.Uint64(entry[0x08:]))
+				s.headerOffset = int64(binary.LittleEndian.Uint64(entry[0x20:]))
+				s.rangeListOffset = int64(binary.LittleEndian.Uint64(entry[0x28:]))
+				s.bitmapOffset = int64(binary.LittleEndian.Uint64(entry[0x30:]))
+			}
+		}
+		offset = header.NextOffset
+	}
+
+	for _, s := range snapshots {
+		if s.headerOffset == 0 || s.blockListOffset == 0 {
+			// Stores that are being created or deleted are incomplete
+			continue
+		}
+		if err := s.readStoreHeader(block); err != nil {
+			return err
+		}
+		v.Snapshots = append(v.Snapshots, s)
+	}
+	sort.Slice(v.Snapshots, func(i, j int) bool {
+		return v.Snapshots[i].Created.Before(v.Snapshots[j].Created)
+	})
+	for i, s := range v.Snapshots {
+		s.Index = i
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package vss_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/gentlemanautomaton/volmgmt/internal/filetime"
+	"github.com/gentlemanautomaton/volmgmt/partition"
+	"github.com/gentlemanautomaton/volmgmt/vss"
+)
+
+const blocks = 64
+
+var (
+	storeA = partition.MustParseGUID("{0A7C43D2-0C5E-4A39-9F1D-6A6E1B3C4D01}")
+	storeB = partition.MustParseGUID("{0A7C43D2-0C5E-4A39-9F1D-6A6E1B3C4D02}")
+	copyA  = partition.MustParseGUID("{5B1E2F90-7A11-4C3D-8E2F-1A2B3C4D5E01}")
+	copyB  = partition.MustParseGUID("{5B1E2F90-7A11-4C3D-8E2F-1A2B3C4D5E02}")
+
+	createdA = time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	createdB = time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC)
+)
+
+func block(n int64) int64 { return n * vss.BlockSize }
+
+// blockHeader writes the header of a catalog or store block.
+func blockHeader(b []byte, typ uint32, offset int64) {
+	copy(b, vss.Identifier[:])
+	binary.LittleEndian.PutUint32(b[0x10:], 1)
+	binary.LittleEndian.PutUint32(b[0x14:], typ)
+	binary.LittleEndian.PutUint64(b[0x20:], uint64(offset))
+}
+
+// storeHeader writes a store header block.
+func storeHeader(img []byte, offset int64, copyID partition.GUID, machine string) {
+	b := img[offset : offset+vss.BlockSize]
+	blockHeader(b, 4, offset)
+	info := b[128:]
+	copy(info[0x10:], copyID[:])
+	name := utf16.Encode([]rune(machine))
+	binary.LittleEndian.PutUint16(info[0x40:], uint16(len(name)*2))
+	for i, c := range name {
+		binary.LittleEndian.PutUint16(info[0x42+i*2:], c)
+	}
+}
+
+// descriptor is an entry in a store's block list.
+type descriptor struct {
+	original, relative, data int64
+	flags, bitmap            uint32
+}
+
+// blockList writes a store block list.
+func blockList(img []byte, offset int64, list ...descriptor) {
+	b := img[offset : offset+vss.BlockSize]
+	blockHeader(b, 3, offset)
+	for i, d := range list {
+		e := b[128+i*32:]
+		binary.LittleEndian.PutUint64(e[0x00:], uint64(d.original))
+		binary.LittleEndian.PutUint64(e[0x08:], uint64(d.relative))
+		binary.LittleEndian.PutUint64(e[0x10:], uint64(d.data))
+		binary.LittleEndian.PutUint32(e[0x18:], d.flags)
+		binary.LittleEndian.PutUint32(e[0x1C:], d.bitmap)
+	}
+}
+
+func fill(img []byte, offset int64, c byte) {
+	copy(img[offset:offset+vss.BlockSize], bytes.Repeat([]byte{c}, vss.BlockSize))
+}
+
+// image builds a volume with two shadow copies. Store A is the older.
+//
+//	Block 20 was preserved by A.
+//	Block 21 was preserved by B.
+//	Block 22 had its first sector preserved by an overlay in A.
+//	Block 23 was forwarded by A to block 24, which was preserved by B.
+func image() []byte {
+	img := make([]byte, block(blocks))
+	for n := int64(16); n < blocks; n++ {
+		fill(img, block(n), 'C')
+	}
+
+	h := img[vss.HeaderOffset:]
+	copy(h, vss.Identifier[:])
+	binary.LittleEndian.PutUint32(h[0x10:], 1)
+	binary.LittleEndian.PutUint32(h[0x14:], 1)
+	binary.LittleEndian.PutUint64(h[0x30:], uint64(block(1)))
+
+	catalog := img[block(1):block(2)]
+	blockHeader(catalog, 2, block(1))
+	entry := func(i int, typ uint64, id partition.GUID) []byte {
+		e := catalog[128+i*128 : 128+(i+1)*128]
+		binary.LittleEndian.PutUint64(e, typ)
+		copy(e[0x10:], id[:])
+		return e
+	}
+	// Store B is listed first to verify that snapshots are sorted
+	e := entry(0, 2, storeB)
+	binary.LittleEndian.PutUint64(e[0x08:], uint64(block(blocks)))
+	binary.LittleEndian.PutUint64(e[0x30:], filetime.FromTime(createdB))
+	e = entry(1, 3, storeB)
+	binary.LittleEndian.PutUint64(e[0x08:], uint64(block(5)))
+	binary.LittleEndian.PutUint64(e[0x20:], uint64(block(4)))
+	e = entry(2, 2, storeA)
+	binary.LittleEndian.PutUint64(e[0x08:], uint64(block(blocks)))
+	binary.LittleEndian.PutUint64(e[0x30:], filetime.FromTime(createdA))
+	e = entry(3, 3, storeA)
+	binary.LittleEndian.PutUint64(e[0x08:], uint64(block(3)))
+	binary.LittleEndian.PutUint64(e[0x20:], uint64(block(2)))
+
+	storeHeader(img, block(2), copyA, "host-a")
+	blockList(img, block(3),
+		descriptor{original: block(20), data: block(10)},
+		descriptor{original: block(22), data: block(12), flags: 2, bitmap: 1},
+		descriptor{original: block(23), relative: block(24), flags: 1},
+	)
+	storeHeader(img, block(4), copyB, "host-b")
+	blockList(img, block(5),
+		descriptor{original: block(21), data: block(11)},
+		descriptor{original: block(24), data: block(13)},
+	)
+
+	fill(img, block(10), 'A')
+	fill(img, block(11), 'B')
+	fill(img, block(12), 'O')
+	fill(img, block(13), 'F')
+	return img
+}
+
+func TestOpen(t *testing.T) {
+	v, err := vss.Open(bytes.NewReader(image()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Snapshots) != 2 {
+		t.Fatalf("%d snapshots, want 2", len(v.Snapshots))
+	}
+	for i, want := range []struct {
+		store, copy partition.GUID
+		created     time.Time
+		machine     string
+	}{
+		{storeA, copyA, createdA, "host-a"},
+		{storeB, copyB, createdB, "host-b"},
+	} {
+		s := v.Snapshots[i]
+		if s.Index != i || s.StoreID != want.store || s.CopyID != want.copy {
+			t.Errorf("snapshot %d: index %d, store %s, copy %s", i, s.Index, s.StoreID, s.CopyID)
+		}
+		if !s.Created.Equal(want.created) {
+			t.Errorf("snapshot %d: created %v, want %v", i, s.Created, want.created)
+		}
+		if s.OperatingMachine != want.machine {
+			t.Errorf("snapshot %d: machine %q, want %q", i, s.OperatingMachine, want.machine)
+		}
+		if s.Size() != block(blocks) {
+			t.Errorf("snapshot %d: size %d", i, s.Size())
+		}
+	}
+}
+
+func TestNoShadowCopies(t *testing.T) {
+	_, err := vss.Open(bytes.NewReader(make([]byte, block(1))))
+	if !errors.Is(err, vss.ErrNoShadowCopies) {
+		t.Fatalf("got %v, want ErrNoShadowCopies", err)
+	}
+}
+
+func TestCatalogLoop(t *testing.T) {
+	img := image()
+	binary.LittleEndian.PutUint64(img[block(1)+0x28:], uint64(block(1)))
+	_, err := vss.Open(bytes.NewReader(img))
+	if !errors.Is(err, vss.ErrInvalidBlock) {
+		t.Fatalf("got %v, want ErrInvalidBlock", err)
+	}
+}
+
+func TestBlockListLoop(t *testing.T) {
+	img := image()
+	binary.LittleEndian.PutUint64(img[block(3)+0x28:], uint64(block(3)))
+	v, err := vss.Open(bytes.NewReader(img))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = v.Snapshots[0].ReadAt(make([]byte, 512), block(20))
+	if !errors.Is(err, vss.ErrInvalidBlock) {
+		t.Fatalf("got %v, want ErrInvalidBlock", err)
+	}
+}
+
+func TestReadAt(t *testing.T) {
+	v, err := vss.Open(bytes.NewReader(image()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := v.Snapshots[0], v.Snapshots[1]
+
+	const sector = vss.BlockSize / 32
+	for _, tt := range []struct {
+		name   string
+		s      *vss.Snapshot
+		offset int64
+		want   byte
+	}{
+		{"own store", a, block(20), 'A'},
+		{"unchanged", b, block(20), 'C'},
+		{"later store", a, block(21), 'B'},
+		{"latest store", b, block(21), 'B'},
+		{"overlay", a, block(22), 'O'},
+		{"beyond overlay", a, block(22) + sector, 'C'},
+		{"forwarded", a, block(23), 'F'},
+		{"not forwarded", b, block(23), 'C'},
+		{"forward target", b, block(24), 'F'},
+	} {
+		p := make([]byte, sector)
+		if _, err := tt.s.ReadAt(p, tt.offset); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !bytes.Equal(p, bytes.Repeat([]byte{tt.want}, sector)) {
+			t.Errorf("%s: read %q..., want %q", tt.name, p[:4], tt.want)
+		}
+	}
+
+	// Whole blocks are read directly into the caller's buffer
+	p := make([]byte, vss.BlockSize)
+	if _, err := a.ReadAt(p, block(22)); err != nil {
+		t.Fatal(err)
+	}
+	if p[0] != 'O' || p[sector-1] != 'O' || p[sector] != 'C' || p[vss.BlockSize-1] != 'C' {
+		t.Errorf("whole block read: %q, %q, %q", p[0], p[sector], p[vss.BlockSize-1])
+	}
+
+	// Reads that span blocks
+	p = make([]byte, 2*sector)
+	if _, err := a.ReadAt(p, block(21)-sector); err != nil {
+		t.Fatal(err)
+	}
+	if p[0] != 'A' || p[sector] != 'B' {
+		t.Errorf("spanning read: %q, %q", p[0], p[sector])
+	}
+
+	if n, err := a.ReadAt(p, block(blocks)-sector); n != sector || err != io.EOF {
+		t.Errorf("read at end: %d, %v", n, err)
+	}
+}
+
+func TestOverlaySectors(t *testing.T) {
+	const sector = vss.BlockSize / 32
+	img := image()
+	// Sectors 1, 2 and 31 of block 22 are preserved by the overlay
+	binary.LittleEndian.PutUint32(img[block(3)+128+32+0x1C:], 1<<1|1<<2|1<<31)
+	v, err := vss.Open(bytes.NewReader(img))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := make([]byte, vss.BlockSize)
+	if _, err := v.Snapshots[0].ReadAt(p, block(22)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 32; i++ {
+		want := byte('C')
+		if i == 1 || i == 2 || i == 31 {
+			want = 'O'
+		}
+		if got := p[i*sector:(i+1)*sector]; !bytes.Equal(got, bytes.Repeat([]byte{want}, sector)) {
+			t.Errorf("sector %d: read %q..., want %q", i, got[:4], want)
+		}
+	}
+}
+
+// The store bitmap is not consulted, so blocks read from the current volume
+// whether or not they are marked as used.
+func TestStoreBitmapIgnored(t *testing.T) {
+	for _, c := range []byte{0x00, 0xFF} {
+		img := image()
+		binary.LittleEndian.PutUint64(img[block(1)+128+3*128+0x30:], uint64(block(6)))
+		fill(img, block(6), c)
+		blockHeader(img[block(6):], 6, block(6))
+
+		v, err := vss.Open(bytes.NewReader(img))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := make([]byte, 512)
+		if _, err := v.Snapshots[0].ReadAt(p, block(25)); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p, bytes.Repeat([]byte{'C'}, 512)) {
+			t.Errorf("bitmap of %#02x: read %q..., want %q", c, p[:4], 'C')
+		}
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	v, err := vss.Open(bytes.NewReader(image()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range [][]int64{
+		{block(20), block(21), block(22), block(23), block(24)},
+		{block(21), block(24)},
+	} {
+		got, err := v.Snapshots[i].Blocks()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("snapshot %d: blocks %v, want %v", i, got, want)
+		}
+	}
+}